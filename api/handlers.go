@@ -0,0 +1,463 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"tryffel.net/go/bookmarker/storage"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//Bookmark is json presentation of bookmark
+type Bookmark struct {
+	Id          int               `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Link        string            `json:"link"`
+	Project     string            `json:"project"`
+	Tags        []string          `json:"tags"`
+	Archived    bool              `json:"archived"`
+	State       string            `json:"state"`
+	Priority    int               `json:"priority"`
+	DueAt       *time.Time        `json:"due_at,omitempty"`
+	Notes       string            `json:"notes,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+//BookmarkRequest is a request to create or update bookmark. Fields that are nil are not modified
+type BookmarkRequest struct {
+	Name        *string           `json:"name"`
+	Description *string           `json:"description"`
+	Link        *string           `json:"link"`
+	Project     *string           `json:"project"`
+	Tags        *[]string         `json:"tags"`
+	Archived    *bool             `json:"archived"`
+	State       *string           `json:"state"`
+	Priority    *int              `json:"priority"`
+	DueAt       *time.Time        `json:"due_at"`
+	Notes       *string           `json:"notes"`
+	Metadata    map[string]string `json:"metadata"`
+}
+
+//Project is json presentation of project tree
+type Project struct {
+	Name     string     `json:"name"`
+	FullName string     `json:"full_name"`
+	Count    int        `json:"count"`
+	Total    int        `json:"total"`
+	Children []*Project `json:"children"`
+}
+
+func toBookmark(b *models.Bookmark) *Bookmark {
+	j := &Bookmark{
+		Id:          b.Id,
+		Name:        b.Name,
+		Description: b.Description,
+		Link:        b.Content,
+		Project:     b.Project,
+		Tags:        b.Tags,
+		Archived:    b.Archived,
+		State:       b.ReadState(),
+		Priority:    b.Priority,
+		DueAt:       b.DueAt,
+		Notes:       b.Notes,
+		CreatedAt:   b.CreatedAt,
+		UpdatedAt:   b.UpdatedAt,
+	}
+	if j.Tags == nil {
+		j.Tags = []string{}
+	}
+	if b.Metadata != nil {
+		j.Metadata = map[string]string{}
+		for key, value := range *b.Metadata {
+			if value != "" {
+				j.Metadata[key] = value
+			}
+		}
+	}
+	return j
+}
+
+func toProject(p *models.Project) *Project {
+	j := &Project{
+		Name:     p.Name,
+		FullName: p.FullName(),
+		Count:    p.Count,
+		Total:    p.TotalCount(),
+		Children: make([]*Project, len(p.Children)),
+	}
+	for i, v := range p.Children {
+		j.Children[i] = toProject(v)
+	}
+	return j
+}
+
+//validate returns error message if request has invalid values
+func (r *BookmarkRequest) validate() string {
+	if r.State != nil && !models.IsReadState(*r.State) {
+		return fmt.Sprintf("invalid state '%s', expected unread, reading or read", *r.State)
+	}
+	return ""
+}
+
+//apply sets request fields to bookmark
+func (r *BookmarkRequest) apply(b *models.Bookmark) {
+	if r.Name != nil {
+		b.Name = *r.Name
+	}
+	if r.Description != nil {
+		b.Description = *r.Description
+	}
+	if r.Link != nil {
+		b.Content = *r.Link
+	}
+	if r.Project != nil {
+		b.Project = *r.Project
+	}
+	if r.Tags != nil {
+		b.Tags = *r.Tags
+	}
+	if r.Archived != nil {
+		b.Archived = *r.Archived
+	}
+	if r.State != nil {
+		b.State = *r.State
+	}
+	if r.Priority != nil {
+		b.Priority = *r.Priority
+	}
+	if r.DueAt != nil {
+		b.DueAt = r.DueAt
+	}
+	if r.Notes != nil {
+		b.Notes = *r.Notes
+	}
+	for key, value := range r.Metadata {
+		b.AddMetadata(key, value)
+	}
+	b.LowerName = strings.ToLower(b.Name)
+}
+
+// /api/v1/bookmarks
+func (s *Server) bookmarks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.listBookmarks(w, r)
+	case http.MethodPost:
+		s.createBookmark(w, r)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+// /api/v1/bookmarks/<id>
+func (s *Server) bookmark(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/v1/bookmarks/"))
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodPut, http.MethodDelete:
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodDelete)
+		return
+	}
+
+	b, err := s.store.GetBookmark(id)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("bookmark %d not found", id))
+		return
+	}
+	if err == nil {
+		err = s.store.GetBookmarkMetadata(b)
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJson(w, http.StatusOK, toBookmark(b))
+	case http.MethodPut:
+		s.updateBookmark(w, r, b)
+	case http.MethodDelete:
+		err = s.store.DeleteBookmark(b)
+		if err != nil {
+			internalError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) listBookmarks(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := params.Get("q")
+
+	limit := -1
+	if params.Get("limit") != "" {
+		var err error
+		limit, err = strconv.Atoi(params.Get("limit"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+	}
+
+	filter, err := storage.NewFilter(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid query: %v", err))
+		return
+	}
+
+	var bookmarks []*models.Bookmark
+	if filter.IsPlainQuery() {
+		bookmarks, err = s.store.SearchBookmarks(filter.Query)
+		// full text search highlights results, get plain bookmarks
+		for i := 0; err == nil && i < len(bookmarks); i++ {
+			bookmarks[i], err = s.store.GetBookmark(bookmarks[i].Id)
+		}
+		if err == nil && limit > 0 && len(bookmarks) > limit {
+			bookmarks = bookmarks[:limit]
+		}
+	} else {
+		if query == "" {
+			filter.Clear(s.store.Options().HideArchived)
+		}
+		// sort parameter overrides sort: term of query
+		if sort := params.Get("sort"); sort != "" {
+			field, ok := storage.SortField(sort)
+			if !ok {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid sort field: %s", sort))
+				return
+			}
+			filter.SortField = field
+		}
+		if params.Get("desc") == "true" {
+			filter.SortDir = "DESC"
+		}
+		filter.Limit = limit
+		bookmarks, err = s.store.FilterBookmarks(filter)
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	data := make([]*Bookmark, len(bookmarks))
+	for i, v := range bookmarks {
+		data[i] = toBookmark(v)
+	}
+	writeJson(w, http.StatusOK, data)
+}
+
+func (s *Server) createBookmark(w http.ResponseWriter, r *http.Request) {
+	req := &BookmarkRequest{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid body: %v", err))
+		return
+	}
+	if req.Link == nil || *req.Link == "" {
+		writeError(w, http.StatusBadRequest, "link is required")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	policy := storage.DuplicateCreate
+	if name := r.URL.Query().Get("duplicates"); name != "" {
+		policy, err = storage.ParseDuplicatePolicy(name)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	b := &models.Bookmark{
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	b.FillDefaultMetadata()
+	req.apply(b)
+
+	existing := []*models.Bookmark{}
+	if policy != storage.DuplicateCreate {
+		existing, err = s.store.GetBookmarksByLink(b.Content)
+		if err != nil {
+			internalError(w, err)
+			return
+		}
+	}
+	if len(existing) > 0 {
+		s.saveDuplicate(w, existing[0].Id, b, policy)
+		return
+	}
+
+	if b.Name == "" {
+		b.Name = b.Content
+		b.LowerName = strings.ToLower(b.Name)
+	}
+	err = s.store.NewBookmark(b)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/bookmarks/%d", b.Id))
+	writeJson(w, http.StatusCreated, toBookmark(b))
+}
+
+//saveDuplicate skips or merges bookmark into existing bookmark with same link and writes existing bookmark
+func (s *Server) saveDuplicate(w http.ResponseWriter, id int, b *models.Bookmark, policy storage.DuplicatePolicy) {
+	if policy != storage.DuplicateSkip {
+		duplicates, ok := s.store.(storage.DuplicateStore)
+		if !ok {
+			writeError(w, http.StatusNotImplemented, storage.ErrNotSupported.Error())
+			return
+		}
+		err := duplicates.MergeBookmark(id, b, policy == storage.DuplicateUpdate)
+		if err != nil {
+			internalError(w, err)
+			return
+		}
+	}
+
+	existing, err := s.store.GetBookmark(id)
+	if err == nil {
+		err = s.store.GetBookmarkMetadata(existing)
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJson(w, http.StatusOK, toBookmark(existing))
+}
+
+func (s *Server) updateBookmark(w http.ResponseWriter, r *http.Request, b *models.Bookmark) {
+	req := &BookmarkRequest{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid body: %v", err))
+		return
+	}
+	if req.Link != nil && *req.Link == "" {
+		writeError(w, http.StatusBadRequest, "link cannot be empty")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	req.apply(b)
+	b.UpdatedAt = time.Now()
+	err = s.store.UpdateBookmark(b)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJson(w, http.StatusOK, toBookmark(b))
+}
+
+// /api/v1/tags
+func (s *Server) tags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	tags, err := s.store.GetAllTags()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	data := map[string]int{}
+	if tags != nil {
+		data = *tags
+	}
+	writeJson(w, http.StatusOK, data)
+}
+
+// /api/v1/projects
+func (s *Server) projects(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	projects, err := s.store.GetAllProjects("", false)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	data := make([]*Project, len(projects))
+	for i, v := range projects {
+		data[i] = toProject(v)
+	}
+	writeJson(w, http.StatusOK, data)
+}
+
+// /api/v1/stats
+func (s *Server) stats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	stats, err := s.store.GetStatistics()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJson(w, http.StatusOK, stats)
+}
+
+// /api/v1/metadata/complete?key=<key>&value=<value>
+func (s *Server) completeMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	key := r.URL.Query().Get("key")
+	value := r.URL.Query().Get("value")
+	if key == "" {
+		writeError(w, http.StatusBadRequest, "key is required")
+		return
+	}
+
+	results, err := s.store.SearchKeyValue(key, value)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJson(w, http.StatusOK, results)
+}
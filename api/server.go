@@ -0,0 +1,114 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+//Package api implements local http/json api to bookmarks, e.g. for browser extensions and editor plugins.
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+	"time"
+	"tryffel.net/go/bookmarker/storage"
+)
+
+//Server serves api
+type Server struct {
+	store storage.BookmarkStore
+	token string
+	mux   *http.ServeMux
+}
+
+//NewServer creates new api server. Every request must have header 'Authorization: Bearer <token>'.
+func NewServer(store storage.BookmarkStore, token string) *Server {
+	s := &Server{
+		store: store,
+		token: token,
+		mux:   http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/api/v1/bookmarks", s.bookmarks)
+	s.mux.HandleFunc("/api/v1/bookmarks/", s.bookmark)
+	s.mux.HandleFunc("/api/v1/tags", s.tags)
+	s.mux.HandleFunc("/api/v1/projects", s.projects)
+	s.mux.HandleFunc("/api/v1/stats", s.stats)
+	s.mux.HandleFunc("/api/v1/metadata/complete", s.completeMetadata)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+	if !s.authorized(r) {
+		writeError(rw, http.StatusUnauthorized, "invalid or missing token")
+	} else {
+		s.mux.ServeHTTP(rw, r)
+	}
+	logrus.Debugf("Api %s %s: %d in %d ms", r.Method, r.URL.Path, rw.status, time.Since(start).Milliseconds())
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return false
+	}
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+	token := strings.TrimPrefix(header, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+//statusWriter records response status for logging
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusWriter) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJson(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(data)
+	if err != nil {
+		logrus.Errorf("Api: write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJson(w, status, &errorResponse{Error: msg})
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
+
+func internalError(w http.ResponseWriter, err error) {
+	logrus.Errorf("Api: %v", err)
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
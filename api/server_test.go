@@ -0,0 +1,320 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"tryffel.net/go/bookmarker/storage"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//newMemoryStore returns empty store for tests
+func newMemoryStore() *storage.MemoryStore {
+	return storage.NewMemoryStore(storage.Options{AutoCompleteMaxResults: 10})
+}
+
+const testToken = "secret"
+
+func request(t *testing.T, s *Server, method, url, token, body string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	r := httptest.NewRequest(method, url, reader)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, data interface{}) {
+	err := json.NewDecoder(w.Body).Decode(data)
+	if err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+}
+
+func TestServer_Auth(t *testing.T) {
+	tests := []struct {
+		name        string
+		serverToken string
+		token       string
+		want        int
+	}{
+		{name: "valid token", serverToken: testToken, token: testToken, want: http.StatusOK},
+		{name: "missing token", serverToken: testToken, token: "", want: http.StatusUnauthorized},
+		{name: "invalid token", serverToken: testToken, token: "invalid", want: http.StatusUnauthorized},
+		{name: "server without token", serverToken: "", token: "", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(newMemoryStore(), tt.serverToken)
+			w := request(t, s, http.MethodGet, "/api/v1/stats", tt.token, "")
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+//mergeStore is MemoryStore that merges duplicate bookmarks
+type mergeStore struct {
+	*storage.MemoryStore
+}
+
+func (m *mergeStore) GetDuplicates() ([][]*models.Bookmark, error) {
+	return nil, storage.ErrNotSupported
+}
+
+func (m *mergeStore) MergeDuplicates(ids []int) (*models.Bookmark, error) {
+	return nil, storage.ErrNotSupported
+}
+
+func (m *mergeStore) MergeBookmark(id int, bookmark *models.Bookmark, overwrite bool) error {
+	existing, err := m.GetBookmark(id)
+	if err != nil {
+		return err
+	}
+	existing.Merge(bookmark, overwrite)
+	bookmark.Id = id
+	return m.UpdateBookmark(existing)
+}
+
+func TestServer_createDuplicates(t *testing.T) {
+	s := NewServer(&mergeStore{MemoryStore: newMemoryStore()}, testToken)
+	w := request(t, s, http.MethodPost, "/api/v1/bookmarks", testToken,
+		`{"name": "golang", "link": "https://golang.org", "description": "go home"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+
+	tests := []struct {
+		name       string
+		duplicates string
+		body       string
+		want       int
+		wantId     int
+		wantName   string
+	}{
+		{name: "skip", duplicates: "skip", body: `{"name": "go", "link": "https://golang.org/"}`,
+			want: http.StatusOK, wantId: 1, wantName: "golang"},
+		{name: "merge", duplicates: "merge", body: `{"name": "go", "link": "https://golang.org", "project": "go"}`,
+			want: http.StatusOK, wantId: 1, wantName: "golang"},
+		{name: "update", duplicates: "update", body: `{"name": "go", "link": "https://golang.org"}`,
+			want: http.StatusOK, wantId: 1, wantName: "go"},
+		{name: "skip new link", duplicates: "skip", body: `{"link": "https://go.dev"}`,
+			want: http.StatusCreated, wantId: 2, wantName: "https://go.dev"},
+		{name: "create", duplicates: "create", body: `{"name": "golang", "link": "https://golang.org"}`,
+			want: http.StatusCreated, wantId: 3, wantName: "golang"},
+		{name: "default", body: `{"name": "golang", "link": "https://golang.org"}`,
+			want: http.StatusCreated, wantId: 4, wantName: "golang"},
+		{name: "invalid", duplicates: "ignore", body: `{"link": "https://golang.org"}`,
+			want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := "/api/v1/bookmarks"
+			if tt.duplicates != "" {
+				url += "?duplicates=" + tt.duplicates
+			}
+			w := request(t, s, http.MethodPost, url, testToken, tt.body)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if tt.wantId == 0 {
+				return
+			}
+			got := &Bookmark{}
+			decode(t, w, got)
+			if got.Id != tt.wantId || got.Name != tt.wantName {
+				t.Errorf("got bookmark %d %s, want %d %s", got.Id, got.Name, tt.wantId, tt.wantName)
+			}
+		})
+	}
+
+	w = request(t, s, http.MethodGet, "/api/v1/bookmarks/1", testToken, "")
+	got := &Bookmark{}
+	decode(t, w, got)
+	if got.Project != "go" || got.Description != "go home" {
+		t.Errorf("merged bookmark = %+v, want project go and description kept", got)
+	}
+
+	s = NewServer(newMemoryStore(), testToken)
+	request(t, s, http.MethodPost, "/api/v1/bookmarks", testToken, `{"link": "https://golang.org"}`)
+	w = request(t, s, http.MethodPost, "/api/v1/bookmarks?duplicates=merge", testToken, `{"link": "https://golang.org"}`)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("merge without duplicate store: status = %d, want %d", w.Code, http.StatusNotImplemented)
+	}
+}
+
+func TestServer_Bookmarks(t *testing.T) {
+	store := newMemoryStore()
+	s := NewServer(store, testToken)
+
+	w := request(t, s, http.MethodPost, "/api/v1/bookmarks", testToken,
+		`{"name": "golang", "link": "https://golang.org", "description": "go home", "tags": ["go", "lang"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+	created := &Bookmark{}
+	decode(t, w, created)
+	if created.Id != 1 || created.Link != "https://golang.org" || created.Name != "golang" {
+		t.Errorf("create: got %+v", created)
+	}
+	if w.Header().Get("Location") != "/api/v1/bookmarks/1" {
+		t.Errorf("create: location = %s", w.Header().Get("Location"))
+	}
+
+	w = request(t, s, http.MethodPost, "/api/v1/bookmarks", testToken, `{"name": "no link"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("create without link: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = request(t, s, http.MethodPut, "/api/v1/bookmarks/1", testToken, `{"project": "go", "tags": ["go"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	updated := &Bookmark{}
+	decode(t, w, updated)
+	if updated.Project != "go" || updated.Name != "golang" || !reflect.DeepEqual(updated.Tags, []string{"go"}) {
+		t.Errorf("update: got %+v", updated)
+	}
+
+	w = request(t, s, http.MethodGet, "/api/v1/bookmarks/1", testToken, "")
+	got := &Bookmark{}
+	decode(t, w, got)
+	if got.Project != "go" {
+		t.Errorf("get: project = %s, want go", got.Project)
+	}
+
+	w = request(t, s, http.MethodPost, "/api/v1/bookmarks", testToken,
+		`{"name": "golang tour", "link": "https://go.dev/tour", "description": "tour"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create second: status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+
+	w = request(t, s, http.MethodGet, "/api/v1/bookmarks?q=name:gol&sort=name&desc=true&limit=10", testToken, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("filter: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	list := []*Bookmark{}
+	decode(t, w, &list)
+	if len(list) != 2 || list[0].Id != 2 || list[1].Id != 1 {
+		t.Errorf("filter: got %+v, want bookmarks 2, 1", list)
+	}
+
+	w = request(t, s, http.MethodGet, "/api/v1/bookmarks?q=name:gol&sort=name&limit=1", testToken, "")
+	list = []*Bookmark{}
+	decode(t, w, &list)
+	if len(list) != 1 || list[0].Id != 1 {
+		t.Errorf("filter with limit: got %+v, want bookmark 1", list)
+	}
+
+	w = request(t, s, http.MethodGet, "/api/v1/bookmarks?q=name:gol%20sort:link", testToken, "")
+	list = []*Bookmark{}
+	decode(t, w, &list)
+	if len(list) != 2 || list[0].Id != 2 {
+		t.Errorf("filter with sort term: got %+v, want bookmark 2 first", list)
+	}
+
+	w = request(t, s, http.MethodGet, "/api/v1/bookmarks?q=home", testToken, "")
+	list = []*Bookmark{}
+	decode(t, w, &list)
+	if len(list) != 1 {
+		t.Errorf("search: got %d bookmarks, want 1", len(list))
+	}
+
+	w = request(t, s, http.MethodGet, "/api/v1/bookmarks?q=archived:maybe", testToken, "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid query: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = request(t, s, http.MethodDelete, "/api/v1/bookmarks/1", testToken, "")
+	if w.Code != http.StatusNoContent {
+		t.Errorf("delete: status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+
+	w = request(t, s, http.MethodGet, "/api/v1/bookmarks/1", testToken, "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("get deleted: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestServer_Tags(t *testing.T) {
+	store := newMemoryStore()
+	store.NewBookmark(&models.Bookmark{Name: "a", Tags: []string{"go", "web"}})
+	store.NewBookmark(&models.Bookmark{Name: "b", Tags: []string{"go"}})
+	s := NewServer(store, testToken)
+
+	w := request(t, s, http.MethodGet, "/api/v1/tags", testToken, "")
+	tags := map[string]int{}
+	decode(t, w, &tags)
+	want := map[string]int{"go": 2, "web": 1}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("tags = %v, want %v", tags, want)
+	}
+
+	w = request(t, s, http.MethodPost, "/api/v1/tags", testToken, "")
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("post tags: status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestServer_Projects(t *testing.T) {
+	store := newMemoryStore()
+	store.NewBookmark(&models.Bookmark{Name: "a", Project: "go"})
+	store.NewBookmark(&models.Bookmark{Name: "b", Project: "go.http"})
+	store.NewBookmark(&models.Bookmark{Name: "c", Project: "go.http"})
+	s := NewServer(store, testToken)
+	w := request(t, s, http.MethodGet, "/api/v1/projects", testToken, "")
+	projects := []*Project{}
+	decode(t, w, &projects)
+	if len(projects) != 1 || projects[0].Name != "go" || projects[0].Total != 3 {
+		t.Fatalf("projects: got %+v", projects)
+	}
+	if len(projects[0].Children) != 1 || projects[0].Children[0].FullName != "go.http" {
+		t.Errorf("projects: got children %+v", projects[0].Children)
+	}
+}
+
+func TestServer_CompleteMetadata(t *testing.T) {
+	store := newMemoryStore()
+	store.NewBookmark(&models.Bookmark{Name: "a", Metadata: &map[string]string{"Author": "John"}})
+	store.NewBookmark(&models.Bookmark{Name: "b", Metadata: &map[string]string{"Author": "joe"}})
+	store.NewBookmark(&models.Bookmark{Name: "c", Metadata: &map[string]string{"Author": "rob"}})
+	s := NewServer(store, testToken)
+	w := request(t, s, http.MethodGet, "/api/v1/metadata/complete?key=Author&value=jo", testToken, "")
+	results := []string{}
+	decode(t, w, &results)
+	want := []string{"joe", "john"}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("complete = %v, want %v", results, want)
+	}
+
+	w = request(t, s, http.MethodGet, "/api/v1/metadata/complete", testToken, "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("complete without key: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -27,6 +27,8 @@ import (
 	"io"
 	"os"
 	"sync"
+	"time"
+	"tryffel.net/go/bookmarker/cli"
 	"tryffel.net/go/bookmarker/config"
 	"tryffel.net/go/bookmarker/storage"
 	"tryffel.net/go/bookmarker/storage/migrations"
@@ -40,8 +42,16 @@ func main() {
 		"for data also. This can be configured from config file.")
 
 	version := flag.Bool("version", false, "Print version info")
+	flag.Usage = func() {
+		cli.Usage(flag.CommandLine.Output())
+		fmt.Fprintf(flag.CommandLine.Output(), "\nFlags:\n")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
+	// Any arguments after flags are cli commands, run without terminal ui
+	headless := flag.NArg() > 0
+
 	if *version {
 		fmt.Printf("%s v%s\n", config.AppName, config.Version)
 		return
@@ -85,15 +95,23 @@ func main() {
 		logrus.Error("failed to open log file: ", err.Error())
 	}
 
-	// put log output to both log file and stdout
-	mw := io.MultiWriter(os.Stdout, file)
+	// put log output to both log file and stdout. Keep stdout clean for cli commands
+	var stdout io.Writer = os.Stdout
+	if headless {
+		stdout = os.Stderr
+	}
+	mw := io.MultiWriter(stdout, file)
 
 	logrus.SetOutput(file)
 	logrus.Infof("############ %s v%s ############", config.AppName, config.Version)
 	logrus.SetLevel(level)
 	logrus.SetOutput(mw)
 
-	db, err := storage.NewDatabase(conf.DbFile())
+	db, err := storage.NewDatabase(conf.DbFile(), storage.Options{
+		FullTextSearch:         conf.EnableFullTextSearch,
+		HideArchived:           conf.HideArchived,
+		AutoCompleteMaxResults: conf.AutoCompleteMaxResults,
+	})
 	defer db.Close()
 	if err != nil {
 		logrus.Fatalf("database connection failed: %v", err)
@@ -118,8 +136,24 @@ func main() {
 		logrus.Fatalf("database migrations failed: %v", err)
 		os.Exit(1)
 	}
+
+	// purge bookmarks that have been in trash longer than retention period, 0 keeps them forever
+	if conf.TrashRetentionDays > 0 {
+		count, err := db.PurgeDeleted(time.Now().AddDate(0, 0, -conf.TrashRetentionDays))
+		if err != nil {
+			logrus.Errorf("purge deleted bookmarks: %v", err)
+		} else if count > 0 {
+			logrus.Infof("Purged %d bookmarks from trash", count)
+		}
+	}
 	logrus.SetOutput(file)
 
+	if headless {
+		code := cli.Run(db, flag.Args(), os.Stdout, os.Stderr)
+		db.Close()
+		os.Exit(code)
+	}
+
 	app := ui.NewWindow(conf.Colors, &conf.Shortcuts, db)
 	err = app.Run()
 	if err != nil {
@@ -0,0 +1,143 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"tryffel.net/go/bookmarker/external"
+	"tryffel.net/go/bookmarker/linkcheck"
+	"tryffel.net/go/bookmarker/storage"
+)
+
+func (c *Cli) archive(args []string) int {
+	fs := c.flags("archive")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	store, ok := c.store.(storage.SnapshotStore)
+	if !ok {
+		return c.fail("archive", storage.ErrNotSupported)
+	}
+	filter, err := c.newFilter(strings.Join(fs.Args(), " "), "", false)
+	if err != nil {
+		return c.invalid("archive", "%v", err)
+	}
+	bookmarks, err := c.query(filter, -1)
+	if err != nil {
+		return c.fail("archive", err)
+	}
+
+	archived := 0
+	failed := 0
+	for _, b := range bookmarks {
+		if !linkcheck.Checkable(b) {
+			continue
+		}
+		snapshot, err := external.ArchivePage(b)
+		if err == nil {
+			var created bool
+			created, err = store.NewSnapshot(snapshot)
+			if err == nil {
+				status := "unchanged"
+				if created {
+					status = "created"
+				}
+				fmt.Fprintf(c.out, "%d\t%d\t%s\t%s\n", b.Id, snapshot.Id, status, b.Content)
+				archived += 1
+				continue
+			}
+		}
+		fmt.Fprintf(c.errOut, "archive %d %s: %v\n", b.Id, b.Content, err)
+		failed += 1
+	}
+	fmt.Fprintf(c.errOut, "archived %d pages, %d failed\n", archived, failed)
+
+	if failed > 0 {
+		return ExitError
+	}
+	if archived == 0 {
+		return ExitNotFound
+	}
+	return ExitOk
+}
+
+func (c *Cli) snapshot(args []string) int {
+	fs := c.flags("snapshot")
+	list := fs.Bool("list", false, "List snapshots of bookmark")
+	html := fs.Bool("html", false, "Print page html instead of readable text")
+	snapshotId := fs.Int("snapshot", 0, "Snapshot id, default is latest snapshot")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		return c.invalid("snapshot", "expected single id")
+	}
+	id, err := parseId(fs.Arg(0))
+	if err != nil {
+		return c.invalid("snapshot", "%v", err)
+	}
+
+	store, ok := c.store.(storage.SnapshotStore)
+	if !ok {
+		return c.fail("snapshot", storage.ErrNotSupported)
+	}
+	snapshots, err := store.GetSnapshots(id)
+	if err != nil {
+		return c.fail("snapshot", err)
+	}
+	if len(snapshots) == 0 {
+		fmt.Fprintf(c.errOut, "snapshot: bookmark %d has no snapshots\n", id)
+		return ExitNotFound
+	}
+
+	if *list {
+		for _, v := range snapshots {
+			fmt.Fprintf(c.out, "%d\t%s\t%s\t%s\n", v.Id, v.CreatedAt.Format("2006-01-02 15:04"), v.Title, v.Url)
+		}
+		return ExitOk
+	}
+
+	selected := snapshots[0].Id
+	if *snapshotId != 0 {
+		selected = 0
+		for _, v := range snapshots {
+			if v.Id == *snapshotId {
+				selected = v.Id
+			}
+		}
+		if selected == 0 {
+			fmt.Fprintf(c.errOut, "snapshot: bookmark %d has no snapshot %d\n", id, *snapshotId)
+			return ExitNotFound
+		}
+	}
+
+	snapshot, err := store.GetSnapshot(selected)
+	if err != nil {
+		return c.fail("snapshot", err)
+	}
+	if *html {
+		_, err = c.out.Write(snapshot.Html)
+	} else {
+		_, err = fmt.Fprintln(c.out, snapshot.Text)
+	}
+	if err != nil {
+		return c.fail("snapshot", err)
+	}
+	return ExitOk
+}
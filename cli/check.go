@@ -0,0 +1,104 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	"tryffel.net/go/bookmarker/config"
+	"tryffel.net/go/bookmarker/linkcheck"
+	"tryffel.net/go/bookmarker/storage"
+)
+
+func (c *Cli) check(args []string) int {
+	fs := c.flags("check")
+	conf := config.Configuration
+	format := fs.String("format", formatTable, "Output format: table, json or tsv")
+	workers := fs.Int("workers", conf.LinkCheckWorkers, "Max number of concurrent requests")
+	timeout := fs.Duration("timeout", time.Duration(conf.LinkCheckTimeout)*time.Second, "Timeout for single link")
+	dead := fs.Bool("dead", false, "Only print dead links")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+	if !validFormat(*format) {
+		return c.invalid("check", "invalid format: %s", *format)
+	}
+
+	store, ok := c.store.(linkcheck.Store)
+	if !ok {
+		return c.fail("check", storage.ErrNotSupported)
+	}
+	filter, err := c.newFilter(strings.Join(fs.Args(), " "), "", false)
+	if err != nil {
+		return c.invalid("check", "%v", err)
+	}
+	bookmarks, err := c.query(filter, -1)
+	if err != nil {
+		return c.fail("check", err)
+	}
+
+	// stop checking on interrupt, links checked so far are still stored
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	checker := linkcheck.NewChecker(*workers, *timeout, time.Duration(conf.LinkCheckHostInterval)*time.Millisecond)
+	checked := 0
+	results, err := checker.Run(ctx, store, bookmarks, func(result *linkcheck.Result) {
+		checked += 1
+		if result.Dead() {
+			fmt.Fprintf(c.errOut, "dead link: %d %s: %s\n", result.Bookmark.Id, result.Bookmark.Content, result.Status)
+		}
+	})
+	if err != nil {
+		return c.fail("check", err)
+	}
+	fmt.Fprintf(c.errOut, "checked %d links\n", checked)
+
+	if *dead {
+		deadResults := results[:0]
+		for _, v := range results {
+			if v.Dead() {
+				deadResults = append(deadResults, v)
+			}
+		}
+		results = deadResults
+	}
+
+	err = writeLinkResults(c.out, results, *format)
+	if err != nil {
+		return c.fail("check", err)
+	}
+	if len(results) == 0 {
+		return ExitNotFound
+	}
+	return ExitOk
+}
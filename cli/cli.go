@@ -0,0 +1,158 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+//Package cli implements headless command line interface for Bookmarker.
+//Commands operate directly on bookmark store and don't need a terminal ui. Commands that need
+//features the store does not implement, like snapshots with MemoryStore, fail with storage.ErrNotSupported.
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"tryffel.net/go/bookmarker/config"
+	"tryffel.net/go/bookmarker/storage"
+)
+
+// Exit codes
+const (
+	// Command succeeded
+	ExitOk = 0
+	// Command failed, e.g. database or io error
+	ExitError = 1
+	// Invalid command, arguments or query
+	ExitUsage = 2
+	// Bookmark was not found or query returned no results
+	ExitNotFound = 3
+)
+
+type command struct {
+	name        string
+	args        string
+	description string
+	run         func(c *Cli, args []string) int
+}
+
+var commands = []command{
+	{"add", "[flags] <link>", "Create new bookmark", (*Cli).add},
+	{"list", "[flags] [query]", "List bookmarks, optionally filtered with query", (*Cli).list},
+	{"search", "[flags] <query>", "Search bookmarks with filter or full text query", (*Cli).search},
+	{"show", "[flags] <id>", "Show bookmark with its metadata", (*Cli).show},
+	{"edit", "[flags] <id>", "Edit bookmark. Only given fields are modified", (*Cli).edit},
+	{"delete", "<id>...", "Move bookmarks to trash", (*Cli).delete},
+	{"tag", "<id> [+tag|-tag|tag]...", "Add (+tag / tag) or remove (-tag) tags", (*Cli).tag},
+	{"tags", "[flags] [tag]", "List tags, or rename, merge or delete tag and its children", (*Cli).tags},
+	{"import", "[flags] <file>", "Import bookmarks from browser or bookmark manager export", (*Cli).importFile},
+	{"export", "[flags] [query]", "Export bookmarks into html, json, csv, markdown or org", (*Cli).export},
+	{"stats", "[flags]", "Print statistics", (*Cli).stats},
+	{"check", "[flags] [query]", "Check links and store link status, e.g. status:dead", (*Cli).check},
+	{"saved", "[flags] [name]", "List saved searches, list bookmarks of saved search, or save or delete it", (*Cli).saved},
+	{"duplicates", "[flags]", "List bookmarks that have same link, or merge them", (*Cli).duplicates},
+	{"archive", "[query]", "Save snapshots of pages with readable text", (*Cli).archive},
+	{"snapshot", "[flags] <id>", "Print latest or given snapshot of bookmark", (*Cli).snapshot},
+	{"serve", "[flags]", "Serve http api until interrupted", (*Cli).serve},
+}
+
+//Cli runs single command against bookmark store
+type Cli struct {
+	store  storage.BookmarkStore
+	out    io.Writer
+	errOut io.Writer
+	// command being run
+	cmd *command
+}
+
+//Run runs command defined in args, where args[0] is command name. Return exit code.
+func Run(store storage.BookmarkStore, args []string, stdout, stderr io.Writer) int {
+	c := &Cli{
+		store:  store,
+		out:    stdout,
+		errOut: stderr,
+	}
+
+	if len(args) == 0 {
+		c.usage()
+		return ExitUsage
+	}
+
+	for i, cmd := range commands {
+		if cmd.name == args[0] {
+			c.cmd = &commands[i]
+			return cmd.run(c, args[1:])
+		}
+	}
+
+	if args[0] == "help" {
+		c.usage()
+		return ExitOk
+	}
+
+	fmt.Fprintf(c.errOut, "unknown command: %s\n", args[0])
+	c.usage()
+	return ExitUsage
+}
+
+//Usage prints available commands to writer
+func Usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s [--config file] <command> [flags] [args]\n", config.AppNameLower)
+	fmt.Fprintf(w, "Without command terminal ui is started.\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %-26s %s\n", cmd.name, cmd.args, cmd.description)
+	}
+	fmt.Fprintf(w, "\nRun '%s <command> -h' for command flags.\n", config.AppNameLower)
+	fmt.Fprintf(w, "Exit codes: %d ok, %d error, %d invalid usage, %d not found / no results\n",
+		ExitOk, ExitError, ExitUsage, ExitNotFound)
+}
+
+func (c *Cli) usage() {
+	Usage(c.errOut)
+}
+
+//flags creates flag set for given command
+func (c *Cli) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.errOut)
+	if c.cmd != nil {
+		cmd := c.cmd
+		fs.Usage = func() {
+			fmt.Fprintf(c.errOut, "Usage: %s %s %s\n%s\n", config.AppNameLower, cmd.name, cmd.args, cmd.description)
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+//parse parses flags and returns exit code if parsing failed
+func (c *Cli) parse(fs *flag.FlagSet, args []string) (int, bool) {
+	err := fs.Parse(args)
+	if err == flag.ErrHelp {
+		return ExitOk, false
+	}
+	if err != nil {
+		return ExitUsage, false
+	}
+	return ExitOk, true
+}
+
+func (c *Cli) fail(cmd string, err error) int {
+	fmt.Fprintf(c.errOut, "%s: %v\n", cmd, err)
+	return ExitError
+}
+
+func (c *Cli) invalid(cmd string, format string, args ...interface{}) int {
+	fmt.Fprintf(c.errOut, "%s: %s\n", cmd, fmt.Sprintf(format, args...))
+	return ExitUsage
+}
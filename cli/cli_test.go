@@ -0,0 +1,148 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"tryffel.net/go/bookmarker/storage"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//run runs command against store and returns exit code, stdout and stderr
+func run(store storage.BookmarkStore, args ...string) (int, string, string) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	code := Run(store, args, stdout, stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_bookmarks(t *testing.T) {
+	store := storage.NewMemoryStore(storage.Options{})
+
+	code, out, errOut := run(store, "add", "-name", "golang", "-project", "Go", "-tags", "go,lang", "https://golang.org")
+	if code != ExitOk || out != "1\n" {
+		t.Fatalf("add = %d, %q, %q, want id 1", code, out, errOut)
+	}
+	code, out, errOut = run(store, "add", "https://golang.org/")
+	if code != ExitOk || out != "1\n" || !strings.Contains(errOut, "skipping") {
+		t.Errorf("add duplicate = %d, %q, %q, want skipped", code, out, errOut)
+	}
+
+	code, _, errOut = run(store, "edit", "-description", "go home", "1")
+	if code != ExitOk {
+		t.Fatalf("edit = %d, %q", code, errOut)
+	}
+	code, out, _ = run(store, "show", "-format", "json", "1")
+	if code != ExitOk {
+		t.Fatalf("show = %d", code)
+	}
+	shown := map[string]interface{}{}
+	err := json.Unmarshal([]byte(out), &shown)
+	if err != nil {
+		t.Fatalf("show: parse json: %v: %s", err, out)
+	}
+	if shown["description"] != "go home" || shown["project"] != "go" {
+		t.Errorf("show = %v", shown)
+	}
+
+	code, out, _ = run(store, "list", "-format", "tsv", "name:gol")
+	if code != ExitOk || !strings.Contains(out, "https://golang.org") {
+		t.Errorf("list = %d, %q", code, out)
+	}
+
+	code, _, _ = run(store, "delete", "1")
+	if code != ExitOk {
+		t.Fatalf("delete = %d", code)
+	}
+	code, _, _ = run(store, "list")
+	if code != ExitNotFound {
+		t.Errorf("list after delete = %d, want %d", code, ExitNotFound)
+	}
+}
+
+//failingStore is MemoryStore that fails to filter bookmarks
+type failingStore struct {
+	*storage.MemoryStore
+}
+
+func (f *failingStore) FilterBookmarks(filter *storage.Filter) ([]*models.Bookmark, error) {
+	return nil, fmt.Errorf("database is locked")
+}
+
+func TestRun_list(t *testing.T) {
+	store := storage.NewMemoryStore(storage.Options{})
+	for _, args := range [][]string{{"-name", "b", "https://a.com"}, {"-name", "a", "https://b.com"}} {
+		code, _, errOut := run(store, append([]string{"add"}, args...)...)
+		if code != ExitOk {
+			t.Fatalf("add = %d, %q", code, errOut)
+		}
+	}
+
+	tests := []struct {
+		args  []string
+		want  int
+		first string
+	}{
+		{args: []string{}, want: ExitOk, first: "https://b.com"},
+		{args: []string{"sort:link"}, want: ExitOk, first: "https://a.com"},
+		{args: []string{"-sort", "name", "sort:link"}, want: ExitOk, first: "https://b.com"},
+		{args: []string{"-sort", "size"}, want: ExitUsage},
+		{args: []string{"archived:maybe"}, want: ExitUsage},
+	}
+	for _, tt := range tests {
+		code, out, errOut := run(store, append([]string{"list", "-format", "tsv"}, tt.args...)...)
+		if code != tt.want {
+			t.Errorf("%v = %d, %q, want %d", tt.args, code, errOut, tt.want)
+			continue
+		}
+		if tt.first != "" && !strings.Contains(strings.SplitN(out, "\n", 2)[0], tt.first) {
+			t.Errorf("%v = %q, want %s first", tt.args, out, tt.first)
+		}
+	}
+
+	code, _, errOut := run(&failingStore{MemoryStore: store}, "list", "name:a")
+	if code != ExitError || !strings.Contains(errOut, "database is locked") {
+		t.Errorf("list with store error = %d, %q, want %d", code, errOut, ExitError)
+	}
+}
+
+func TestRun_notSupported(t *testing.T) {
+	store := storage.NewMemoryStore(storage.Options{})
+	code, _, _ := run(store, "add", "https://golang.org")
+	if code != ExitOk {
+		t.Fatalf("add = %d", code)
+	}
+
+	tests := [][]string{
+		{"add", "-duplicates", "merge", "https://golang.org"},
+		{"duplicates"},
+		{"saved"},
+		{"snapshot", "1"},
+		{"export", "-format", "json"},
+		{"tags", "-unused"},
+	}
+	for _, args := range tests {
+		code, _, errOut := run(store, args...)
+		if code != ExitError || !strings.Contains(errOut, storage.ErrNotSupported.Error()) {
+			t.Errorf("%v = %d, %q, want not supported", args, code, errOut)
+		}
+	}
+}
@@ -0,0 +1,678 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package cli
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"tryffel.net/go/bookmarker/external"
+	"tryffel.net/go/bookmarker/storage"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//keyValues is a repeatable flag of key=value pairs
+type keyValues struct {
+	keys   []string
+	values map[string]string
+}
+
+func (k *keyValues) String() string {
+	pairs := make([]string, len(k.keys))
+	for i, key := range k.keys {
+		pairs[i] = key + "=" + k.values[key]
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (k *keyValues) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("expected key=value, got '%s'", value)
+	}
+	if k.values == nil {
+		k.values = map[string]string{}
+	}
+	if _, ok := k.values[parts[0]]; !ok {
+		k.keys = append(k.keys, parts[0])
+	}
+	k.values[parts[0]] = parts[1]
+	return nil
+}
+
+//bookmarkFlags are flags to set bookmark fields
+type bookmarkFlags struct {
+	name        *string
+	description *string
+	link        *string
+	project     *string
+	tags        *string
+	archived    *bool
+	state       *string
+	priority    *int
+	due         *string
+	notes       *string
+	metadata    *keyValues
+}
+
+func newBookmarkFlags(fs *flag.FlagSet) *bookmarkFlags {
+	b := &bookmarkFlags{
+		name:        fs.String("name", "", "Name"),
+		description: fs.String("description", "", "Description"),
+		link:        fs.String("link", "", "Link"),
+		project:     fs.String("project", "", "Project, e.g. 'project.subproject'"),
+		tags:        fs.String("tags", "", "Comma separated list of tags"),
+		archived:    fs.Bool("archived", false, "Archived"),
+		state:       fs.String("state", "", "Reading state: unread, reading or read"),
+		priority:    fs.Int("priority", 0, "Priority in reading list, higher is more important"),
+		due:         fs.String("due", "", "Due date as yyyy-mm-dd, empty removes due date"),
+		notes:       fs.String("notes", "", "Notes in markdown"),
+		metadata:    &keyValues{},
+	}
+	fs.Var(b.metadata, "meta", "Metadata as key=value, can be repeated")
+	return b
+}
+
+//validate checks values of flags that were given in command line
+func (b *bookmarkFlags) validate(fs *flag.FlagSet) error {
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "state":
+			if !models.IsReadState(*b.state) {
+				err = fmt.Errorf("invalid state '%s', expected unread, reading or read", *b.state)
+			}
+		case "due":
+			_, dueErr := models.ParseDueDate(*b.due)
+			if dueErr != nil {
+				err = dueErr
+			}
+		}
+	})
+	return err
+}
+
+//apply sets fields that were given in command line to bookmark
+func (b *bookmarkFlags) apply(fs *flag.FlagSet, bookmark *models.Bookmark) {
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "name":
+			bookmark.Name = *b.name
+			bookmark.LowerName = strings.ToLower(bookmark.Name)
+		case "description":
+			bookmark.Description = *b.description
+		case "link":
+			bookmark.Content = *b.link
+		case "project":
+			bookmark.Project = *b.project
+		case "tags":
+			bookmark.Tags = splitTags(*b.tags)
+		case "archived":
+			bookmark.Archived = *b.archived
+		case "state":
+			bookmark.State = *b.state
+		case "priority":
+			bookmark.Priority = *b.priority
+		case "due":
+			bookmark.DueAt, _ = models.ParseDueDate(*b.due)
+		case "notes":
+			bookmark.Notes = *b.notes
+		case "meta":
+			for _, key := range b.metadata.keys {
+				bookmark.AddMetadata(key, b.metadata.values[key])
+			}
+		}
+	})
+}
+
+func splitTags(text string) []string {
+	tags := []string{}
+	for _, v := range strings.Split(text, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			tags = append(tags, v)
+		}
+	}
+	return tags
+}
+
+func parseId(text string) (int, error) {
+	id, err := strconv.Atoi(text)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid id: '%s'", text)
+	}
+	return id, nil
+}
+
+//getBookmark returns bookmark with its metadata
+func (c *Cli) getBookmark(id int) (*models.Bookmark, error) {
+	b, err := c.store.GetBookmark(id)
+	if err != nil {
+		return nil, err
+	}
+	err = c.store.GetBookmarkMetadata(b)
+	return b, err
+}
+
+//bookmarkError returns exit code for error when getting bookmark
+func (c *Cli) bookmarkError(cmd string, id int, err error) int {
+	if err == sql.ErrNoRows {
+		fmt.Fprintf(c.errOut, "%s: bookmark %d not found\n", cmd, id)
+		return ExitNotFound
+	}
+	return c.fail(cmd, err)
+}
+
+func (c *Cli) add(args []string) int {
+	fs := c.flags("add")
+	bf := newBookmarkFlags(fs)
+	fetchTitle := fs.Bool("fetch-title", false, "Fetch page title and metadata. Title is used as name if name is not given")
+	duplicates := fs.String("duplicates", "skip", "Bookmark with existing link: skip, update, merge or create")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+	policy, err := storage.ParseDuplicatePolicy(*duplicates)
+	if err != nil {
+		return c.invalid("add", "%v", err)
+	}
+	err = bf.validate(fs)
+	if err != nil {
+		return c.invalid("add", "%v", err)
+	}
+
+	if *bf.link == "" && fs.NArg() == 1 {
+		_ = fs.Set("link", fs.Arg(0))
+	} else if fs.NArg() > 1 {
+		return c.invalid("add", "too many arguments")
+	}
+	if *bf.link == "" {
+		return c.invalid("add", "link is required")
+	}
+
+	b := &models.Bookmark{
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	b.FillDefaultMetadata()
+	bf.apply(fs, b)
+
+	existing := []*models.Bookmark{}
+	if policy != storage.DuplicateCreate {
+		existing, err = c.store.GetBookmarksByLink(b.Content)
+		if err != nil {
+			return c.fail("add", err)
+		}
+	}
+	if len(existing) > 0 && policy == storage.DuplicateSkip {
+		fmt.Fprintf(c.errOut, "add: link already exists in bookmark %d, skipping\n", existing[0].Id)
+		fmt.Fprintln(c.out, existing[0].Id)
+		return ExitOk
+	}
+
+	if *fetchTitle {
+		metadata, err := external.GetPageMetadata(b.Content)
+		if err != nil {
+			fmt.Fprintf(c.errOut, "add: get page metadata: %v\n", err)
+		} else {
+			// fill configured metadata fields that were not given
+			fields := metadata.Fields()
+			for _, key := range models.DefaulMetadata {
+				if fields[key] != "" && (key == "Title" || (*b.Metadata)[key] == "") {
+					b.AddMetadata(key, fields[key])
+				}
+			}
+			if b.Name == "" {
+				b.Name = metadata.Title
+			}
+			if b.Description == "" {
+				b.Description = metadata.Description
+			}
+		}
+	}
+	if b.Name == "" && len(existing) == 0 {
+		b.Name = b.Content
+	}
+	b.LowerName = strings.ToLower(b.Name)
+
+	if len(existing) > 0 {
+		duplicates, ok := c.store.(storage.DuplicateStore)
+		if !ok {
+			return c.fail("add", storage.ErrNotSupported)
+		}
+		err = duplicates.MergeBookmark(existing[0].Id, b, policy == storage.DuplicateUpdate)
+	} else {
+		err = c.store.NewBookmark(b)
+	}
+	if err != nil {
+		return c.fail("add", err)
+	}
+	fmt.Fprintln(c.out, b.Id)
+	return ExitOk
+}
+
+//newFilter parses query into filter. Sort overrides sort: term of query unless it is empty.
+func (c *Cli) newFilter(query string, sort string, desc bool) (*storage.Filter, error) {
+	filter, err := storage.NewFilter(query)
+	if err != nil {
+		return nil, err
+	}
+	if query == "" {
+		filter.Clear(c.store.Options().HideArchived)
+	}
+	if sort != "" {
+		field, ok := storage.SortField(sort)
+		if !ok {
+			return nil, fmt.Errorf("invalid sort field: %s", sort)
+		}
+		filter.SortField = field
+	}
+	if desc {
+		filter.SortDir = "DESC"
+	}
+	return filter, nil
+}
+
+//query returns bookmarks with filter or full text query
+func (c *Cli) query(filter *storage.Filter, limit int) ([]*models.Bookmark, error) {
+	if filter.IsPlainQuery() {
+		if limit == 0 {
+			limit = -1
+		}
+		bookmarks, _, err := c.store.SearchBookmarksPage(filter.Query, 0, limit)
+		if err != nil {
+			return nil, err
+		}
+		// full text search highlights results, get plain bookmarks
+		for i, v := range bookmarks {
+			bookmarks[i], err = c.store.GetBookmark(v.Id)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return bookmarks, nil
+	}
+
+	filter.Limit = limit
+	return c.store.FilterBookmarks(filter)
+}
+
+func (c *Cli) listBookmarks(name string, args []string, queryRequired bool) int {
+	fs := c.flags(name)
+	format := fs.String("format", formatTable, "Output format: table, json or tsv")
+	sort := fs.String("sort", "", "Sort by: name, description, project, link, added, state, priority or due. "+
+		"Overrides sort: term of query, default is name")
+	desc := fs.Bool("desc", false, "Sort descending")
+	limit := fs.Int("limit", -1, "Max number of results, -1 for no limit")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+	if !validFormat(*format) {
+		return c.invalid(name, "invalid format: %s", *format)
+	}
+
+	query := strings.Join(fs.Args(), " ")
+	if queryRequired && query == "" {
+		return c.invalid(name, "query is required")
+	}
+
+	filter, err := c.newFilter(query, *sort, *desc)
+	if err != nil {
+		return c.invalid(name, "%v", err)
+	}
+	bookmarks, err := c.query(filter, *limit)
+	if err != nil {
+		return c.fail(name, err)
+	}
+
+	err = writeBookmarks(c.out, bookmarks, *format)
+	if err != nil {
+		return c.fail(name, err)
+	}
+	if len(bookmarks) == 0 {
+		return ExitNotFound
+	}
+	return ExitOk
+}
+
+func (c *Cli) list(args []string) int {
+	return c.listBookmarks("list", args, false)
+}
+
+func (c *Cli) search(args []string) int {
+	return c.listBookmarks("search", args, true)
+}
+
+func (c *Cli) show(args []string) int {
+	fs := c.flags("show")
+	format := fs.String("format", formatTable, "Output format: table, json or tsv")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+	if !validFormat(*format) {
+		return c.invalid("show", "invalid format: %s", *format)
+	}
+	if fs.NArg() != 1 {
+		return c.invalid("show", "expected single id")
+	}
+	id, err := parseId(fs.Arg(0))
+	if err != nil {
+		return c.invalid("show", "%v", err)
+	}
+
+	b, err := c.getBookmark(id)
+	if err != nil {
+		return c.bookmarkError("show", id, err)
+	}
+
+	err = writeBookmark(c.out, b, *format)
+	if err != nil {
+		return c.fail("show", err)
+	}
+	return ExitOk
+}
+
+func (c *Cli) edit(args []string) int {
+	fs := c.flags("edit")
+	bf := newBookmarkFlags(fs)
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		return c.invalid("edit", "expected single id")
+	}
+	id, err := parseId(fs.Arg(0))
+	if err != nil {
+		return c.invalid("edit", "%v", err)
+	}
+	err = bf.validate(fs)
+	if err != nil {
+		return c.invalid("edit", "%v", err)
+	}
+	if fs.NFlag() == 0 {
+		return c.invalid("edit", "nothing to edit")
+	}
+
+	b, err := c.getBookmark(id)
+	if err != nil {
+		return c.bookmarkError("edit", id, err)
+	}
+
+	bf.apply(fs, b)
+	b.UpdatedAt = time.Now()
+	err = c.store.UpdateBookmark(b)
+	if err != nil {
+		return c.fail("edit", err)
+	}
+	return ExitOk
+}
+
+func (c *Cli) delete(args []string) int {
+	fs := c.flags("delete")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		return c.invalid("delete", "id is required")
+	}
+
+	ids := make([]int, fs.NArg())
+	for i, v := range fs.Args() {
+		id, err := parseId(v)
+		if err != nil {
+			return c.invalid("delete", "%v", err)
+		}
+		ids[i] = id
+	}
+
+	code := ExitOk
+	for _, id := range ids {
+		b, err := c.store.GetBookmark(id)
+		if err != nil {
+			code = c.bookmarkError("delete", id, err)
+			continue
+		}
+		err = c.store.DeleteBookmark(b)
+		if err != nil {
+			return c.fail("delete", err)
+		}
+	}
+	return code
+}
+
+func (c *Cli) tag(args []string) int {
+	fs := c.flags("tag")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() < 2 {
+		return c.invalid("tag", "expected id and tags")
+	}
+	id, err := parseId(fs.Arg(0))
+	if err != nil {
+		return c.invalid("tag", "%v", err)
+	}
+
+	b, err := c.getBookmark(id)
+	if err != nil {
+		return c.bookmarkError("tag", id, err)
+	}
+
+	tags := map[string]bool{}
+	for _, v := range b.Tags {
+		tags[v] = true
+	}
+
+	for _, v := range fs.Args()[1:] {
+		remove := strings.HasPrefix(v, "-")
+		name := strings.TrimLeft(v, "+-")
+		if name == "" {
+			return c.invalid("tag", "invalid tag: '%s'", v)
+		}
+
+		if remove {
+			delete(tags, name)
+		} else if !tags[name] {
+			tags[name] = true
+			b.Tags = append(b.Tags, name)
+		}
+	}
+
+	result := []string{}
+	for _, v := range b.Tags {
+		if tags[v] {
+			result = append(result, v)
+		}
+	}
+	b.Tags = result
+	b.UpdatedAt = time.Now()
+
+	err = c.store.UpdateBookmark(b)
+	if err != nil {
+		return c.fail("tag", err)
+	}
+	return ExitOk
+}
+
+func (c *Cli) importFile(args []string) int {
+	fs := c.flags("import")
+	tags := fs.String("tags", "", "Comma separated list of tags to add to every imported bookmark")
+	projects := fs.Bool("projects", true, "Map folders to projects")
+	duplicates := fs.String("duplicates", "skip", "Bookmarks with existing link: skip, update, merge or create")
+	format := fs.String("format", "", "File format, detected by default: "+strings.Join(external.ImporterNames(), ", "))
+	preview := fs.Bool("preview", false, "Print projects, existing links and invalid links without importing")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		return c.invalid("import", "expected single file")
+	}
+	policy, err := storage.ParseDuplicatePolicy(*duplicates)
+	if err != nil {
+		return c.invalid("import", "%v", err)
+	}
+
+	var importer external.Importer
+	if *format == "" {
+		importer, err = external.DetectImporter(fs.Arg(0))
+	} else {
+		importer, err = external.GetImporter(*format)
+		if err != nil {
+			return c.invalid("import", "%v", err)
+		}
+	}
+	if err != nil {
+		return c.fail("import", err)
+	}
+
+	bookmarks, err := importer.Import(fs.Arg(0), *projects)
+	if err != nil {
+		return c.fail("import", fmt.Errorf("%s: %v", importer.Name(), err))
+	}
+
+	store, ok := c.store.(storage.ImportStore)
+	if !ok {
+		return c.fail("import", storage.ErrNotSupported)
+	}
+	items, err := store.PreviewImport(bookmarks)
+	if err != nil {
+		return c.fail("import", err)
+	}
+	if *preview {
+		c.printImportPreview(importer.Name(), items, policy)
+		return ExitOk
+	}
+
+	// Invalid links are not selected
+	result, err := store.NewBookmarks(items.Selected(), splitTags(*tags), policy)
+	if err != nil {
+		return c.fail("import", err)
+	}
+	_, _, _, _, invalid := items.Counts()
+	fmt.Fprintf(c.out, "Imported %d bookmarks, updated %d, skipped %d duplicates and %d invalid links\n",
+		result.Created, result.Updated, result.Skipped, invalid)
+	return ExitOk
+}
+
+//printImportPreview prints summary of bookmarks to import
+func (c *Cli) printImportPreview(format string, preview *storage.ImportPreview, policy storage.DuplicatePolicy) {
+	total, _, existing, repeated, invalid := preview.Counts()
+	fmt.Fprintf(c.out, "Format %s: %d bookmarks, %d existing links (%s), %d repeated in file, %d invalid links\n",
+		format, total, existing, policy, repeated, invalid)
+
+	counts := preview.Projects()
+	projects := make([]string, 0, len(counts))
+	for project := range counts {
+		projects = append(projects, project)
+	}
+	sort.Strings(projects)
+	fmt.Fprintf(c.out, "Projects:\n")
+	for _, project := range projects {
+		name := project
+		if name == "" {
+			name = "(no project)"
+		}
+		fmt.Fprintf(c.out, "  %-30s %d\n", name, counts[project])
+	}
+
+	if invalid > 0 {
+		fmt.Fprintf(c.out, "Invalid links:\n")
+		for _, v := range preview.Items {
+			if v.Invalid != "" {
+				fmt.Fprintf(c.out, "  %s: %s (%s)\n", v.Invalid, v.Bookmark.Content, v.Bookmark.Name)
+			}
+		}
+	}
+}
+
+func (c *Cli) export(args []string) int {
+	fs := c.flags("export")
+	output := fs.String("o", "", "Output file, defaults to stdout")
+	format := fs.String("format", "", "Export format, defaults to extension of output file or html: "+
+		strings.Join(external.ExporterNames(), ", "))
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	var exporter external.Exporter
+	var err error
+	if *format != "" {
+		exporter, err = external.GetExporter(*format)
+	} else if *output != "" {
+		exporter, err = external.ExporterForFile(*output)
+	} else {
+		exporter = external.Exporters[0]
+	}
+	if err != nil {
+		return c.invalid("export", "%v", err)
+	}
+
+	filter, err := storage.NewFilter(strings.Join(fs.Args(), " "))
+	if err != nil {
+		return c.invalid("export", "%v", err)
+	}
+
+	store, ok := c.store.(storage.ImportStore)
+	if !ok {
+		return c.fail("export", storage.ErrNotSupported)
+	}
+	bookmarks, err := store.ExportBookmarks(filter)
+	if err != nil {
+		return c.fail("export", err)
+	}
+
+	out := c.out
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return c.fail("export", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	err = exporter.Export(out, bookmarks)
+	if err != nil {
+		return c.fail("export", err)
+	}
+	return ExitOk
+}
+
+func (c *Cli) stats(args []string) int {
+	fs := c.flags("stats")
+	format := fs.String("format", formatTable, "Output format: table, json or tsv")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+	if !validFormat(*format) {
+		return c.invalid("stats", "invalid format: %s", *format)
+	}
+
+	stats, err := c.store.GetStatistics()
+	if err != nil {
+		return c.fail("stats", err)
+	}
+	err = writeStatistics(c.out, stats, *format)
+	if err != nil {
+		return c.fail("stats", err)
+	}
+	return ExitOk
+}
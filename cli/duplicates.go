@@ -0,0 +1,68 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"tryffel.net/go/bookmarker/storage"
+)
+
+func (c *Cli) duplicates(args []string) int {
+	fs := c.flags("duplicates")
+	merge := fs.Bool("merge", false, "Merge duplicates into oldest bookmark and move others to trash")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return c.invalid("duplicates", "too many arguments")
+	}
+
+	store, ok := c.store.(storage.DuplicateStore)
+	if !ok {
+		return c.fail("duplicates", storage.ErrNotSupported)
+	}
+	groups, err := store.GetDuplicates()
+	if err != nil {
+		return c.fail("duplicates", err)
+	}
+	if len(groups) == 0 {
+		fmt.Fprintln(c.errOut, "no duplicates")
+		return ExitNotFound
+	}
+
+	for _, group := range groups {
+		ids := make([]int, len(group))
+		idStrings := make([]string, len(group))
+		for i, v := range group {
+			ids[i] = v.Id
+			idStrings[i] = strconv.Itoa(v.Id)
+		}
+		if !*merge {
+			fmt.Fprintf(c.out, "%s\t%s\n", group[0].Content, strings.Join(idStrings, ","))
+			continue
+		}
+
+		merged, err := store.MergeDuplicates(ids)
+		if err != nil {
+			return c.fail("duplicates", err)
+		}
+		fmt.Fprintf(c.out, "%s\tmerged %s into %d\n", group[0].Content, strings.Join(idStrings, ","), merged.Id)
+	}
+	return ExitOk
+}
@@ -0,0 +1,274 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"tryffel.net/go/bookmarker/linkcheck"
+	"tryffel.net/go/bookmarker/storage"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+// Output formats
+const (
+	formatTable = "table"
+	formatJson  = "json"
+	formatTsv   = "tsv"
+)
+
+const timeFormat = "2006-01-02 15:04"
+
+func validFormat(format string) bool {
+	return format == formatTable || format == formatJson || format == formatTsv
+}
+
+//jsonBookmark is json presentation of bookmark
+type jsonBookmark struct {
+	Id          int               `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Link        string            `json:"link"`
+	Project     string            `json:"project"`
+	Tags        []string          `json:"tags"`
+	Archived    bool              `json:"archived"`
+	State       string            `json:"state"`
+	Priority    int               `json:"priority"`
+	DueAt       *time.Time        `json:"due_at,omitempty"`
+	Notes       string            `json:"notes,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+func toJson(b *models.Bookmark) *jsonBookmark {
+	j := &jsonBookmark{
+		Id:          b.Id,
+		Name:        b.Name,
+		Description: b.Description,
+		Link:        b.Content,
+		Project:     b.Project,
+		Tags:        b.Tags,
+		Archived:    b.Archived,
+		State:       b.ReadState(),
+		Priority:    b.Priority,
+		DueAt:       b.DueAt,
+		Notes:       b.Notes,
+		CreatedAt:   b.CreatedAt,
+		UpdatedAt:   b.UpdatedAt,
+	}
+	if j.Tags == nil {
+		j.Tags = []string{}
+	}
+	if b.Metadata != nil {
+		j.Metadata = map[string]string{}
+		for key, value := range *b.Metadata {
+			if value != "" {
+				j.Metadata[key] = value
+			}
+		}
+	}
+	return j
+}
+
+func writeJson(w io.Writer, data interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+//tsvField removes characters that would break tsv row
+func tsvField(text string) string {
+	return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(text)
+}
+
+//writeBookmarks writes list of bookmarks in given format
+func writeBookmarks(w io.Writer, bookmarks []*models.Bookmark, format string) error {
+	switch format {
+	case formatJson:
+		data := make([]*jsonBookmark, len(bookmarks))
+		for i, v := range bookmarks {
+			data[i] = toJson(v)
+		}
+		return writeJson(w, data)
+	case formatTsv:
+		for _, b := range bookmarks {
+			_, err := fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%t\t%s\t%s\n", b.Id, tsvField(b.Name),
+				tsvField(b.Description), tsvField(b.Content), tsvField(b.Project), tsvField(b.TagsString(false)),
+				b.Archived, b.CreatedAt.Format(time.RFC3339), b.UpdatedAt.Format(time.RFC3339))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tPROJECT\tTAGS\tLINK\tADDED")
+		for _, b := range bookmarks {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", b.Id, tsvField(b.Name), tsvField(b.Project),
+				tsvField(b.TagsString(true)), tsvField(b.Content), b.CreatedAt.Format(timeFormat))
+		}
+		return tw.Flush()
+	}
+}
+
+//writeBookmark writes single bookmark with its metadata in given format
+func writeBookmark(w io.Writer, b *models.Bookmark, format string) error {
+	if format == formatJson {
+		return writeJson(w, toJson(b))
+	}
+
+	fields := [][2]string{
+		{"Id", fmt.Sprint(b.Id)},
+		{"Name", b.Name},
+		{"Description", b.Description},
+		{"Link", b.Content},
+		{"Project", b.Project},
+		{"Tags", b.TagsString(true)},
+		{"Archived", fmt.Sprint(b.Archived)},
+		{"State", b.ReadState()},
+		{"Priority", fmt.Sprint(b.Priority)},
+		{"Due", b.DueString()},
+		{"Created at", b.CreatedAt.Format(timeFormat)},
+		{"Updated at", b.UpdatedAt.Format(timeFormat)},
+	}
+	if b.MetadataKeys != nil {
+		for _, key := range *b.MetadataKeys {
+			fields = append(fields, [2]string{key, (*b.Metadata)[key]})
+		}
+	}
+
+	if format == formatTsv {
+		fields = append(fields, [2]string{"Notes", b.Notes})
+		for _, v := range fields {
+			_, err := fmt.Fprintf(w, "%s\t%s\n", tsvField(v[0]), tsvField(v[1]))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, v := range fields {
+		fmt.Fprintf(tw, "%s:\t%s\n", v[0], tsvField(v[1]))
+	}
+	err := tw.Flush()
+	if err != nil || b.Notes == "" {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "\nNotes:\n%s\n", b.Notes)
+	return err
+}
+
+//jsonLinkResult is json presentation of link check result
+type jsonLinkResult struct {
+	Id         int       `json:"id"`
+	Name       string    `json:"name"`
+	Link       string    `json:"link"`
+	LinkStatus string    `json:"link_status"`
+	HttpStatus string    `json:"http_status"`
+	FinalUrl   string    `json:"final_url"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+//writeLinkResults writes link check results in given format, sorted by bookmark id
+func writeLinkResults(w io.Writer, results []*linkcheck.Result, format string) error {
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Bookmark.Id < results[j].Bookmark.Id
+	})
+	data := make([]*jsonLinkResult, len(results))
+	for i, v := range results {
+		data[i] = &jsonLinkResult{
+			Id:         v.Bookmark.Id,
+			Name:       v.Bookmark.Name,
+			Link:       v.Bookmark.Content,
+			LinkStatus: models.LinkStatusOk,
+			HttpStatus: v.Status,
+			FinalUrl:   v.FinalUrl,
+			CheckedAt:  v.CheckedAt,
+		}
+		if v.Dead() {
+			data[i].LinkStatus = models.LinkStatusDead
+		}
+	}
+
+	switch format {
+	case formatJson:
+		return writeJson(w, data)
+	case formatTsv:
+		for _, r := range data {
+			_, err := fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Id, tsvField(r.Name), tsvField(r.Link),
+				r.LinkStatus, tsvField(r.HttpStatus), tsvField(r.FinalUrl), r.CheckedAt.Format(time.RFC3339))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tSTATUS\tHTTP STATUS\tFINAL URL")
+		for _, r := range data {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", r.Id, tsvField(r.Name), r.LinkStatus,
+				tsvField(r.HttpStatus), tsvField(r.FinalUrl))
+		}
+		return tw.Flush()
+	}
+}
+
+//writeStatistics writes statistics in given format
+func writeStatistics(w io.Writer, s *storage.Statistics, format string) error {
+	if format == formatJson {
+		return writeJson(w, s)
+	}
+
+	fields := [][2]string{
+		{"Bookmarks", fmt.Sprint(s.Bookmarks)},
+		{"Archived", fmt.Sprint(s.Archived)},
+		{"Deleted", fmt.Sprint(s.Deleted)},
+		{"Tags", fmt.Sprint(s.Tags)},
+		{"Projects", fmt.Sprint(s.Projects)},
+		{"Unread", fmt.Sprint(s.Unread)},
+		{"Reading", fmt.Sprint(s.Reading)},
+		{"Read", fmt.Sprint(s.Read)},
+		{"Overdue", fmt.Sprint(s.Overdue)},
+		{"Last bookmark", s.LastBookmark.Format(timeFormat)},
+		{"Full text search", fmt.Sprint(s.FullTextSearchSupported)},
+		{"Metadata keys", strings.Join(s.MetadataKeys, ",")},
+	}
+
+	if format == formatTsv {
+		for _, v := range fields {
+			_, err := fmt.Fprintf(w, "%s\t%s\n", v[0], tsvField(v[1]))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, v := range fields {
+		fmt.Fprintf(tw, "%s:\t%s\n", v[0], v[1])
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,110 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package cli
+
+import (
+	"bytes"
+	"testing"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+func Test_writeBookmarks(t *testing.T) {
+	ts := time.Date(2020, 2, 1, 12, 0, 0, 0, time.UTC)
+	bookmarks := []*models.Bookmark{
+		{
+			Id:          1,
+			Name:        "Go",
+			Description: "tabs\tand\nnewlines",
+			Content:     "https://golang.org",
+			Project:     "dev.go",
+			CreatedAt:   ts,
+			UpdatedAt:   ts,
+			Tags:        []string{"go", "lang"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{
+			name:   "tsv",
+			format: formatTsv,
+			want: "1\tGo\ttabs and newlines\thttps://golang.org\tdev.go\tgo,lang\tfalse\t" +
+				"2020-02-01T12:00:00Z\t2020-02-01T12:00:00Z\n",
+		},
+		{
+			name:   "table",
+			format: formatTable,
+			want: "ID  NAME  PROJECT  TAGS      LINK                ADDED\n" +
+				"1   Go    dev.go   go, lang  https://golang.org  2020-02-01 12:00\n",
+		},
+		{
+			name:   "json",
+			format: formatJson,
+			want: `[
+  {
+    "id": 1,
+    "name": "Go",
+    "description": "tabs\tand\nnewlines",
+    "link": "https://golang.org",
+    "project": "dev.go",
+    "tags": [
+      "go",
+      "lang"
+    ],
+    "archived": false,
+    "state": "unread",
+    "priority": 0,
+    "created_at": "2020-02-01T12:00:00Z",
+    "updated_at": "2020-02-01T12:00:00Z"
+  }
+]
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := writeBookmarks(buf, bookmarks, tt.format)
+			if err != nil {
+				t.Errorf("writeBookmarks() error = %v", err)
+				return
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("writeBookmarks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_keyValues_Set(t *testing.T) {
+	kv := &keyValues{}
+	for _, v := range []string{"Author=Jack", "Title=a=b", "Author=John"} {
+		if err := kv.Set(v); err != nil {
+			t.Errorf("Set(%s) error = %v", v, err)
+		}
+	}
+	if err := kv.Set("invalid"); err == nil {
+		t.Errorf("Set(invalid) expected error")
+	}
+	if got := kv.String(); got != "Author=John,Title=a=b" {
+		t.Errorf("String() = %s", got)
+	}
+}
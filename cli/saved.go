@@ -0,0 +1,160 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"tryffel.net/go/bookmarker/storage"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+func (c *Cli) saved(args []string) int {
+	fs := c.flags("saved")
+	format := fs.String("format", formatTable, "Output format: table, json or tsv")
+	save := fs.String("save", "", "Save query with given name, existing search is updated")
+	sort := fs.String("sort", "", "Sort saved search by: name, description, project, link, added, state, priority or due")
+	desc := fs.Bool("desc", false, "Sort saved search descending")
+	remove := fs.Bool("delete", false, "Delete saved search")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+	if !validFormat(*format) {
+		return c.invalid("saved", "invalid format: %s", *format)
+	}
+
+	store, ok := c.store.(storage.SavedSearchStore)
+	if !ok {
+		return c.fail("saved", storage.ErrNotSupported)
+	}
+	name := strings.Join(fs.Args(), " ")
+	if name == "" {
+		if *save != "" || *remove {
+			return c.invalid("saved", "name is required")
+		}
+		return c.listSavedSearches(store, *format)
+	}
+
+	search, err := store.GetSavedSearch(name)
+	if err != nil {
+		return c.fail("saved", err)
+	}
+
+	if *save != "" {
+		return c.saveSearch(store, search, name, *save, *sort, *desc)
+	}
+	if search == nil {
+		fmt.Fprintf(c.errOut, "saved: saved search '%s' not found\n", name)
+		return ExitNotFound
+	}
+	if *remove {
+		err = store.DeleteSavedSearch(search.Id)
+		if err != nil {
+			return c.fail("saved", err)
+		}
+		return ExitOk
+	}
+
+	filter, err := storage.NewSavedSearchFilter(search)
+	if err != nil {
+		return c.invalid("saved", "%v", err)
+	}
+	bookmarks, err := c.query(filter, -1)
+	if err != nil {
+		return c.fail("saved", err)
+	}
+	err = writeBookmarks(c.out, bookmarks, *format)
+	if err != nil {
+		return c.fail("saved", err)
+	}
+	if len(bookmarks) == 0 {
+		return ExitNotFound
+	}
+	return ExitOk
+}
+
+//saveSearch creates new saved search or updates existing search
+func (c *Cli) saveSearch(store storage.SavedSearchStore, search *models.SavedSearch, name, query, sort string,
+	desc bool) int {
+	if search == nil {
+		search = &models.SavedSearch{}
+	}
+	search.Name = name
+	search.Query = query
+	search.SortField = ""
+	if sort != "" {
+		field, ok := storage.SortField(sort)
+		if !ok {
+			return c.invalid("saved", "invalid sort field: %s", sort)
+		}
+		search.SortField = field
+	}
+	search.SortDir = "ASC"
+	if desc {
+		search.SortDir = "DESC"
+	}
+
+	var err error
+	if search.Id == 0 {
+		err = store.NewSavedSearch(search)
+	} else {
+		err = store.UpdateSavedSearch(search)
+	}
+	if err != nil {
+		return c.invalid("saved", "%v", err)
+	}
+	return ExitOk
+}
+
+func (c *Cli) listSavedSearches(store storage.SavedSearchStore, format string) int {
+	searches, err := store.GetSavedSearches()
+	if err != nil {
+		return c.fail("saved", err)
+	}
+
+	switch format {
+	case formatJson:
+		err = writeJson(c.out, searches)
+	case formatTsv:
+		for _, v := range searches {
+			_, err = fmt.Fprintf(c.out, "%s\t%d\t%s\t%s\t%s\n", tsvField(v.Name), v.Count, tsvField(v.Query),
+				v.SortField, v.SortDir)
+			if err != nil {
+				break
+			}
+		}
+	default:
+		tw := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tCOUNT\tQUERY\tSORT")
+		for _, v := range searches {
+			sort := ""
+			if v.SortField != "" {
+				sort = v.SortField + " " + v.SortDir
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", v.Name, v.Count, v.Query, sort)
+		}
+		err = tw.Flush()
+	}
+	if err != nil {
+		return c.fail("saved", err)
+	}
+	if len(searches) == 0 {
+		return ExitNotFound
+	}
+	return ExitOk
+}
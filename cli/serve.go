@@ -0,0 +1,77 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"tryffel.net/go/bookmarker/api"
+	"tryffel.net/go/bookmarker/config"
+)
+
+func (c *Cli) serve(args []string) int {
+	fs := c.flags("serve")
+	address := fs.String("address", config.Configuration.ApiBindAddress, "Address to listen on")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return ExitUsage
+	}
+	if config.Configuration.ApiToken == "" {
+		return c.invalid("serve", "api_token is not set in config file")
+	}
+
+	server := &http.Server{
+		Addr:         *address,
+		Handler:      api.NewServer(c.store, config.Configuration.ApiToken),
+		ReadTimeout:  time.Second * 15,
+		WriteTimeout: time.Second * 30,
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	fmt.Fprintf(c.errOut, "serving api at http://%s/api/v1\n", *address)
+	select {
+	case err := <-errs:
+		return c.fail("serve", err)
+	case <-stop:
+	}
+
+	logrus.Info("Stopping api server")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	err := server.Shutdown(ctx)
+	if err != nil {
+		return c.fail("serve", err)
+	}
+	return ExitOk
+}
@@ -0,0 +1,133 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"tryffel.net/go/bookmarker/storage"
+)
+
+func (c *Cli) tags(args []string) int {
+	fs := c.flags("tags")
+	format := fs.String("format", formatTable, "Output format: table, json or tsv")
+	rename := fs.String("rename", "", "Rename tag and its children to given name")
+	merge := fs.String("merge", "", "Merge tag and its children into given tag")
+	remove := fs.Bool("delete", false, "Remove tag and its children from all bookmarks")
+	unused := fs.Bool("unused", false, "List tags that are not assigned to any bookmark")
+	prune := fs.Bool("prune", false, "Delete tags that are not assigned to any bookmark")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+	if !validFormat(*format) {
+		return c.invalid("tags", "invalid format: %s", *format)
+	}
+
+	tag := strings.Join(fs.Args(), " ")
+	if *rename != "" || *merge != "" || *remove {
+		if tag == "" {
+			return c.invalid("tags", "tag is required")
+		}
+		var count int
+		var err error
+		if *rename != "" {
+			count, err = c.store.RenameTag(tag, *rename)
+		} else if *merge != "" {
+			count, err = c.store.MergeTag(tag, *merge)
+		} else {
+			count, err = c.store.DeleteTag(tag)
+		}
+		if err != nil {
+			return c.invalid("tags", "%v", err)
+		}
+		fmt.Fprintf(c.out, "Modified %d bookmarks\n", count)
+		return ExitOk
+	}
+	if tag != "" {
+		return c.invalid("tags", "too many arguments")
+	}
+
+	unusedTags, ok := c.store.(storage.UnusedTagStore)
+	if (*prune || *unused) && !ok {
+		return c.fail("tags", storage.ErrNotSupported)
+	}
+	if *prune {
+		count, err := unusedTags.DeleteUnusedTags()
+		if err != nil {
+			return c.fail("tags", err)
+		}
+		fmt.Fprintf(c.out, "Deleted %d unused tags\n", count)
+		return ExitOk
+	}
+
+	var names []string
+	counts := map[string]int{}
+	if *unused {
+		var err error
+		names, err = unusedTags.GetUnusedTags()
+		if err != nil {
+			return c.fail("tags", err)
+		}
+		for _, v := range names {
+			counts[v] = 0
+		}
+	} else {
+		tags, err := c.store.GetAllTags()
+		if err != nil {
+			return c.fail("tags", err)
+		}
+		if tags != nil {
+			counts = *tags
+		}
+		for name := range counts {
+			names = append(names, name)
+		}
+		storage.SortTags(names)
+	}
+
+	err := writeTags(c.out, names, counts, *format)
+	if err != nil {
+		return c.fail("tags", err)
+	}
+	if len(names) == 0 {
+		return ExitNotFound
+	}
+	return ExitOk
+}
+
+func writeTags(w io.Writer, names []string, counts map[string]int, format string) error {
+	switch format {
+	case formatJson:
+		return writeJson(w, counts)
+	case formatTsv:
+		for _, v := range names {
+			_, err := fmt.Fprintf(w, "%s\t%d\n", tsvField(v), counts[v])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TAG\tCOUNT")
+	for _, v := range names {
+		fmt.Fprintf(tw, "%s\t%d\n", v, counts[v])
+	}
+	return tw.Flush()
+}
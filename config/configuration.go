@@ -32,6 +32,12 @@ type ApplicationConfig struct {
 	AutoComplete           bool     `toml:"autocomplete"`
 	AutoCompleteMaxResults int      `toml:"autocomplete_max_results"`
 	EnableFullTextSearch   bool     `toml:"full_text_search"`
+	ApiBindAddress         string   `toml:"api_bind_address"`
+	ApiToken               string   `toml:"api_token"`
+	TrashRetentionDays     int      `toml:"trash_retention_days"`
+	LinkCheckWorkers       int      `toml:"link_check_workers"`
+	LinkCheckTimeout       int      `toml:"link_check_timeout_seconds"`
+	LinkCheckHostInterval  int      `toml:"link_check_host_interval_ms"`
 	Colors                 Colors
 	Shortcuts              Shortcuts
 	configDir              string
@@ -65,6 +71,11 @@ func defaultConfig() *ApplicationConfig {
 		AutoComplete:           true,
 		AutoCompleteMaxResults: 20,
 		EnableFullTextSearch:   true,
+		ApiBindAddress:         "127.0.0.1:8080",
+		TrashRetentionDays:     30,
+		LinkCheckWorkers:       8,
+		LinkCheckTimeout:       15,
+		LinkCheckHostInterval:  1000,
 		Colors:                 defaultColors(),
 		Shortcuts:              defaultShortcuts(),
 	}
@@ -0,0 +1,292 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package external
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"golang.org/x/net/html"
+	"io"
+	"strings"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//min length of paragraph to count it as content
+const minParagraphLength = 25
+
+//elements that are never part of readable content
+var skippedElements = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"noscript": true,
+	"template": true,
+	"head":     true,
+	"nav":      true,
+	"header":   true,
+	"footer":   true,
+	"aside":    true,
+	"form":     true,
+	"button":   true,
+	"select":   true,
+	"iframe":   true,
+	"svg":      true,
+}
+
+var blockElements = map[string]bool{
+	"p":          true,
+	"div":        true,
+	"section":    true,
+	"article":    true,
+	"main":       true,
+	"ul":         true,
+	"ol":         true,
+	"dl":         true,
+	"dt":         true,
+	"dd":         true,
+	"table":      true,
+	"tr":         true,
+	"figure":     true,
+	"figcaption": true,
+	"address":    true,
+}
+
+//ArchivePage downloads bookmarked page and creates a snapshot of it with readable text of page as markdown.
+func ArchivePage(bookmark *models.Bookmark) (*models.Snapshot, error) {
+	page, err := fetchPage(bookmark.Content)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := parsePageMetadata(bytes.NewReader(page.body), page.url)
+	if err != nil {
+		return nil, fmt.Errorf("parse page: %v", err)
+	}
+	text, err := readableText(bytes.NewReader(page.body))
+	if err != nil {
+		return nil, fmt.Errorf("extract text: %v", err)
+	}
+
+	hash := sha256.Sum256(page.body)
+	snapshot := &models.Snapshot{
+		Bookmark:  bookmark.Id,
+		CreatedAt: time.Now(),
+		Url:       page.url.String(),
+		Title:     metadata.Title,
+		Hash:      hex.EncodeToString(hash[:]),
+		Text:      text,
+		Html:      page.body,
+	}
+	return snapshot, nil
+}
+
+//readableText extracts main content of page as markdown, leaving out navigation, scripts and such.
+func readableText(r io.Reader) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", err
+	}
+
+	md := &markdown{}
+	md.render(contentRoot(doc))
+	md.flush()
+	return strings.Join(md.blocks, "\n\n"), nil
+}
+
+//contentRoot returns element that most likely contains main content of page:
+//longest article, main element or element with most paragraph text.
+func contentRoot(doc *html.Node) *html.Node {
+	var article, main, body *html.Node
+	articleLength := 0
+	scores := map[*html.Node]int{}
+
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode {
+			if skippedElements[node.Data] {
+				return
+			}
+			switch node.Data {
+			case "body":
+				body = node
+			case "article":
+				if length := len(collapseSpace(textContent(node))); length > articleLength {
+					article = node
+					articleLength = length
+				}
+			case "main":
+				if main == nil {
+					main = node
+				}
+			case "p", "pre", "blockquote":
+				length := len(collapseSpace(textContent(node)))
+				if length >= minParagraphLength && node.Parent != nil {
+					scores[node.Parent] += length
+					if node.Parent.Parent != nil {
+						scores[node.Parent.Parent] += length / 2
+					}
+				}
+			}
+			if main == nil && attr(node, "role") == "main" {
+				main = node
+			}
+		}
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	if article != nil {
+		return article
+	}
+	if main != nil {
+		return main
+	}
+
+	var best *html.Node
+	for node, score := range scores {
+		if best == nil || score > scores[best] {
+			best = node
+		}
+	}
+	if best != nil {
+		return best
+	}
+	if body != nil {
+		return body
+	}
+	return doc
+}
+
+//markdown renders html nodes into markdown blocks
+type markdown struct {
+	blocks []string
+	line   strings.Builder
+}
+
+//flush adds current inline text as a block
+func (m *markdown) flush() {
+	text := collapseSpace(m.line.String())
+	if text != "" {
+		m.blocks = append(m.blocks, text)
+	}
+	m.line.Reset()
+}
+
+func (m *markdown) add(block string) {
+	m.flush()
+	if block != "" {
+		m.blocks = append(m.blocks, block)
+	}
+}
+
+//renderBlocks renders children of node and returns them as blocks
+func renderBlocks(node *html.Node) []string {
+	md := &markdown{}
+	md.renderChildren(node)
+	md.flush()
+	return md.blocks
+}
+
+func (m *markdown) renderChildren(node *html.Node) {
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		m.render(c)
+	}
+}
+
+func (m *markdown) render(node *html.Node) {
+	if node.Type == html.TextNode {
+		m.line.WriteString(node.Data)
+		return
+	}
+	if node.Type != html.ElementNode {
+		m.renderChildren(node)
+		return
+	}
+	if skippedElements[node.Data] || hasAttr(node, "hidden") || attr(node, "aria-hidden") == "true" {
+		return
+	}
+
+	switch node.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		text := collapseSpace(textContent(node))
+		if text != "" {
+			level := int(node.Data[1] - '0')
+			m.add(strings.Repeat("#", level) + " " + text)
+		}
+	case "pre":
+		text := strings.Trim(textContent(node), "\n")
+		if strings.TrimSpace(text) != "" {
+			m.add("```\n" + text + "\n```")
+		}
+	case "li":
+		text := strings.Join(renderBlocks(node), " ")
+		if text != "" {
+			m.add("- " + text)
+		}
+	case "blockquote":
+		lines := strings.Split(strings.Join(renderBlocks(node), "\n\n"), "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimSpace("> " + line)
+		}
+		m.add(strings.Join(lines, "\n"))
+	case "br":
+		m.flush()
+	case "hr":
+		m.add("---")
+	case "img":
+		// images are left out
+	default:
+		if blockElements[node.Data] {
+			m.flush()
+			m.renderChildren(node)
+			m.flush()
+		} else {
+			m.renderChildren(node)
+		}
+	}
+}
+
+func hasAttr(node *html.Node, key string) bool {
+	for _, v := range node.Attr {
+		if strings.ToLower(v.Key) == key {
+			return true
+		}
+	}
+	return false
+}
+
+//textContent returns all text of node, leaving out skipped elements
+func textContent(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	if node.Type == html.ElementNode && skippedElements[node.Data] {
+		return ""
+	}
+	builder := strings.Builder{}
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		builder.WriteString(textContent(c))
+	}
+	return builder.String()
+}
+
+func collapseSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
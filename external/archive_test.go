@@ -0,0 +1,125 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package external
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+const wantArticleText = "# Archiving pages\n\n" +
+	"Bookmarks rot over time, so it is useful to keep a copy of the page.\n\n" +
+	"Readable text leaves out navigation, scripts and other clutter.\n\n" +
+	"## Steps\n\n" +
+	"- Download page\n\n" +
+	"- Extract main content\n\n" +
+	"> Links are forever, until they are not.\n\n" +
+	"```\nbookmarker archive 1\n```"
+
+func Test_readableText(t *testing.T) {
+	article, err := ioutil.ReadFile(filepath.Join("testdata", "article.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "content with most paragraphs",
+			html: string(article),
+			want: wantArticleText,
+		},
+		{
+			name: "article element",
+			html: `<body><p>Comments and other text outside article element</p>
+<article><h3>Title</h3><p>Article text<br>next line</p></article></body>`,
+			want: "### Title\n\nArticle text\n\nnext line",
+		},
+		{
+			name: "body without paragraphs",
+			html: `<body><div>Only <b>text</b></div><script>x()</script></body>`,
+			want: "Only text",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readableText(strings.NewReader(tt.html))
+			if err != nil {
+				t.Errorf("readableText() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("readableText() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArchivePage(t *testing.T) {
+	article, err := ioutil.ReadFile(filepath.Join("testdata", "article.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/article", http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(article)
+	}))
+	defer server.Close()
+
+	bookmark := &models.Bookmark{Id: 5, Content: server.URL + "/old"}
+	got, err := ArchivePage(bookmark)
+	if err != nil {
+		t.Fatalf("ArchivePage() error = %v", err)
+	}
+
+	hash := sha256.Sum256(article)
+	if got.Bookmark != 5 {
+		t.Errorf("ArchivePage() bookmark = %d, want 5", got.Bookmark)
+	}
+	if got.Url != server.URL+"/article" {
+		t.Errorf("ArchivePage() url = %s, want %s", got.Url, server.URL+"/article")
+	}
+	if got.Title != "Archived article" {
+		t.Errorf("ArchivePage() title = %s", got.Title)
+	}
+	if got.Hash != hex.EncodeToString(hash[:]) {
+		t.Errorf("ArchivePage() hash = %s, want %s", got.Hash, hex.EncodeToString(hash[:]))
+	}
+	if got.Text != wantArticleText {
+		t.Errorf("ArchivePage() text = %q", got.Text)
+	}
+	if string(got.Html) != string(article) {
+		t.Errorf("ArchivePage() html differs from page")
+	}
+	if got.CreatedAt.IsZero() {
+		t.Errorf("ArchivePage() created at is not set")
+	}
+}
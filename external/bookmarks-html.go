@@ -17,10 +17,13 @@
 package external
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/net/html"
 	"io"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -45,6 +48,12 @@ const replaceDots = "-"
 
 const separator = "."
 
+//detectNetscapeHtml returns true if file has doctype of bookmarks.html or looks like bookmark list
+func detectNetscapeHtml(head []byte) bool {
+	head = bytes.ToLower(head)
+	return bytes.Contains(head, []byte("netscape-bookmark-file")) || bytes.Contains(head, []byte("<dt>"))
+}
+
 //ImportBookmarksHtml parses bookmark.html export and returns an array of bookmarks and possible error
 func ImportBookmarksHtml(reader io.Reader, mapFoldersProjects bool) ([]*models.Bookmark, error) {
 	doc, err := html.Parse(reader)
@@ -59,6 +68,8 @@ func ImportBookmarksHtml(reader io.Reader, mapFoldersProjects bool) ([]*models.B
 	folderCounts := &map[string]int{}
 
 	bookmarks := []*models.Bookmark{}
+	// <DD> after bookmark holds its description
+	var lastBookmark *models.Bookmark
 
 	// Parse single node and its children
 	parseFolder = func(node *html.Node) bool {
@@ -83,6 +94,7 @@ func ImportBookmarksHtml(reader io.Reader, mapFoldersProjects bool) ([]*models.B
 				folder = append(folder, f)
 				foundDir = true
 			}
+			lastBookmark = nil
 		}
 
 		// Parse bookmark
@@ -99,7 +111,16 @@ func ImportBookmarksHtml(reader io.Reader, mapFoldersProjects bool) ([]*models.B
 				}
 				bookmarks = append(bookmarks, b)
 				(*folderCounts)[strings.Join(folder, ".")] += 1
+				lastBookmark = b
+			}
+		}
+
+		// Parse description
+		if node.Type == html.TextNode && node.Parent.Type == html.ElementNode && node.Parent.Data == "dd" {
+			if lastBookmark != nil && lastBookmark.Description == "" {
+				lastBookmark.Description = strings.TrimSpace(node.Data)
 			}
+			lastBookmark = nil
 		}
 
 		// Should we skip current folder
@@ -157,6 +178,101 @@ func ImportBookmarksHtml(reader io.Reader, mapFoldersProjects bool) ([]*models.B
 	return bookmarks, nil
 }
 
+//ExportBookmarksHtml writes bookmarks into writer in Netscape bookmarks.html format, which browsers
+// and ImportBookmarksHtml are able to read. Projects are written as nested folders,
+// e.g. project 'a.b' results in folder b inside folder a.
+func ExportBookmarksHtml(writer io.Writer, bookmarks []*models.Bookmark) error {
+	w := bufio.NewWriter(writer)
+
+	// Sort by project so that each folder is written only once
+	sorted := make([]*models.Bookmark, len(bookmarks))
+	copy(sorted, bookmarks)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareFolders(projectFolders(sorted[i].Project), projectFolders(sorted[j].Project)) < 0
+	})
+
+	_, _ = w.WriteString(`<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file.
+     It will be read and overwritten.
+     DO NOT EDIT! -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+`)
+
+	// Currently open folders
+	open := []string{}
+	for _, b := range sorted {
+		folders := projectFolders(b.Project)
+
+		common := 0
+		for common < len(open) && common < len(folders) && open[common] == folders[common] {
+			common += 1
+		}
+
+		// Close folders that this bookmark is not part of
+		for i := len(open); i > common; i-- {
+			_, _ = w.WriteString(indent(i) + "</DL><p>\n")
+		}
+		open = open[:common]
+
+		// Open new folders
+		for _, f := range folders[common:] {
+			_, _ = w.WriteString(fmt.Sprintf("%s<DT><H3>%s</H3>\n", indent(len(open)+1), html.EscapeString(f)))
+			open = append(open, f)
+			_, _ = w.WriteString(indent(len(open)) + "<DL><p>\n")
+		}
+
+		_, _ = w.WriteString(fmt.Sprintf("%s<DT><A HREF=\"%s\" ADD_DATE=\"%d\" LAST_MODIFIED=\"%d\"",
+			indent(len(open)+1), html.EscapeString(b.Content), b.CreatedAt.Unix(), b.UpdatedAt.Unix()))
+		if len(b.Tags) > 0 {
+			_, _ = w.WriteString(fmt.Sprintf(" TAGS=\"%s\"", html.EscapeString(b.TagsString(false))))
+		}
+		_, _ = w.WriteString(fmt.Sprintf(">%s</A>\n", html.EscapeString(b.Name)))
+		if b.Description != "" {
+			_, _ = w.WriteString(fmt.Sprintf("%s<DD>%s\n", indent(len(open)+1), html.EscapeString(b.Description)))
+		}
+	}
+
+	for i := len(open); i > 0; i-- {
+		_, _ = w.WriteString(indent(i) + "</DL><p>\n")
+	}
+	_, _ = w.WriteString("</DL><p>\n")
+
+	err := w.Flush()
+	if err != nil {
+		return fmt.Errorf("write bookmarks: %v", err)
+	}
+	logrus.Infof("Exported %d bookmarks", len(bookmarks))
+	return nil
+}
+
+// split project name into folders. Empty project has no folders
+func projectFolders(project string) []string {
+	if project == "" {
+		return []string{}
+	}
+	return strings.Split(project, separator)
+}
+
+// compare folder paths element by element, parents before children
+func compareFolders(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+func indent(level int) string {
+	return strings.Repeat("    ", level)
+}
+
 func keysTobookmark(keys map[string]string) *models.Bookmark {
 	b := &models.Bookmark{
 		Content:   keys["href"],
@@ -0,0 +1,104 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package external
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+func TestExportBookmarksHtml(t *testing.T) {
+	bookmarks := []*models.Bookmark{
+		{
+			Name:        "Go",
+			Description: "The Go programming language",
+			Content:     "https://golang.org",
+			Project:     "dev.go",
+			CreatedAt:   time.Unix(1580000000, 0),
+			UpdatedAt:   time.Unix(1580000100, 0),
+			Tags:        []string{"go", "lang"},
+		},
+		{
+			Name:      "No project",
+			Content:   "https://example.com/?a=1&b=2",
+			CreatedAt: time.Unix(1580000000, 0),
+			UpdatedAt: time.Unix(1580000000, 0),
+		},
+		{
+			Name:      "Dev <tools>",
+			Content:   "https://github.com",
+			Project:   "dev",
+			CreatedAt: time.Unix(1580000200, 0),
+			UpdatedAt: time.Unix(1580000300, 0),
+			Tags:      []string{"git"},
+		},
+		{
+			Name:      "Sqlite",
+			Content:   "https://sqlite.org",
+			Project:   "dev.db",
+			CreatedAt: time.Unix(1580000400, 0),
+			UpdatedAt: time.Unix(1580000400, 0),
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	err := ExportBookmarksHtml(buf, bookmarks)
+	if err != nil {
+		t.Fatalf("ExportBookmarksHtml() error = %v", err)
+	}
+
+	imported, err := ImportBookmarksHtml(bytes.NewReader(buf.Bytes()), true)
+	if err != nil {
+		t.Fatalf("ImportBookmarksHtml() error = %v", err)
+	}
+
+	if len(imported) != len(bookmarks) {
+		t.Fatalf("round trip: got %d bookmarks, want %d", len(imported), len(bookmarks))
+	}
+
+	byLink := map[string]*models.Bookmark{}
+	for _, v := range imported {
+		byLink[v.Content] = v
+	}
+
+	for _, want := range bookmarks {
+		got := byLink[want.Content]
+		if got == nil {
+			t.Errorf("round trip: missing bookmark %s", want.Content)
+			continue
+		}
+		if got.Name != want.Name {
+			t.Errorf("round trip: name = %s, want %s", got.Name, want.Name)
+		}
+		if got.Description != want.Description {
+			t.Errorf("round trip: description = %s, want %s", got.Description, want.Description)
+		}
+		if got.Project != want.Project {
+			t.Errorf("round trip: project = %s, want %s", got.Project, want.Project)
+		}
+		if !got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) {
+			t.Errorf("round trip: timestamps = %v / %v, want %v / %v", got.CreatedAt, got.UpdatedAt,
+				want.CreatedAt, want.UpdatedAt)
+		}
+		if !reflect.DeepEqual(got.Tags, want.Tags) {
+			t.Errorf("round trip: tags = %v, want %v", got.Tags, want.Tags)
+		}
+	}
+}
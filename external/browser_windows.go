@@ -17,3 +17,6 @@
 package external
 
 var browserOpenUrl = "start"
+
+//defaultEditor is used when neither $VISUAL nor $EDITOR is set
+var defaultEditor = "notepad"
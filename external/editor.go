@@ -0,0 +1,73 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package external
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+//editorCommand returns editor from $VISUAL or $EDITOR, or default editor of platform.
+//Command may contain arguments, e.g. 'code --wait'.
+func editorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.Fields(os.Getenv(env)); len(editor) > 0 {
+			return editor
+		}
+	}
+	return []string{defaultEditor}
+}
+
+//EditText opens text in external editor and returns edited text without trailing newlines.
+//Editor runs in current terminal, so terminal ui must be suspended while editing.
+//Text is stored in temporary markdown file, which name starts with prefix.
+func EditText(text string, prefix string) (string, error) {
+	file, err := ioutil.TempFile("", prefix+"-*.md")
+	if err != nil {
+		return text, fmt.Errorf("create file: %v", err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString(text)
+	if err != nil {
+		file.Close()
+		return text, fmt.Errorf("write file: %v", err)
+	}
+	err = file.Close()
+	if err != nil {
+		return text, fmt.Errorf("write file: %v", err)
+	}
+
+	editor := editorCommand()
+	cmd := exec.Command(editor[0], append(editor[1:], file.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return text, fmt.Errorf("run editor '%s': %v", editor[0], err)
+	}
+
+	data, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		return text, fmt.Errorf("read file: %v", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
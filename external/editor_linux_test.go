@@ -0,0 +1,48 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package external
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEditText(t *testing.T) {
+	visual := os.Getenv("VISUAL")
+	defer os.Setenv("VISUAL", visual)
+
+	err := os.Setenv("VISUAL", "sed -i s/draft/final/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := EditText("# Notes\n\ndraft text\n", "bookmarker-test")
+	if err != nil {
+		t.Fatalf("EditText() error = %v", err)
+	}
+	if want := "# Notes\n\nfinal text"; got != want {
+		t.Errorf("EditText() = %q, want %q", got, want)
+	}
+
+	err = os.Setenv("VISUAL", "false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = EditText("unchanged", "bookmarker-test")
+	if err == nil || got != "unchanged" {
+		t.Errorf("EditText() = %q, %v, want original text and error", got, err)
+	}
+}
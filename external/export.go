@@ -0,0 +1,148 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package external
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//Exporter writes bookmarks into a file format
+type Exporter interface {
+	//Name returns name of the format
+	Name() string
+	//Extension returns file extension of the format, including dot
+	Extension() string
+	//Export writes bookmarks to writer
+	Export(writer io.Writer, bookmarks []*models.Bookmark) error
+}
+
+//Exporters are supported export formats
+var Exporters = []Exporter{
+	&writerExporter{name: "html", extension: ".html", export: ExportBookmarksHtml},
+	&writerExporter{name: "json", extension: ".json", export: ExportJson},
+	&writerExporter{name: "csv", extension: ".csv", export: ExportCsv},
+	&writerExporter{name: "markdown", extension: ".md", export: ExportMarkdown},
+	&writerExporter{name: "org", extension: ".org", export: ExportOrg},
+}
+
+//ExporterNames returns names of supported export formats
+func ExporterNames() []string {
+	names := make([]string, len(Exporters))
+	for i, v := range Exporters {
+		names[i] = v.Name()
+	}
+	return names
+}
+
+//GetExporter returns exporter with given name
+func GetExporter(name string) (Exporter, error) {
+	for _, v := range Exporters {
+		if v.Name() == name {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown format '%s', supported formats: %s", name, strings.Join(ExporterNames(), ", "))
+}
+
+//ExporterForFile returns exporter that matches extension of file
+func ExporterForFile(file string) (Exporter, error) {
+	ext := strings.ToLower(filepath.Ext(file))
+	for _, v := range Exporters {
+		if v.Extension() == ext {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("no format for file extension '%s'", ext)
+}
+
+type writerExporter struct {
+	name      string
+	extension string
+	export    func(writer io.Writer, bookmarks []*models.Bookmark) error
+}
+
+func (w *writerExporter) Name() string {
+	return w.name
+}
+
+func (w *writerExporter) Extension() string {
+	return w.extension
+}
+
+func (w *writerExporter) Export(writer io.Writer, bookmarks []*models.Bookmark) error {
+	return w.export(writer, bookmarks)
+}
+
+//metadataKeys returns keys of bookmark metadata that have value, in order of MetadataKeys.
+//Keys missing from MetadataKeys are appended in alphabetical order.
+func metadataKeys(b *models.Bookmark) []string {
+	if b.Metadata == nil {
+		return []string{}
+	}
+	keys := []string{}
+	found := map[string]bool{}
+	if b.MetadataKeys != nil {
+		for _, key := range *b.MetadataKeys {
+			if !found[key] && (*b.Metadata)[key] != "" {
+				keys = append(keys, key)
+				found[key] = true
+			}
+		}
+	}
+	others := []string{}
+	for key, value := range *b.Metadata {
+		if !found[key] && value != "" {
+			others = append(others, key)
+		}
+	}
+	sort.Strings(others)
+	return append(keys, others...)
+}
+
+//projectTree groups bookmarks by project. Bookmarks without project are returned separately.
+func projectTree(bookmarks []*models.Bookmark) ([]*models.Project, map[string][]*models.Bookmark) {
+	byProject := map[string][]*models.Bookmark{}
+	for _, v := range bookmarks {
+		byProject[v.Project] = append(byProject[v.Project], v)
+	}
+
+	names := []string{}
+	counts := []int{}
+	for key := range byProject {
+		if key != "" {
+			names = append(names, key)
+		}
+	}
+	sort.Strings(names)
+	for _, v := range names {
+		counts = append(counts, len(byProject[v]))
+	}
+	return models.ParseTrees(names, counts), byProject
+}
+
+//walkProjects calls fn for each project in depth-first order, depth of root projects being 1
+func walkProjects(projects []*models.Project, depth int, fn func(project *models.Project, depth int)) {
+	for _, v := range projects {
+		fn(v, depth)
+		walkProjects(v.Children, depth+1, fn)
+	}
+}
@@ -0,0 +1,147 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+const (
+	jsonFormat  = "bookmarker"
+	jsonVersion = 1
+)
+
+//jsonExport is lossless json export, which is imported back with ImportJson
+type jsonExport struct {
+	Format     string         `json:"format"`
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Bookmarks  []jsonBookmark `json:"bookmarks"`
+}
+
+type jsonBookmark struct {
+	Name        string     `json:"name"`
+	Link        string     `json:"link"`
+	Description string     `json:"description"`
+	Project     string     `json:"project"`
+	Tags        []string   `json:"tags"`
+	Archived    bool       `json:"archived"`
+	Notes       string     `json:"notes,omitempty"`
+	State       string     `json:"state,omitempty"`
+	Priority    int        `json:"priority,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	//Metadata is a list to keep order of keys
+	Metadata []jsonMetadata `json:"metadata"`
+}
+
+type jsonMetadata struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+//ExportJson writes bookmarks into json with all fields, metadata and timestamps
+func ExportJson(writer io.Writer, bookmarks []*models.Bookmark) error {
+	export := jsonExport{
+		Format:     jsonFormat,
+		Version:    jsonVersion,
+		ExportedAt: time.Now(),
+		Bookmarks:  make([]jsonBookmark, len(bookmarks)),
+	}
+
+	for i, b := range bookmarks {
+		v := jsonBookmark{
+			Name:        b.Name,
+			Link:        b.Content,
+			Description: b.Description,
+			Project:     b.Project,
+			Tags:        b.Tags,
+			Archived:    b.Archived,
+			Notes:       b.Notes,
+			State:       b.State,
+			Priority:    b.Priority,
+			DueAt:       b.DueAt,
+			CreatedAt:   b.CreatedAt,
+			UpdatedAt:   b.UpdatedAt,
+			Metadata:    []jsonMetadata{},
+		}
+		if v.Tags == nil {
+			v.Tags = []string{}
+		}
+		for _, key := range metadataKeys(b) {
+			v.Metadata = append(v.Metadata, jsonMetadata{Key: key, Value: (*b.Metadata)[key]})
+		}
+		export.Bookmarks[i] = v
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(export)
+	if err != nil {
+		return fmt.Errorf("write json: %v", err)
+	}
+	return nil
+}
+
+func detectJson(head []byte) bool {
+	return containsAll(head, `"format"`, `"`+jsonFormat+`"`, `"bookmarks"`)
+}
+
+//ImportJson parses json written by ExportJson. Projects are always kept.
+func ImportJson(reader io.Reader, mapFoldersProjects bool) ([]*models.Bookmark, error) {
+	export := jsonExport{}
+	err := json.NewDecoder(reader).Decode(&export)
+	if err != nil {
+		return nil, fmt.Errorf("parse json: %v", err)
+	}
+	if export.Format != jsonFormat {
+		return nil, fmt.Errorf("not a %s export", jsonFormat)
+	}
+	if export.Version > jsonVersion {
+		return nil, fmt.Errorf("unsupported version %d", export.Version)
+	}
+
+	bookmarks := make([]*models.Bookmark, 0, len(export.Bookmarks))
+	for _, v := range export.Bookmarks {
+		if v.Link == "" {
+			continue
+		}
+		b := newBookmark(v.Name, v.Link, v.CreatedAt, v.UpdatedAt)
+		b.Description = v.Description
+		b.Project = v.Project
+		b.Tags = v.Tags
+		b.Archived = v.Archived
+		b.Notes = v.Notes
+		b.State = v.State
+		b.Priority = v.Priority
+		b.DueAt = v.DueAt
+		if len(v.Metadata) > 0 {
+			b.Metadata = &map[string]string{}
+			b.MetadataKeys = &[]string{}
+			for _, m := range v.Metadata {
+				b.AddMetadata(m.Key, m.Value)
+			}
+		}
+		bookmarks = append(bookmarks, b)
+	}
+	return bookmarks, nil
+}
@@ -0,0 +1,193 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package external
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+func exportBookmarks() []*models.Bookmark {
+	goLang := &models.Bookmark{
+		Name:        "Effective Go",
+		Description: "Tips for writing\nclear Go",
+		Content:     "https://golang.org/doc/effective_go.html",
+		Project:     "dev.go",
+		CreatedAt:   time.Date(2020, 2, 22, 18, 0, 0, 0, time.UTC),
+		UpdatedAt:   time.Date(2020, 2, 23, 18, 0, 0, 0, time.UTC),
+		Tags:        []string{"go", "docs"},
+		Notes:       "# Summary\nRead *formatting* first",
+		State:       models.StateReading,
+		Priority:    2,
+	}
+	due := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	goLang.DueAt = &due
+	goLang.FillDefaultMetadata()
+	goLang.AddMetadata("Title", "Effective Go - The Go Programming Language")
+	goLang.AddMetadata("Author", "Go team")
+
+	return []*models.Bookmark{
+		goLang,
+		{
+			Name:      "SQLite [FTS5]",
+			Content:   "https://sqlite.org/fts5.html",
+			Project:   "dev",
+			Archived:  true,
+			CreatedAt: time.Date(2020, 2, 22, 18, 1, 40, 0, time.UTC),
+			UpdatedAt: time.Date(2020, 2, 22, 18, 1, 40, 0, time.UTC),
+		},
+		{
+			Name:      "Example",
+			Content:   "https://example.com/a page",
+			CreatedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+			UpdatedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+			Tags:      []string{"to read"},
+		},
+	}
+}
+
+func TestExportJson(t *testing.T) {
+	bookmarks := exportBookmarks()
+	buf := &bytes.Buffer{}
+	err := ExportJson(buf, bookmarks)
+	if err != nil {
+		t.Fatalf("ExportJson() error = %v", err)
+	}
+	if !detectJson(buf.Bytes()) {
+		t.Errorf("detectJson() = false, want true")
+	}
+
+	imported, err := ImportJson(bytes.NewReader(buf.Bytes()), false)
+	if err != nil {
+		t.Fatalf("ImportJson() error = %v", err)
+	}
+	if len(imported) != len(bookmarks) {
+		t.Fatalf("round trip: got %d bookmarks, want %d", len(imported), len(bookmarks))
+	}
+	for i, want := range bookmarks {
+		got := imported[i]
+		if want.Tags == nil {
+			want.Tags = []string{}
+		}
+		want.LowerName = got.LowerName
+		if !got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) {
+			t.Errorf("round trip: timestamps = %v / %v, want %v / %v", got.CreatedAt, got.UpdatedAt,
+				want.CreatedAt, want.UpdatedAt)
+		}
+		got.CreatedAt, got.UpdatedAt = want.CreatedAt, want.UpdatedAt
+		if (got.DueAt == nil) != (want.DueAt == nil) || (got.DueAt != nil && !got.DueAt.Equal(*want.DueAt)) {
+			t.Errorf("round trip: due = %v, want %v", got.DueAt, want.DueAt)
+		}
+		got.DueAt = want.DueAt
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("round trip: bookmark = %+v, want %+v", got, want)
+		}
+	}
+	wantKeys := []string{"Title", "Author"}
+	if !reflect.DeepEqual(*imported[0].MetadataKeys, wantKeys) {
+		t.Errorf("round trip: metadata keys = %v, want %v", *imported[0].MetadataKeys, wantKeys)
+	}
+}
+
+func TestExportText(t *testing.T) {
+	tests := []struct {
+		name   string
+		export func(*bytes.Buffer, []*models.Bookmark) error
+		want   string
+	}{
+		{
+			name: "csv",
+			export: func(buf *bytes.Buffer, bookmarks []*models.Bookmark) error {
+				return ExportCsv(buf, bookmarks)
+			},
+			want: `name,link,description,project,tags,archived,created_at,updated_at,Title,Author
+Effective Go,https://golang.org/doc/effective_go.html,"Tips for writing
+clear Go",dev.go,"go,docs",false,2020-02-22T18:00:00Z,2020-02-23T18:00:00Z,Effective Go - The Go Programming Language,Go team
+SQLite [FTS5],https://sqlite.org/fts5.html,,dev,,true,2020-02-22T18:01:40Z,2020-02-22T18:01:40Z,,
+Example,https://example.com/a page,,,to read,false,2020-01-01T00:00:00Z,2020-01-01T00:00:00Z,,
+`,
+		},
+		{
+			name: "markdown",
+			export: func(buf *bytes.Buffer, bookmarks []*models.Bookmark) error {
+				return ExportMarkdown(buf, bookmarks)
+			},
+			want: "# Bookmarks\n" +
+				"\n" +
+				"- [Example](https://example.com/a%20page) `to read`\n" +
+				"\n" +
+				"## dev\n" +
+				"\n" +
+				"- [SQLite \\[FTS5\\]](https://sqlite.org/fts5.html)\n" +
+				"\n" +
+				"### go\n" +
+				"\n" +
+				"- [Effective Go](https://golang.org/doc/effective_go.html) - Tips for writing clear Go `go` `docs`\n",
+		},
+		{
+			name: "org",
+			export: func(buf *bytes.Buffer, bookmarks []*models.Bookmark) error {
+				return ExportOrg(buf, bookmarks)
+			},
+			want: `#+TITLE: Bookmarks
+* [[https://example.com/a page][Example]] :to_read:
+* dev
+** [[https://sqlite.org/fts5.html][SQLite {FTS5}]]
+** go
+*** [[https://golang.org/doc/effective_go.html][Effective Go]] :go:docs:
+Tips for writing clear Go
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := tt.export(buf, exportBookmarks())
+			if err != nil {
+				t.Fatalf("export error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("export = \n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestExporterForFile(t *testing.T) {
+	tests := map[string]string{
+		"bookmarks.html":  "html",
+		"reading-list.MD": "markdown",
+		"export.json":     "json",
+		"export.org":      "org",
+	}
+	for file, want := range tests {
+		got, err := ExporterForFile(file)
+		if err != nil {
+			t.Errorf("ExporterForFile(%s) error = %v", file, err)
+			continue
+		}
+		if got.Name() != want {
+			t.Errorf("ExporterForFile(%s) = %s, want %s", file, got.Name(), want)
+		}
+	}
+	if _, err := ExporterForFile("export.txt"); err == nil {
+		t.Errorf("ExporterForFile() expected error for unknown extension")
+	}
+}
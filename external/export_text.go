@@ -0,0 +1,163 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package external
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//csv columns before metadata columns
+var csvColumns = []string{"name", "link", "description", "project", "tags", "archived", "created_at", "updated_at"}
+
+//ExportCsv writes bookmarks into csv with a column for each metadata key
+func ExportCsv(writer io.Writer, bookmarks []*models.Bookmark) error {
+	keys := []string{}
+	found := map[string]bool{}
+	for _, b := range bookmarks {
+		for _, key := range metadataKeys(b) {
+			if !found[key] {
+				keys = append(keys, key)
+				found[key] = true
+			}
+		}
+	}
+
+	w := csv.NewWriter(writer)
+	_ = w.Write(append(append([]string{}, csvColumns...), keys...))
+	for _, b := range bookmarks {
+		record := []string{b.Name, b.Content, b.Description, b.Project, b.TagsString(false),
+			strconv.FormatBool(b.Archived), b.CreatedAt.Format(time.RFC3339), b.UpdatedAt.Format(time.RFC3339)}
+		for _, key := range keys {
+			value := ""
+			if b.Metadata != nil {
+				value = (*b.Metadata)[key]
+			}
+			record = append(record, value)
+		}
+		_ = w.Write(record)
+	}
+	w.Flush()
+	err := w.Error()
+	if err != nil {
+		return fmt.Errorf("write csv: %v", err)
+	}
+	return nil
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "*", `\*`, "_", `\_`, "`", "\\`")
+var markdownLinkEscaper = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29")
+
+//ExportMarkdown writes bookmarks as markdown outline with a heading for each project
+func ExportMarkdown(writer io.Writer, bookmarks []*models.Bookmark) error {
+	w := bufio.NewWriter(writer)
+	_, _ = w.WriteString("# Bookmarks\n")
+
+	writeBookmarks := func(bookmarks []*models.Bookmark) {
+		if len(bookmarks) == 0 {
+			return
+		}
+		_, _ = w.WriteString("\n")
+		for _, b := range bookmarks {
+			_, _ = w.WriteString(fmt.Sprintf("- [%s](%s)", markdownEscaper.Replace(b.Name),
+				markdownLinkEscaper.Replace(b.Content)))
+			if b.Description != "" {
+				_, _ = w.WriteString(" - " + markdownEscaper.Replace(oneLine(b.Description)))
+			}
+			if len(b.Tags) > 0 {
+				_, _ = w.WriteString(" `" + strings.Join(b.Tags, "` `") + "`")
+			}
+			_, _ = w.WriteString("\n")
+		}
+	}
+
+	projects, byProject := projectTree(bookmarks)
+	writeBookmarks(byProject[""])
+	walkProjects(projects, 2, func(project *models.Project, depth int) {
+		if depth > 6 {
+			depth = 6
+		}
+		_, _ = w.WriteString(fmt.Sprintf("\n%s %s\n", strings.Repeat("#", depth), markdownEscaper.Replace(project.Name)))
+		writeBookmarks(byProject[project.FullName()])
+	})
+
+	err := w.Flush()
+	if err != nil {
+		return fmt.Errorf("write markdown: %v", err)
+	}
+	return nil
+}
+
+//org tags may only contain letters, numbers, _, @, # and %
+var orgTagInvalid = regexp.MustCompile(`[^\p{L}\p{N}_@#%]`)
+
+var orgEscaper = strings.NewReplacer("[", "{", "]", "}")
+
+//ExportOrg writes bookmarks as org-mode outline. Projects are headlines and bookmarks are
+//headlines under their project with tags and description.
+func ExportOrg(writer io.Writer, bookmarks []*models.Bookmark) error {
+	w := bufio.NewWriter(writer)
+	_, _ = w.WriteString("#+TITLE: Bookmarks\n")
+
+	writeBookmarks := func(bookmarks []*models.Bookmark, depth int) {
+		for _, b := range bookmarks {
+			_, _ = w.WriteString(fmt.Sprintf("%s [[%s][%s]]", strings.Repeat("*", depth),
+				orgEscaper.Replace(b.Content), orgEscaper.Replace(b.Name)))
+			if len(b.Tags) > 0 {
+				tags := make([]string, len(b.Tags))
+				for i, v := range b.Tags {
+					tags[i] = orgTagInvalid.ReplaceAllString(v, "_")
+				}
+				_, _ = w.WriteString(" :" + strings.Join(tags, ":") + ":")
+			}
+			_, _ = w.WriteString("\n")
+			if b.Description != "" {
+				description := oneLine(b.Description)
+				// Line starting with * would be a headline
+				if strings.HasPrefix(description, "*") {
+					description = "," + description
+				}
+				_, _ = w.WriteString(description + "\n")
+			}
+		}
+	}
+
+	projects, byProject := projectTree(bookmarks)
+	writeBookmarks(byProject[""], 1)
+	walkProjects(projects, 1, func(project *models.Project, depth int) {
+		_, _ = w.WriteString(fmt.Sprintf("%s %s\n", strings.Repeat("*", depth), project.Name))
+		writeBookmarks(byProject[project.FullName()], depth+1)
+	})
+
+	err := w.Flush()
+	if err != nil {
+		return fmt.Errorf("write org: %v", err)
+	}
+	return nil
+}
+
+//oneLine replaces line breaks with spaces
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
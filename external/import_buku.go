@@ -0,0 +1,97 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//Buku doesn't store timestamps, so bookmarks are created with import time
+type bukuBookmark struct {
+	Uri         string `json:"uri" db:"url"`
+	Title       string `json:"title" db:"metadata"`
+	Description string `json:"description" db:"desc"`
+	Tags        string `json:"tags" db:"tags"`
+}
+
+func (b *bukuBookmark) bookmark() *models.Bookmark {
+	bookmark := newBookmark(b.Title, b.Uri, time.Time{}, time.Time{})
+	bookmark.Description = b.Description
+	bookmark.Tags = splitTags(b.Tags, ",")
+	return bookmark
+}
+
+func detectBukuJson(head []byte) bool {
+	return containsAll(head, `"uri"`, `"index"`)
+}
+
+//ImportBukuJson parses json printed with 'buku --print --json'
+func ImportBukuJson(reader io.Reader, mapFoldersProjects bool) ([]*models.Bookmark, error) {
+	items := []bukuBookmark{}
+	err := json.NewDecoder(reader).Decode(&items)
+	if err != nil {
+		return nil, fmt.Errorf("parse json: %v", err)
+	}
+
+	bookmarks := make([]*models.Bookmark, 0, len(items))
+	for _, v := range items {
+		if v.Uri != "" {
+			bookmarks = append(bookmarks, v.bookmark())
+		}
+	}
+	return bookmarks, nil
+}
+
+//BukuDbImporter reads bookmarks from buku database bookmarks.db or database exported with 'buku --export file.db'
+type BukuDbImporter struct{}
+
+func (b *BukuDbImporter) Name() string {
+	return "buku-db"
+}
+
+func (b *BukuDbImporter) Detect(file string, head []byte) bool {
+	return sqliteHasColumns(file, head, "bookmarks", "url", "metadata", "desc", "flags")
+}
+
+func (b *BukuDbImporter) Import(file string, mapFoldersProjects bool) ([]*models.Bookmark, error) {
+	db, err := openSqlite(file)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	items := []bukuBookmark{}
+	err = db.Select(&items, `
+SELECT URL AS url, COALESCE(metadata, '') AS metadata, COALESCE(desc, '') AS desc, 
+	COALESCE(tags, '') AS tags
+FROM bookmarks ORDER BY id;`)
+	if err != nil {
+		return nil, fmt.Errorf("query bookmarks: %v", err)
+	}
+
+	bookmarks := make([]*models.Bookmark, 0, len(items))
+	for _, v := range items {
+		if v.Uri != "" {
+			bookmarks = append(bookmarks, v.bookmark())
+		}
+	}
+	return bookmarks, nil
+}
@@ -0,0 +1,123 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//Chromium timestamps are microseconds since 1601-01-01
+var chromiumEpoch = time.Date(1601, 1, 1, 0, 0, 0, 0, time.UTC)
+
+//Chromium root folders in order, roots are not mapped to projects
+var chromiumRoots = []string{"bookmark_bar", "other", "synced"}
+
+type chromiumNode struct {
+	Name         string         `json:"name"`
+	Type         string         `json:"type"`
+	Url          string         `json:"url"`
+	DateAdded    string         `json:"date_added"`
+	DateModified string         `json:"date_modified"`
+	Children     []chromiumNode `json:"children"`
+}
+
+func detectChromiumJson(head []byte) bool {
+	return containsAll(head, `"roots"`, `"checksum"`)
+}
+
+//ImportChromiumJson parses 'Bookmarks' file in Chrome, Chromium, Edge, Brave or Vivaldi profile directory
+func ImportChromiumJson(reader io.Reader, mapFoldersProjects bool) ([]*models.Bookmark, error) {
+	file := struct {
+		Roots map[string]json.RawMessage `json:"roots"`
+	}{}
+	err := json.NewDecoder(reader).Decode(&file)
+	if err != nil {
+		return nil, fmt.Errorf("parse json: %v", err)
+	}
+
+	names := []string{}
+	for _, v := range chromiumRoots {
+		if _, ok := file.Roots[v]; ok {
+			names = append(names, v)
+		}
+	}
+	others := []string{}
+	for key := range file.Roots {
+		if !chromiumKnownRoot(key) {
+			others = append(others, key)
+		}
+	}
+	sort.Strings(others)
+	names = append(names, others...)
+
+	bookmarks := []*models.Bookmark{}
+	var parse func(node *chromiumNode, folders []string)
+	parse = func(node *chromiumNode, folders []string) {
+		switch node.Type {
+		case "url":
+			if node.Url == "" {
+				return
+			}
+			created := chromiumTime(node.DateAdded)
+			b := newBookmark(node.Name, node.Url, created, created)
+			if mapFoldersProjects {
+				b.Project = folderProject(folders)
+			}
+			bookmarks = append(bookmarks, b)
+		case "folder":
+			folders = append(folders[:len(folders):len(folders)], node.Name)
+			for i := range node.Children {
+				parse(&node.Children[i], folders)
+			}
+		}
+	}
+
+	for _, name := range names {
+		// Other values in roots, e.g. sync_transaction_version, are not folders
+		root := chromiumNode{}
+		if json.Unmarshal(file.Roots[name], &root) != nil || root.Type != "folder" {
+			continue
+		}
+		for i := range root.Children {
+			parse(&root.Children[i], []string{})
+		}
+	}
+	return bookmarks, nil
+}
+
+func chromiumKnownRoot(name string) bool {
+	for _, v := range chromiumRoots {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+func chromiumTime(ts string) time.Time {
+	micros, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return microsToTime(micros, chromiumEpoch)
+}
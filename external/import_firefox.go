@@ -0,0 +1,205 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+const (
+	firefoxBookmark = "text/x-moz-place"
+	firefoxFolder   = "text/x-moz-place-container"
+	//root folder guid of tags in places.sqlite
+	firefoxTagsGuid = "tags________"
+	//old backups store tags as folders
+	firefoxTagsRoot = "tagsFolder"
+	//places queries, e.g. 'Most visited', are not bookmarks
+	firefoxQuery = "place:"
+)
+
+//Firefox root folders, e.g. 'Bookmarks Menu', are not mapped to projects
+var firefoxRootGuids = map[string]bool{
+	"root________": true,
+	"menu________": true,
+	"toolbar_____": true,
+	"unfiled_____": true,
+	"mobile______": true,
+}
+
+type firefoxNode struct {
+	Title        string        `json:"title"`
+	Uri          string        `json:"uri"`
+	Type         string        `json:"type"`
+	Root         string        `json:"root"`
+	DateAdded    int64         `json:"dateAdded"`
+	LastModified int64         `json:"lastModified"`
+	Tags         string        `json:"tags"`
+	Children     []firefoxNode `json:"children"`
+}
+
+func detectFirefoxJson(head []byte) bool {
+	return containsAll(head, firefoxFolder, `"root"`)
+}
+
+//ImportFirefoxJson parses bookmarks backup in json format made with Firefox library
+func ImportFirefoxJson(reader io.Reader, mapFoldersProjects bool) ([]*models.Bookmark, error) {
+	root := firefoxNode{}
+	err := json.NewDecoder(reader).Decode(&root)
+	if err != nil {
+		return nil, fmt.Errorf("parse json: %v", err)
+	}
+
+	bookmarks := []*models.Bookmark{}
+	var parse func(node *firefoxNode, folders []string)
+	parse = func(node *firefoxNode, folders []string) {
+		switch node.Type {
+		case firefoxBookmark:
+			if node.Uri == "" || strings.HasPrefix(node.Uri, firefoxQuery) {
+				return
+			}
+			b := newBookmark(node.Title, node.Uri, microsToTime(node.DateAdded, time.Unix(0, 0)),
+				microsToTime(node.LastModified, time.Unix(0, 0)))
+			b.Tags = splitTags(node.Tags, ",")
+			if mapFoldersProjects {
+				b.Project = folderProject(folders)
+			}
+			bookmarks = append(bookmarks, b)
+		case firefoxFolder:
+			if node.Root == firefoxTagsRoot {
+				return
+			}
+			if node.Root == "" {
+				folders = append(folders[:len(folders):len(folders)], node.Title)
+			}
+			for i := range node.Children {
+				parse(&node.Children[i], folders)
+			}
+		}
+	}
+	parse(&root, []string{})
+	return bookmarks, nil
+}
+
+//FirefoxPlacesImporter reads bookmarks from Firefox profile database places.sqlite.
+//Database is opened as immutable, so it can be read while Firefox is running.
+type FirefoxPlacesImporter struct{}
+
+type placesItem struct {
+	Id           int
+	Type         int
+	Parent       int
+	Title        string
+	Guid         string
+	Url          string
+	DateAdded    int64 `db:"date_added"`
+	LastModified int64 `db:"last_modified"`
+}
+
+func (f *FirefoxPlacesImporter) Name() string {
+	return "firefox-places"
+}
+
+func (f *FirefoxPlacesImporter) Detect(file string, head []byte) bool {
+	return sqliteHasColumns(file, head, "moz_bookmarks", "fk", "parent", "guid")
+}
+
+func (f *FirefoxPlacesImporter) Import(file string, mapFoldersProjects bool) ([]*models.Bookmark, error) {
+	db, err := openSqlite(file)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	items := []placesItem{}
+	err = db.Select(&items, `
+SELECT b.id, b.type, b.parent, COALESCE(b.title, '') AS title, COALESCE(b.guid, '') AS guid,
+	COALESCE(p.url, '') AS url, COALESCE(b.dateAdded, 0) AS date_added, 
+	COALESCE(b.lastModified, 0) AS last_modified
+FROM moz_bookmarks b
+LEFT JOIN moz_places p ON b.fk = p.id
+ORDER BY b.parent, b.position;`)
+	if err != nil {
+		return nil, fmt.Errorf("query bookmarks: %v", err)
+	}
+
+	byId := make(map[int]*placesItem, len(items))
+	for i := range items {
+		byId[items[i].Id] = &items[i]
+	}
+
+	// Tags are folders in tags root and tagged urls are bookmarks inside them
+	tags := map[string][]string{}
+	isTag := func(item *placesItem) bool {
+		parent, ok := byId[item.Parent]
+		return ok && parent.Guid == firefoxTagsGuid
+	}
+
+	// Returns folders of item, or false if item is inside tags root
+	var folders func(id int) ([]string, bool)
+	folders = func(id int) ([]string, bool) {
+		item, ok := byId[id]
+		if !ok {
+			return []string{}, true
+		}
+		if item.Guid == firefoxTagsGuid {
+			return nil, false
+		}
+		parents, ok := folders(item.Parent)
+		if !ok || firefoxRootGuids[item.Guid] {
+			return parents, ok
+		}
+		return append(parents, item.Title), true
+	}
+
+	type placesBookmark struct {
+		item    *placesItem
+		folders []string
+	}
+	found := []placesBookmark{}
+	for i := range items {
+		item := &items[i]
+		if item.Type != 1 || item.Url == "" || strings.HasPrefix(item.Url, firefoxQuery) {
+			continue
+		}
+		if parent, ok := byId[item.Parent]; ok && isTag(parent) {
+			tags[item.Url] = append(tags[item.Url], parent.Title)
+			continue
+		}
+		path, ok := folders(item.Parent)
+		if !ok {
+			continue
+		}
+		found = append(found, placesBookmark{item: item, folders: path})
+	}
+
+	bookmarks := make([]*models.Bookmark, 0, len(found))
+	for _, v := range found {
+		b := newBookmark(v.item.Title, v.item.Url, microsToTime(v.item.DateAdded, time.Unix(0, 0)),
+			microsToTime(v.item.LastModified, time.Unix(0, 0)))
+		b.Tags = append([]string{}, tags[v.item.Url]...)
+		if mapFoldersProjects {
+			b.Project = folderProject(v.folders)
+		}
+		bookmarks = append(bookmarks, b)
+	}
+	return bookmarks, nil
+}
@@ -0,0 +1,66 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//Pinboard marks unread bookmarks with toread, they are imported with this tag
+const pinboardToRead = "toread"
+
+type pinboardBookmark struct {
+	Href        string `json:"href"`
+	Description string `json:"description"`
+	Extended    string `json:"extended"`
+	Time        string `json:"time"`
+	ToRead      string `json:"toread"`
+	Tags        string `json:"tags"`
+}
+
+func detectPinboardJson(head []byte) bool {
+	return containsAll(head, `"href"`, `"toread"`)
+}
+
+//ImportPinboardJson parses json exported from Pinboard
+func ImportPinboardJson(reader io.Reader, mapFoldersProjects bool) ([]*models.Bookmark, error) {
+	items := []pinboardBookmark{}
+	err := json.NewDecoder(reader).Decode(&items)
+	if err != nil {
+		return nil, fmt.Errorf("parse json: %v", err)
+	}
+
+	bookmarks := make([]*models.Bookmark, 0, len(items))
+	for _, v := range items {
+		if v.Href == "" {
+			continue
+		}
+		created, _ := time.Parse(time.RFC3339, v.Time)
+		b := newBookmark(v.Description, v.Href, created, created)
+		b.Description = v.Extended
+		b.Tags = splitTags(v.Tags, " ")
+		if v.ToRead == "yes" {
+			b.AddTag(pinboardToRead)
+		}
+		bookmarks = append(bookmarks, b)
+	}
+	return bookmarks, nil
+}
@@ -0,0 +1,126 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package external
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"golang.org/x/net/html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//Pocket lists items in 'Unread' and 'Read Archive' sections, archived items are imported as archived
+const pocketArchive = "read archive"
+
+func detectPocketHtml(head []byte) bool {
+	return bytes.Contains(bytes.ToLower(head), []byte("<title>pocket export</title>"))
+}
+
+func detectPocketCsv(head []byte) bool {
+	return bytes.HasPrefix(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")), []byte("title,url,time_added"))
+}
+
+//ImportPocketHtml parses ril_export.html exported from Pocket
+func ImportPocketHtml(reader io.Reader, mapFoldersProjects bool) ([]*models.Bookmark, error) {
+	doc, err := html.Parse(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse document: %v", err)
+	}
+
+	bookmarks := []*models.Bookmark{}
+	archived := false
+
+	var parse func(node *html.Node)
+	parse = func(node *html.Node) {
+		if node.Type == html.ElementNode {
+			switch node.Data {
+			case "h1":
+				archived = strings.ToLower(strings.TrimSpace(text(node))) == pocketArchive
+			case "a":
+				link := attr(node, "href")
+				if link != "" {
+					created := parseUnixTs(attr(node, "time_added"))
+					b := newBookmark(text(node), link, created, created)
+					b.Tags = splitTags(attr(node, "tags"), ",")
+					b.Archived = archived
+					bookmarks = append(bookmarks, b)
+				}
+				return
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			parse(child)
+		}
+	}
+	parse(doc)
+	return bookmarks, nil
+}
+
+//ImportPocketCsv parses part_*.csv exported from Pocket
+func ImportPocketCsv(reader io.Reader, mapFoldersProjects bool) ([]*models.Bookmark, error) {
+	r := csv.NewReader(reader)
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %v", err)
+	}
+	columns := map[string]int{}
+	for i, v := range header {
+		columns[strings.TrimPrefix(strings.TrimSpace(v), "\ufeff")] = i
+	}
+	if _, ok := columns["url"]; !ok {
+		return nil, fmt.Errorf("no url column")
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return record[i]
+	}
+
+	bookmarks := []*models.Bookmark{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read line: %v", err)
+		}
+		link := field(record, "url")
+		if link == "" {
+			continue
+		}
+
+		created := time.Time{}
+		if ts, err := strconv.ParseInt(field(record, "time_added"), 10, 64); err == nil {
+			created = time.Unix(ts, 0)
+		}
+		b := newBookmark(field(record, "title"), link, created, created)
+		b.Tags = splitTags(field(record, "tags"), "|")
+		b.Archived = field(record, "status") == "archive"
+		bookmarks = append(bookmarks, b)
+	}
+	return bookmarks, nil
+}
@@ -0,0 +1,71 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+type shioriBookmark struct {
+	Url      string `json:"url"`
+	Title    string `json:"title"`
+	Excerpt  string `json:"excerpt"`
+	Modified string `json:"modified"`
+	Tags     []struct {
+		Name string `json:"name"`
+	} `json:"tags"`
+}
+
+//shiori stores modification time without time zone
+const shioriTimeFormat = "2006-01-02 15:04:05"
+
+func detectShioriJson(head []byte) bool {
+	return containsAll(head, `"url"`, `"excerpt"`)
+}
+
+//ImportShioriJson parses json printed with 'shiori print --json'
+func ImportShioriJson(reader io.Reader, mapFoldersProjects bool) ([]*models.Bookmark, error) {
+	items := []shioriBookmark{}
+	err := json.NewDecoder(reader).Decode(&items)
+	if err != nil {
+		return nil, fmt.Errorf("parse json: %v", err)
+	}
+
+	bookmarks := make([]*models.Bookmark, 0, len(items))
+	for _, v := range items {
+		if v.Url == "" {
+			continue
+		}
+		modified, err := time.Parse(shioriTimeFormat, v.Modified)
+		if err != nil {
+			modified, _ = time.Parse(time.RFC3339, v.Modified)
+		}
+		b := newBookmark(v.Title, v.Url, modified, modified)
+		b.Description = v.Excerpt
+		for _, tag := range v.Tags {
+			if tag.Name != "" {
+				b.AddTag(tag.Name)
+			}
+		}
+		bookmarks = append(bookmarks, b)
+	}
+	return bookmarks, nil
+}
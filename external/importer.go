@@ -0,0 +1,247 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package external
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//bytes read from beginning of file to detect its format
+const detectSize = 4096
+
+//Importer reads bookmarks from file exported by browser or other bookmark manager
+type Importer interface {
+	//Name returns name of the format
+	Name() string
+	//Detect returns true if file is in importer's format. Head contains beginning of file.
+	Detect(file string, head []byte) bool
+	//Import reads bookmarks from file. If mapFoldersProjects is set, folders are mapped to projects.
+	Import(file string, mapFoldersProjects bool) ([]*models.Bookmark, error)
+}
+
+//Importers are supported formats in order of detection
+var Importers = []Importer{
+	&FirefoxPlacesImporter{},
+	&BukuDbImporter{},
+	&readerImporter{name: "bookmarker-json", detect: detectJson, parse: ImportJson},
+	&readerImporter{name: "pocket-html", detect: detectPocketHtml, parse: ImportPocketHtml},
+	&readerImporter{name: "netscape-html", detect: detectNetscapeHtml, parse: ImportBookmarksHtml},
+	&readerImporter{name: "pocket-csv", detect: detectPocketCsv, parse: ImportPocketCsv},
+	&readerImporter{name: "firefox-json", detect: detectFirefoxJson, parse: ImportFirefoxJson},
+	&readerImporter{name: "chromium-json", detect: detectChromiumJson, parse: ImportChromiumJson},
+	&readerImporter{name: "pinboard-json", detect: detectPinboardJson, parse: ImportPinboardJson},
+	&readerImporter{name: "shiori-json", detect: detectShioriJson, parse: ImportShioriJson},
+	&readerImporter{name: "buku-json", detect: detectBukuJson, parse: ImportBukuJson},
+}
+
+//ImporterNames returns names of supported formats
+func ImporterNames() []string {
+	names := make([]string, len(Importers))
+	for i, v := range Importers {
+		names[i] = v.Name()
+	}
+	return names
+}
+
+//GetImporter returns importer with given name
+func GetImporter(name string) (Importer, error) {
+	for _, v := range Importers {
+		if v.Name() == name {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown format '%s', supported formats: %s", name, strings.Join(ImporterNames(), ", "))
+}
+
+//DetectImporter returns importer that is able to read file
+func DetectImporter(file string) (Importer, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, detectSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("read file: %v", err)
+	}
+	head = head[:n]
+
+	for _, v := range Importers {
+		if v.Detect(file, head) {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown file format")
+}
+
+//ImportFile detects format of file and reads bookmarks from it. Returns bookmarks and name of format.
+func ImportFile(file string, mapFoldersProjects bool) ([]*models.Bookmark, string, error) {
+	importer, err := DetectImporter(file)
+	if err != nil {
+		return nil, "", err
+	}
+	bookmarks, err := importer.Import(file, mapFoldersProjects)
+	if err != nil {
+		return nil, importer.Name(), fmt.Errorf("import %s: %v", importer.Name(), err)
+	}
+	return bookmarks, importer.Name(), nil
+}
+
+//readerImporter is importer for formats that are parsed from io.Reader
+type readerImporter struct {
+	name   string
+	detect func(head []byte) bool
+	parse  func(reader io.Reader, mapFoldersProjects bool) ([]*models.Bookmark, error)
+}
+
+func (r *readerImporter) Name() string {
+	return r.name
+}
+
+func (r *readerImporter) Detect(file string, head []byte) bool {
+	return r.detect(head)
+}
+
+func (r *readerImporter) Import(file string, mapFoldersProjects bool) ([]*models.Bookmark, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return r.parse(f, mapFoldersProjects)
+}
+
+//folderProject returns project for folder path
+func folderProject(folders []string) string {
+	names := make([]string, 0, len(folders))
+	for _, v := range folders {
+		if v == "" {
+			continue
+		}
+		names = append(names, strings.Replace(v, separator, replaceDots, -1))
+	}
+	return strings.Join(names, separator)
+}
+
+//splitTags splits tags with separator, removing empty tags
+func splitTags(tags string, separator string) []string {
+	result := []string{}
+	for _, v := range strings.Split(tags, separator) {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+//newBookmark returns bookmark with name, defaulting to link if name is empty
+func newBookmark(name, link string, createdAt, updatedAt time.Time) *models.Bookmark {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = link
+	}
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	if updatedAt.IsZero() {
+		updatedAt = createdAt
+	}
+	return &models.Bookmark{
+		Name:      name,
+		LowerName: strings.ToLower(name),
+		Content:   link,
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
+	}
+}
+
+//microsToTime converts microseconds since epoch to time, zero value returns zero time
+func microsToTime(micros int64, epoch time.Time) time.Time {
+	if micros == 0 {
+		return time.Time{}
+	}
+	// Duration overflows for timestamps since 1601
+	return time.Unix(epoch.Unix()+micros/1000000, (micros%1000000)*1000)
+}
+
+//containsAll returns true if head contains all keys
+func containsAll(head []byte, keys ...string) bool {
+	text := string(head)
+	for _, v := range keys {
+		if !strings.Contains(text, v) {
+			return false
+		}
+	}
+	return true
+}
+
+//sqliteMagic is header of sqlite database file
+var sqliteMagic = []byte("SQLite format 3\x00")
+
+//escape characters that have special meaning in sqlite uri
+var sqlitePathEscaper = strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23")
+
+//openSqlite opens sqlite database in read-only mode
+func openSqlite(file string) (*sqlx.DB, error) {
+	url := fmt.Sprintf("file:%s?mode=ro&immutable=1", sqlitePathEscaper.Replace(file))
+	db, err := sqlx.Connect("sqlite3", url)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %v", err)
+	}
+	return db, nil
+}
+
+//sqliteHasColumns returns true if file is sqlite database that has table with given columns
+func sqliteHasColumns(file string, head []byte, table string, columns ...string) bool {
+	if !bytes.HasPrefix(head, sqliteMagic) {
+		return false
+	}
+	db, err := openSqlite(file)
+	if err != nil {
+		return false
+	}
+	defer db.Close()
+
+	names := []string{}
+	err = db.Select(&names, "SELECT LOWER(name) FROM pragma_table_info(?);", table)
+	if err != nil {
+		return false
+	}
+	for _, column := range columns {
+		found := false
+		for _, v := range names {
+			if v == strings.ToLower(column) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
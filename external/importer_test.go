@@ -0,0 +1,289 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package external
+
+import (
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//importedBookmark holds fields that are compared in import tests. Zero created is not compared.
+type importedBookmark struct {
+	name        string
+	link        string
+	project     string
+	description string
+	tags        []string
+	archived    bool
+	created     int64
+}
+
+func compareImported(t *testing.T, name string, got []*models.Bookmark, want []importedBookmark) {
+	if len(got) != len(want) {
+		t.Errorf("%s: got %d bookmarks, want %d", name, len(got), len(want))
+		return
+	}
+	for i, w := range want {
+		b := got[i]
+		tags := b.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		if w.tags == nil {
+			w.tags = []string{}
+		}
+		g := importedBookmark{
+			name:        b.Name,
+			link:        b.Content,
+			project:     b.Project,
+			description: b.Description,
+			tags:        tags,
+			archived:    b.Archived,
+			created:     w.created,
+		}
+		if w.created != 0 {
+			g.created = b.CreatedAt.Unix()
+		}
+		if !reflect.DeepEqual(g, w) {
+			t.Errorf("%s: bookmark %d = %+v, want %+v", name, i, g, w)
+		}
+		if b.CreatedAt.IsZero() || b.UpdatedAt.IsZero() {
+			t.Errorf("%s: bookmark %d has empty timestamps", name, i)
+		}
+	}
+}
+
+func TestImportFile(t *testing.T) {
+	golang := importedBookmark{
+		name:    "Effective Go",
+		link:    "https://golang.org/doc/effective_go.html",
+		tags:    []string{"go", "docs"},
+		created: 1582394400,
+	}
+	sqlite := importedBookmark{
+		name:    "SQLite FTS5 Extension",
+		link:    "https://sqlite.org/fts5.html",
+		created: 1582394500,
+	}
+	with := func(b importedBookmark, modify func(b *importedBookmark)) importedBookmark {
+		modify(&b)
+		return b
+	}
+
+	tests := []struct {
+		file   string
+		format string
+		want   []importedBookmark
+	}{
+		{
+			file:   "netscape.html",
+			format: "netscape-html",
+			want: []importedBookmark{
+				with(golang, func(b *importedBookmark) {
+					b.project = "dev"
+					b.description = "Tips for writing clear Go"
+				}),
+				sqlite,
+			},
+		},
+		{
+			file:   "pocket.html",
+			format: "pocket-html",
+			want:   []importedBookmark{golang, with(sqlite, func(b *importedBookmark) { b.archived = true })},
+		},
+		{
+			file:   "pocket.csv",
+			format: "pocket-csv",
+			want: []importedBookmark{golang, with(sqlite, func(b *importedBookmark) {
+				b.name = "SQLite FTS5, Extension"
+				b.archived = true
+			})},
+		},
+		{
+			file:   "pinboard.json",
+			format: "pinboard-json",
+			want: []importedBookmark{
+				with(golang, func(b *importedBookmark) {
+					b.description = "Tips for writing clear Go"
+					b.tags = []string{"go", "docs", "toread"}
+				}),
+				sqlite,
+			},
+		},
+		{
+			file:   "firefox.json",
+			format: "firefox-json",
+			want:   []importedBookmark{with(golang, func(b *importedBookmark) { b.project = "dev.golang-org" }), sqlite},
+		},
+		{
+			file:   "Bookmarks",
+			format: "chromium-json",
+			want: []importedBookmark{
+				with(golang, func(b *importedBookmark) {
+					b.project = "dev.golang-org"
+					b.tags = nil
+				}),
+				sqlite,
+			},
+		},
+		{
+			file:   "shiori.json",
+			format: "shiori-json",
+			want: []importedBookmark{
+				with(golang, func(b *importedBookmark) { b.description = "Tips for writing clear Go" }),
+				sqlite,
+			},
+		},
+		{
+			file:   "buku.json",
+			format: "buku-json",
+			want: []importedBookmark{
+				with(golang, func(b *importedBookmark) {
+					b.description = "Tips for writing clear Go"
+					b.tags = []string{"docs", "go"}
+					b.created = 0
+				}),
+				with(sqlite, func(b *importedBookmark) { b.created = 0 }),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, format, err := ImportFile(filepath.Join("testdata", "import", tt.file), true)
+			if err != nil {
+				t.Fatalf("ImportFile() error = %v", err)
+			}
+			if format != tt.format {
+				t.Errorf("ImportFile() format = %s, want %s", format, tt.format)
+			}
+			compareImported(t, tt.file, got, tt.want)
+		})
+	}
+}
+
+func TestImportFile_unknown(t *testing.T) {
+	_, _, err := ImportFile(filepath.Join("testdata", "article.html"), false)
+	if err == nil {
+		t.Errorf("ImportFile() expected error for unknown format")
+	}
+}
+
+//createSqlite creates sqlite database in dir with given statements
+func createSqlite(t *testing.T, dir, name string, statements ...string) string {
+	file := filepath.Join(dir, name)
+	db, err := sqlx.Connect("sqlite3", file)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	defer db.Close()
+	for _, v := range statements {
+		if _, err := db.Exec(v); err != nil {
+			t.Fatalf("create database: %v", err)
+		}
+	}
+	return file
+}
+
+func TestImportFile_sqlite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bookmarker-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	places := createSqlite(t, dir, "places.sqlite",
+		`CREATE TABLE moz_places (id INTEGER PRIMARY KEY, url LONGVARCHAR, title LONGVARCHAR);`,
+		`CREATE TABLE moz_bookmarks (id INTEGER PRIMARY KEY, type INTEGER, fk INTEGER DEFAULT NULL, 
+			parent INTEGER, position INTEGER, title LONGVARCHAR, dateAdded INTEGER, lastModified INTEGER, 
+			guid TEXT);`,
+		`INSERT INTO moz_places (id, url) VALUES (1, 'https://golang.org/doc/effective_go.html'), 
+			(2, 'https://sqlite.org/fts5.html'), (3, 'place:sort=8');`,
+		fmt.Sprintf(`INSERT INTO moz_bookmarks (id, type, fk, parent, position, title, dateAdded, lastModified, guid) 
+		VALUES 
+			(1, 2, NULL, 0, 0, '', 0, 0, 'root________'),
+			(2, 2, NULL, 1, 0, 'menu', 0, 0, 'menu________'),
+			(3, 2, NULL, 1, 1, 'toolbar', 0, 0, 'toolbar_____'),
+			(4, 2, NULL, 1, 2, 'tags', 0, 0, 'tags________'),
+			(5, 2, NULL, 2, 0, 'dev', 0, 0, 'aB3dEfGhIjKl'),
+			(6, 2, NULL, 5, 0, 'golang.org', 0, 0, 'bC4eFgHiJkLm'),
+			(7, 1, 1, 6, 0, 'Effective Go', %[1]d, %[1]d, 'cD5fGhIjKlMn'),
+			(8, 1, 2, 3, 0, 'SQLite FTS5 Extension', %[2]d, %[2]d, 'dE6gHiJkLmNo'),
+			(9, 1, 3, 2, 1, 'Most Visited', 0, 0, 'eF7hIjKlMnOp'),
+			(10, 2, NULL, 4, 0, 'go', 0, 0, 'fG8iJkLmNoPq'),
+			(11, 1, 1, 10, 0, NULL, 0, 0, 'gH9jKlMnOpQr'),
+			(12, 2, NULL, 4, 1, 'docs', 0, 0, 'hI0kLmNoPqRs'),
+			(13, 1, 1, 12, 0, NULL, 0, 0, 'iJ1lMnOpQrSt');`,
+			int64(1582394400)*1000000, int64(1582394500)*1000000),
+	)
+
+	buku := createSqlite(t, dir, "bookmarks.db",
+		`CREATE TABLE bookmarks (id integer PRIMARY KEY, URL text NOT NULL UNIQUE, metadata text default '', 
+			tags text default ',', desc text default '', flags integer default 0);`,
+		`INSERT INTO bookmarks (URL, metadata, tags, desc) VALUES 
+			('https://golang.org/doc/effective_go.html', 'Effective Go', ',docs,go,', 'Tips for writing clear Go'),
+			('https://sqlite.org/fts5.html', 'SQLite FTS5 Extension', ',', '');`,
+	)
+
+	tests := []struct {
+		file   string
+		format string
+		want   []importedBookmark
+	}{
+		{
+			file:   places,
+			format: "firefox-places",
+			want: []importedBookmark{
+				{name: "SQLite FTS5 Extension", link: "https://sqlite.org/fts5.html", created: 1582394500},
+				{name: "Effective Go", link: "https://golang.org/doc/effective_go.html", project: "dev.golang-org",
+					tags: []string{"go", "docs"}, created: 1582394400},
+			},
+		},
+		{
+			file:   buku,
+			format: "buku-db",
+			want: []importedBookmark{
+				{name: "Effective Go", link: "https://golang.org/doc/effective_go.html",
+					description: "Tips for writing clear Go", tags: []string{"docs", "go"}},
+				{name: "SQLite FTS5 Extension", link: "https://sqlite.org/fts5.html"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, format, err := ImportFile(tt.file, true)
+			if err != nil {
+				t.Fatalf("ImportFile() error = %v", err)
+			}
+			if format != tt.format {
+				t.Errorf("ImportFile() format = %s, want %s", format, tt.format)
+			}
+			compareImported(t, tt.format, got, tt.want)
+		})
+	}
+
+	// Database of other application is not detected
+	other := createSqlite(t, dir, "other.db", `CREATE TABLE bookmarks (id INTEGER PRIMARY KEY, name TEXT);`)
+	if _, err := DetectImporter(other); err == nil {
+		t.Errorf("DetectImporter() expected error for unknown database")
+	}
+}
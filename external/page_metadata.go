@@ -17,49 +17,335 @@
 package external
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"tryffel.net/go/bookmarker/config"
 )
 
+//Max time to fetch page
+const pageTimeout = time.Second * 15
+
+//Max bytes to read from page
+const maxPageSize = 4 * 1024 * 1024
+
+var pageClient = &http.Client{Timeout: pageTimeout}
+
+//PageMetadata is metadata of a web page. Values are collected from html head, OpenGraph and
+//Twitter card tags and JSON-LD Article data.
 type PageMetadata struct {
-	Title string
+	Title       string
+	Description string
+	Author      string
+	//PublishedAt is zero if page does not define it
+	PublishedAt  time.Time
+	Language     string
+	SiteName     string
+	CanonicalUrl string
+	FaviconUrl   string
 }
 
-func GetPageMetadata(url string) (*PageMetadata, error) {
-	metadata := &PageMetadata{}
+//Fields returns page metadata as bookmark metadata fields. Empty values are not included.
+func (p *PageMetadata) Fields() map[string]string {
+	fields := map[string]string{}
+	set := func(key, value string) {
+		if value != "" {
+			fields[key] = value
+		}
+	}
+	set("Title", p.Title)
+	set("Author", p.Author)
+	set("Language", p.Language)
+	if !p.PublishedAt.IsZero() {
+		set("Published At", p.PublishedAt.Format("2006-01-02"))
+	}
+	set("Site Name", p.SiteName)
+	set("Canonical Url", p.CanonicalUrl)
+	set("Favicon", p.FaviconUrl)
+	return fields
+}
 
-	resp, err := http.Get(url)
+//GetPageMetadata fetches page and parses its metadata
+func GetPageMetadata(link string) (*PageMetadata, error) {
+	page, err := fetchPage(link)
 	if err != nil {
-		return metadata, err
+		return &PageMetadata{}, err
+	}
+	return parsePageMetadata(bytes.NewReader(page.body), page.url)
+}
+
+//page is fetched html page
+type page struct {
+	//url after redirects
+	url *url.URL
+	//body decoded to utf-8
+	body []byte
+}
+
+//fetchPage downloads page and decodes it to utf-8 using charset from content type or page meta tags
+func fetchPage(link string) (*page, error) {
+	req, err := http.NewRequest(http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", fmt.Sprintf("%s/%s", config.AppNameLower, config.Version))
+	resp, err := pageClient.Do(req)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	doc, err := html.Parse(resp.Body)
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("http status: %s", resp.Status)
+	}
+
+	reader, err := charset.NewReader(io.LimitReader(resp.Body, maxPageSize), resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("page charset: %v", err)
+	}
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("read page: %v", err)
+	}
+	return &page{url: resp.Request.URL, body: body}, nil
+}
+
+//pageTags are values collected from html document
+type pageTags struct {
+	title    string
+	lang     string
+	meta     map[string]string
+	links    map[string]string
+	articles []*jsonLdArticle
+}
+
+//parsePageMetadata parses metadata from utf-8 encoded html. Relative urls are resolved against pageUrl.
+func parsePageMetadata(r io.Reader, pageUrl *url.URL) (*PageMetadata, error) {
+	metadata := &PageMetadata{}
+	doc, err := html.Parse(r)
 	if err != nil {
 		return metadata, err
 	}
 
-	var iterate func(node *html.Node)
+	tags := &pageTags{
+		meta:  map[string]string{},
+		links: map[string]string{},
+	}
+	tags.collect(doc)
+	article := &jsonLdArticle{}
+	if len(tags.articles) > 0 {
+		article = tags.articles[0]
+	}
 
-	it := func(node *html.Node) {
-		if metadata.Title != "" {
-			return
-		}
-		if node.Type == html.ElementNode && node.Data == "title" && metadata.Title == "" {
-			if node.FirstChild != nil {
-				metadata.Title = node.FirstChild.Data
-				return
+	meta := tags.meta
+	metadata.Title = first(meta["og:title"], meta["twitter:title"], tags.title, article.Headline)
+	metadata.Description = first(meta["og:description"], meta["twitter:description"], meta["description"],
+		article.Description)
+	metadata.Author = first(meta["author"], strings.Join(article.Authors, ", "), notUrl(meta["article:author"]),
+		meta["twitter:creator"])
+	metadata.PublishedAt = parsePublished(first(meta["article:published_time"], article.DatePublished,
+		meta["datepublished"], meta["date"]))
+	metadata.Language = first(tags.lang, meta["content-language"], article.InLanguage,
+		strings.Replace(meta["og:locale"], "_", "-", 1))
+	metadata.SiteName = first(meta["og:site_name"], meta["application-name"])
+	metadata.CanonicalUrl = resolveUrl(pageUrl, first(tags.links["canonical"], meta["og:url"]))
+	metadata.FaviconUrl = resolveUrl(pageUrl, first(tags.links["icon"], tags.links["shortcut icon"]))
+	return metadata, nil
+}
+
+//collect walks html tree collecting first value of each tag
+func (p *pageTags) collect(node *html.Node) {
+	if node.Type == html.ElementNode {
+		switch node.Data {
+		case "html":
+			p.lang = attr(node, "lang")
+		case "title":
+			if p.title == "" {
+				p.title = strings.TrimSpace(text(node))
+			}
+		case "meta":
+			key := first(attr(node, "property"), attr(node, "name"), attr(node, "itemprop"), attr(node, "http-equiv"))
+			key = strings.ToLower(key)
+			if value := strings.TrimSpace(attr(node, "content")); key != "" && p.meta[key] == "" {
+				p.meta[key] = value
+			}
+		case "link":
+			rel := strings.ToLower(strings.Join(strings.Fields(attr(node, "rel")), " "))
+			if href := strings.TrimSpace(attr(node, "href")); rel != "" && p.links[rel] == "" {
+				p.links[rel] = href
 			}
+		case "script":
+			if strings.ToLower(attr(node, "type")) == "application/ld+json" {
+				p.articles = append(p.articles, parseJsonLd(text(node))...)
+			}
+		}
+	}
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		p.collect(c)
+	}
+}
+
+//attr returns value of attribute key or empty string
+func attr(node *html.Node, key string) string {
+	for _, v := range node.Attr {
+		if strings.ToLower(v.Key) == key {
+			return v.Val
+		}
+	}
+	return ""
+}
+
+//text returns text content of node
+func text(node *html.Node) string {
+	builder := strings.Builder{}
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			builder.WriteString(c.Data)
 		}
+	}
+	return builder.String()
+}
+
+//first returns first non-empty value
+func first(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+//notUrl returns value if it is not an url, e.g. author profile
+func notUrl(value string) string {
+	if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+		return ""
+	}
+	return value
+}
 
-		for c := node.FirstChild; c != nil; c = c.NextSibling {
-			iterate(c)
-			if metadata.Title != "" {
-				return
+func resolveUrl(base *url.URL, link string) string {
+	if link == "" {
+		return ""
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	if base == nil {
+		return u.String()
+	}
+	return base.ResolveReference(u).String()
+}
+
+//parsePublished parses date in common formats, returns zero time if date is invalid
+func parsePublished(value string) time.Time {
+	layouts := []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05Z0700",
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04Z07:00",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+	}
+	for _, layout := range layouts {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+//jsonLdArticle is Article (or any of its subtypes) defined with JSON-LD
+type jsonLdArticle struct {
+	Headline      string
+	Description   string
+	Authors       []string
+	DatePublished string
+	InLanguage    string
+}
+
+//parseJsonLd returns articles in JSON-LD script. Script may contain single object,
+//array of objects or objects in @graph.
+func parseJsonLd(data string) []*jsonLdArticle {
+	var root interface{}
+	err := json.Unmarshal([]byte(data), &root)
+	if err != nil {
+		return nil
+	}
+
+	articles := []*jsonLdArticle{}
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]interface{}:
+			if graph, ok := v["@graph"]; ok {
+				walk(graph)
+			}
+			if isArticle(v["@type"]) {
+				articles = append(articles, &jsonLdArticle{
+					Headline:      jsonString(v["headline"]),
+					Description:   jsonString(v["description"]),
+					Authors:       jsonNames(v["author"]),
+					DatePublished: jsonString(v["datePublished"]),
+					InLanguage:    jsonString(v["inLanguage"]),
+				})
 			}
 		}
 	}
-	iterate = it
-	it(doc)
+	walk(root)
+	return articles
+}
 
-	return metadata, nil
+//isArticle returns true if JSON-LD type is Article or its subtype, e.g. NewsArticle or BlogPosting
+func isArticle(value interface{}) bool {
+	types := []interface{}{value}
+	if list, ok := value.([]interface{}); ok {
+		types = list
+	}
+	for _, v := range types {
+		name, _ := v.(string)
+		if strings.HasSuffix(name, "Article") || name == "BlogPosting" || name == "Report" {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonString(value interface{}) string {
+	text, _ := value.(string)
+	return strings.TrimSpace(text)
+}
+
+//jsonNames returns names of persons, which can be defined as text, objects with name or list of either
+func jsonNames(value interface{}) []string {
+	names := []string{}
+	switch v := value.(type) {
+	case string:
+		if name := strings.TrimSpace(v); name != "" {
+			names = append(names, name)
+		}
+	case map[string]interface{}:
+		if name := jsonString(v["name"]); name != "" {
+			names = append(names, name)
+		}
+	case []interface{}:
+		for _, item := range v {
+			names = append(names, jsonNames(item)...)
+		}
+	}
+	return names
 }
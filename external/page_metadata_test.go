@@ -0,0 +1,152 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package external
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_parsePageMetadata(t *testing.T) {
+	tests := []struct {
+		file   string
+		want   *PageMetadata
+		fields map[string]string
+	}{
+		{
+			file: "opengraph.html",
+			want: &PageMetadata{
+				Title:        "OpenGraph title",
+				Description:  "OpenGraph description",
+				Author:       "Jane Doe",
+				PublishedAt:  time.Date(2020, 3, 14, 7, 26, 53, 0, time.UTC),
+				Language:     "en",
+				SiteName:     "Example",
+				CanonicalUrl: "https://example.com/articles/1",
+				FaviconUrl:   "https://example.com/static/favicon.ico",
+			},
+			fields: map[string]string{
+				"Title":         "OpenGraph title",
+				"Author":        "Jane Doe",
+				"Published At":  "2020-03-14",
+				"Language":      "en",
+				"Site Name":     "Example",
+				"Canonical Url": "https://example.com/articles/1",
+				"Favicon":       "https://example.com/static/favicon.ico",
+			},
+		},
+		{
+			file: "jsonld.html",
+			want: &PageMetadata{
+				Title:       "JSON-LD headline",
+				Description: "JSON-LD description",
+				Author:      "John Smith, Mary Major",
+				PublishedAt: time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC),
+				Language:    "fi",
+				FaviconUrl:  "https://cdn.example.com/icon.png",
+			},
+			fields: map[string]string{
+				"Title":        "JSON-LD headline",
+				"Author":       "John Smith, Mary Major",
+				"Published At": "2019-12-01",
+				"Language":     "fi",
+				"Favicon":      "https://cdn.example.com/icon.png",
+			},
+		},
+	}
+
+	base, _ := url.Parse("https://example.com/blog/post")
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			file, err := os.Open(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			got, err := parsePageMetadata(file, base)
+			if err != nil {
+				t.Errorf("parsePageMetadata() error = %v", err)
+				return
+			}
+			if !got.PublishedAt.Equal(tt.want.PublishedAt) {
+				t.Errorf("parsePageMetadata() PublishedAt = %v, want %v", got.PublishedAt, tt.want.PublishedAt)
+			}
+			got.PublishedAt = tt.want.PublishedAt
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePageMetadata() got = %+v, want %+v", got, tt.want)
+			}
+			if fields := got.Fields(); !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("Fields() got = %v, want %v", fields, tt.fields)
+			}
+		})
+	}
+}
+
+func TestGetPageMetadata(t *testing.T) {
+	latin1, err := ioutil.ReadFile(filepath.Join("testdata", "latin1.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latin1":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write(latin1)
+		case "/header-charset":
+			w.Header().Set("Content-Type", "text/html; charset=windows-1252")
+			w.Write([]byte("<title>Caf\xe9</title>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path     string
+		title    string
+		language string
+		wantErr  bool
+	}{
+		{path: "/latin1", title: "Grüße aus Köln", language: "de"},
+		{path: "/header-charset", title: "Café"},
+		{path: "/missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := GetPageMetadata(server.URL + tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetPageMetadata() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got.Title != tt.title {
+				t.Errorf("GetPageMetadata() title = %q, want %q", got.Title, tt.title)
+			}
+			if got.Language != tt.language {
+				t.Errorf("GetPageMetadata() language = %q, want %q", got.Language, tt.language)
+			}
+		})
+	}
+}
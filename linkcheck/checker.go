@@ -0,0 +1,285 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+//Package linkcheck checks whether bookmark links still resolve and stores link status as bookmark metadata.
+package linkcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"tryffel.net/go/bookmarker/config"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//Result is outcome of checking single bookmark link
+type Result struct {
+	Bookmark *models.Bookmark
+	//StatusCode of final response, 0 if request failed
+	StatusCode int
+	//Status of final response or error message if request failed
+	Status string
+	//FinalUrl is link after following redirects
+	FinalUrl  string
+	CheckedAt time.Time
+	Err       error
+}
+
+//Dead returns true if link could not be reached or server responded with error
+func (r *Result) Dead() bool {
+	return r.Err != nil || r.StatusCode >= 400
+}
+
+//Metadata returns link status metadata of result. Failures are counted from previous metadata
+//and reset on successful check.
+func (r *Result) Metadata(previous map[string]string) map[string]string {
+	metadata := map[string]string{
+		models.MetadataLinkStatus: models.LinkStatusOk,
+		models.MetadataHttpStatus: r.Status,
+		models.MetadataFinalUrl:   r.FinalUrl,
+		models.MetadataCheckedAt:  r.CheckedAt.Format(time.RFC3339),
+		models.MetadataFailures:   "0",
+	}
+	if r.Dead() {
+		failures, _ := strconv.Atoi(previous[models.MetadataFailures])
+		metadata[models.MetadataLinkStatus] = models.LinkStatusDead
+		metadata[models.MetadataFailures] = strconv.Itoa(failures + 1)
+	}
+	return metadata
+}
+
+//Checkable returns true if bookmark content is a http(s) link
+func Checkable(b *models.Bookmark) bool {
+	u, err := url.Parse(b.Content)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+//Checker checks links with bounded number of workers, limiting rate of requests per host.
+type Checker struct {
+	//Workers is max number of concurrent requests
+	Workers int
+	//HostInterval is minimum time between starting requests to same host
+	HostInterval time.Duration
+
+	client  *http.Client
+	timeout time.Duration
+	lock    sync.Mutex
+	// next allowed request time for each host
+	hosts map[string]time.Time
+}
+
+//NewChecker creates new checker. Timeout applies to single link, including redirects and retrying HEAD with GET.
+//Timeout starts when request to host is allowed, so that links waiting for host interval do not time out.
+func NewChecker(workers int, timeout time.Duration, hostInterval time.Duration) *Checker {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Checker{
+		Workers:      workers,
+		HostInterval: hostInterval,
+		client:       &http.Client{},
+		timeout:      timeout,
+		hosts:        map[string]time.Time{},
+	}
+}
+
+//Check checks links of bookmarks and calls resultFunc for each checked bookmark from single goroutine.
+//Bookmarks that are not http(s) links are skipped. Check returns after all links are checked
+//or ctx is cancelled.
+func (c *Checker) Check(ctx context.Context, bookmarks []*models.Bookmark, resultFunc func(result *Result)) {
+	jobs := make(chan *models.Bookmark)
+	results := make(chan *Result)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < c.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range jobs {
+				result := c.check(ctx, b)
+				if ctx.Err() != nil {
+					// cancelled, result is not valid
+					continue
+				}
+				results <- result
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, b := range bookmarks {
+			if !Checkable(b) {
+				continue
+			}
+			select {
+			case jobs <- b:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for result := range results {
+		if resultFunc != nil {
+			resultFunc(result)
+		}
+	}
+}
+
+//wait blocks until request to host is allowed
+func (c *Checker) wait(ctx context.Context, host string) error {
+	c.lock.Lock()
+	now := time.Now()
+	next := c.hosts[host]
+	if next.Before(now) {
+		next = now
+	}
+	c.hosts[host] = next.Add(c.HostInterval)
+	c.lock.Unlock()
+
+	if !next.After(now) {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(next.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Checker) check(ctx context.Context, b *models.Bookmark) *Result {
+	result := &Result{
+		Bookmark: b,
+		FinalUrl: b.Content,
+	}
+
+	resp, err := c.get(ctx, b.Content)
+	result.CheckedAt = time.Now()
+	if err != nil {
+		result.Err = err
+		result.Status = err.Error()
+		return result
+	}
+
+	result.StatusCode = resp.StatusCode
+	result.Status = resp.Status
+	result.FinalUrl = resp.Request.URL.String()
+	return result
+}
+
+//get requests link with HEAD and retries with GET if HEAD fails. Both requests share single timeout.
+func (c *Checker) get(ctx context.Context, link string) (*http.Response, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	host := strings.ToLower(u.Host)
+	err = c.wait(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	// some servers don't support HEAD, retry failed requests with GET
+	resp, err := c.request(ctx, http.MethodHead, link)
+	if err != nil || resp.StatusCode >= 400 {
+		err = c.wait(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		resp, err = c.request(ctx, http.MethodGet, link)
+	}
+	return resp, err
+}
+
+func (c *Checker) request(ctx context.Context, method string, link string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", fmt.Sprintf("%s/%s", config.AppNameLower, config.Version))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	// only status is needed, drain small part of body to allow reusing connection
+	_, _ = io.CopyN(ioutil.Discard, resp.Body, 4096)
+	_ = resp.Body.Close()
+	return resp, nil
+}
+
+//Store stores link check results
+type Store interface {
+	GetBookmarkMetadata(bookmark *models.Bookmark) error
+	SetMetadata(metadata map[int]map[string]string) error
+}
+
+//Run checks links of bookmarks and stores results into bookmark metadata in single transaction.
+//ResultFunc is called for each result and may be nil. If ctx is cancelled, results checked so far are stored.
+func (c *Checker) Run(ctx context.Context, store Store, bookmarks []*models.Bookmark,
+	resultFunc func(result *Result)) ([]*Result, error) {
+	previous := map[int]map[string]string{}
+	for _, b := range bookmarks {
+		if !Checkable(b) {
+			continue
+		}
+		err := store.GetBookmarkMetadata(b)
+		if err != nil {
+			return nil, fmt.Errorf("get metadata of bookmark %d: %v", b.Id, err)
+		}
+		previous[b.Id] = *b.Metadata
+	}
+
+	results := []*Result{}
+	metadata := map[int]map[string]string{}
+	c.Check(ctx, bookmarks, func(result *Result) {
+		results = append(results, result)
+		metadata[result.Bookmark.Id] = result.Metadata(previous[result.Bookmark.Id])
+		if resultFunc != nil {
+			resultFunc(result)
+		}
+	})
+
+	err := store.SetMetadata(metadata)
+	if err != nil {
+		return results, fmt.Errorf("store link status: %v", err)
+	}
+	return results, nil
+}
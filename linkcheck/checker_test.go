@@ -0,0 +1,205 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package linkcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+func testServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond * 200)
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/slow-no-head", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond * 70)
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	return httptest.NewServer(mux)
+}
+
+func checkLinks(checker *Checker, links ...string) map[string]*Result {
+	bookmarks := make([]*models.Bookmark, len(links))
+	for i, v := range links {
+		bookmarks[i] = &models.Bookmark{Id: i + 1, Content: v}
+	}
+	results := map[string]*Result{}
+	checker.Check(context.Background(), bookmarks, func(result *Result) {
+		results[result.Bookmark.Content] = result
+	})
+	return results
+}
+
+func TestChecker_Check(t *testing.T) {
+	server := testServer()
+	defer server.Close()
+
+	checker := NewChecker(4, time.Millisecond*100, 0)
+	results := checkLinks(checker, server.URL+"/ok", server.URL+"/redirect", server.URL+"/no-head",
+		server.URL+"/missing", server.URL+"/slow", server.URL+"/slow-no-head", "ipfs://abc", "not a link")
+
+	tests := []struct {
+		path       string
+		statusCode int
+		finalUrl   string
+		dead       bool
+	}{
+		{path: "/ok", statusCode: 200, finalUrl: server.URL + "/ok"},
+		{path: "/redirect", statusCode: 200, finalUrl: server.URL + "/ok"},
+		{path: "/no-head", statusCode: 200, finalUrl: server.URL + "/no-head"},
+		{path: "/missing", statusCode: 404, finalUrl: server.URL + "/missing", dead: true},
+		{path: "/slow", statusCode: 0, finalUrl: server.URL + "/slow", dead: true},
+		// timeout covers both HEAD and GET
+		{path: "/slow-no-head", statusCode: 0, finalUrl: server.URL + "/slow-no-head", dead: true},
+	}
+	if len(results) != len(tests) {
+		t.Errorf("Check() got %d results, want %d", len(results), len(tests))
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result := results[server.URL+tt.path]
+			if result == nil {
+				t.Fatalf("no result")
+			}
+			if result.StatusCode != tt.statusCode {
+				t.Errorf("StatusCode = %d, want %d", result.StatusCode, tt.statusCode)
+			}
+			if result.FinalUrl != tt.finalUrl {
+				t.Errorf("FinalUrl = %s, want %s", result.FinalUrl, tt.finalUrl)
+			}
+			if result.Dead() != tt.dead {
+				t.Errorf("Dead() = %t, want %t", result.Dead(), tt.dead)
+			}
+			if result.CheckedAt.IsZero() {
+				t.Errorf("CheckedAt not set")
+			}
+		})
+	}
+}
+
+func TestChecker_hostInterval(t *testing.T) {
+	var lock sync.Mutex
+	requests := []time.Time{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests = append(requests, time.Now())
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	interval := time.Millisecond * 50
+	checker := NewChecker(4, time.Second, interval)
+	checkLinks(checker, server.URL+"/a", server.URL+"/b", server.URL+"/c")
+
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want 3", len(requests))
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].Before(requests[j]) })
+	for i := 1; i < len(requests); i++ {
+		// allow some inaccuracy in timers
+		if diff := requests[i].Sub(requests[i-1]); diff < interval-time.Millisecond*5 {
+			t.Errorf("requests %d and %d only %v apart, want %v", i-1, i, diff, interval)
+		}
+	}
+}
+
+type memoryStore struct {
+	metadata map[int]map[string]string
+}
+
+func (m *memoryStore) GetBookmarkMetadata(bookmark *models.Bookmark) error {
+	metadata := map[string]string{}
+	for key, value := range m.metadata[bookmark.Id] {
+		metadata[key] = value
+	}
+	bookmark.Metadata = &metadata
+	return nil
+}
+
+func (m *memoryStore) SetMetadata(metadata map[int]map[string]string) error {
+	for id, values := range metadata {
+		if m.metadata[id] == nil {
+			m.metadata[id] = map[string]string{}
+		}
+		for key, value := range values {
+			m.metadata[id][key] = value
+		}
+	}
+	return nil
+}
+
+func TestChecker_Run(t *testing.T) {
+	server := testServer()
+	defer server.Close()
+
+	store := &memoryStore{metadata: map[int]map[string]string{
+		1: {"Author": "x", models.MetadataFailures: "2"},
+		2: {models.MetadataFailures: "2"},
+	}}
+	bookmarks := []*models.Bookmark{
+		{Id: 1, Content: server.URL + "/missing"},
+		{Id: 2, Content: server.URL + "/ok"},
+	}
+
+	checker := NewChecker(2, time.Second, 0)
+	results, err := checker.Run(context.Background(), store, bookmarks, nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Run() got %d results, want 2", len(results))
+	}
+
+	dead := store.metadata[1]
+	if dead[models.MetadataLinkStatus] != models.LinkStatusDead || dead[models.MetadataFailures] != "3" ||
+		dead[models.MetadataHttpStatus] != "404 Not Found" || dead["Author"] != "x" {
+		t.Errorf("Run() dead link metadata = %v", dead)
+	}
+	ok := store.metadata[2]
+	if ok[models.MetadataLinkStatus] != models.LinkStatusOk || ok[models.MetadataFailures] != "0" ||
+		ok[models.MetadataFinalUrl] != server.URL+"/ok" || ok[models.MetadataCheckedAt] == "" {
+		t.Errorf("Run() ok link metadata = %v", ok)
+	}
+}
@@ -25,12 +25,23 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
+//Options configure database. They are usually read from configuration file.
+type Options struct {
+	//FullTextSearch searches plain queries with sqlite fts5, otherwise plain queries match any part of fields
+	FullTextSearch bool
+	//HideArchived hides archived bookmarks when filter is cleared
+	HideArchived bool
+	//AutoCompleteMaxResults is max number of metadata values suggested
+	AutoCompleteMaxResults int
+}
+
 type Database struct {
-	conn *sqlx.DB
+	conn    *sqlx.DB
+	options Options
 }
 
-func NewDatabase(file string) (*Database, error) {
-	db := &Database{}
+func NewDatabase(file string, options Options) (*Database, error) {
+	db := &Database{options: options}
 
 	url := fmt.Sprintf("file:%s?_writable_schema=true", file)
 	var err error
@@ -50,3 +61,8 @@ func (d *Database) Close() error {
 func (d *Database) Engine() *sqlx.DB {
 	return d.conn
 }
+
+//Options returns options database was created with
+func (d *Database) Options() Options {
+	return d.options
+}
@@ -0,0 +1,114 @@
+//go:build fts5
+// +build fts5
+
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+	"tryffel.net/go/bookmarker/storage/migrations"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//newTestDatabase creates migrated database in temporary directory. Returned func closes and removes database.
+func newTestDatabase(t *testing.T, fullTextSearch bool) (*Database, func()) {
+	logrus.SetLevel(logrus.ErrorLevel)
+	dir, err := ioutil.TempDir("", "bookmarker-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup := func() {
+		os.RemoveAll(dir)
+	}
+
+	db, err := NewDatabase(filepath.Join(dir, "test.sqlite"),
+		Options{FullTextSearch: fullTextSearch, AutoCompleteMaxResults: 10})
+	if err != nil {
+		cleanup()
+		t.Fatalf("open database: %v", err)
+	}
+	err = migrations.Migrate(db.Engine(), migrations.BookmarkerMigrations)
+	if err != nil {
+		db.Close()
+		cleanup()
+		t.Fatalf("migrate database: %v", err)
+	}
+	return db, func() {
+		db.Close()
+		cleanup()
+	}
+}
+
+//addTestBookmarks creates bookmarks, filling link and times if they are empty
+func addTestBookmarks(t *testing.T, db *Database, bookmarks ...*models.Bookmark) {
+	created := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, b := range bookmarks {
+		b.LowerName = strings.ToLower(b.Name)
+		if b.Content == "" {
+			b.Content = "https://" + strings.Replace(b.LowerName, " ", "-", -1) + ".org"
+		}
+		if b.CreatedAt.IsZero() {
+			b.CreatedAt = created.AddDate(0, i, 0)
+			b.UpdatedAt = b.CreatedAt
+		}
+		err := db.NewBookmark(b)
+		if err != nil {
+			t.Fatalf("new bookmark: %v", err)
+		}
+	}
+}
+
+//getTestBookmark returns bookmark with its metadata
+func getTestBookmark(t *testing.T, db *Database, id int) *models.Bookmark {
+	b, err := db.GetBookmark(id)
+	if err != nil {
+		t.Fatalf("get bookmark %d: %v", id, err)
+	}
+	err = db.GetBookmarkMetadata(b)
+	if err != nil {
+		t.Fatalf("get metadata of bookmark %d: %v", id, err)
+	}
+	return b
+}
+
+//bookmarkIds returns sorted ids of bookmarks
+func bookmarkIds(bookmarks []*models.Bookmark) []int {
+	ids := make([]int, len(bookmarks))
+	for i, v := range bookmarks {
+		ids[i] = v.Id
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+//testProjects returns projects of all bookmarks ordered by id
+func testProjects(t *testing.T, db *Database) []string {
+	projects := []string{}
+	err := db.conn.Select(&projects, "SELECT project FROM bookmarks ORDER BY id")
+	if err != nil {
+		t.Fatalf("select projects: %v", err)
+	}
+	return projects
+}
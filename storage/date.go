@@ -0,0 +1,85 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//sqlTimeFormat is format of time parameters, which are compared in UTC to datetime(column)
+const sqlTimeFormat = "2006-01-02 15:04:05"
+
+var relativeDateRegex = regexp.MustCompile(`^(\d+)(h|d|w|mo|y)$`)
+
+//parseDate parses date relative to now. Supported formats are:
+//absolute date '2006-01-02' or '2006-01-02T15:04' in local time,
+//relative duration '12h', '7d', '2w', '3mo', '1y' before now,
+//keywords 'today', 'yesterday' and 'thisweek' (since monday).
+func parseDate(value string, now time.Time) (time.Time, error) {
+	lower := strings.ToLower(value)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch lower {
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "thisweek":
+		days := (int(today.Weekday()) + 6) % 7
+		return today.AddDate(0, 0, -days), nil
+	}
+
+	if match := relativeDateRegex.FindStringSubmatch(lower); match != nil {
+		count, err := strconv.Atoi(match[1])
+		if err != nil {
+			return time.Time{}, err
+		}
+		switch match[2] {
+		case "h":
+			return now.Add(-time.Duration(count) * time.Hour), nil
+		case "d":
+			return now.AddDate(0, 0, -count), nil
+		case "w":
+			return now.AddDate(0, 0, -count*7), nil
+		case "mo":
+			return now.AddDate(0, -count, 0), nil
+		case "y":
+			return now.AddDate(-count, 0, 0), nil
+		}
+	}
+
+	for _, layout := range []string{"2006-01-02", "2006-01-02T15:04"} {
+		t, err := time.ParseInLocation(layout, value, now.Location())
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date '%s'", value)
+}
+
+//dateCondition compares column to time t: bookmarks created / updated at or after t, or before t
+func dateCondition(column string, after bool, t time.Time, params *[]interface{}) string {
+	*params = append(*params, t.UTC().Format(sqlTimeFormat))
+	if after {
+		return "(datetime(" + column + ") >= ?)"
+	}
+	return "(datetime(" + column + ") < ?)"
+}
@@ -0,0 +1,372 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"sort"
+	"strings"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//DuplicatePolicy defines how bookmark is saved, if bookmark with same canonical link already exists
+type DuplicatePolicy int
+
+const (
+	//DuplicateCreate creates new bookmark anyway
+	DuplicateCreate DuplicatePolicy = iota
+	//DuplicateSkip does not save bookmark
+	DuplicateSkip
+	//DuplicateUpdate overwrites existing bookmark with values of new bookmark, tags and metadata are combined
+	DuplicateUpdate
+	//DuplicateMerge fills empty values of existing bookmark, tags and metadata are combined
+	DuplicateMerge
+)
+
+var duplicatePolicies = []string{"create", "skip", "update", "merge"}
+
+func (p DuplicatePolicy) String() string {
+	if p < 0 || int(p) >= len(duplicatePolicies) {
+		return "unknown"
+	}
+	return duplicatePolicies[p]
+}
+
+//ParseDuplicatePolicy parses policy from its name: create, skip, update or merge
+func ParseDuplicatePolicy(name string) (DuplicatePolicy, error) {
+	for i, v := range duplicatePolicies {
+		if strings.ToLower(name) == v {
+			return DuplicatePolicy(i), nil
+		}
+	}
+	return DuplicateCreate, fmt.Errorf("invalid duplicate policy '%s', expected one of: %s", name,
+		strings.Join(duplicatePolicies, ", "))
+}
+
+//ImportResult tells how many bookmarks were saved in import
+type ImportResult struct {
+	//Created is number of new bookmarks
+	Created int
+	//Updated is number of existing bookmarks that imported bookmarks were merged into
+	Updated int
+	//Skipped is number of duplicates that were skipped or merged into other imported bookmark
+	Skipped int
+}
+
+//updateCanonicalLinks sets canonical link of bookmarks that were created or whose content changed
+//after their canonical link was set
+func updateCanonicalLinks(tx *sqlx.Tx) error {
+	rows := []struct {
+		Id      int    `db:"id"`
+		Content string `db:"content"`
+	}{}
+	err := tx.Select(&rows, "SELECT id, content FROM bookmarks WHERE canonical_link IS NULL")
+	if err != nil {
+		return fmt.Errorf("select links: %v", err)
+	}
+	for _, v := range rows {
+		_, err = tx.Exec("UPDATE bookmarks SET canonical_link = ? WHERE id = ?", models.CanonicalLink(v.Content), v.Id)
+		if err != nil {
+			return fmt.Errorf("update canonical link: %v", err)
+		}
+	}
+	return nil
+}
+
+//linkIndex returns ids of bookmarks, that are not in trash and match condition, grouped by canonical link.
+//Ids are ordered by creation time and links are in order of their first bookmark.
+//Canonical links must be up to date, see updateCanonicalLinks.
+func linkIndex(tx *sqlx.Tx, condition string, args ...interface{}) (map[string][]int, []string, error) {
+	rows := []struct {
+		Id   int    `db:"id"`
+		Link string `db:"canonical_link"`
+	}{}
+	err := tx.Select(&rows, `
+SELECT id, canonical_link
+FROM bookmarks
+WHERE deleted_at IS NULL AND canonical_link != '' AND `+condition+`
+ORDER BY created_at ASC, id ASC`, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("select links: %v", err)
+	}
+
+	index := map[string][]int{}
+	links := []string{}
+	for _, v := range rows {
+		if _, ok := index[v.Link]; !ok {
+			links = append(links, v.Link)
+		}
+		index[v.Link] = append(index[v.Link], v.Id)
+	}
+	return index, links, nil
+}
+
+//existingLinks returns ids of bookmarks, that are not in trash, grouped by canonical link
+//for canonical links of bookmarks, see linkIndex
+func existingLinks(tx *sqlx.Tx, bookmarks []*models.Bookmark) (map[string][]int, error) {
+	err := updateCanonicalLinks(tx)
+	if err != nil {
+		return nil, err
+	}
+	links := []string{}
+	for _, b := range bookmarks {
+		if link := b.CanonicalLink(); link != "" {
+			links = append(links, link)
+		}
+	}
+
+	index := map[string][]int{}
+	//Max variables for sqlite is 999
+	batchSize := 500
+	for len(links) > 0 {
+		batch := links
+		if len(batch) > batchSize {
+			batch = links[:batchSize]
+		}
+		links = links[len(batch):]
+
+		args := make([]interface{}, len(batch))
+		for i, v := range batch {
+			args[i] = v
+		}
+		found, _, err := linkIndex(tx, "canonical_link IN ("+placeholders(len(batch))+")", args...)
+		if err != nil {
+			return nil, err
+		}
+		for link, ids := range found {
+			index[link] = ids
+		}
+	}
+	return index, nil
+}
+
+//linkGroups returns bookmarks grouped by canonical link, see linkIndex
+func (d *Database) linkGroups(condition string, args ...interface{}) ([][]*models.Bookmark, error) {
+	tx, err := d.conn.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %v", err)
+	}
+	err = updateCanonicalLinks(tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	index, links, err := linkIndex(tx, condition, args...)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	groups := [][]*models.Bookmark{}
+	for _, link := range links {
+		bookmarks, err := loadBookmarks(tx, index[link])
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		groups = append(groups, bookmarks)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("transaction failed: %v", err)
+	}
+	return groups, nil
+}
+
+//loadBookmarks returns bookmarks with tags and metadata in order of ids
+func loadBookmarks(q sqlx.Queryer, ids []int) ([]*models.Bookmark, error) {
+	images, err := loadImages(q, ids)
+	if err != nil {
+		return nil, err
+	}
+	bookmarks := make([]*models.Bookmark, 0, len(ids))
+	for _, id := range ids {
+		if image := images[id]; image != nil {
+			bookmarks = append(bookmarks, image.bookmark())
+		}
+	}
+	return bookmarks, nil
+}
+
+//GetBookmarksByLink returns bookmarks that have same canonical link as given link, oldest first.
+func (d *Database) GetBookmarksByLink(link string) ([]*models.Bookmark, error) {
+	link = models.CanonicalLink(link)
+	if link == "" {
+		return []*models.Bookmark{}, nil
+	}
+	groups, err := d.linkGroups("canonical_link = ?", link)
+	if err != nil || len(groups) == 0 {
+		return []*models.Bookmark{}, err
+	}
+	return groups[0], nil
+}
+
+//GetDuplicates returns groups of bookmarks that share canonical link. Bookmarks in group are oldest first.
+func (d *Database) GetDuplicates() ([][]*models.Bookmark, error) {
+	return d.linkGroups(`canonical_link IN (
+	SELECT canonical_link FROM bookmarks
+	WHERE deleted_at IS NULL
+	GROUP BY canonical_link
+	HAVING COUNT(*) > 1)`)
+}
+
+//MergeBookmark merges bookmark into existing bookmark with given id, see models.Bookmark.Merge.
+//Bookmark id is set to id of existing bookmark.
+func (d *Database) MergeBookmark(id int, bookmark *models.Bookmark, overwrite bool) error {
+	operation := fmt.Sprintf("merge duplicate '%s'", bookmark.Name)
+	if overwrite {
+		operation = fmt.Sprintf("update duplicate '%s'", bookmark.Name)
+	}
+	return d.journaled(operation, []int{id}, func(tx *sqlx.Tx) ([]int, error) {
+		current, err := loadImages(tx, []int{id})
+		if err != nil {
+			return nil, err
+		}
+		if current[id] == nil || current[id].DeletedAt != nil {
+			return nil, fmt.Errorf("bookmark %d not found", id)
+		}
+
+		existing := current[id].bookmark()
+		existing.Merge(bookmark, overwrite)
+		existing.UpdatedAt = time.Now()
+		bookmark.Id = id
+		return nil, d.restoreImages(tx, images{id: newImage(existing)})
+	})
+}
+
+//MergeDuplicates merges bookmarks into the oldest of them, combining tags and metadata.
+//Other bookmarks are moved to trash and their snapshots are moved to merged bookmark.
+//Undo moves snapshots back. Returns merged bookmark.
+func (d *Database) MergeDuplicates(ids []int) (*models.Bookmark, error) {
+	if len(ids) < 2 {
+		return nil, fmt.Errorf("at least two bookmarks are required")
+	}
+
+	var merged *models.Bookmark
+	operation := fmt.Sprintf("merge %d duplicate bookmarks", len(ids))
+	err := d.journaled(operation, ids, func(tx *sqlx.Tx) ([]int, error) {
+		current, err := loadImages(tx, ids)
+		if err != nil {
+			return nil, err
+		}
+		bookmarks := make([]*models.Bookmark, 0, len(ids))
+		for _, id := range ids {
+			if current[id] == nil || current[id].DeletedAt != nil {
+				return nil, fmt.Errorf("bookmark %d not found", id)
+			}
+			bookmarks = append(bookmarks, current[id].bookmark())
+		}
+		sort.SliceStable(bookmarks, func(i, j int) bool {
+			if bookmarks[i].CreatedAt.Equal(bookmarks[j].CreatedAt) {
+				return bookmarks[i].Id < bookmarks[j].Id
+			}
+			return bookmarks[i].CreatedAt.Before(bookmarks[j].CreatedAt)
+		})
+
+		merged = bookmarks[0]
+		for _, b := range bookmarks[1:] {
+			merged.Merge(b, false)
+		}
+		merged.UpdatedAt = time.Now()
+		err = d.restoreImages(tx, images{merged.Id: newImage(merged)})
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		for _, b := range bookmarks[1:] {
+			_, err = tx.Exec("UPDATE bookmarks SET deleted_at = ? WHERE id = ?", now, b.Id)
+			if err != nil {
+				return nil, fmt.Errorf("move duplicate to trash: %v", err)
+			}
+			_, err = tx.Exec("UPDATE snapshots SET bookmark = ? WHERE bookmark = ?", merged.Id, b.Id)
+			if err != nil {
+				return nil, fmt.Errorf("move snapshots: %v", err)
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+//resolveDuplicates applies policy to bookmarks whose canonical link already exists or is repeated in bookmarks.
+//Returns bookmarks that need to be created and existing bookmarks before and after merging imported bookmarks.
+func (d *Database) resolveDuplicates(tx *sqlx.Tx, bookmarks []*models.Bookmark, policy DuplicatePolicy,
+	result *ImportResult) ([]*models.Bookmark, images, images, error) {
+	if policy == DuplicateCreate {
+		return bookmarks, images{}, images{}, nil
+	}
+
+	index, err := existingLinks(tx, bookmarks)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	create := make([]*models.Bookmark, 0, len(bookmarks))
+	created := map[string]*models.Bookmark{}
+	matches := map[int][]*models.Bookmark{}
+	matchIds := []int{}
+	for _, b := range bookmarks {
+		link := b.CanonicalLink()
+		if link == "" {
+			create = append(create, b)
+			continue
+		}
+		if first := created[link]; first != nil {
+			if policy != DuplicateSkip {
+				first.Merge(b, policy == DuplicateUpdate)
+			}
+			result.Skipped += 1
+			continue
+		}
+		if ids := index[link]; len(ids) > 0 {
+			if policy == DuplicateSkip {
+				result.Skipped += 1
+				continue
+			}
+			if _, ok := matches[ids[0]]; !ok {
+				matchIds = append(matchIds, ids[0])
+			}
+			matches[ids[0]] = append(matches[ids[0]], b)
+			continue
+		}
+		created[link] = b
+		create = append(create, b)
+	}
+
+	before, err := loadImages(tx, matchIds)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	after := images{}
+	for _, id := range matchIds {
+		existing := before[id].bookmark()
+		for _, b := range matches[id] {
+			existing.Merge(b, policy == DuplicateUpdate)
+			b.Id = id
+		}
+		existing.UpdatedAt = time.Now()
+		after[id] = newImage(existing)
+	}
+	result.Updated = len(after)
+	return create, before, after, nil
+}
@@ -0,0 +1,132 @@
+//go:build fts5
+// +build fts5
+
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"testing"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+// snapshotIds returns ids of snapshots of bookmark
+func snapshotIds(t *testing.T, db *Database, bookmark int) []int {
+	snapshots, err := db.GetSnapshots(bookmark)
+	if err != nil {
+		t.Fatalf("get snapshots: %v", err)
+	}
+	ids := make([]int, len(snapshots))
+	for i, v := range snapshots {
+		ids[i] = v.Id
+	}
+	return ids
+}
+
+func TestDatabase_GetBookmarksByLink(t *testing.T) {
+	db, cleanup := newTestDatabase(t, false)
+	defer cleanup()
+	golang := &models.Bookmark{Name: "Golang", Content: "https://golang.org/"}
+	golangUtm := &models.Bookmark{Name: "Golang utm", Content: "https://GOLANG.org?utm_source=feed"}
+	rust := &models.Bookmark{Name: "Rust", Content: "https://rust-lang.org"}
+	addTestBookmarks(t, db, golang, golangUtm, rust)
+
+	got, err := db.GetBookmarksByLink("https://golang.org#intro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := bookmarkIds(got); len(ids) != 2 || ids[0] != golang.Id || ids[1] != golangUtm.Id {
+		t.Errorf("GetBookmarksByLink() = %v, want %d, %d", ids, golang.Id, golangUtm.Id)
+	}
+
+	groups, err := db.GetDuplicates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || len(groups[0]) != 2 {
+		t.Fatalf("GetDuplicates() = %v, want single group of 2", groups)
+	}
+
+	rust.Content = "https://golang.org/"
+	err = db.UpdateBookmark(rust)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = db.GetBookmarksByLink("https://golang.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Errorf("GetBookmarksByLink() after changing link returned %d bookmarks, want 3", len(got))
+	}
+	got, err = db.GetBookmarksByLink("https://rust-lang.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("GetBookmarksByLink() returned %d bookmarks for old link, want 0", len(got))
+	}
+}
+
+func TestDatabase_MergeDuplicates_snapshots(t *testing.T) {
+	db, cleanup := newTestDatabase(t, false)
+	defer cleanup()
+	golang := &models.Bookmark{Name: "Golang", Content: "https://golang.org"}
+	duplicate := &models.Bookmark{Name: "Go", Content: "https://golang.org/", Tags: []string{"lang"}}
+	addTestBookmarks(t, db, golang, duplicate)
+
+	snapshot := &models.Snapshot{Bookmark: duplicate.Id, CreatedAt: time.Now(), Url: duplicate.Content,
+		Title: "Go", Hash: "1", Text: "go", Html: []byte("<p>go</p>")}
+	_, err := db.NewSnapshot(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merged, err := db.MergeDuplicates([]int{duplicate.Id, golang.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Id != golang.Id {
+		t.Errorf("merged into %d, want %d", merged.Id, golang.Id)
+	}
+	if ids := snapshotIds(t, db, golang.Id); len(ids) != 1 || ids[0] != snapshot.Id {
+		t.Errorf("merged bookmark has snapshots %v, want %d", ids, snapshot.Id)
+	}
+
+	_, err = db.Undo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := snapshotIds(t, db, golang.Id); len(ids) != 0 {
+		t.Errorf("undo left snapshots %v in merged bookmark", ids)
+	}
+	if ids := snapshotIds(t, db, duplicate.Id); len(ids) != 1 || ids[0] != snapshot.Id {
+		t.Errorf("undo moved snapshots %v to duplicate, want %d", ids, snapshot.Id)
+	}
+	if got := getTestBookmark(t, db, duplicate.Id); got.DeletedAt != nil {
+		t.Errorf("undo did not restore duplicate from trash")
+	}
+
+	_, err = db.Redo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := snapshotIds(t, db, golang.Id); len(ids) != 1 || ids[0] != snapshot.Id {
+		t.Errorf("redo moved snapshots %v to merged bookmark, want %d", ids, snapshot.Id)
+	}
+}
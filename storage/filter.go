@@ -18,11 +18,10 @@ package storage
 
 import (
 	"fmt"
-	"github.com/sirupsen/logrus"
-	"regexp"
+	"sort"
 	"strings"
 	"time"
-	"tryffel.net/go/bookmarker/config"
+	"tryffel.net/go/bookmarker/storage/models"
 )
 
 type StringFilter struct {
@@ -31,10 +30,8 @@ type StringFilter struct {
 	Inverse bool
 }
 
-var queryPattern = `([+-])?([a-zA-Z]+):(\w+|'[\w ]+')`
-var queryRegex = regexp.MustCompile(queryPattern)
-
-//Filter is a filter that represents user defined filterin and sorting
+//Filter is a filter that represents user defined filterin and sorting.
+//Fields are combined with AND to parsed query.
 type Filter struct {
 	Name          StringFilter
 	Description   StringFilter
@@ -43,200 +40,305 @@ type Filter struct {
 	Content       StringFilter
 	CreatedAfter  time.Time
 	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
 	Archived      StringFilter
 	CustomTags    map[string]StringFilter
 	SortField     string
 	SortDir       string
 	Query         string
-	isPlain       bool
+	//Limit is max number of results. 0 uses DefaultPageSize, negative value disables limit
+	Limit int
+	//Offset is number of results to skip
+	Offset  int
+	isPlain bool
+	// parsed query
+	expr queryNode
+}
+
+//DefaultPageSize is number of bookmarks in page when filter has no limit
+const DefaultPageSize = 300
+
+//sortFields maps sort:<value> to sort field
+var sortFields = map[string]string{
+	"name":        "Name",
+	"description": "Description",
+	"project":     "Project",
+	"link":        "Link",
+	"added":       "Added at",
+	"state":       "State",
+	"priority":    "Priority",
+	"due":         "Due",
+}
+
+//SortField returns sort field for sort name, e.g. 'added' -> 'Added at'. Ok is false for unknown name.
+func SortField(name string) (field string, ok bool) {
+	field, ok = sortFields[name]
+	return
 }
 
 //NewFilter parses and constructs new filter based on raw query.
-//Query example: "name:asdf* author:'jack' my bookmark"
-//Rules: Each parameter is separated by ' ',
-//Strict match: enclose word with '\'',
-//
+//Query example: "(name:golang OR tags:go) -archived:true author:'jack smith' tutorial"
+//Rules: terms are separated by ' ' and combined with AND unless OR is given,
+//NOT or '-' negates term or group, parentheses group terms,
+//key:value matches field or metadata key, free text matches any field,
+//after:/before: and updated>:/updated<: limit created and updated time with date, e.g. '2020-01-31', '7d' or 'today',
+//quoted value ("" or '') may contain any characters and must match exactly,
+//tags:a,b matches all of tags and tags:a|b any of tags,
+//sort:<name|description|project|link|added> sets sorting.
+//Query that is a single word is plain query for full text search. Queries with operators, phrases
+//or more than one term match any part of fields, tags and metadata.
 func NewFilter(query string) (*Filter, error) {
 	f := &Filter{
 		CustomTags: map[string]StringFilter{},
 	}
 
-	if query == "" {
-		return f, nil
-	}
-
-	//tokens, err := tokenizeQuery(query)
-	tokens, err := parseQuery(query)
+	expr, err := parseQuery(query)
 	if err != nil {
 		return f, err
 	}
-	err = f.parseTokens(tokens)
-	if err != nil {
-		return f, err
+	if isPlainQuery(expr) {
+		f.isPlain = true
+		f.Query = strings.TrimSpace(query)
+	}
+
+	f.expr, err = f.extractSort(expr)
+	return f, err
+}
+
+//QuoteValue quotes value of key:value term so that it must match exactly
+func QuoteValue(value string) string {
+	if strings.Contains(value, "'") {
+		return `"` + value + `"`
+	}
+	return "'" + value + "'"
+}
+
+//SelectionQuery returns query that matches project and tags, which are combined with AND,
+//or with OR if any is true. Empty project or tags are left out.
+func SelectionQuery(project string, tags []string, any bool) string {
+	terms := []string{}
+	if project != "" {
+		terms = append(terms, "project:"+QuoteValue(project))
+	}
+	tagTerms := make([]string, len(tags))
+	for i, v := range tags {
+		tagTerms[i] = "tags:" + QuoteValue(v)
+	}
+	if any && len(tagTerms) > 1 {
+		terms = append(terms, "("+strings.Join(tagTerms, " OR ")+")")
+	} else {
+		terms = append(terms, tagTerms...)
 	}
-	return f, nil
+	return strings.Join(terms, " ")
 }
 
 func (f *Filter) IsPlainQuery() bool {
 	return f.isPlain
 }
 
-func (f *Filter) CustomOnly() bool {
-	return f.Name.Name == "" &&
-		f.Description.Name == "" &&
-		f.Project.Name == "" &&
-		f.Content.Name == "" &&
-		f.Tags.Name == "" &&
-		f.Query == "" &&
-		f.Archived.Strict == false
+//extractSort removes sort terms from top level of query and sets sort field
+func (f *Filter) extractSort(node queryNode) (queryNode, error) {
+	switch n := node.(type) {
+	case *fieldNode:
+		if n.key == "sort" {
+			f.SortField = sortFields[n.value]
+			return nil, nil
+		}
+		return n, nil
+	case *andNode:
+		nodes := []queryNode{}
+		for _, v := range n.nodes {
+			child, err := f.extractSort(v)
+			if err != nil {
+				return nil, err
+			}
+			if child != nil {
+				nodes = append(nodes, child)
+			}
+		}
+		if len(nodes) == 0 {
+			return nil, nil
+		} else if len(nodes) == 1 {
+			return nodes[0], nil
+		}
+		return &andNode{nodes: nodes}, nil
+	default:
+		if pos := sortPosition(node); pos >= 0 {
+			return nil, queryError(pos, "sort cannot be inside OR or NOT")
+		}
+		return node, nil
+	}
 }
 
-func (f *Filter) parseTokens(tokens *map[string]StringFilter) error {
-	if (*tokens)["query"].Name != "" {
-		f.isPlain = true
-		f.Query = (*tokens)["query"].Name
-		return nil
-	}
-
-	var err error
-
-	for key, value := range *tokens {
-		switch strings.ToLower(key) {
-		case "name":
-			f.Name = value
-		case "description":
-			f.Description = value
-		case "project":
-			f.Project = value
-		case "tags":
-			f.Tags = value
-			f.Tags.Strict = false
-		case "link":
-			f.Content = value
-		case "after":
-			//f.CreatedAfter = value
-		case "before":
-			//f.CreatedBefore = value
-		case "sort":
-			f.SortField = value.Name
-		case "archived":
-			if strings.ToLower(value.Name) == "true" {
-				f.Archived.Name = "true"
-				f.Archived.Strict = true
-			} else if strings.ToLower(value.Name) == "false" {
-				f.Archived.Name = "false"
-				f.Archived.Strict = true
-			} else {
-				err = fmt.Errorf("invalid archived format: %v", value.Name)
-			}
-		default:
-			f.CustomTags[key] = value
+//sortPosition returns position of first sort term in node or -1
+func sortPosition(node queryNode) int {
+	var nodes []queryNode
+	switch n := node.(type) {
+	case *fieldNode:
+		if n.key == "sort" {
+			return n.pos
 		}
+	case *notNode:
+		nodes = []queryNode{n.node}
+	case *andNode:
+		nodes = n.nodes
+	case *orNode:
+		nodes = n.nodes
 	}
-	return err
+	for _, v := range nodes {
+		if pos := sortPosition(v); pos >= 0 {
+			return pos
+		}
+	}
+	return -1
 }
 
-//Clear clears filter
-func (f *Filter) Clear() {
+//Clear clears filter. If hideArchived is set, filter still excludes archived bookmarks.
+func (f *Filter) Clear(hideArchived bool) {
 	*f = Filter{}
-	if config.Configuration.HideArchived {
+	if hideArchived {
 		f.Archived.Strict = true
 		f.Archived.Name = "false"
 	}
 }
 
 func (f *Filter) IsEmpty() bool {
-	return f.CustomOnly() && len(f.CustomTags) == 0
+	return f.expr == nil &&
+		f.Name.Name == "" &&
+		f.Description.Name == "" &&
+		f.Project.Name == "" &&
+		f.Content.Name == "" &&
+		f.Tags.Name == "" &&
+		!f.Archived.Strict &&
+		f.CreatedAfter.IsZero() &&
+		f.CreatedBefore.IsZero() &&
+		f.UpdatedAfter.IsZero() &&
+		f.UpdatedBefore.IsZero() &&
+		len(f.CustomTags) == 0
 }
 
-//Tokenize tokenizes sentence into key-value pairs
-func tokenizeQuery(query string) (*map[string]StringFilter, error) {
-	splitChar := " "
-	//exactChar := '\''
-	keyValueChar := ":"
-	queryName := "query"
-	result := &map[string]StringFilter{}
-
-	//left := query
-	//index := 0
-
-	tokens := strings.Split(query, splitChar)
-	if len(tokens) == 0 {
-		return result, nil
-	} else if len(tokens) == 1 && tokens[0] == "" {
-		return result, nil
-	}
-
-	for _, token := range tokens {
-		t := strings.Split(token, keyValueChar)
-		if len(t) == 1 {
-			if (*result)[queryName].Name != "" {
-				return result, fmt.Errorf("invalid query: %s", token)
-			} else {
-				(*result)[queryName] = StringFilter{t[0], false, false}
-			}
-		} else if len(t) > 2 || t[1] == "" {
-			return result, fmt.Errorf("invalid query: '%s'", token)
-		} else {
-			//runes := []rune(t[1])
-			//if runes[0] == exactChar && runes[len(runes)-1] == exactChar {
-			(*result)[t[0]] = StringFilter{t[1], false, false}
+//notDeleted is sql condition for bookmarks b that are not in trash
+const notDeleted = "b.deleted_at IS NULL"
+
+//where returns sql condition for bookmarks b matching filter, or empty string if filter is empty.
+//Condition does not exclude deleted bookmarks.
+func (f *Filter) where(params *[]interface{}) string {
+	conditions := []string{}
+	fields := []struct {
+		column string
+		filter StringFilter
+	}{
+		{"b.lower_name", f.Name},
+		{"b.description_lower", f.Description},
+		{"LOWER(b.content)", f.Content},
+		{"LOWER(b.project)", f.Project},
+	}
+
+	not := func(inverse bool, condition string) string {
+		if inverse {
+			return "NOT (" + condition + ")"
+		}
+		return "(" + condition + ")"
+	}
+
+	for _, v := range fields {
+		if v.filter.Name != "" {
+			conditions = append(conditions, not(v.filter.Inverse, compare(v.column, v.filter.Name, v.filter.Strict, params)))
+		}
+	}
+	if tags := tagsNode(f.Tags.Name); tags != nil {
+		conditions = append(conditions, not(f.Tags.Inverse, tags.sql(params)))
+	}
+	if f.Archived.Strict {
+		conditions = append(conditions, "(b.archived = ?)")
+		*params = append(*params, f.Archived.Name == "true")
+	}
+
+	dates := []struct {
+		column string
+		after  bool
+		time   time.Time
+	}{
+		{"b.created_at", true, f.CreatedAfter},
+		{"b.created_at", false, f.CreatedBefore},
+		{"b.updated_at", true, f.UpdatedAfter},
+		{"b.updated_at", false, f.UpdatedBefore},
+	}
+	for _, v := range dates {
+		if !v.time.IsZero() {
+			conditions = append(conditions, dateCondition(v.column, v.after, v.time, params))
 		}
 	}
-	return result, nil
+
+	keys := make([]string, 0, len(f.CustomTags))
+	for key := range f.CustomTags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		filt := f.CustomTags[key]
+		conditions = append(conditions, not(filt.Inverse, metadataCondition(key, filt.Name, filt.Strict, params)))
+	}
+
+	if f.expr != nil {
+		conditions = append(conditions, f.expr.sql(params))
+	}
+	return strings.Join(conditions, " AND ")
 }
 
-func parseQuery(query string) (*map[string]StringFilter, error) {
-	result := &map[string]StringFilter{}
-	match := queryRegex.FindAllStringSubmatch(query, -1)
+//match returns true if bookmark matches filter like where condition does in sql
+func (f *Filter) match(b *models.Bookmark) bool {
+	fields := []struct {
+		value  string
+		filter StringFilter
+	}{
+		{b.Name, f.Name},
+		{b.Description, f.Description},
+		{b.Content, f.Content},
+		{b.Project, f.Project},
+	}
+	for _, v := range fields {
+		if v.filter.Name != "" && matchValue(v.value, v.filter.Name, v.filter.Strict) == v.filter.Inverse {
+			return false
+		}
+	}
+	if tags := tagsNode(f.Tags.Name); tags != nil && tags.match(b) == f.Tags.Inverse {
+		return false
+	}
+	if f.Archived.Strict && b.Archived != (f.Archived.Name == "true") {
+		return false
+	}
 
-	// No named fields
-	if len(match) == 0 {
-		(*result)["query"] = StringFilter{
-			Name:    query,
-			Strict:  false,
-			Inverse: false,
+	dates := []struct {
+		value time.Time
+		after bool
+		time  time.Time
+	}{
+		{b.CreatedAt, true, f.CreatedAfter},
+		{b.CreatedAt, false, f.CreatedBefore},
+		{b.UpdatedAt, true, f.UpdatedAfter},
+		{b.UpdatedAt, false, f.UpdatedBefore},
+	}
+	for _, v := range dates {
+		if !v.time.IsZero() && !matchDate(v.value, v.after, v.time) {
+			return false
 		}
-	} else {
-		for i := 0; i < len(match); i++ {
-			row := match[i]
-			if len(row) < 4 {
-				logrus.Info("Regex match < 2")
-			} else {
-				value := row[3]
-				filter := StringFilter{
-					Name:    "",
-					Strict:  false,
-					Inverse: false,
-				}
-
-				if row[1] == "+" {
-					filter.Inverse = false
-				} else if row[1] == "-" {
-					filter.Inverse = true
-				}
-
-				if strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
-					filter.Strict = true
-					value = strings.TrimLeft(value, "'")
-					value = strings.TrimRight(value, "'")
-				}
-				filter.Name = value
-
-				(*result)[row[2]] = filter
-			}
+	}
+
+	for key, filt := range f.CustomTags {
+		if matchMetadata(b, key, filt.Name, filt.Strict) == filt.Inverse {
+			return false
 		}
 	}
-	return result, nil
+	return f.expr == nil || f.expr.match(b)
 }
 
 //Construct bookmarks query from filter. Return values: query, parameters, error
 func (f *Filter) bookmarksQuery() (string, *[]interface{}, error) {
-	params := &[]interface{}{}
-	queryMetadata := `
-SELECT * FROM
-(
-	SELECT
+	params := &[]interface{}{strings.ToLower(models.MetadataLinkStatus)}
+	query := `
+SELECT
 	b.id AS id,
 	b.name AS name,
 	b.description AS description,
@@ -245,141 +347,25 @@ SELECT * FROM
 	b.created_at AS created_at,
 	b.updated_at AS updated_at,
 	b.archived AS archived,
-	-- skip tags for now
-	'' as tags
+	b.state AS state,
+	b.priority AS priority,
+	b.due_at AS due_at,
+	b.notes AS notes,
+	(SELECT GROUP_CONCAT(t.name) FROM bookmark_tags bt
+		JOIN tags t ON bt.tag = t.id
+		WHERE bt.bookmark = b.id) AS tags,
+	(SELECT m.value FROM metadata m
+		WHERE m.bookmark = b.id AND m.key_lower = ?) AS link_status
 FROM bookmarks b
-LEFT outer JOIN metadata m on b.id = m.bookmark
-WHERE `
-
-	queryBookmark := `
-SELECT
-	b.id as id,
-	b.name AS name,
-	b.description AS description,
-	b.content as content,
-	b.project AS project,
-	b.created_at AS created_at,
-	b.updated_at AS updated_at,
-	b.archived AS archived,
-GROUP_CONCAT(t.name) AS tags
-FROM bookmarks b
-LEFT JOIN bookmark_tags bt ON b.id = bt.bookmark
-LEFT JOIN tags t ON bt.tag = t.id
-	WHERE `
-
-	queryEnd := `
-GROUP BY b.id
-ORDER BY 
-	`
-
-	queryEndMetadata := ` ) AS b
-GROUP BY b.id
-ORDER BY 
-	`
-
-	queryNoFilters := `
-SELECT
-	b.id as id,
-	b.name AS name,
-	b.description AS description,
-	b.content as content,
-	b.project AS project,
-	b.created_at AS created_at,
-	b.updated_at AS updated_at,
-	b.archived AS archived,
-GROUP_CONCAT(t.name) AS tags
-FROM bookmarks b
-LEFT JOIN bookmark_tags bt ON b.id = bt.bookmark
-LEFT JOIN tags t ON bt.tag = t.id
-GROUP BY b.id
-ORDER BY `
-
-	queryLimit := "LIMIT 300"
-
-	query := ""
-
-	metadata := false
-	if len(f.CustomTags) > 0 {
-		metadata = true
-		query = queryMetadata
+WHERE ` + notDeleted
+	where := f.where(params)
+	if where != "" {
+		query += " AND " + where
 	}
-	i := 0
-	for key, filt := range f.CustomTags {
-		if i > 0 {
-			query += " AND "
-		}
+	query += "\n"
 
-		query += "(m.key_lower = ? AND "
-		*params = append(*params, strings.ToLower(key))
-		if filt.Inverse {
-			query += " NOT "
-		}
-		if filt.Strict {
-			query += "m.value_lower = ?) "
-			*params = append(*params, strings.ToLower(filt.Name))
-		} else {
-			query += "m.value_lower LIKE ?) "
-			*params = append(*params, "%"+strings.ToLower(filt.Name)+"%")
-		}
-		i += 1
-	}
-	if !f.CustomOnly() {
-		if metadata {
-			query += "UNION"
-		}
-
-		query += queryBookmark
-
-		data := map[string]StringFilter{
-			"b.lower_name":        f.Name,
-			"b.description_lower": f.Description,
-			"b.content":           f.Content,
-			"b.project":           f.Project,
-			//"b.tags": 		 f.Tags,
-		}
-
-		i = 0
-		for key, filt := range data {
-			if filt.Name != "" {
-				if i > 0 {
-					query += " AND "
-				}
-				if filt.Inverse {
-					query += " NOT "
-				}
-				query += "(" + key
-
-				if filt.Strict {
-					query += " = ?)"
-					*params = append(*params, strings.ToLower(filt.Name))
-				} else {
-					query += " LIKE ?)"
-					*params = append(*params, "%"+strings.ToLower(filt.Name)+"%")
-				}
-				i += 1
-			}
-		}
-		if f.Archived.Strict {
-			if i > 0 {
-				query += " AND "
-			}
-			query += "b.archived = " + f.Archived.Name
-		}
-	}
-
-	if metadata {
-		query += queryEndMetadata
-	} else if !f.CustomOnly() {
-		query += queryEnd
-	}
-	//validate sorting opts
-	f.parseSort()
-	if f.IsEmpty() {
-		query = queryNoFilters
-	}
-
-	query += " " + f.SortField + " " + f.SortDir + " "
-	query += queryLimit
+	query += "ORDER BY " + f.orderBy()
+	query += pageSql(f.Offset, f.Limit)
 	return query, params, nil
 }
 
@@ -389,88 +375,158 @@ func (f *Filter) projectsQuery() (string, *[]interface{}, error) {
 
 	if f.IsEmpty() {
 		query := `SELECT
-		project,
+		LOWER(project) AS project,
 			count(*) as count
-		FROM bookmarks 
-		GROUP BY project 
-		ORDER BY project ASC;`
+		FROM bookmarks b
+		WHERE ` + notDeleted + `
+		GROUP BY LOWER(project) 
+		ORDER BY LOWER(project) ASC;`
 		return query, params, nil
 	}
 
 	return "", params, fmt.Errorf("not implemented")
 }
 
-//BulkUpdateQuery creates a query that modifies bookmarks with filter to value of modifier
-func (f *Filter) bulkUpdateQuery(modifier *Modifier) (string, *[]interface{}, error) {
+//countQuery creates a query that counts bookmarks matching filter
+func (f *Filter) countQuery() (string, *[]interface{}) {
 	params := &[]interface{}{}
-	query := `
-	UPDATE bookmarks SET `
-	i := 0
-
-	data := map[string]StringFilter{
-		"project":  modifier.Project,
-		"archived": modifier.Archived,
-	}
-	i = 0
-	for key, filt := range data {
-		if filt.Name != "" {
-			if i > 0 {
-				query += " AND "
-			}
-			query += key + " = ?"
-			*params = append(*params, strings.ToLower(filt.Name))
-			i += 1
-		}
-	}
-
-	query += " WHERE "
-	data = map[string]StringFilter{
-		"project":     f.Project,
-		"archived":    f.Archived,
-		"name":        f.Name,
-		"description": f.Description,
+	query := "SELECT COUNT(*) FROM bookmarks b WHERE " + notDeleted
+	where := f.where(params)
+	if where != "" {
+		query += " AND " + where
 	}
-	i = 0
+	return query, params
+}
 
-	for key, filt := range data {
-		if filt.Name != "" {
-			if i > 0 {
-				query += " AND "
-			}
-			query += "(" + key
-			if filt.Strict {
-				query += " = ?)"
-				*params = append(*params, strings.ToLower(filt.Name))
-			} else {
-				query += " LIKE ?)"
-				*params = append(*params, "%"+strings.ToLower(filt.Name)+"%")
-			}
-			i += 1
-		}
+//idsQuery creates a query that selects ids of bookmarks matching filter for bulk modify.
+//Empty filter returns error to prevent modifying all bookmarks.
+func (f *Filter) idsQuery() (string, *[]interface{}, error) {
+	params := &[]interface{}{}
+	where := f.where(params)
+	if where == "" {
+		return "", params, fmt.Errorf("empty filter would modify all bookmarks")
 	}
-	return query, params, nil
+	return "SELECT b.id FROM bookmarks b WHERE " + notDeleted + " AND " + where, params, nil
 }
 
-func (f *Filter) parseSort() {
+//orderBy returns sql ordering for sort field and direction. Bookmarks with equal sort field
+//are ordered by id to keep pages stable.
+func (f *Filter) orderBy() string {
+	column := "b.name"
 	switch f.SortField {
-	case "Name":
-		f.SortField = "b.name"
 	case "Description":
-		f.SortField = "b.description"
+		column = "b.description"
 	case "Project":
-		f.SortField = "b.project"
+		column = "b.project"
 	case "Added at":
-		f.SortField = "b.created_at"
+		column = "b.created_at"
 	case "Link":
-		f.SortField = "b.content"
-	default:
-		f.SortField = "b.name"
+		column = "b.content"
+	case "State":
+		column = "CASE b.state WHEN 'unread' THEN 0 WHEN 'reading' THEN 1 ELSE 2 END"
+	case "Priority":
+		column = "b.priority"
+	case "Due":
+		column = "b.due_at"
 	}
 
-	switch f.SortDir {
-	case "ASC":
-	case "DESC":
-	default:
-		f.SortDir = "ASC"
+	dir := "ASC"
+	if f.SortDir == "DESC" {
+		dir = "DESC"
+	}
+	if f.SortField == "Due" {
+		// bookmarks without due date are last in both directions
+		return "b.due_at IS NULL, " + column + " " + dir + ", b.id " + dir
+	}
+	return column + " " + dir + ", b.id " + dir
+}
+
+//stateOrder returns position of reading state in models.ReadStates
+func stateOrder(state string) int {
+	for i, v := range models.ReadStates {
+		if v == state {
+			return i
+		}
+	}
+	return len(models.ReadStates)
+}
+
+//sortBookmarks sorts bookmarks in memory like orderBy does in sql
+func (f *Filter) sortBookmarks(bookmarks []*models.Bookmark) {
+	less := func(a, b *models.Bookmark) bool {
+		var x, y string
+		switch f.SortField {
+		case "Description":
+			x, y = a.Description, b.Description
+		case "Project":
+			x, y = a.Project, b.Project
+		case "Added at":
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+		case "Link":
+			x, y = a.Content, b.Content
+		case "State":
+			if a.ReadState() != b.ReadState() {
+				return stateOrder(a.ReadState()) < stateOrder(b.ReadState())
+			}
+		case "Priority":
+			if a.Priority != b.Priority {
+				return a.Priority < b.Priority
+			}
+		case "Due":
+			if a.DueAt != nil && b.DueAt != nil && !a.DueAt.Equal(*b.DueAt) {
+				return a.DueAt.Before(*b.DueAt)
+			}
+		default:
+			x, y = a.Name, b.Name
+		}
+		if x != y {
+			return x < y
+		}
+		return a.Id < b.Id
+	}
+	sort.Slice(bookmarks, func(i, j int) bool {
+		a, b := bookmarks[i], bookmarks[j]
+		if f.SortField == "Due" && (a.DueAt == nil) != (b.DueAt == nil) {
+			return b.DueAt == nil
+		}
+		if f.SortDir == "DESC" {
+			return less(bookmarks[j], bookmarks[i])
+		}
+		return less(bookmarks[i], bookmarks[j])
+	})
+}
+
+//page returns bookmarks in page like pageSql does in sql
+func page(bookmarks []*models.Bookmark, offset, limit int) []*models.Bookmark {
+	if limit == 0 {
+		limit = DefaultPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(bookmarks) {
+		return []*models.Bookmark{}
+	}
+	bookmarks = bookmarks[offset:]
+	if limit > 0 && limit < len(bookmarks) {
+		bookmarks = bookmarks[:limit]
+	}
+	return bookmarks
+}
+
+//pageSql returns sql limit and offset. Zero limit uses DefaultPageSize and negative limit returns all rows.
+func pageSql(offset, limit int) string {
+	if limit == 0 {
+		limit = DefaultPageSize
+	} else if limit < 0 {
+		limit = -1
+	}
+	if offset > 0 {
+		return fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+	} else if limit > 0 {
+		return fmt.Sprintf(" LIMIT %d", limit)
 	}
+	return ""
 }
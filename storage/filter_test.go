@@ -18,114 +18,458 @@ package storage
 
 import (
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
 )
 
-func Test_tokenize(t *testing.T) {
+func TestNewFilter(t *testing.T) {
 	tests := []struct {
-		name    string
-		query   string
-		want    *map[string]StringFilter
-		wantErr bool
-	}{{
-		name:  "simple query",
-		query: "want:one test:two",
-		want: &map[string]StringFilter{
-			"want": {Name: "one"},
-			"test": {Name: "two"},
-		},
-		wantErr: false,
-	},
-		{
-			name:  "plain query",
-			query: "bookmark",
-			want: &map[string]StringFilter{
-				"query": {Name: "bookmark"},
-			},
-			wantErr: false,
-		},
-		{
-			name:    "empty query",
-			query:   "",
-			want:    &map[string]StringFilter{},
-			wantErr: false,
-		},
-		{
-			name:  "single value",
-			query: "test:one",
-			want: &map[string]StringFilter{
-				"test": {Name: "one"},
-			},
-			wantErr: false,
-		},
-		{
-			name:  "value and query",
-			query: "test:one two",
-			want: &map[string]StringFilter{
-				"test":  {Name: "one"},
-				"query": {Name: "two"},
-			},
-			wantErr: false,
-		},
-		{
-			name:    "key with no value",
-			query:   "test: ",
-			want:    &map[string]StringFilter{},
+		name      string
+		query     string
+		want      string
+		wantPlain bool
+		wantSort  string
+		wantErr   bool
+	}{
+		{
+			name:  "simple query",
+			query: "name:a description:b",
+			want:  "(AND name:a description:b)",
+		},
+		{
+			name:      "empty query",
+			query:     "  ",
+			want:      "",
+			wantPlain: false,
+		},
+		{
+			name:      "plain query",
+			query:     "golang",
+			want:      "golang",
+			wantPlain: true,
+		},
+		{
+			name:      "prefix query",
+			query:     "gol*",
+			want:      "gol",
+			wantPlain: true,
+		},
+		{
+			name:  "free text with operators",
+			query: `help "page one" OR site`,
+			want:  `(OR (AND help "page one") site)`,
+		},
+		{
+			name:  "negated word",
+			query: "-alpha",
+			want:  "(NOT alpha)",
+		},
+		{
+			name:  "phrase",
+			query: `"alpha one"`,
+			want:  `"alpha one"`,
+		},
+		{
+			name:  "repeated keys",
+			query: "tags:go tags:db",
+			want:  "(AND tags:go tags:db)",
+		},
+		{
+			name:  "all of tags",
+			query: "tags:go,db",
+			want:  "(AND tags:go tags:db)",
+		},
+		{
+			name:  "any of tags",
+			query: "tags:go|db|web",
+			want:  "(OR tags:go tags:db tags:web)",
+		},
+		{
+			name:  "none of tags",
+			query: "-tags:old,draft",
+			want:  "(NOT (AND tags:old tags:draft))",
+		},
+		{
+			name:  "all and any of tags",
+			query: "tags:go,db|sql, -tag:old",
+			want:  "(AND (AND tags:go (OR tags:db tags:sql)) (NOT tags:old))",
+		},
+		{
+			name:  "quoted tag",
+			query: "tags:'a,b'",
+			want:  "tags:'a,b'",
+		},
+		{
+			name:    "empty tags",
+			query:   "tags:,|",
+			wantErr: true,
+		},
+		{
+			name:  "or, grouping and negation",
+			query: "(name:go OR project:dev) AND NOT archived:TRUE -author:'Rob Pike'",
+			want:  "(AND (OR name:go project:dev) (NOT archived:true) (NOT author:'Rob Pike'))",
+		},
+		{
+			name:  "free text with fields",
+			query: "golang link:https://golang.org/doc -(tag:old)",
+			want:  "(AND golang link:https://golang.org/doc (NOT tags:old))",
+		},
+		{
+			name:      "pasted url",
+			query:     "https://example.com/a?b=c:d",
+			want:      "https://example.com/a?b=c:d",
+			wantPlain: true,
+		},
+		{
+			name:  "pasted url with fields",
+			query: "project:dev https://example.com/a",
+			want:  "(AND project:dev https://example.com/a)",
+		},
+		{
+			name:  "quoted key and escaped quote",
+			query: `"Published At":'2020' +name:"it's \"quoted\""`,
+			want:  `(AND published at:'2020' name:'it's "quoted"')`,
+		},
+		{
+			name:     "sort",
+			query:    "project:dev sort:Added",
+			want:     "project:dev",
+			wantSort: "Added at",
+		},
+		{
+			name:  "dates",
+			query: "after:2020-01-31 before:3mo OR updated>:today",
+			want:  "(OR (AND after:2020-01-31 before:3mo) updated>:today)",
+		},
+		{
+			name:    "invalid date",
+			query:   "after:tomorrow",
+			wantErr: true,
+		},
+		{
+			name:    "invalid archived",
+			query:   "archived:maybe",
+			wantErr: true,
+		},
+		{
+			name:     "reading list",
+			query:    "state:Reading priority:>2 due:overdue sort:due",
+			want:     "(AND state:reading priority:>2 due:overdue)",
+			wantSort: "Due",
+		},
+		{
+			name:    "invalid state",
+			query:   "state:done",
+			wantErr: true,
+		},
+		{
+			name:    "invalid priority",
+			query:   "priority:>high",
+			wantErr: true,
+		},
+		{
+			name:    "sort inside or",
+			query:   "name:a OR sort:name",
 			wantErr: true,
 		},
-
-		/*
-			{
-				name: "value with space",
-				query: "test:'one two'",
-				want: &map[string]string{
-					"test": "one",
-					"query": "two",
-				},
-				wantErr: false,
-			},
-		*/
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := tokenizeQuery(tt.query)
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("tokenize() = %v, want %v", got, tt.want)
-			}
+			got, err := NewFilter(tt.query)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("tokenize() return err = %v, wantErr = %v", err, tt.wantErr)
+				t.Errorf("NewFilter() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			expr := ""
+			if got.expr != nil {
+				expr = got.expr.String()
+			}
+			if expr != tt.want {
+				t.Errorf("NewFilter() got = %s, want %s", expr, tt.want)
+			}
+			if got.IsPlainQuery() != tt.wantPlain {
+				t.Errorf("NewFilter() plain = %v, want %v", got.IsPlainQuery(), tt.wantPlain)
+			}
+			if got.SortField != tt.wantSort {
+				t.Errorf("NewFilter() sort = %s, want %s", got.SortField, tt.wantSort)
 			}
 		})
 	}
 }
 
-func TestNewFilter(t *testing.T) {
+func Test_parseQuery_errors(t *testing.T) {
 	tests := []struct {
-		name    string
 		query   string
-		want    *Filter
+		wantPos int
+	}{
+		{query: "name:", wantPos: 1},
+		{query: "golang (name:a", wantPos: 8},
+		{query: "name:a)", wantPos: 7},
+		{query: "a OR", wantPos: 5},
+		{query: "a 'unterminated", wantPos: 3},
+		{query: "()", wantPos: 1},
+		{query: ":value", wantPos: 1},
+		{query: "a archived:no", wantPos: 12},
+		{query: "äö after:1x", wantPos: 10},
+		{query: "a priority:=x", wantPos: 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := parseQuery(tt.query)
+			qErr, ok := err.(*QueryError)
+			if !ok {
+				t.Fatalf("parseQuery() error = %v, want QueryError", err)
+			}
+			if qErr.Pos != tt.wantPos {
+				t.Errorf("parseQuery() error position = %d, want %d: %v", qErr.Pos, tt.wantPos, err)
+			}
+		})
+	}
+}
+
+func TestFilter_bookmarksQuery(t *testing.T) {
+	f, err := NewFilter("(name:go OR tags:db) -author:'Rob' 50%")
+	if err != nil {
+		t.Fatalf("NewFilter() error = %v", err)
+	}
+	f.Archived = StringFilter{Name: "false", Strict: true}
+	f.Limit = -1
+
+	query, params, err := f.bookmarksQuery()
+	if err != nil {
+		t.Fatalf("bookmarksQuery() error = %v", err)
+	}
+	wantWhere := "WHERE b.deleted_at IS NULL AND (b.archived = ?) AND (((b.lower_name LIKE ? ESCAPE '\\') OR EXISTS (SELECT 1 FROM bookmark_tags bt " +
+		"JOIN tags t ON bt.tag = t.id WHERE bt.bookmark = b.id AND (LOWER(t.name) = ? OR LOWER(t.name) LIKE ? ESCAPE '\\'))) AND NOT EXISTS " +
+		"(SELECT 1 FROM metadata m WHERE m.bookmark = b.id AND m.key_lower = ? AND m.value_lower = ?) AND ("
+	if !strings.Contains(query, wantWhere) {
+		t.Errorf("bookmarksQuery() = %s, want where %s", query, wantWhere)
+	}
+	if !strings.HasSuffix(query, "ORDER BY b.name ASC, b.id ASC") {
+		t.Errorf("bookmarksQuery() = %s, want no limit", query)
+	}
+
+	want := []interface{}{"status", false, "%go%", "db", "db/%", "author", "rob"}
+	for i := 0; i < 7; i++ {
+		want = append(want, `%50\%%`)
+	}
+	if !reflect.DeepEqual(*params, want) {
+		t.Errorf("bookmarksQuery() params = %v, want %v", *params, want)
+	}
+}
+
+func Test_parseDate(t *testing.T) {
+	// wednesday
+	now := time.Date(2020, 4, 15, 13, 30, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
 		wantErr bool
+	}{
+		{value: "2020-01-31", want: time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{value: "2020-01-31T08:15", want: time.Date(2020, 1, 31, 8, 15, 0, 0, time.UTC)},
+		{value: "12h", want: time.Date(2020, 4, 15, 1, 30, 0, 0, time.UTC)},
+		{value: "7d", want: time.Date(2020, 4, 8, 13, 30, 0, 0, time.UTC)},
+		{value: "2w", want: time.Date(2020, 4, 1, 13, 30, 0, 0, time.UTC)},
+		{value: "3mo", want: time.Date(2020, 1, 15, 13, 30, 0, 0, time.UTC)},
+		{value: "1y", want: time.Date(2019, 4, 15, 13, 30, 0, 0, time.UTC)},
+		{value: "Today", want: time.Date(2020, 4, 15, 0, 0, 0, 0, time.UTC)},
+		{value: "yesterday", want: time.Date(2020, 4, 14, 0, 0, 0, 0, time.UTC)},
+		{value: "thisweek", want: time.Date(2020, 4, 13, 0, 0, 0, 0, time.UTC)},
+		{value: "2020-13-01", wantErr: true},
+		{value: "7", wantErr: true},
+		{value: "lastyear", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseDate(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseDate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_tags(t *testing.T) {
+	tagQuery := "EXISTS (SELECT 1 FROM bookmark_tags bt JOIN tags t ON bt.tag = t.id WHERE bt.bookmark = b.id AND " +
+		"(LOWER(t.name) = ? OR LOWER(t.name) LIKE ? ESCAPE '\\'))"
+	tests := []struct {
+		name       string
+		filter     *Filter
+		wantWhere  string
+		wantParams []interface{}
 	}{
 		{
-			name:  "simple query",
-			query: "name:a description:b",
-			want: &Filter{
-				Name:        StringFilter{Name: "a"},
-				Description: StringFilter{Name: "b"},
-			},
-			wantErr: false,
+			name:       "all of",
+			filter:     &Filter{Tags: StringFilter{Name: "Go,db"}},
+			wantWhere:  "((" + tagQuery + " AND " + tagQuery + "))",
+			wantParams: []interface{}{"go", "go/%", "db", "db/%"},
+		},
+		{
+			name:       "any of",
+			filter:     &Filter{Tags: StringFilter{Name: "go|db"}},
+			wantWhere:  "((" + tagQuery + " OR " + tagQuery + "))",
+			wantParams: []interface{}{"go", "go/%", "db", "db/%"},
+		},
+		{
+			name:       "none of",
+			filter:     &Filter{Tags: StringFilter{Name: "go|db", Inverse: true}},
+			wantWhere:  "NOT ((" + tagQuery + " OR " + tagQuery + "))",
+			wantParams: []interface{}{"go", "go/%", "db", "db/%"},
+		},
+		{
+			name:       "parent",
+			filter:     &Filter{Tags: StringFilter{Name: "lang/"}},
+			wantWhere:  "(" + tagQuery + ")",
+			wantParams: []interface{}{"lang", "lang/%"},
+		},
+		{
+			name:      "empty",
+			filter:    &Filter{Tags: StringFilter{Name: ","}},
+			wantWhere: "",
 		},
-		// TODO: Add test cases.
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := NewFilter(tt.query)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("NewFilter() error = %v, wantErr %v", err, tt.wantErr)
+			params := &[]interface{}{}
+			got := tt.filter.where(params)
+			if got != tt.wantWhere {
+				t.Errorf("where() = %s, want %s", got, tt.wantWhere)
+			}
+			if len(*params) > 0 && !reflect.DeepEqual(*params, tt.wantParams) {
+				t.Errorf("where() params = %v, want %v", *params, tt.wantParams)
+			}
+		})
+	}
+}
+
+func TestFilter_idsQuery(t *testing.T) {
+	f, err := NewFilter("tags:go|db -tags:old")
+	if err != nil {
+		t.Fatalf("NewFilter() error = %v", err)
+	}
+
+	query, params, err := f.idsQuery()
+	if err != nil {
+		t.Fatalf("idsQuery() error = %v", err)
+	}
+	if strings.Count(query, "bookmark_tags") != 3 {
+		t.Errorf("idsQuery() = %s, want 3 tag conditions", query)
+	}
+	want := []interface{}{"go", "go/%", "db", "db/%", "old", "old/%"}
+	if !reflect.DeepEqual(*params, want) {
+		t.Errorf("idsQuery() params = %v, want %v", *params, want)
+	}
+
+	_, _, err = (&Filter{}).idsQuery()
+	if err == nil {
+		t.Errorf("idsQuery() with empty filter, want error")
+	}
+}
+
+func TestNewSavedSearchFilter(t *testing.T) {
+	search := &models.SavedSearch{Query: "tags:ml -archived:true sort:added", SortField: "Name", SortDir: "DESC"}
+	f, err := NewSavedSearchFilter(search)
+	if err != nil {
+		t.Fatalf("NewSavedSearchFilter() error = %v", err)
+	}
+	if f.SortField != "Name" || f.SortDir != "DESC" {
+		t.Errorf("NewSavedSearchFilter() sort = %s %s, want Name DESC", f.SortField, f.SortDir)
+	}
+
+	search.SortField = ""
+	f, err = NewSavedSearchFilter(search)
+	if err != nil {
+		t.Fatalf("NewSavedSearchFilter() error = %v", err)
+	}
+	if f.SortField != "Added at" {
+		t.Errorf("NewSavedSearchFilter() sort = %s, want sort from query", f.SortField)
+	}
+
+	query, params := f.countQuery()
+	want := "SELECT COUNT(*) FROM bookmarks b WHERE b.deleted_at IS NULL AND (EXISTS"
+	if !strings.HasPrefix(query, want) || strings.Contains(query, "ORDER BY") {
+		t.Errorf("countQuery() = %s, want prefix %s", query, want)
+	}
+	if !reflect.DeepEqual(*params, []interface{}{"ml", "ml/%", true}) {
+		t.Errorf("countQuery() params = %v", *params)
+	}
+
+	_, err = NewSavedSearchFilter(&models.SavedSearch{Query: "(tags:ml"})
+	if err == nil {
+		t.Errorf("NewSavedSearchFilter() with invalid query, want error")
+	}
+}
+
+func TestSelectionQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		project string
+		tags    []string
+		any     bool
+		want    string
+	}{
+		{name: "empty", want: ""},
+		{name: "project", project: "dev.go", want: "project:'dev.go'"},
+		{name: "all of", project: "dev", tags: []string{"go", "read later"},
+			want: "project:'dev' tags:'go' tags:'read later'"},
+		{name: "any of", tags: []string{"lang/go", "it's"}, any: true, want: `(tags:'lang/go' OR tags:"it's")`},
+		{name: "single any", tags: []string{"go"}, any: true, want: "tags:'go'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SelectionQuery(tt.project, tt.tags, tt.any)
+			if got != tt.want {
+				t.Errorf("SelectionQuery() = %s, want %s", got, tt.want)
+			}
+			if got == "" {
 				return
 			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("NewFilter() got = %v, want %v", got, tt.want)
+			f, err := NewFilter(got)
+			if err != nil {
+				t.Fatalf("NewFilter() error = %v", err)
+			}
+			if f.IsPlainQuery() {
+				t.Errorf("SelectionQuery() = %s is plain query", got)
+			}
+		})
+	}
+}
+
+func TestFilter_page(t *testing.T) {
+	tests := []struct {
+		name   string
+		sort   string
+		dir    string
+		offset int
+		limit  int
+		want   string
+	}{
+		{name: "default", want: "ORDER BY b.name ASC, b.id ASC LIMIT 300"},
+		{name: "second page", sort: "Added at", dir: "DESC", offset: 300,
+			want: "ORDER BY b.created_at DESC, b.id DESC LIMIT 300 OFFSET 300"},
+		{name: "no limit", sort: "Project", limit: -1, want: "ORDER BY b.project ASC, b.id ASC"},
+		{name: "offset without limit", offset: 10, limit: -1, want: "ORDER BY b.name ASC, b.id ASC LIMIT -1 OFFSET 10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Filter{SortField: tt.sort, SortDir: tt.dir, Offset: tt.offset, Limit: tt.limit}
+			for i := 0; i < 2; i++ {
+				query, _, err := f.bookmarksQuery()
+				if err != nil {
+					t.Fatalf("bookmarksQuery() error = %v", err)
+				}
+				if !strings.HasSuffix(query, tt.want) {
+					t.Errorf("bookmarksQuery() = %s, want suffix %s", query, tt.want)
+				}
 			}
 		})
 	}
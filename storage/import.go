@@ -0,0 +1,191 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//ImportItem is a bookmark in import preview
+type ImportItem struct {
+	Bookmark *models.Bookmark
+	//Folder is project of bookmark in imported file
+	Folder string
+	//Existing is id of stored bookmark that has same link, 0 if link is new
+	Existing int
+	//Repeated is set if an earlier item in import has same link
+	Repeated bool
+	//Invalid tells why link is invalid, empty if link is valid
+	Invalid string
+	//Selected items are imported
+	Selected bool
+}
+
+//ImportMapping is folder of imported file and project its bookmarks are imported to
+type ImportMapping struct {
+	Folder  string
+	Project string
+	Count   int
+}
+
+//ImportPreview holds parsed bookmarks that are reviewed before import. Items can be deselected
+//and moved to other projects before importing selected bookmarks.
+type ImportPreview struct {
+	Items []*ImportItem
+}
+
+//PreviewImport checks bookmarks to import for invalid links, links that already exist and
+//links that are repeated in bookmarks. Items with invalid link are not selected.
+func (d *Database) PreviewImport(bookmarks []*models.Bookmark) (*ImportPreview, error) {
+	tx, err := d.conn.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %v", err)
+	}
+	index, err := existingLinks(tx, bookmarks)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("transaction failed: %v", err)
+	}
+
+	preview := &ImportPreview{Items: make([]*ImportItem, len(bookmarks))}
+	seen := map[string]bool{}
+	for i, b := range bookmarks {
+		item := &ImportItem{
+			Bookmark: b,
+			Folder:   b.Project,
+			Selected: true,
+		}
+		if err := models.ValidateLink(b.Content); err != nil {
+			item.Invalid = err.Error()
+			item.Selected = false
+		}
+		link := b.CanonicalLink()
+		if link != "" {
+			if ids := index[link]; len(ids) > 0 {
+				item.Existing = ids[0]
+			}
+			item.Repeated = seen[link]
+			seen[link] = true
+		}
+		preview.Items[i] = item
+	}
+	return preview, nil
+}
+
+//Selected returns bookmarks of selected items
+func (p *ImportPreview) Selected() []*models.Bookmark {
+	bookmarks := []*models.Bookmark{}
+	for _, v := range p.Items {
+		if v.Selected {
+			bookmarks = append(bookmarks, v.Bookmark)
+		}
+	}
+	return bookmarks
+}
+
+//SelectAll selects or deselects all items. Items with invalid link are only deselected.
+func (p *ImportPreview) SelectAll(selected bool) {
+	for _, v := range p.Items {
+		v.Selected = selected && v.Invalid == ""
+	}
+}
+
+//Retarget moves bookmarks in folder and its sub folders to project, keeping sub folders
+//as sub projects: folder 'a', project 'b' moves folder 'a.c' to 'b.c'. Returns number of moved bookmarks.
+func (p *ImportPreview) Retarget(folder, project string) int {
+	count := 0
+	for _, v := range p.Items {
+		target := ""
+		if v.Folder == folder {
+			target = project
+		} else if folder == "" || strings.HasPrefix(v.Folder, folder+".") {
+			target = strings.Trim(project+"."+strings.TrimPrefix(strings.TrimPrefix(v.Folder, folder), "."), ".")
+		} else {
+			continue
+		}
+		v.Bookmark.Project = target
+		count += 1
+	}
+	return count
+}
+
+//Counts returns number of all, selected, existing, repeated and invalid items
+func (p *ImportPreview) Counts() (total, selected, existing, repeated, invalid int) {
+	for _, v := range p.Items {
+		if v.Selected {
+			selected += 1
+		}
+		if v.Existing != 0 {
+			existing += 1
+		}
+		if v.Repeated {
+			repeated += 1
+		}
+		if v.Invalid != "" {
+			invalid += 1
+		}
+	}
+	return len(p.Items), selected, existing, repeated, invalid
+}
+
+//Projects returns number of selected bookmarks for each target project
+func (p *ImportPreview) Projects() map[string]int {
+	projects := map[string]int{}
+	for _, v := range p.Items {
+		if v.Selected {
+			projects[v.Bookmark.Project] += 1
+		}
+	}
+	return projects
+}
+
+//Mappings returns folders and projects their selected bookmarks are imported to, sorted by folder
+func (p *ImportPreview) Mappings() []*ImportMapping {
+	type key struct {
+		folder  string
+		project string
+	}
+	found := map[key]*ImportMapping{}
+	mappings := []*ImportMapping{}
+	for _, v := range p.Items {
+		if !v.Selected {
+			continue
+		}
+		k := key{folder: v.Folder, project: v.Bookmark.Project}
+		mapping := found[k]
+		if mapping == nil {
+			mapping = &ImportMapping{Folder: v.Folder, Project: v.Bookmark.Project}
+			found[k] = mapping
+			mappings = append(mappings, mapping)
+		}
+		mapping.Count += 1
+	}
+	sort.SliceStable(mappings, func(i, j int) bool {
+		if mappings[i].Folder != mappings[j].Folder {
+			return mappings[i].Folder < mappings[j].Folder
+		}
+		return mappings[i].Project < mappings[j].Project
+	})
+	return mappings
+}
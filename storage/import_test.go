@@ -0,0 +1,121 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+func testPreview() *ImportPreview {
+	items := []*ImportItem{}
+	for _, v := range []struct {
+		folder  string
+		invalid string
+	}{
+		{folder: ""},
+		{folder: "dev"},
+		{folder: "dev.go"},
+		{folder: "dev.go", invalid: "no scheme"},
+		{folder: "devops"},
+	} {
+		items = append(items, &ImportItem{
+			Bookmark: &models.Bookmark{Project: v.folder},
+			Folder:   v.folder,
+			Invalid:  v.invalid,
+			Selected: v.invalid == "",
+		})
+	}
+	return &ImportPreview{Items: items}
+}
+
+func projects(p *ImportPreview) []string {
+	result := make([]string, len(p.Items))
+	for i, v := range p.Items {
+		result[i] = v.Bookmark.Project
+	}
+	return result
+}
+
+func TestImportPreview_Retarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		folder  string
+		project string
+		count   int
+		want    []string
+	}{
+		{
+			name:    "folder and sub folders",
+			folder:  "dev",
+			project: "code",
+			count:   3,
+			want:    []string{"", "code", "code.go", "code.go", "devops"},
+		},
+		{
+			name:    "sub folder to root",
+			folder:  "dev.go",
+			project: "",
+			count:   2,
+			want:    []string{"", "dev", "", "", "devops"},
+		},
+		{
+			name:    "all under project",
+			folder:  "",
+			project: "imported",
+			count:   5,
+			want:    []string{"imported", "imported.dev", "imported.dev.go", "imported.dev.go", "imported.devops"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testPreview()
+			if got := p.Retarget(tt.folder, tt.project); got != tt.count {
+				t.Errorf("Retarget() = %d, want %d", got, tt.count)
+			}
+			if got := projects(p); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Retarget() projects = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportPreview_Mappings(t *testing.T) {
+	p := testPreview()
+	p.Retarget("dev.go", "go")
+	p.Items[4].Selected = false
+
+	want := []*ImportMapping{
+		{Folder: "", Project: "", Count: 1},
+		{Folder: "dev", Project: "dev", Count: 1},
+		{Folder: "dev.go", Project: "go", Count: 1},
+	}
+	if got := p.Mappings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Mappings() = %v, want %v", got, want)
+	}
+	wantProjects := map[string]int{"": 1, "dev": 1, "go": 1}
+	if got := p.Projects(); !reflect.DeepEqual(got, wantProjects) {
+		t.Errorf("Projects() = %v, want %v", got, wantProjects)
+	}
+
+	p.SelectAll(true)
+	total, selected, _, _, invalid := p.Counts()
+	if total != 5 || selected != 4 || invalid != 1 {
+		t.Errorf("Counts() after SelectAll = %d total, %d selected, %d invalid, want 5, 4, 1", total, selected, invalid)
+	}
+}
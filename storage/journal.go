@@ -0,0 +1,598 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"sort"
+	"strings"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//maxJournalEntries is number of operations kept in journal. Older entries are removed.
+const maxJournalEntries = 500
+
+//bookmarkImage is complete state of single bookmark, stored in journal.
+//Snapshots are ids of snapshots of bookmark.
+type bookmarkImage struct {
+	Id          int               `json:"id" db:"id"`
+	Name        string            `json:"name" db:"name"`
+	Description string            `json:"description" db:"description"`
+	Content     string            `json:"content" db:"content"`
+	Project     string            `json:"project" db:"project"`
+	Notes       string            `json:"notes,omitempty" db:"notes"`
+	CreatedAt   time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at" db:"updated_at"`
+	Archived    bool              `json:"archived" db:"archived"`
+	State       string            `json:"state" db:"state"`
+	Priority    int               `json:"priority" db:"priority"`
+	DueAt       *time.Time        `json:"due_at,omitempty" db:"due_at"`
+	DeletedAt   *time.Time        `json:"deleted_at,omitempty" db:"deleted_at"`
+	Tags        []string          `json:"tags" db:"-"`
+	Metadata    map[string]string `json:"metadata" db:"-"`
+	Snapshots   []int             `json:"snapshots,omitempty" db:"-"`
+}
+
+//newImage returns image of bookmark
+func newImage(b *models.Bookmark) *bookmarkImage {
+	image := &bookmarkImage{
+		Id:          b.Id,
+		Name:        b.Name,
+		Description: b.Description,
+		Content:     b.Content,
+		Project:     b.Project,
+		Notes:       b.Notes,
+		CreatedAt:   b.CreatedAt,
+		UpdatedAt:   b.UpdatedAt,
+		Archived:    b.Archived,
+		State:       b.State,
+		Priority:    b.Priority,
+		DueAt:       b.DueAt,
+		DeletedAt:   b.DeletedAt,
+		Tags:        append([]string{}, b.Tags...),
+		Metadata:    map[string]string{},
+	}
+	if b.Metadata != nil {
+		for key, value := range *b.Metadata {
+			image.Metadata[key] = value
+		}
+	}
+	return image
+}
+
+//bookmark returns bookmark in state of image
+func (i *bookmarkImage) bookmark() *models.Bookmark {
+	b := &models.Bookmark{
+		Id:           i.Id,
+		Name:         i.Name,
+		LowerName:    strings.ToLower(i.Name),
+		Description:  i.Description,
+		Content:      i.Content,
+		Project:      i.Project,
+		Notes:        i.Notes,
+		CreatedAt:    i.CreatedAt,
+		UpdatedAt:    i.UpdatedAt,
+		Archived:     i.Archived,
+		State:        i.State,
+		Priority:     i.Priority,
+		DueAt:        i.DueAt,
+		DeletedAt:    i.DeletedAt,
+		Tags:         append([]string{}, i.Tags...),
+		Metadata:     &map[string]string{},
+		MetadataKeys: &[]string{},
+	}
+	keys := make([]string, 0, len(i.Metadata))
+	for key := range i.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b.AddMetadata(key, i.Metadata[key])
+	}
+	return b
+}
+
+//state returns reading state of image. Images in old journal entries have no state.
+func (i *bookmarkImage) state() string {
+	if i.State == "" {
+		return models.StateUnread
+	}
+	return i.State
+}
+
+//images maps bookmark id to its state. Nil image means bookmark does not exist.
+type images map[int]*bookmarkImage
+
+//ids returns sorted ids of images
+func (i images) ids() []int {
+	ids := make([]int, 0, len(i))
+	for id := range i {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+//changed returns copies of before and after, that only contain bookmarks that differ
+func changed(before, after images) (images, images) {
+	b := images{}
+	a := images{}
+	for id, image := range after {
+		old := before[id]
+		if old == nil && image == nil {
+			continue
+		}
+		if old != nil && image != nil {
+			oldJson, _ := json.Marshal(old)
+			newJson, _ := json.Marshal(image)
+			if string(oldJson) == string(newJson) {
+				continue
+			}
+		}
+		b[id] = old
+		a[id] = image
+	}
+	return b, a
+}
+
+type journalEntry struct {
+	Id        int    `db:"id"`
+	Operation string `db:"operation"`
+	Before    string `db:"before"`
+	After     string `db:"after"`
+}
+
+//loadImages returns current state of bookmarks. Every id is included in result,
+//bookmarks that do not exist have nil image.
+func loadImages(q sqlx.Queryer, ids []int) (images, error) {
+	result := images{}
+	for _, id := range ids {
+		result[id] = nil
+	}
+
+	//Max variables for sqlite is 999
+	batchSize := 500
+	for left := ids; len(left) > 0; {
+		batch := left
+		if len(batch) > batchSize {
+			batch = left[:batchSize]
+		}
+		left = left[len(batch):]
+
+		args := make([]interface{}, len(batch))
+		for i, v := range batch {
+			args[i] = v
+		}
+		in := "(" + placeholders(len(batch)) + ")"
+
+		bookmarks := []*bookmarkImage{}
+		err := sqlx.Select(q, &bookmarks, `
+SELECT
+	id,
+	name,
+	COALESCE(description, '') AS description,
+	content,
+	COALESCE(project, '') AS project,
+	notes,
+	created_at,
+	updated_at,
+	archived,
+	state,
+	priority,
+	due_at,
+	deleted_at
+FROM bookmarks
+WHERE id IN `+in, args...)
+		if err != nil {
+			return result, fmt.Errorf("select bookmarks: %v", err)
+		}
+		for _, b := range bookmarks {
+			b.Tags = []string{}
+			b.Metadata = map[string]string{}
+			result[b.Id] = b
+		}
+
+		tags := []struct {
+			Bookmark int    `db:"bookmark"`
+			Name     string `db:"name"`
+		}{}
+		err = sqlx.Select(q, &tags, `
+SELECT bt.bookmark AS bookmark, t.name AS name
+FROM bookmark_tags bt
+JOIN tags t ON bt.tag = t.id
+WHERE bt.bookmark IN `+in+`
+ORDER BY t.name`, args...)
+		if err != nil {
+			return result, fmt.Errorf("select tags: %v", err)
+		}
+		for _, t := range tags {
+			if b := result[t.Bookmark]; b != nil {
+				b.Tags = append(b.Tags, t.Name)
+			}
+		}
+
+		metadata := []struct {
+			Bookmark int    `db:"bookmark"`
+			Key      string `db:"key"`
+			Value    string `db:"value"`
+		}{}
+		err = sqlx.Select(q, &metadata,
+			"SELECT bookmark, key, value FROM metadata WHERE bookmark IN "+in, args...)
+		if err != nil {
+			return result, fmt.Errorf("select metadata: %v", err)
+		}
+		for _, m := range metadata {
+			if b := result[m.Bookmark]; b != nil {
+				b.Metadata[m.Key] = m.Value
+			}
+		}
+
+		snapshots := []struct {
+			Id       int `db:"id"`
+			Bookmark int `db:"bookmark"`
+		}{}
+		err = sqlx.Select(q, &snapshots,
+			"SELECT id, bookmark FROM snapshots WHERE bookmark IN "+in+" ORDER BY id", args...)
+		if err != nil {
+			return result, fmt.Errorf("select snapshots: %v", err)
+		}
+		for _, v := range snapshots {
+			if b := result[v.Bookmark]; b != nil {
+				b.Snapshots = append(b.Snapshots, v.Id)
+			}
+		}
+	}
+	return result, nil
+}
+
+//recordJournal stores operation to journal. Any undone operations are discarded,
+//since they cannot be redone after new operation.
+func recordJournal(tx *sqlx.Tx, operation string, before, after images) error {
+	before, after = changed(before, after)
+	if len(after) == 0 {
+		return nil
+	}
+	err := recordRevisions(tx, operation, before, after)
+	if err != nil {
+		return err
+	}
+
+	beforeJson, err := json.Marshal(before)
+	if err != nil {
+		return fmt.Errorf("marshal before image: %v", err)
+	}
+	afterJson, err := json.Marshal(after)
+	if err != nil {
+		return fmt.Errorf("marshal after image: %v", err)
+	}
+
+	_, err = tx.Exec("DELETE FROM journal WHERE undone = 1")
+	if err != nil {
+		return fmt.Errorf("discard undone operations: %v", err)
+	}
+	_, err = tx.Exec("INSERT INTO journal (created_at, operation, before, after) VALUES (?, ?, ?, ?)",
+		time.Now(), operation, string(beforeJson), string(afterJson))
+	if err != nil {
+		return fmt.Errorf("insert journal entry: %v", err)
+	}
+	_, err = tx.Exec(`
+DELETE FROM journal WHERE id NOT IN (
+	SELECT id FROM journal ORDER BY id DESC LIMIT ?
+)`, maxJournalEntries)
+	if err != nil {
+		return fmt.Errorf("remove old journal entries: %v", err)
+	}
+	return nil
+}
+
+//forgetJournal removes bookmarks from journal entries. Entries that have no other bookmarks are removed.
+func forgetJournal(tx *sqlx.Tx, ids []int) error {
+	entries := []journalEntry{}
+	err := tx.Select(&entries, "SELECT id, operation, before, after FROM journal")
+	if err != nil {
+		return fmt.Errorf("get journal entries: %v", err)
+	}
+
+	for _, entry := range entries {
+		before := images{}
+		after := images{}
+		err = json.Unmarshal([]byte(entry.Before), &before)
+		if err == nil {
+			err = json.Unmarshal([]byte(entry.After), &after)
+		}
+		if err != nil {
+			return fmt.Errorf("parse journal entry %d: %v", entry.Id, err)
+		}
+
+		found := false
+		for _, id := range ids {
+			_, inBefore := before[id]
+			_, inAfter := after[id]
+			if inBefore || inAfter {
+				delete(before, id)
+				delete(after, id)
+				found = true
+			}
+		}
+		if !found {
+			continue
+		}
+		if len(before) == 0 && len(after) == 0 {
+			_, err = tx.Exec("DELETE FROM journal WHERE id = ?", entry.Id)
+			if err != nil {
+				return fmt.Errorf("remove journal entry: %v", err)
+			}
+			continue
+		}
+
+		beforeJson, err := json.Marshal(before)
+		if err != nil {
+			return fmt.Errorf("marshal before image: %v", err)
+		}
+		afterJson, err := json.Marshal(after)
+		if err != nil {
+			return fmt.Errorf("marshal after image: %v", err)
+		}
+		_, err = tx.Exec("UPDATE journal SET before = ?, after = ? WHERE id = ?",
+			string(beforeJson), string(afterJson), entry.Id)
+		if err != nil {
+			return fmt.Errorf("update journal entry: %v", err)
+		}
+	}
+	return nil
+}
+
+//journaled runs fn in transaction and records the changes it made to bookmarks with given ids.
+//Fn may return ids of additional bookmarks it created, which are then recorded as new bookmarks.
+func (d *Database) journaled(operation string, ids []int, fn func(tx *sqlx.Tx) ([]int, error)) error {
+	tx, err := d.conn.Beginx()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %v", err)
+	}
+
+	before, err := loadImages(tx, ids)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	created, err := fn(tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	after, err := loadImages(tx, append(ids, created...))
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	err = recordJournal(tx, operation, before, after)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("transaction failed: %v", err)
+	}
+	return nil
+}
+
+//restoreImages sets bookmarks to state defined in images. Bookmarks with nil image are deleted.
+func (d *Database) restoreImages(tx *sqlx.Tx, images images) error {
+	query := `
+INSERT INTO bookmarks (id, name, lower_name, description, description_lower, content, 
+                       project, notes, created_at, updated_at, archived, state, priority, due_at, deleted_at)
+VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
+ON CONFLICT(id) DO UPDATE SET
+	name = excluded.name,
+	lower_name = excluded.lower_name,
+	description = excluded.description,
+	description_lower = excluded.description_lower,
+	content = excluded.content,
+	project = excluded.project,
+	notes = excluded.notes,
+	created_at = excluded.created_at,
+	updated_at = excluded.updated_at,
+	archived = excluded.archived,
+	state = excluded.state,
+	priority = excluded.priority,
+	due_at = excluded.due_at,
+	deleted_at = excluded.deleted_at`
+
+	for _, id := range images.ids() {
+		b := images[id]
+		if b == nil {
+			err := deleteBookmark(tx, id)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		_, err := tx.Exec(query, b.Id, b.Name, strings.ToLower(b.Name), b.Description,
+			strings.ToLower(b.Description), b.Content, b.Project, b.Notes, b.CreatedAt, b.UpdatedAt, b.Archived, b.state(),
+			b.Priority, b.DueAt, b.DeletedAt)
+		if err != nil {
+			return fmt.Errorf("restore bookmark %d: %v", id, err)
+		}
+
+		err = restoreMetadata(tx, id, b.Metadata)
+		if err != nil {
+			return fmt.Errorf("restore metadata: %v", err)
+		}
+
+		err = d.InsertTags(b.Tags, tx)
+		if err != nil {
+			return fmt.Errorf("restore tags: %v", err)
+		}
+		err = updateBookmarkTags(tx, id, b.Tags)
+		if err != nil {
+			return fmt.Errorf("restore tags: %v", err)
+		}
+		err = restoreSnapshots(tx, id, b.Snapshots)
+		if err != nil {
+			return fmt.Errorf("restore snapshots: %v", err)
+		}
+	}
+	return nil
+}
+
+//restoreSnapshots moves snapshots to bookmark, if they were moved to other bookmark.
+//Snapshots are not journaled, so snapshots created or deleted since are kept as they are.
+func restoreSnapshots(tx *sqlx.Tx, id int, snapshots []int) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+	args := []interface{}{id, id}
+	for _, v := range snapshots {
+		args = append(args, v)
+	}
+	_, err := tx.Exec("UPDATE snapshots SET bookmark = ? WHERE bookmark != ? AND id IN ("+
+		placeholders(len(snapshots))+")", args...)
+	return err
+}
+
+//restoreMetadata sets metadata of bookmark to metadata. Link check results are not journaled,
+//so current results are kept, and results in metadata are only restored if bookmark has none.
+func restoreMetadata(tx *sqlx.Tx, id int, metadata map[string]string) error {
+	keys := make([]interface{}, len(models.LinkCheckMetadata))
+	for i, v := range models.LinkCheckMetadata {
+		keys[i] = strings.ToLower(v)
+	}
+	_, err := tx.Exec("DELETE FROM metadata WHERE bookmark = ? AND key_lower NOT IN ("+placeholders(len(keys))+")",
+		append([]interface{}{id}, keys...)...)
+	if err != nil {
+		return err
+	}
+
+	fields := map[string]string{}
+	for key, value := range metadata {
+		if models.IsLinkCheckMetadata(key) {
+			_, err = tx.Exec(`INSERT OR IGNORE INTO metadata (bookmark, key, key_lower, value, value_lower)
+VALUES (?, ?, ?, ?, ?)`, id, key, strings.ToLower(key), value, strings.ToLower(value))
+			if err != nil {
+				return err
+			}
+		} else {
+			fields[key] = value
+		}
+	}
+	return upsertMetadata(tx, id, fields)
+}
+
+//Undo reverts latest operation in journal. Return description of reverted operation.
+func (d *Database) Undo() (string, error) {
+	return d.stepJournal(true)
+}
+
+//Redo applies latest undone operation in journal again. Return description of applied operation.
+func (d *Database) Redo() (string, error) {
+	return d.stepJournal(false)
+}
+
+func (d *Database) stepJournal(undo bool) (string, error) {
+	query := "SELECT id, operation, before, after FROM journal WHERE undone = 0 ORDER BY id DESC LIMIT 1"
+	if !undo {
+		query = "SELECT id, operation, before, after FROM journal WHERE undone = 1 ORDER BY id ASC LIMIT 1"
+	}
+
+	logger := beginQuery(query, "step journal")
+	tx, err := d.conn.Beginx()
+	if err != nil {
+		return "", fmt.Errorf("begin transaction: %v", err)
+	}
+
+	entry := journalEntry{}
+	err = tx.Get(&entry, query)
+	if err == sql.ErrNoRows {
+		_ = tx.Rollback()
+		logger.log(nil)
+		if undo {
+			return "", fmt.Errorf("nothing to undo")
+		}
+		return "", fmt.Errorf("nothing to redo")
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		logger.log(err)
+		return "", err
+	}
+
+	data := entry.After
+	if undo {
+		data = entry.Before
+	}
+	state := images{}
+	err = json.Unmarshal([]byte(data), &state)
+	if err != nil {
+		_ = tx.Rollback()
+		logger.log(err)
+		return "", fmt.Errorf("parse journal entry %d: %v", entry.Id, err)
+	}
+
+	current, err := loadImages(tx, state.ids())
+	if err != nil {
+		_ = tx.Rollback()
+		logger.log(err)
+		return "", err
+	}
+	err = d.restoreImages(tx, state)
+	if err != nil {
+		_ = tx.Rollback()
+		logger.log(err)
+		return "", err
+	}
+	restored, err := loadImages(tx, state.ids())
+	if err != nil {
+		_ = tx.Rollback()
+		logger.log(err)
+		return "", err
+	}
+	operation := "redo " + entry.Operation
+	if undo {
+		operation = "undo " + entry.Operation
+	}
+	before, after := changed(current, restored)
+	err = recordRevisions(tx, operation, before, after)
+	if err != nil {
+		_ = tx.Rollback()
+		logger.log(err)
+		return "", err
+	}
+
+	_, err = tx.Exec("UPDATE journal SET undone = ? WHERE id = ?", undo, entry.Id)
+	if err != nil {
+		_ = tx.Rollback()
+		logger.log(err)
+		return "", err
+	}
+
+	err = tx.Commit()
+	logger.log(err)
+	if err != nil {
+		return "", fmt.Errorf("transaction failed: %v", err)
+	}
+	return entry.Operation, nil
+}
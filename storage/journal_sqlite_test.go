@@ -0,0 +1,440 @@
+//go:build fts5
+// +build fts5
+
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//journalCount returns number of entries in journal
+func journalCount(t *testing.T, db *Database) int {
+	count := 0
+	err := db.conn.Get(&count, "SELECT COUNT(*) FROM journal")
+	if err != nil {
+		t.Fatalf("count journal entries: %v", err)
+	}
+	return count
+}
+
+func TestDatabase_SetMetadata_notJournaled(t *testing.T) {
+	db, cleanup := newTestDatabase(t, true)
+	defer cleanup()
+	b := &models.Bookmark{Name: "Golang", Metadata: &map[string]string{"Author": "rob"}}
+	addTestBookmarks(t, db, b)
+
+	b.Description = "language"
+	err := db.UpdateBookmark(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.SetMetadata(map[int]map[string]string{
+		b.Id: {models.MetadataLinkStatus: models.LinkStatusOk, models.MetadataCheckedAt: "2020-01-01"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := journalCount(t, db); count != 2 {
+		t.Errorf("journal has %d entries, want 2", count)
+	}
+
+	operation, err := db.Undo()
+	if err != nil || operation != "update bookmark 'Golang'" {
+		t.Fatalf("Undo() = %s, %v, want update", operation, err)
+	}
+	got := getTestBookmark(t, db, b.Id)
+	if got.Description != "" || (*got.Metadata)["Author"] != "rob" {
+		t.Errorf("undo did not restore bookmark: %q, %v", got.Description, *got.Metadata)
+	}
+	if status := (*got.Metadata)[models.MetadataLinkStatus]; status != models.LinkStatusOk {
+		t.Errorf("undo changed link status to %q", status)
+	}
+
+	_, err = db.Redo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = getTestBookmark(t, db, b.Id)
+	if got.Description != "language" || (*got.Metadata)[models.MetadataCheckedAt] != "2020-01-01" {
+		t.Errorf("redo = %q, %v", got.Description, *got.Metadata)
+	}
+}
+
+func TestDatabase_PurgeDeleted_notJournaled(t *testing.T) {
+	db, cleanup := newTestDatabase(t, false)
+	defer cleanup()
+	golang := &models.Bookmark{Name: "Golang"}
+	rust := &models.Bookmark{Name: "Rust"}
+	addTestBookmarks(t, db, golang, rust)
+
+	rust.Description = "language"
+	err := db.UpdateBookmark(rust)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.DeleteBookmark(golang)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := journalCount(t, db); count != 4 {
+		t.Fatalf("journal has %d entries, want 4", count)
+	}
+
+	count, err := db.PurgeDeleted(time.Now().Add(time.Minute))
+	if err != nil || count != 1 {
+		t.Fatalf("PurgeDeleted() = %d, %v, want 1", count, err)
+	}
+	if count := journalCount(t, db); count != 2 {
+		t.Errorf("journal has %d entries after purge, want 2", count)
+	}
+
+	operation, err := db.Undo()
+	if err != nil || operation != "update bookmark 'Rust'" {
+		t.Fatalf("Undo() = %s, %v, want update", operation, err)
+	}
+	if got := getTestBookmark(t, db, rust.Id); got.Description != "" {
+		t.Errorf("undo did not restore description, got %q", got.Description)
+	}
+	_, err = db.Undo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Undo(); err == nil {
+		t.Errorf("Undo() succeeded, want nothing to undo")
+	}
+	count = 0
+	err = db.conn.Get(&count, "SELECT COUNT(*) FROM bookmarks")
+	if err != nil || count != 0 {
+		t.Errorf("undo left %d bookmarks, want 0: %v", count, err)
+	}
+}
+
+//testState returns current state of bookmarks as json
+func testState(t *testing.T, db *Database, ids ...int) string {
+	state, err := loadImages(db.conn, ids)
+	if err != nil {
+		t.Fatalf("load images: %v", err)
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatalf("marshal images: %v", err)
+	}
+	return string(data)
+}
+
+//testUndoRedo runs operation and checks that undo restores bookmarks to their state before operation
+//and redo to their state after it
+func testUndoRedo(t *testing.T, db *Database, name string, ids []int, operation func() error) {
+	before := testState(t, db, ids...)
+	err := operation()
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	after := testState(t, db, ids...)
+	if after == before {
+		t.Fatalf("%s did not modify bookmarks", name)
+	}
+
+	_, err = db.Undo()
+	if err != nil {
+		t.Fatalf("%s: undo: %v", name, err)
+	}
+	if got := testState(t, db, ids...); got != before {
+		t.Errorf("%s: state after undo = %s, want %s", name, got, before)
+	}
+	_, err = db.Redo()
+	if err != nil {
+		t.Fatalf("%s: redo: %v", name, err)
+	}
+	if got := testState(t, db, ids...); got != after {
+		t.Errorf("%s: state after redo = %s, want %s", name, got, after)
+	}
+}
+
+func TestDatabase_UndoRedo(t *testing.T) {
+	db, cleanup := newTestDatabase(t, false)
+	defer cleanup()
+	b := &models.Bookmark{Name: "Golang", Project: "lang", Tags: []string{"go"},
+		Metadata: &map[string]string{"Author": "rob"}}
+	addTestBookmarks(t, db, b)
+
+	testUndoRedo(t, db, "update bookmark", []int{b.Id}, func() error {
+		b.Name = "Go"
+		b.LowerName = "go"
+		b.Tags = []string{"lang", "web"}
+		(*b.Metadata)["Author"] = "ken"
+		return db.UpdateBookmark(b)
+	})
+
+	created := &models.Bookmark{Name: "Rust", Tags: []string{"lang"}, Metadata: &map[string]string{"Author": "graydon"}}
+	testUndoRedo(t, db, "new bookmark", []int{b.Id + 1}, func() error {
+		addTestBookmarks(t, db, created)
+		return nil
+	})
+	if got := getTestBookmark(t, db, created.Id); got.Name != "Rust" || (*got.Metadata)["Author"] != "graydon" {
+		t.Errorf("redo created %s, %v", got.Name, *got.Metadata)
+	}
+
+	_, err := db.Redo()
+	if err == nil {
+		t.Errorf("Redo() without undone operations succeeded")
+	}
+}
+
+func TestDatabase_Undo_discardsUndone(t *testing.T) {
+	db, cleanup := newTestDatabase(t, false)
+	defer cleanup()
+	b := &models.Bookmark{Name: "Golang"}
+	addTestBookmarks(t, db, b)
+
+	b.Description = "first"
+	err := db.UpdateBookmark(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Undo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Description = "second"
+	err = db.UpdateBookmark(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Redo()
+	if err == nil {
+		t.Errorf("Redo() after new operation succeeded")
+	}
+	if count := journalCount(t, db); count != 2 {
+		t.Errorf("journal has %d entries, want 2", count)
+	}
+	operation, err := db.Undo()
+	if err != nil || operation != "update bookmark 'Golang'" {
+		t.Fatalf("Undo() = %s, %v", operation, err)
+	}
+	if got := getTestBookmark(t, db, b.Id); got.Description != "" {
+		t.Errorf("undo restored description %q, want empty", got.Description)
+	}
+}
+
+func TestDatabase_journalLimit(t *testing.T) {
+	db, cleanup := newTestDatabase(t, false)
+	defer cleanup()
+	b := &models.Bookmark{Name: "Golang"}
+	addTestBookmarks(t, db, b)
+
+	for i := 0; i < maxJournalEntries+5; i++ {
+		b.Priority = i + 1
+		err := db.UpdateBookmark(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count := journalCount(t, db); count != maxJournalEntries {
+		t.Errorf("journal has %d entries, want %d", count, maxJournalEntries)
+	}
+	for i := 0; i < maxJournalEntries; i++ {
+		_, err := db.Undo()
+		if err != nil {
+			t.Fatalf("undo %d: %v", i, err)
+		}
+	}
+	if got := getTestBookmark(t, db, b.Id); got.Priority != 5 {
+		t.Errorf("priority after undoing all = %d, want 5", got.Priority)
+	}
+	_, err := db.Undo()
+	if err == nil {
+		t.Errorf("Undo() succeeded past journal limit")
+	}
+}
+
+func TestDatabase_projects_undoRedo(t *testing.T) {
+	db, cleanup := newTestDatabase(t, false)
+	defer cleanup()
+	addTestBookmarks(t, db,
+		&models.Bookmark{Name: "a", Project: "work"},
+		&models.Bookmark{Name: "b", Project: "work.dev"},
+		&models.Bookmark{Name: "c", Project: "work.dev.go"},
+		&models.Bookmark{Name: "d", Project: "home"},
+	)
+	ids := []int{1, 2, 3, 4}
+
+	testUndoRedo(t, db, "rename project", ids, func() error {
+		_, err := db.RenameProject("work", "job")
+		return err
+	})
+	if got, want := testProjects(t, db), []string{"job", "job.dev", "job.dev.go", "home"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("projects after rename = %v, want %v", got, want)
+	}
+	testUndoRedo(t, db, "move project", ids, func() error {
+		_, err := db.MoveProject("job.dev", "home")
+		return err
+	})
+	if got, want := testProjects(t, db), []string{"job", "home.dev", "home.dev.go", "home"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("projects after move = %v, want %v", got, want)
+	}
+	testUndoRedo(t, db, "merge project", ids, func() error {
+		_, err := db.MergeProject("home.dev", "job")
+		return err
+	})
+	if got, want := testProjects(t, db), []string{"job", "job", "job.go", "home"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("projects after merge = %v, want %v", got, want)
+	}
+}
+
+func TestDatabase_trash_undoRedo(t *testing.T) {
+	db, cleanup := newTestDatabase(t, false)
+	defer cleanup()
+	b := &models.Bookmark{Name: "Golang", Tags: []string{"go"}}
+	addTestBookmarks(t, db, b)
+
+	testUndoRedo(t, db, "delete bookmark", []int{b.Id}, func() error {
+		return db.DeleteBookmark(b)
+	})
+	deleted, err := db.GetDeletedBookmarks()
+	if err != nil || len(deleted) != 1 {
+		t.Fatalf("GetDeletedBookmarks() = %d, %v, want 1", len(deleted), err)
+	}
+	testUndoRedo(t, db, "restore bookmark", []int{b.Id}, func() error {
+		return db.RestoreBookmark(b)
+	})
+	deleted, err = db.GetDeletedBookmarks()
+	if err != nil || len(deleted) != 0 {
+		t.Errorf("GetDeletedBookmarks() after restore = %d, %v, want 0", len(deleted), err)
+	}
+}
+
+func TestDatabase_tags_undoRedo(t *testing.T) {
+	db, cleanup := newTestDatabase(t, false)
+	defer cleanup()
+	addTestBookmarks(t, db,
+		&models.Bookmark{Name: "a", Tags: []string{"lang"}},
+		&models.Bookmark{Name: "b", Tags: []string{"lang/go", "web"}},
+		&models.Bookmark{Name: "c", Tags: []string{"lang/go/http", "golang"}},
+	)
+	ids := []int{1, 2, 3}
+	tags := func() [][]string {
+		result := [][]string{}
+		for _, id := range ids {
+			names := append([]string{}, getTestBookmark(t, db, id).Tags...)
+			sort.Strings(names)
+			result = append(result, names)
+		}
+		return result
+	}
+
+	testUndoRedo(t, db, "rename tag", ids, func() error {
+		_, err := db.RenameTag("lang", "language")
+		return err
+	})
+	want := [][]string{{"language"}, {"language/go", "web"}, {"golang", "language/go/http"}}
+	if got := tags(); !reflect.DeepEqual(got, want) {
+		t.Errorf("tags after rename = %v, want %v", got, want)
+	}
+	testUndoRedo(t, db, "merge tag", ids, func() error {
+		_, err := db.MergeTag("golang", "language/go")
+		return err
+	})
+	want = [][]string{{"language"}, {"language/go", "web"}, {"language/go", "language/go/http"}}
+	if got := tags(); !reflect.DeepEqual(got, want) {
+		t.Errorf("tags after merge = %v, want %v", got, want)
+	}
+	testUndoRedo(t, db, "delete tag", ids, func() error {
+		_, err := db.DeleteTag("language/go")
+		return err
+	})
+	want = [][]string{{"language"}, {"web"}, {}}
+	if got := tags(); !reflect.DeepEqual(got, want) {
+		t.Errorf("tags after delete = %v, want %v", got, want)
+	}
+}
+
+func TestDatabase_RestoreRevision_undoRedo(t *testing.T) {
+	db, cleanup := newTestDatabase(t, false)
+	defer cleanup()
+	b := &models.Bookmark{Name: "Golang", Description: "first", Tags: []string{"go"}}
+	addTestBookmarks(t, db, b)
+
+	b.Description = "second"
+	b.Tags = []string{"lang"}
+	err := db.UpdateBookmark(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := db.GetRevisions(b.Id)
+	if err != nil || len(revisions) != 1 {
+		t.Fatalf("GetRevisions() = %d, %v, want 1", len(revisions), err)
+	}
+
+	testUndoRedo(t, db, "restore revision", []int{b.Id}, func() error {
+		return db.RestoreRevision(revisions[0].Id)
+	})
+	got := getTestBookmark(t, db, b.Id)
+	if got.Description != "first" || !reflect.DeepEqual(got.Tags, []string{"go"}) {
+		t.Errorf("restored bookmark = %q, %v", got.Description, got.Tags)
+	}
+}
+
+func TestDatabase_readingList_undoRedo(t *testing.T) {
+	db, cleanup := newTestDatabase(t, false)
+	defer cleanup()
+	a := &models.Bookmark{Name: "a"}
+	b := &models.Bookmark{Name: "b"}
+	addTestBookmarks(t, db, a, b)
+	due := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	testUndoRedo(t, db, "update reading list", []int{a.Id}, func() error {
+		a.State = models.StateReading
+		a.Priority = 2
+		a.DueAt = &due
+		return db.UpdateBookmark(a)
+	})
+	got := getTestBookmark(t, db, a.Id)
+	if got.State != models.StateReading || got.Priority != 2 || got.DueAt == nil || !got.DueAt.Equal(due) {
+		t.Errorf("bookmark = %s, %d, %v", got.State, got.Priority, got.DueAt)
+	}
+
+	testUndoRedo(t, db, "bulk modify", []int{a.Id, b.Id}, func() error {
+		filter, err := NewFilter("archived:false")
+		if err != nil {
+			return err
+		}
+		modifier, err := NewModifier("state:read priority:3 -due")
+		if err != nil {
+			return err
+		}
+		_, err = db.BulkModify(filter, modifier)
+		return err
+	})
+	for _, id := range []int{a.Id, b.Id} {
+		got := getTestBookmark(t, db, id)
+		if got.State != models.StateRead || got.Priority != 3 || got.DueAt != nil {
+			t.Errorf("bookmark %d = %s, %d, %v", id, got.State, got.Priority, got.DueAt)
+		}
+	}
+	if revisions, _ := db.GetRevisions(a.Id); len(revisions) != 0 {
+		t.Errorf("reading list changes added %d revisions", len(revisions))
+	}
+}
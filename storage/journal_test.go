@@ -0,0 +1,57 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func Test_changed(t *testing.T) {
+	image := func(id int, name string) *bookmarkImage {
+		return &bookmarkImage{Id: id, Name: name, Tags: []string{"go"}, Metadata: map[string]string{"Title": name}}
+	}
+	before := images{1: image(1, "a"), 2: image(2, "b"), 3: image(3, "c"), 4: nil}
+	after := images{1: image(1, "a"), 2: image(2, "b2"), 3: nil, 4: image(4, "d")}
+
+	gotBefore, gotAfter := changed(before, after)
+	wantBefore := images{2: image(2, "b"), 3: image(3, "c"), 4: nil}
+	wantAfter := images{2: image(2, "b2"), 3: nil, 4: image(4, "d")}
+	if !reflect.DeepEqual(gotBefore, wantBefore) {
+		t.Errorf("changed() before = %v, want %v", gotBefore, wantBefore)
+	}
+	if !reflect.DeepEqual(gotAfter, wantAfter) {
+		t.Errorf("changed() after = %v, want %v", gotAfter, wantAfter)
+	}
+	if got := gotAfter.ids(); !reflect.DeepEqual(got, []int{2, 3, 4}) {
+		t.Errorf("ids() = %v, want [2 3 4]", got)
+	}
+
+	data, err := json.Marshal(gotAfter)
+	if err != nil {
+		t.Fatalf("marshal images: %v", err)
+	}
+	parsed := images{}
+	err = json.Unmarshal(data, &parsed)
+	if err != nil {
+		t.Fatalf("unmarshal images: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, wantAfter) {
+		t.Errorf("unmarshal images = %v, want %v", parsed, wantAfter)
+	}
+}
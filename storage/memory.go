@@ -0,0 +1,553 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//MemoryStore is a BookmarkStore that keeps bookmarks in memory, e.g. for tests. Nothing is persisted.
+//Queries match like in Database without full text search. Deleted bookmarks are hidden.
+type MemoryStore struct {
+	lock      sync.RWMutex
+	options   Options
+	bookmarks map[int]*models.Bookmark
+	nextId    int
+}
+
+//NewMemoryStore creates new empty store
+func NewMemoryStore(options Options) *MemoryStore {
+	return &MemoryStore{
+		options:   options,
+		bookmarks: map[int]*models.Bookmark{},
+		nextId:    1,
+	}
+}
+
+//Options returns options store was created with
+func (m *MemoryStore) Options() Options {
+	return m.options
+}
+
+//copyBookmark returns deep copy of bookmark. If metadata is not set, copy has no metadata,
+//as bookmarks from Database have metadata only after GetBookmarkMetadata.
+func copyBookmark(b *models.Bookmark, metadata bool) *models.Bookmark {
+	copied := *b
+	copied.Tags = append([]string(nil), b.Tags...)
+	copied.Metadata = nil
+	copied.MetadataKeys = nil
+	if b.DueAt != nil {
+		due := *b.DueAt
+		copied.DueAt = &due
+	}
+	if metadata && b.Metadata != nil {
+		values := map[string]string{}
+		for key, value := range *b.Metadata {
+			values[key] = value
+		}
+		copied.Metadata = &values
+	}
+	if b.Metadata != nil {
+		copied.LinkStatus = (*b.Metadata)[models.MetadataLinkStatus]
+	}
+	return &copied
+}
+
+//list returns copies of bookmarks not in trash that match
+func (m *MemoryStore) list(match func(b *models.Bookmark) bool) []*models.Bookmark {
+	bookmarks := []*models.Bookmark{}
+	for _, b := range m.bookmarks {
+		if b.DeletedAt == nil && match(b) {
+			bookmarks = append(bookmarks, copyBookmark(b, false))
+		}
+	}
+	return bookmarks
+}
+
+//GetBookmark returns single bookmark without metadata
+func (m *MemoryStore) GetBookmark(id int) (*models.Bookmark, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	b, ok := m.bookmarks[id]
+	if !ok || b.DeletedAt != nil {
+		return &models.Bookmark{}, sql.ErrNoRows
+	}
+	return copyBookmark(b, false), nil
+}
+
+//GetBookmarksByLink returns bookmarks that have same canonical link as given link, oldest first.
+func (m *MemoryStore) GetBookmarksByLink(link string) ([]*models.Bookmark, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	link = models.CanonicalLink(link)
+	bookmarks := m.list(func(b *models.Bookmark) bool {
+		return models.CanonicalLink(b.Content) == link
+	})
+	sort.Slice(bookmarks, func(i, j int) bool {
+		if !bookmarks[i].CreatedAt.Equal(bookmarks[j].CreatedAt) {
+			return bookmarks[i].CreatedAt.Before(bookmarks[j].CreatedAt)
+		}
+		return bookmarks[i].Id < bookmarks[j].Id
+	})
+	return bookmarks, nil
+}
+
+//NewBookmark stores new bookmark and sets its id
+func (m *MemoryStore) NewBookmark(b *models.Bookmark) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	b.Id = m.nextId
+	m.nextId += 1
+	stored := copyBookmark(b, true)
+	stored.Project = strings.ToLower(stored.Project)
+	stored.State = stored.ReadState()
+	m.bookmarks[b.Id] = stored
+	return nil
+}
+
+//UpdateBookmark updates all fields on bookmark. Metadata is added to existing metadata.
+func (m *MemoryStore) UpdateBookmark(b *models.Bookmark) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	old, ok := m.bookmarks[b.Id]
+	if !ok {
+		return fmt.Errorf("bookmark %d not found", b.Id)
+	}
+	updated := copyBookmark(b, true)
+	updated.Project = strings.ToLower(updated.Project)
+	updated.State = updated.ReadState()
+	updated.DeletedAt = old.DeletedAt
+	if old.Metadata != nil {
+		if updated.Metadata == nil {
+			updated.Metadata = &map[string]string{}
+		}
+		for key, value := range *old.Metadata {
+			if _, ok := (*updated.Metadata)[key]; !ok {
+				(*updated.Metadata)[key] = value
+			}
+		}
+	}
+	m.bookmarks[b.Id] = updated
+	return nil
+}
+
+//DeleteBookmark moves bookmark to trash
+func (m *MemoryStore) DeleteBookmark(bookmark *models.Bookmark) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	b, ok := m.bookmarks[bookmark.Id]
+	if !ok {
+		return fmt.Errorf("bookmark %d not found", bookmark.Id)
+	}
+	now := time.Now()
+	b.DeletedAt = &now
+	return nil
+}
+
+//FilterBookmarks returns page of bookmarks that match filter
+func (m *MemoryStore) FilterBookmarks(filter *Filter) ([]*models.Bookmark, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	bookmarks := m.list(filter.match)
+	filter.sortBookmarks(bookmarks)
+	return page(bookmarks, filter.Offset, filter.Limit), nil
+}
+
+//SearchBookmarks returns all bookmarks that match any field with every term of text
+func (m *MemoryStore) SearchBookmarks(text string) ([]*models.Bookmark, error) {
+	bookmarks, _, err := m.SearchBookmarksPage(text, 0, -1)
+	return bookmarks, err
+}
+
+//SearchBookmarksPage returns page of search results starting at offset and total number of results.
+//Zero limit uses DefaultPageSize and negative limit returns all results.
+func (m *MemoryStore) SearchBookmarksPage(text string, offset, limit int) ([]*models.Bookmark, int, error) {
+	expr, err := parseQuery(text)
+	if err != nil {
+		return nil, 0, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	bookmarks := m.list(func(b *models.Bookmark) bool {
+		return expr == nil || expr.match(b)
+	})
+	(&Filter{}).sortBookmarks(bookmarks)
+	return page(bookmarks, offset, limit), len(bookmarks), nil
+}
+
+//BookmarksPage returns bookmarks that match filter starting from filter.Offset, and total number of
+//bookmarks that match filter.
+func (m *MemoryStore) BookmarksPage(filter *Filter) ([]*models.Bookmark, int, error) {
+	if filter.IsPlainQuery() {
+		return m.SearchBookmarksPage(filter.Query, filter.Offset, filter.Limit)
+	}
+	total, err := m.CountBookmarks(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	bookmarks, err := m.FilterBookmarks(filter)
+	return bookmarks, total, err
+}
+
+//CountBookmarks returns number of bookmarks that match filter
+func (m *MemoryStore) CountBookmarks(filter *Filter) (int, error) {
+	if filter.IsPlainQuery() {
+		_, total, err := m.SearchBookmarksPage(filter.Query, 0, 1)
+		return total, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return len(m.list(filter.match)), nil
+}
+
+//GetBookmarkMetadata fills metadata of bookmark
+func (m *MemoryStore) GetBookmarkMetadata(bookmark *models.Bookmark) error {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	bookmark.FillDefaultMetadata()
+	b, ok := m.bookmarks[bookmark.Id]
+	if !ok || b.Metadata == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(*b.Metadata))
+	for key := range *b.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.ToLower(keys[i]) < strings.ToLower(keys[j])
+	})
+	for _, key := range keys {
+		bookmark.AddMetadata(key, (*b.Metadata)[key])
+	}
+	return nil
+}
+
+//GetMetadataKeys returns all known metadata keys
+func (m *MemoryStore) GetMetadataKeys() ([]string, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	found := map[string]bool{}
+	keys := []string{}
+	for _, b := range m.bookmarks {
+		if b.Metadata == nil {
+			continue
+		}
+		for key := range *b.Metadata {
+			if !found[key] {
+				found[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+//SearchKeyValue returns lower case values of metadata key, or projects if key is project, that contain value.
+//Number of results is limited with Options.AutoCompleteMaxResults.
+func (m *MemoryStore) SearchKeyValue(key, value string) ([]string, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	key = strings.ToLower(key)
+	found := map[string]bool{}
+	results := []string{}
+	add := func(v string) {
+		if matchValue(v, value, false) && !found[v] {
+			found[v] = true
+			results = append(results, v)
+		}
+	}
+	for _, b := range m.bookmarks {
+		if b.DeletedAt != nil {
+			continue
+		}
+		if key == "project" {
+			add(b.Project)
+		} else if b.Metadata != nil {
+			for k, v := range *b.Metadata {
+				if strings.ToLower(k) == key {
+					add(strings.ToLower(v))
+				}
+			}
+		}
+	}
+	sort.Strings(results)
+	if limit := m.options.AutoCompleteMaxResults; limit >= 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+//GetAllTags returns tags of bookmarks and number of bookmarks that have the tag
+func (m *MemoryStore) GetAllTags() (*map[string]int, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	tags := map[string]int{}
+	for _, b := range m.bookmarks {
+		if b.DeletedAt != nil {
+			continue
+		}
+		for _, tag := range b.Tags {
+			tags[tag] += 1
+		}
+	}
+	return &tags, nil
+}
+
+//RenameTag renames tag and its children on all bookmarks. Renaming fails if new tag already exists.
+//Return number of bookmarks modified.
+func (m *MemoryStore) RenameTag(old string, new string) (int, error) {
+	return m.renameTag(old, new, false)
+}
+
+//MergeTag replaces source tag and its children with target tag, which may already exist.
+func (m *MemoryStore) MergeTag(source string, target string) (int, error) {
+	return m.renameTag(source, target, true)
+}
+
+//DeleteTag removes tag and its children from all bookmarks. Return number of bookmarks modified.
+func (m *MemoryStore) DeleteTag(name string) (int, error) {
+	return m.renameTag(name, "", true)
+}
+
+//renameTag replaces tag old and its children with new like Database.renameTag. Empty new deletes tags.
+func (m *MemoryStore) renameTag(old string, new string, merge bool) (int, error) {
+	old = strings.TrimSpace(old)
+	new = strings.TrimSpace(new)
+	if old == "" {
+		return 0, fmt.Errorf("tag name cannot be empty")
+	}
+	if new != "" {
+		err := validateTagName(new)
+		if err != nil {
+			return 0, err
+		}
+		if old == new {
+			return 0, fmt.Errorf("tag '%s' is already named '%s'", old, new)
+		}
+		if strings.HasPrefix(strings.ToLower(new), strings.ToLower(old+TagSeparator)) {
+			return 0, fmt.Errorf("cannot move tag '%s' into itself", old)
+		}
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	renames := map[string]string{}
+	existing := map[string]string{}
+	for _, b := range m.bookmarks {
+		for _, tag := range b.Tags {
+			existing[strings.ToLower(tag)] = tag
+			if !matchTag(&models.Bookmark{Tags: []string{tag}}, old) {
+				continue
+			}
+			if new == "" || len(tag) < len(old) {
+				renames[tag] = ""
+			} else {
+				renames[tag] = new + tag[len(old):]
+			}
+		}
+	}
+	if len(renames) == 0 {
+		return 0, fmt.Errorf("tag '%s' not found", old)
+	}
+	if !merge {
+		for _, v := range renames {
+			if name, ok := existing[strings.ToLower(v)]; ok {
+				if _, renamed := renames[name]; !renamed {
+					return 0, fmt.Errorf("tag '%s' already exists", name)
+				}
+			}
+		}
+	}
+
+	count := 0
+	for _, b := range m.bookmarks {
+		tags := []string{}
+		modified := false
+		for _, tag := range b.Tags {
+			to, renamed := renames[tag]
+			if renamed {
+				modified = true
+				tag = to
+			}
+			if tag != "" && !containsString(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		if modified {
+			b.Tags = tags
+			count += 1
+		}
+	}
+	return count, nil
+}
+
+//containsString returns true if values has value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+//projects returns project trees of bookmarks that match
+func (m *MemoryStore) projects(match func(b *models.Bookmark) bool) []*models.Project {
+	counts := map[string]int{}
+	for _, b := range m.list(match) {
+		counts[b.Project] += 1
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := make([]int, len(names))
+	for i, name := range names {
+		values[i] = counts[name]
+	}
+	return models.ParseTrees(names, values)
+}
+
+//GetAllProjects gets all projects. If name is specified, project must contain name,
+//or match it exactly if strict is set.
+func (m *MemoryStore) GetAllProjects(name string, strict bool) ([]*models.Project, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.projects(func(b *models.Bookmark) bool {
+		if name == "" {
+			return true
+		} else if strict {
+			return b.Project == name
+		}
+		return strings.Contains(b.Project, name)
+	}), nil
+}
+
+//FilterProject returns projects of bookmarks that match filter
+func (m *MemoryStore) FilterProject(filter *Filter) ([]*models.Project, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.projects(filter.match), nil
+}
+
+//RenameProject renames project and all its children. Renaming fails if new project already exists.
+//Return number of bookmarks modified.
+func (m *MemoryStore) RenameProject(old string, new string) (int, error) {
+	return m.renameProject(old, new, false)
+}
+
+//MoveProject moves project and all its children under parent, keeping project name.
+func (m *MemoryStore) MoveProject(name string, parent string) (int, error) {
+	return m.renameProject(name, movedProject(name, parent), false)
+}
+
+//MergeProject moves bookmarks of source project and all its children into target project,
+//which may already exist.
+func (m *MemoryStore) MergeProject(source string, target string) (int, error) {
+	return m.renameProject(source, target, true)
+}
+
+//renameProject renames project old and its children like Database.renameProject
+func (m *MemoryStore) renameProject(old string, new string, merge bool) (int, error) {
+	old = strings.ToLower(strings.TrimSpace(old))
+	new = strings.ToLower(strings.TrimSpace(new))
+	err := validateProjectRename(old, new)
+	if err != nil {
+		return 0, err
+	}
+	inProject := func(project, name string) bool {
+		project = strings.ToLower(project)
+		return project == name || strings.HasPrefix(project, name+".")
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if !merge {
+		for _, b := range m.bookmarks {
+			if inProject(b.Project, new) {
+				return 0, fmt.Errorf("project '%s' already exists", new)
+			}
+		}
+	}
+
+	count := 0
+	now := time.Now()
+	for _, b := range m.bookmarks {
+		if inProject(b.Project, old) {
+			b.Project = new + b.Project[len(old):]
+			b.UpdatedAt = now
+			count += 1
+		}
+	}
+	if count == 0 {
+		return 0, fmt.Errorf("project '%s' not found", old)
+	}
+	return count, nil
+}
+
+//GetStatistics gets various stats related to stored bookmarks
+func (m *MemoryStore) GetStatistics() (*Statistics, error) {
+	keys, err := m.GetMetadataKeys()
+	if err != nil {
+		return nil, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	s := &Statistics{MetadataKeys: keys}
+	now := time.Now()
+	projects := map[string]bool{}
+	tags := map[string]bool{}
+	for _, b := range m.bookmarks {
+		if b.DeletedAt != nil {
+			s.Deleted += 1
+			continue
+		}
+		s.Bookmarks += 1
+		if b.Archived {
+			s.Archived += 1
+		}
+		switch b.ReadState() {
+		case models.StateUnread:
+			s.Unread += 1
+		case models.StateReading:
+			s.Reading += 1
+		case models.StateRead:
+			s.Read += 1
+		}
+		if b.IsOverdue(now) {
+			s.Overdue += 1
+		}
+		projects[b.Project] = true
+		for _, tag := range b.Tags {
+			tags[tag] = true
+		}
+		if b.CreatedAt.After(s.LastBookmark) {
+			s.LastBookmark = b.CreatedAt
+		}
+	}
+	s.Projects = len(projects)
+	s.Tags = len(tags)
+	return s, nil
+}
@@ -0,0 +1,172 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+func newTestStore(t *testing.T) *MemoryStore {
+	store := NewMemoryStore(Options{AutoCompleteMaxResults: 10})
+	created := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	due := func(year int, month time.Month, day int) *time.Time {
+		t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	bookmarks := []*models.Bookmark{
+		{Name: "Golang", Content: "https://golang.org", Project: "Dev.Go", Tags: []string{"lang/go", "web"},
+			Metadata: &map[string]string{"Author": "Rob"}},
+		{Name: "Rust", Content: "https://rust-lang.org", Project: "dev.rust", Tags: []string{"lang/rust"},
+			State: models.StateReading, Priority: 3, DueAt: due(2020, 1, 10)},
+		{Name: "News", Description: "daily news", Content: "https://news.com", Project: "misc", Archived: true,
+			DueAt: due(2999, 1, 1)},
+		{Name: "Go tour", Content: "https://tour.golang.org", Project: "dev.go", Tags: []string{"lang"},
+			State: models.StateRead, Priority: 1, DueAt: due(2020, 1, 20)},
+	}
+	for i, b := range bookmarks {
+		b.CreatedAt = created.AddDate(0, i, 0)
+		err := store.NewBookmark(b)
+		if err != nil {
+			t.Fatalf("new bookmark: %v", err)
+		}
+	}
+	return store
+}
+
+func bookmarkNames(bookmarks []*models.Bookmark) []string {
+	names := make([]string, len(bookmarks))
+	for i, v := range bookmarks {
+		names[i] = v.Name
+	}
+	return names
+}
+
+func TestMemoryStore_FilterBookmarks(t *testing.T) {
+	store := newTestStore(t)
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "project:dev", want: []string{"Go tour", "Golang", "Rust"}},
+		{query: "project:'dev.go' sort:added", want: []string{"Golang", "Go tour"}},
+		{query: "tags:lang", want: []string{"Go tour", "Golang", "Rust"}},
+		{query: "tags:lang/go|web", want: []string{"Golang"}},
+		{query: "-tags:lang archived:false", want: []string{}},
+		{query: "author:rob", want: []string{"Golang"}},
+		{query: "golang -name:tour", want: []string{"Golang"}},
+		{query: "news OR tags:lang/rust", want: []string{"News", "Rust"}},
+		{query: "after:2020-02-01 before:2020-04-01", want: []string{"News", "Rust"}},
+		{query: "state:unread", want: []string{"Golang", "News"}},
+		{query: "priority:>0 -priority:3", want: []string{"Go tour"}},
+		{query: "due:overdue", want: []string{"Rust"}},
+		{query: "due:none", want: []string{"Golang"}},
+		{query: "due:2020-02-01", want: []string{"Go tour", "Rust"}},
+		{query: "sort:due", want: []string{"Rust", "Go tour", "News", "Golang"}},
+		{query: "sort:state", want: []string{"Golang", "News", "Rust", "Go tour"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			filter, err := NewFilter(tt.query)
+			if err != nil {
+				t.Fatalf("parse filter: %v", err)
+			}
+			bookmarks, total, err := store.BookmarksPage(filter)
+			if err != nil {
+				t.Fatalf("filter bookmarks: %v", err)
+			}
+			if got := bookmarkNames(bookmarks); !reflect.DeepEqual(got, tt.want) || total != len(tt.want) {
+				t.Errorf("FilterBookmarks() = %v (total %d), want %v", got, total, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStore_Page(t *testing.T) {
+	store := newTestStore(t)
+	filter := &Filter{SortDir: "DESC", Limit: 2, Offset: 1}
+	bookmarks, total, err := store.BookmarksPage(filter)
+	if err != nil {
+		t.Fatalf("page: %v", err)
+	}
+	if got := bookmarkNames(bookmarks); !reflect.DeepEqual(got, []string{"News", "Golang"}) || total != 4 {
+		t.Errorf("BookmarksPage() = %v (total %d)", got, total)
+	}
+
+	bookmarks, total, err = store.SearchBookmarksPage("golang", 1, 0)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if got := bookmarkNames(bookmarks); !reflect.DeepEqual(got, []string{"Golang"}) || total != 2 {
+		t.Errorf("SearchBookmarksPage() = %v (total %d)", got, total)
+	}
+}
+
+func TestMemoryStore_Modify(t *testing.T) {
+	store := newTestStore(t)
+
+	count, err := store.RenameTag("lang", "language")
+	if err != nil || count != 3 {
+		t.Fatalf("rename tag: count %d, error %v", count, err)
+	}
+	if _, err = store.RenameTag("web", "language"); err == nil {
+		t.Errorf("rename tag to existing tag must fail")
+	}
+	count, err = store.MergeTag("web", "language/go")
+	if err != nil || count != 1 {
+		t.Fatalf("merge tag: count %d, error %v", count, err)
+	}
+	tags, _ := store.GetAllTags()
+	want := map[string]int{"language": 1, "language/go": 1, "language/rust": 1}
+	if !reflect.DeepEqual(*tags, want) {
+		t.Errorf("tags = %v, want %v", *tags, want)
+	}
+
+	count, err = store.MoveProject("dev.go", "misc")
+	if err != nil || count != 2 {
+		t.Fatalf("move project: count %d, error %v", count, err)
+	}
+	if _, err = store.RenameProject("misc", "dev"); err == nil {
+		t.Errorf("rename project to existing project must fail")
+	}
+
+	bookmark, err := store.GetBookmark(1)
+	if err != nil {
+		t.Fatalf("get bookmark: %v", err)
+	}
+	if bookmark.Project != "misc.go" || !reflect.DeepEqual(bookmark.Tags, []string{"language/go"}) {
+		t.Errorf("bookmark = %+v", bookmark)
+	}
+	err = store.DeleteBookmark(bookmark)
+	if err != nil {
+		t.Fatalf("delete bookmark: %v", err)
+	}
+	if _, err = store.GetBookmark(1); err == nil {
+		t.Errorf("deleted bookmark must not be found")
+	}
+
+	stats, err := store.GetStatistics()
+	if err != nil {
+		t.Fatalf("statistics: %v", err)
+	}
+	if stats.Bookmarks != 3 || stats.Deleted != 1 || stats.Archived != 1 || stats.Projects != 3 ||
+		stats.Unread != 1 || stats.Reading != 1 || stats.Read != 1 || stats.Overdue != 1 {
+		t.Errorf("statistics = %+v", stats)
+	}
+}
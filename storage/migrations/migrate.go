@@ -52,6 +52,56 @@ var BookmarkerMigrations = []Migrator{
 		Level:  5,
 		Schema: v5,
 	},
+	&Migration{
+		Name:   "fix tags constraints",
+		Level:  6,
+		Schema: v6,
+	},
+	&Migration{
+		Name:   "fix metadata fts delete trigger",
+		Level:  7,
+		Schema: v7,
+	},
+	&Migration{
+		Name:   "add journal",
+		Level:  8,
+		Schema: v8,
+	},
+	&Migration{
+		Name:   "add trash",
+		Level:  9,
+		Schema: v9,
+	},
+	&Migration{
+		Name:   "add snapshots",
+		Level:  10,
+		Schema: v10,
+	},
+	&Migration{
+		Name:   "add saved searches",
+		Level:  11,
+		Schema: v11,
+	},
+	&Migration{
+		Name:   "add bookmark revisions",
+		Level:  12,
+		Schema: v12,
+	},
+	&Migration{
+		Name:   "add reading list",
+		Level:  13,
+		Schema: v13,
+	},
+	&Migration{
+		Name:   "add bookmark notes",
+		Level:  14,
+		Schema: v14,
+	},
+	&Migration{
+		Name:   "add canonical link",
+		Level:  15,
+		Schema: v15,
+	},
 }
 
 type Schema struct {
@@ -142,30 +192,34 @@ CREATE TABLE "schemas" (
 	return nil
 }
 
-// Run single migration
+// Run single migration in transaction, so that failed migration leaves schema unchanged
 func migrateSingle(db *sqlx.DB, migration Migrator) error {
 	start := time.Now()
-	_, merr := db.Exec(migration.MSchema())
+	tx, err := db.Beginx()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %v", err)
+	}
+
+	_, err = tx.Exec(migration.MSchema())
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("migration failed: %v", err)
+	}
 
 	s := &Schema{
 		Level:     migration.MLevel(),
+		Success:   1,
 		Timestamp: time.Now(),
 		TookMs:    int(time.Since(start).Nanoseconds() / 1000000),
 	}
 
-	if merr == nil {
-		s.Success = 1
-	} else {
-		s.Success = 0
-	}
-
-	_, err := db.Exec("INSERT INTO schemas (level, success, timestamp, took_ms) "+
+	_, err = tx.Exec("INSERT INTO schemas (level, success, timestamp, took_ms) "+
 		"VALUES ($1, $2, $3, $4)", s.Level, s.Success, s.Timestamp, s.TookMs)
-
-	if merr != nil {
-		return fmt.Errorf("migration failed: insert schema: %v", merr)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("insert schema: %v", err)
 	}
-	return err
+	return tx.Commit()
 }
 
 // CurrentVersion returns current version
@@ -0,0 +1,139 @@
+//go:build fts5
+// +build fts5
+
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package migrations
+
+import (
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func openTestDatabase(t *testing.T) (*sqlx.DB, func()) {
+	dir, err := ioutil.TempDir("", "bookmarker-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sqlx.Connect("sqlite3", filepath.Join(dir, "test.sqlite"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestMigrate_v5(t *testing.T) {
+	db, cleanup := openTestDatabase(t)
+	defer cleanup()
+
+	err := Migrate(db, BookmarkerMigrations[:5])
+	if err != nil {
+		t.Fatalf("migrate to v5: %v", err)
+	}
+	_, err = db.Exec(`
+INSERT INTO bookmarks (id, name, lower_name, description, content, project) VALUES
+	(1, 'Go', 'go', '', 'https://golang.org', ''),
+	(2, 'Go tour', 'go tour', '', 'https://tour.golang.org', '');
+INSERT INTO tags (id, name) VALUES (1, 'go'), (2, 'go'), (3, 'lang');
+INSERT INTO bookmark_tags (bookmark, tag) VALUES (1, 1), (2, 2), (1, 3);`)
+	if err != nil {
+		t.Fatalf("insert v5 data: %v", err)
+	}
+
+	err = Migrate(db, BookmarkerMigrations)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	current, err := CurrentVersion(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := BookmarkerMigrations[len(BookmarkerMigrations)-1].MLevel(); current.Level != want {
+		t.Errorf("level = %d, want %d", current.Level, want)
+	}
+
+	var tags []string
+	err = db.Select(&tags, "SELECT name FROM tags ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"go", "lang"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("tags = %v, want %v", tags, want)
+	}
+
+	var rows []string
+	err = db.Select(&rows, `SELECT b.name || ':' || t.name FROM bookmark_tags bt
+JOIN bookmarks b ON bt.bookmark = b.id JOIN tags t ON bt.tag = t.id ORDER BY b.id, t.name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Go:go", "Go:lang", "Go tour:go"}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("bookmark tags = %v, want %v", rows, want)
+	}
+
+	// same tag can now be added to another bookmark
+	_, err = db.Exec("INSERT INTO bookmark_tags (bookmark, tag) VALUES (2, 2)")
+	if err != nil {
+		t.Errorf("add tag to second bookmark: %v", err)
+	}
+}
+
+func TestMigrate_rollback(t *testing.T) {
+	db, cleanup := openTestDatabase(t)
+	defer cleanup()
+
+	err := Migrate(db, BookmarkerMigrations[:5])
+	if err != nil {
+		t.Fatalf("migrate to v5: %v", err)
+	}
+	migrations := append([]Migrator{}, BookmarkerMigrations[:5]...)
+	migrations = append(migrations, &Migration{
+		Name:   "broken",
+		Level:  6,
+		Schema: "DROP TABLE tags; CREATE TABLE tags (id INTEGER PRIMARY KEY); INSERT INTO missing VALUES (1);",
+	})
+	err = Migrate(db, migrations)
+	if err == nil {
+		t.Fatal("broken migration did not fail")
+	}
+
+	current, err := CurrentVersion(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Level != 5 || current.Success != 1 {
+		t.Errorf("schema = level %d, success %d, want level 5, success 1", current.Level, current.Success)
+	}
+	_, err = db.Exec("INSERT INTO tags (id, name) VALUES (1, 'go')")
+	if err != nil {
+		t.Errorf("tags table was not restored: %v", err)
+	}
+
+	err = Migrate(db, BookmarkerMigrations)
+	if err != nil {
+		t.Errorf("migrate after failed migration: %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package migrations
+
+// snapshots store archived copies of bookmarked pages.
+// Text of latest snapshot is indexed to metadata_fts with key 'snapshot'
+
+const v10 = `
+CREATE TABLE snapshots (
+	id         INTEGER
+		CONSTRAINT snapshots_pk
+			PRIMARY KEY AUTOINCREMENT,
+	bookmark   INTEGER NOT NULL
+		CONSTRAINT bookmark
+			REFERENCES bookmarks,
+	created_at TIMESTAMP NOT NULL,
+	url        TEXT NOT NULL,
+	title      TEXT NOT NULL,
+	hash       TEXT NOT NULL,
+	text       TEXT NOT NULL,
+	html       BLOB NOT NULL
+);
+
+CREATE INDEX snapshots_bookmark ON snapshots(bookmark, created_at);
+
+CREATE TRIGGER create_snapshot_fts
+    AFTER INSERT ON snapshots BEGIN
+    DELETE FROM metadata_fts
+    WHERE id = new.bookmark AND key = 'snapshot';
+    INSERT INTO metadata_fts(id, key, value)
+    VALUES (new.bookmark, 'snapshot', new.text);
+END;
+
+CREATE TRIGGER delete_snapshot_fts
+    AFTER DELETE ON snapshots BEGIN
+    DELETE FROM metadata_fts
+    WHERE id = old.bookmark AND key = 'snapshot';
+    INSERT INTO metadata_fts(id, key, value)
+    SELECT bookmark, 'snapshot', text
+    FROM snapshots
+    WHERE bookmark = old.bookmark
+    ORDER BY created_at DESC, id DESC
+    LIMIT 1;
+END;
+`
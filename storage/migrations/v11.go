@@ -0,0 +1,35 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package migrations
+
+// saved_searches are named filter queries shown in sidebar.
+// sort_field and sort_dir are filter sort settings, empty uses default sorting
+
+const v11 = `
+CREATE TABLE saved_searches (
+	id         INTEGER
+		CONSTRAINT saved_searches_pk
+			PRIMARY KEY AUTOINCREMENT,
+	name       TEXT NOT NULL
+		CONSTRAINT saved_searches_name
+			UNIQUE,
+	query      TEXT NOT NULL,
+	sort_field TEXT NOT NULL DEFAULT '',
+	sort_dir   TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL
+);
+`
@@ -0,0 +1,42 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package migrations
+
+// bookmark_revisions store previous states of bookmarks. Revision is added whenever bookmark changes.
+// tags is json array and metadata json object of key-values
+
+const v12 = `
+CREATE TABLE bookmark_revisions (
+	id          INTEGER
+		CONSTRAINT bookmark_revisions_pk
+			PRIMARY KEY AUTOINCREMENT,
+	bookmark    INTEGER NOT NULL
+		CONSTRAINT bookmark
+			REFERENCES bookmarks,
+	created_at  TIMESTAMP NOT NULL,
+	operation   TEXT NOT NULL,
+	name        TEXT NOT NULL,
+	description TEXT NOT NULL,
+	content     TEXT NOT NULL,
+	project     TEXT NOT NULL,
+	archived    BOOLEAN NOT NULL,
+	tags        TEXT NOT NULL,
+	metadata    TEXT NOT NULL
+);
+
+CREATE INDEX bookmark_revisions_bookmark ON bookmark_revisions(bookmark, id);
+`
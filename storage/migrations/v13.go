@@ -0,0 +1,28 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package migrations
+
+// reading list: state is one of unread, reading, read. Higher priority is more important.
+// due_at is optional date by which bookmark should be read
+
+const v13 = `
+ALTER TABLE bookmarks ADD COLUMN state TEXT NOT NULL DEFAULT 'unread';
+ALTER TABLE bookmarks ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE bookmarks ADD COLUMN due_at TIMESTAMP;
+
+CREATE INDEX bookmarks_state ON bookmarks(state, priority);
+`
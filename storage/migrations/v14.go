@@ -0,0 +1,68 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package migrations
+
+// notes are long-form markdown notes of bookmark. fts5 tables cannot be altered,
+// so bookmark_fts is recreated with notes column and filled again.
+
+const v14 = `
+ALTER TABLE bookmarks ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+ALTER TABLE bookmark_revisions ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+
+DROP TRIGGER create_bookmark_fts;
+DROP TRIGGER update_bookmark_fts;
+DROP TRIGGER delete_bookmark_fts;
+DROP TABLE bookmark_fts;
+
+CREATE VIRTUAL TABLE
+bookmark_fts
+USING fts5(
+	id UNINDEXED,
+	name,
+	description,
+	content,
+	project,
+	notes);
+
+INSERT INTO bookmark_fts(id, name, description, content, project, notes)
+SELECT
+	id, name, description, content, project, notes
+FROM bookmarks;
+
+CREATE TRIGGER create_bookmark_fts
+    AFTER INSERT ON bookmarks BEGIN
+    INSERT INTO bookmark_fts(id, name, description, content, project, notes)
+        VALUES (new.id, new.name, new.description, new.content, new.project, new.notes);
+END;
+
+CREATE TRIGGER update_bookmark_fts
+    AFTER UPDATE ON bookmarks BEGIN
+    UPDATE bookmark_fts SET
+                            name = new.name,
+                            description = new.description,
+                            content = new.content,
+                            project = new.project,
+                            notes = new.notes
+        WHERE id = new.id;
+END;
+
+CREATE TRIGGER delete_bookmark_fts
+    AFTER DELETE ON bookmarks BEGIN
+        DELETE FROM bookmark_fts
+        WHERE id = old.id;
+END;
+`
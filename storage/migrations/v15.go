@@ -0,0 +1,53 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package migrations
+
+// canonical_link is canonical form of bookmark content, used to find duplicate bookmarks.
+// It is computed by application, so column is reset to NULL whenever content changes
+// and NULL values are filled before duplicates are searched.
+// Snapshots moved to other bookmark are reindexed to metadata_fts of both bookmarks.
+
+const v15 = `
+ALTER TABLE bookmarks ADD COLUMN canonical_link TEXT;
+
+CREATE INDEX bookmarks_canonical_link ON bookmarks(canonical_link);
+
+CREATE TRIGGER reset_canonical_link
+    AFTER UPDATE OF content ON bookmarks
+    WHEN old.content IS NOT new.content BEGIN
+    UPDATE bookmarks SET canonical_link = NULL WHERE id = new.id;
+END;
+
+CREATE TRIGGER move_snapshot_fts
+    AFTER UPDATE OF bookmark ON snapshots
+    WHEN old.bookmark IS NOT new.bookmark BEGIN
+    DELETE FROM metadata_fts
+    WHERE key = 'snapshot' AND id IN (old.bookmark, new.bookmark);
+    INSERT INTO metadata_fts(id, key, value)
+    SELECT bookmark, 'snapshot', text
+    FROM snapshots
+    WHERE bookmark = old.bookmark
+    ORDER BY created_at DESC, id DESC
+    LIMIT 1;
+    INSERT INTO metadata_fts(id, key, value)
+    SELECT bookmark, 'snapshot', text
+    FROM snapshots
+    WHERE bookmark = new.bookmark
+    ORDER BY created_at DESC, id DESC
+    LIMIT 1;
+END;
+`
@@ -0,0 +1,64 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package migrations
+
+// make tag names unique and allow same tag for multiple bookmarks:
+// bookmark_tags primary key was tag only, so a tag could only belong to one bookmark,
+// and tags were not unique by name. Duplicate tags are combined into the one with lowest id
+// and bookmark_tags is rebuilt with primary key (bookmark, tag). Tags and bookmark_tags
+// are replaced with the copies only after both copies have been filled.
+
+const v6 = `
+CREATE TABLE tags_copy
+(
+	id   INTEGER
+		CONSTRAINT tags_pk
+			PRIMARY KEY AUTOINCREMENT,
+	name STRING NOT NULL
+		CONSTRAINT tags_name_unique
+			UNIQUE
+);
+INSERT INTO tags_copy(id, name)
+SELECT
+	MIN(id), name
+FROM tags
+GROUP BY name;
+
+CREATE TABLE bookmark_tags_copy
+(
+	bookmark INTEGER NOT NULL
+		CONSTRAINT bookmark
+			REFERENCES bookmarks,
+	tag      INTEGER NOT NULL
+		CONSTRAINT tags
+			REFERENCES tags,
+	CONSTRAINT bookmark_tags_pk
+		PRIMARY KEY (bookmark, tag)
+);
+INSERT OR IGNORE INTO bookmark_tags_copy(bookmark, tag)
+SELECT
+	bt.bookmark, tc.id
+FROM bookmark_tags bt
+JOIN tags t ON bt.tag = t.id
+JOIN tags_copy tc ON t.name = tc.name
+WHERE bt.bookmark IS NOT NULL;
+
+DROP TABLE bookmark_tags;
+DROP TABLE tags;
+ALTER TABLE tags_copy RENAME TO tags;
+ALTER TABLE bookmark_tags_copy RENAME TO bookmark_tags;
+`
@@ -0,0 +1,29 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package migrations
+
+// delete only removed metadata key from full-text-search,
+// previously deleting any key removed all keys of bookmark
+
+const v7 = `
+DROP TRIGGER delete_metadata_fts;
+CREATE TRIGGER delete_metadata_fts
+    AFTER DELETE ON metadata BEGIN
+    DELETE FROM metadata_fts
+    WHERE id = old.bookmark AND key = old.key;
+END;
+`
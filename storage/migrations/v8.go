@@ -0,0 +1,33 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package migrations
+
+// journal stores before and after images of modified bookmarks as json
+// to allow undoing and redoing operations
+
+const v8 = `
+CREATE TABLE journal (
+	id         INTEGER
+		CONSTRAINT journal_pk
+			PRIMARY KEY AUTOINCREMENT,
+	created_at TIMESTAMP NOT NULL,
+	operation  TEXT NOT NULL,
+	before     TEXT NOT NULL,
+	after      TEXT NOT NULL,
+	undone     BOOLEAN NOT NULL DEFAULT 0
+);
+`
@@ -0,0 +1,28 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package migrations
+
+// deleted bookmarks are kept in trash until purged.
+// remove rows orphaned by previous hard deletes
+
+const v9 = `
+ALTER TABLE bookmarks
+ADD COLUMN deleted_at TIMESTAMP;
+
+DELETE FROM bookmark_tags WHERE bookmark NOT IN (SELECT id FROM bookmarks);
+DELETE FROM metadata WHERE bookmark NOT IN (SELECT id FROM bookmarks);
+`
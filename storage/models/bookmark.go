@@ -20,7 +20,9 @@
 package models
 
 import (
+	"fmt"
 	"net/url"
+	"sort"
 	"strings"
 	"time"
 )
@@ -31,6 +33,61 @@ var DefaulMetadata = []string{
 	"Title",
 }
 
+//Link check metadata keys
+const (
+	//MetadataLinkStatus is LinkStatusOk or LinkStatusDead
+	MetadataLinkStatus = "Status"
+	//MetadataHttpStatus is http status of latest check or error message if request failed
+	MetadataHttpStatus = "Http Status"
+	//MetadataFinalUrl is link after following redirects
+	MetadataFinalUrl = "Final Url"
+	//MetadataCheckedAt is time of latest check
+	MetadataCheckedAt = "Checked At"
+	//MetadataFailures is number of consecutive failed checks
+	MetadataFailures = "Failures"
+)
+
+//LinkCheckMetadata are metadata keys that link check sets. They describe the link rather than
+//user's changes, so undo keeps them and revisions leave them out.
+var LinkCheckMetadata = []string{MetadataLinkStatus, MetadataHttpStatus, MetadataFinalUrl, MetadataCheckedAt,
+	MetadataFailures}
+
+//IsLinkCheckMetadata returns true if key is one of LinkCheckMetadata, ignoring case
+func IsLinkCheckMetadata(key string) bool {
+	for _, v := range LinkCheckMetadata {
+		if strings.EqualFold(v, key) {
+			return true
+		}
+	}
+	return false
+}
+
+//Link statuses
+const (
+	LinkStatusOk   = "ok"
+	LinkStatusDead = "dead"
+)
+
+//Reading states
+const (
+	StateUnread  = "unread"
+	StateReading = "reading"
+	StateRead    = "read"
+)
+
+//ReadStates are reading states in order they are cycled
+var ReadStates = []string{StateUnread, StateReading, StateRead}
+
+//IsReadState returns true if state is one of ReadStates
+func IsReadState(state string) bool {
+	for _, v := range ReadStates {
+		if v == state {
+			return true
+		}
+	}
+	return false
+}
+
 type Bookmark struct {
 	Id          int
 	Name        string
@@ -41,6 +98,18 @@ type Bookmark struct {
 	CreatedAt   time.Time `db:"created_at"`
 	UpdatedAt   time.Time `db:"updated_at"`
 	Archived    bool
+	//Notes are long-form notes in markdown
+	Notes string
+	//State is reading state, one of ReadStates. Empty state is unread
+	State string
+	//Priority orders reading list, higher is more important
+	Priority int
+	//DueAt is optional date by which bookmark should be read
+	DueAt *time.Time `db:"due_at"`
+	//DeletedAt is set when bookmark is in trash
+	DeletedAt *time.Time `db:"deleted_at"`
+	//LinkStatus is status of latest link check, empty if link has not been checked
+	LinkStatus string
 
 	Tags []string
 	//Metadata key-values. Not in order
@@ -49,6 +118,55 @@ type Bookmark struct {
 	MetadataKeys *[]string
 }
 
+//ReadState returns reading state of bookmark, which is unread if state is not set
+func (b *Bookmark) ReadState() string {
+	if b.State == "" {
+		return StateUnread
+	}
+	return b.State
+}
+
+//NextState returns reading state that follows current state: unread -> reading -> read -> unread
+func (b *Bookmark) NextState() string {
+	for i, v := range ReadStates {
+		if v == b.ReadState() {
+			return ReadStates[(i+1)%len(ReadStates)]
+		}
+	}
+	return StateUnread
+}
+
+//IsOverdue returns true if bookmark has due date before now and it has not been read
+func (b *Bookmark) IsOverdue(now time.Time) bool {
+	return b.DueAt != nil && b.DueAt.Before(now) && b.ReadState() != StateRead
+}
+
+//DueDateFormat is format of due date in user input and output
+const DueDateFormat = "2006-01-02"
+
+//ParseDueDate parses due date '2006-01-02' or '2006-01-02T15:04' in local time. Empty text returns nil.
+func ParseDueDate(text string) (*time.Time, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{DueDateFormat, "2006-01-02T15:04"} {
+		t, err := time.ParseInLocation(layout, text, time.Local)
+		if err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid due date '%s', expected yyyy-mm-dd", text)
+}
+
+//DueString returns due date formatted with DueDateFormat or empty string if there is no due date
+func (b *Bookmark) DueString() string {
+	if b.DueAt == nil {
+		return ""
+	}
+	return b.DueAt.Format(DueDateFormat)
+}
+
 //Return domain of the content if it is a link
 func (b *Bookmark) ContentDomain() string {
 
@@ -122,3 +240,68 @@ func (b *Bookmark) AddTags(tags []string) {
 	b.Tags = append(b.Tags, tags...)
 
 }
+
+//Merge merges other bookmark into b. Tags and metadata keys are combined and earliest CreatedAt is kept.
+//If overwrite is set, name, description, notes, project, archived status, reading state, priority, due date
+//and metadata values of other replace values of b, else only empty values of b are filled.
+func (b *Bookmark) Merge(other *Bookmark, overwrite bool) {
+	set := func(value *string, otherValue string) {
+		if otherValue != "" && (overwrite || *value == "") {
+			*value = otherValue
+		}
+	}
+	set(&b.Name, other.Name)
+	b.LowerName = strings.ToLower(b.Name)
+	set(&b.Description, other.Description)
+	set(&b.Notes, other.Notes)
+	set(&b.Project, other.Project)
+	if overwrite {
+		b.Archived = other.Archived
+	}
+	set(&b.State, other.State)
+	if other.Priority != 0 && (overwrite || b.Priority == 0) {
+		b.Priority = other.Priority
+	}
+	if other.DueAt != nil && (overwrite || b.DueAt == nil) {
+		due := *other.DueAt
+		b.DueAt = &due
+	}
+	if !other.CreatedAt.IsZero() && (b.CreatedAt.IsZero() || other.CreatedAt.Before(b.CreatedAt)) {
+		b.CreatedAt = other.CreatedAt
+	}
+
+	for _, tag := range other.Tags {
+		exists := false
+		for _, v := range b.Tags {
+			if v == tag {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			b.AddTag(tag)
+		}
+	}
+
+	if other.Metadata == nil {
+		return
+	}
+	if b.Metadata == nil {
+		b.Metadata = &map[string]string{}
+	}
+	if b.MetadataKeys == nil {
+		b.MetadataKeys = &[]string{}
+	}
+	keys := make([]string, 0, len(*other.Metadata))
+	for key := range *other.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := (*other.Metadata)[key]
+		old, exists := (*b.Metadata)[key]
+		if !exists || (value != "" && (overwrite || old == "")) {
+			b.AddMetadata(key, value)
+		}
+	}
+}
@@ -17,7 +17,9 @@
 package models
 
 import (
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestBookmark_ContentDomain(t *testing.T) {
@@ -101,3 +103,69 @@ func TestBookmark_TagsString(t *testing.T) {
 		})
 	}
 }
+
+func TestBookmark_Merge(t *testing.T) {
+	early := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	newBookmark := func() *Bookmark {
+		return &Bookmark{
+			Name:         "Existing",
+			Project:      "",
+			CreatedAt:    late,
+			Tags:         []string{"a", "b"},
+			Metadata:     &map[string]string{"Author": "", "Title": "old title"},
+			MetadataKeys: &[]string{"Author", "Title"},
+		}
+	}
+	other := &Bookmark{
+		Name:      "Imported",
+		Project:   "import",
+		Archived:  true,
+		CreatedAt: early,
+		Tags:      []string{"b", "c"},
+		Metadata:  &map[string]string{"Author": "Rob", "Title": "new title", "Class": "book"},
+	}
+
+	tests := []struct {
+		name      string
+		overwrite bool
+		want      *Bookmark
+	}{
+		{
+			name:      "merge",
+			overwrite: false,
+			want: &Bookmark{
+				Name:         "Existing",
+				LowerName:    "existing",
+				Project:      "import",
+				CreatedAt:    early,
+				Tags:         []string{"a", "b", "c"},
+				Metadata:     &map[string]string{"Author": "Rob", "Title": "old title", "Class": "book"},
+				MetadataKeys: &[]string{"Author", "Title", "Class"},
+			},
+		},
+		{
+			name:      "overwrite",
+			overwrite: true,
+			want: &Bookmark{
+				Name:         "Imported",
+				LowerName:    "imported",
+				Project:      "import",
+				Archived:     true,
+				CreatedAt:    early,
+				Tags:         []string{"a", "b", "c"},
+				Metadata:     &map[string]string{"Author": "Rob", "Title": "new title", "Class": "book"},
+				MetadataKeys: &[]string{"Author", "Title", "Class"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBookmark()
+			b.Merge(other, tt.overwrite)
+			if !reflect.DeepEqual(b, tt.want) {
+				t.Errorf("Merge() got = %+v, want %+v", b, tt.want)
+			}
+		})
+	}
+}
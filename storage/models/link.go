@@ -0,0 +1,108 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package models
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+//trackingParams are query parameters that do not change page content
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_ga":     true,
+}
+
+//CanonicalLink returns link in form that is equal for links pointing to same page:
+//scheme and host are lowercase, default port, trailing slash, fragment and tracking parameters
+//such as utm_source are removed and remaining query parameters are sorted.
+//Content that is not an absolute url is only trimmed.
+func CanonicalLink(link string) string {
+	link = strings.TrimSpace(link)
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return link
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+		u.Host = u.Hostname()
+	}
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = strings.TrimRight(u.RawPath, "/")
+
+	query := u.Query()
+	for key := range query {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") || trackingParams[strings.ToLower(key)] {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+
+	// keep hashbang routes, they are part of page address
+	if !strings.HasPrefix(u.Fragment, "!") {
+		u.Fragment = ""
+	}
+	return u.String()
+}
+
+//CanonicalLink returns canonical form of bookmark content. See CanonicalLink.
+func (b *Bookmark) CanonicalLink() string {
+	return CanonicalLink(b.Content)
+}
+
+//schemes that require host
+var hostSchemes = map[string]bool{
+	"http":  true,
+	"https": true,
+	"ftp":   true,
+}
+
+//ValidateLink returns error if link is not an absolute url that can be opened,
+//e.g. link is empty, relative, javascript bookmarklet or web address without host.
+func ValidateLink(link string) error {
+	link = strings.TrimSpace(link)
+	if link == "" {
+		return errors.New("empty link")
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return errors.New("invalid url")
+	}
+	scheme := strings.ToLower(u.Scheme)
+	switch {
+	case scheme == "":
+		return errors.New("no scheme")
+	case scheme == "javascript":
+		return errors.New("bookmarklet")
+	case scheme == "data":
+		return errors.New("data url")
+	case hostSchemes[scheme] && u.Host == "":
+		return errors.New("no host")
+	}
+	return nil
+}
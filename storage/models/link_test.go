@@ -0,0 +1,113 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package models
+
+import "testing"
+
+func TestCanonicalLink(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want string
+	}{
+		{
+			name: "scheme and host case",
+			link: "HTTPS://Example.COM/Some/Path",
+			want: "https://example.com/Some/Path",
+		},
+		{
+			name: "trailing slash",
+			link: "https://example.com/blog/",
+			want: "https://example.com/blog",
+		},
+		{
+			name: "root",
+			link: "https://example.com/",
+			want: "https://example.com",
+		},
+		{
+			name: "default port",
+			link: "http://example.com:80/a",
+			want: "http://example.com/a",
+		},
+		{
+			name: "other port",
+			link: "http://example.com:8080/a",
+			want: "http://example.com:8080/a",
+		},
+		{
+			name: "tracking params",
+			link: "https://example.com/a?utm_source=rss&b=2&UTM_Medium=x&a=1&fbclid=abc",
+			want: "https://example.com/a?a=1&b=2",
+		},
+		{
+			name: "only tracking params",
+			link: "https://example.com/a/?utm_campaign=x",
+			want: "https://example.com/a",
+		},
+		{
+			name: "fragment",
+			link: "https://example.com/a#section-2",
+			want: "https://example.com/a",
+		},
+		{
+			name: "hashbang",
+			link: "https://example.com/#!/page",
+			want: "https://example.com#!/page",
+		},
+		{
+			name: "not url",
+			link: "  some note ",
+			want: "some note",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanonicalLink(tt.link); got != tt.want {
+				t.Errorf("CanonicalLink() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateLink(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{link: "https://example.com/a", want: ""},
+		{link: "mailto:someone@example.com", want: ""},
+		{link: "file:///home/user/doc.pdf", want: ""},
+		{link: "  ", want: "empty link"},
+		{link: "example.com/a", want: "no scheme"},
+		{link: "javascript:alert(1)", want: "bookmarklet"},
+		{link: "data:text/html,hello", want: "data url"},
+		{link: "https:///a", want: "no host"},
+		{link: "http://exa mple.com", want: "invalid url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			got := ""
+			if err := ValidateLink(tt.link); err != nil {
+				got = err.Error()
+			}
+			if got != tt.want {
+				t.Errorf("ValidateLink() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package models
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Revision is previous state of bookmark
+type Revision struct {
+	Id       int
+	Bookmark int
+	//CreatedAt is time when bookmark was changed from this state
+	CreatedAt time.Time
+	//Operation is operation that changed bookmark from this state
+	Operation   string
+	Name        string
+	Description string
+	Content     string
+	Project     string
+	Notes       string
+	Archived    bool
+	Tags        []string
+	Metadata    map[string]string
+}
+
+//RevisionChange is a field that differs between two revisions
+type RevisionChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+//NewRevision returns revision that has current state of bookmark. Bookmark metadata should be loaded.
+func NewRevision(b *Bookmark) *Revision {
+	r := &Revision{
+		Bookmark:    b.Id,
+		CreatedAt:   b.UpdatedAt,
+		Name:        b.Name,
+		Description: b.Description,
+		Content:     b.Content,
+		Project:     b.Project,
+		Notes:       b.Notes,
+		Archived:    b.Archived,
+		Tags:        append([]string{}, b.Tags...),
+		Metadata:    map[string]string{},
+	}
+	if b.Metadata != nil {
+		for key, value := range *b.Metadata {
+			if !IsLinkCheckMetadata(key) {
+				r.Metadata[key] = value
+			}
+		}
+	}
+	return r
+}
+
+//Diff returns fields that differ in newer revision. Bookmark fields are first and metadata keys
+//follow in alphabetical order. Empty metadata value equals missing key. Link check metadata is ignored.
+func (r *Revision) Diff(newer *Revision) []RevisionChange {
+	changes := []RevisionChange{}
+	add := func(field, old, new string) {
+		if old != new {
+			changes = append(changes, RevisionChange{Field: field, Old: old, New: new})
+		}
+	}
+	tags := func(tags []string) string {
+		sorted := append([]string{}, tags...)
+		sort.Strings(sorted)
+		return strings.Join(sorted, ", ")
+	}
+
+	add("Name", r.Name, newer.Name)
+	add("Description", r.Description, newer.Description)
+	add("Link", r.Content, newer.Content)
+	add("Project", r.Project, newer.Project)
+	add("Notes", r.Notes, newer.Notes)
+	add("Tags", tags(r.Tags), tags(newer.Tags))
+	add("Archived", strconv.FormatBool(r.Archived), strconv.FormatBool(newer.Archived))
+
+	keys := []string{}
+	for key := range r.Metadata {
+		keys = append(keys, key)
+	}
+	for key := range newer.Metadata {
+		if _, ok := r.Metadata[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !IsLinkCheckMetadata(key) {
+			add(key, r.Metadata[key], newer.Metadata[key])
+		}
+	}
+	return changes
+}
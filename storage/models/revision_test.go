@@ -0,0 +1,52 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRevision_Diff(t *testing.T) {
+	old := &Revision{
+		Name:     "go",
+		Content:  "https://golang.org",
+		Tags:     []string{"lang", "go"},
+		Metadata: map[string]string{"Title": "", "Author": "rob", "Status": "ok", "Checked At": "2020-01-01"},
+	}
+	newer := &Revision{
+		Name:     "golang",
+		Content:  "https://golang.org",
+		Notes:    "# Go\n- fast",
+		Archived: true,
+		Tags:     []string{"go", "lang"},
+		Metadata: map[string]string{"Author": "ken", "Language": "en", "Checked At": "2020-02-01"},
+	}
+	want := []RevisionChange{
+		{Field: "Name", Old: "go", New: "golang"},
+		{Field: "Notes", Old: "", New: "# Go\n- fast"},
+		{Field: "Archived", Old: "false", New: "true"},
+		{Field: "Author", Old: "rob", New: "ken"},
+		{Field: "Language", Old: "", New: "en"},
+	}
+	if got := old.Diff(newer); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+	if got := newer.Diff(newer); len(got) != 0 {
+		t.Errorf("Diff() with itself = %v, want no changes", got)
+	}
+}
@@ -0,0 +1,34 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package models
+
+import "time"
+
+//SavedSearch is a named search query with sort settings
+type SavedSearch struct {
+	Id   int
+	Name string
+	//Query is search or filter query, see storage.NewFilter
+	Query string
+	//SortField is bookmarks column to sort with, e.g. 'Name' or 'Added at'. Empty uses default sorting
+	SortField string `db:"sort_field"`
+	//SortDir is either ASC or DESC
+	SortDir   string    `db:"sort_dir"`
+	CreatedAt time.Time `db:"created_at"`
+	//Count is number of bookmarks that match query, it is not stored
+	Count int `db:"-"`
+}
@@ -0,0 +1,35 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package models
+
+import "time"
+
+//Snapshot is an archived copy of bookmarked page
+type Snapshot struct {
+	Id        int
+	Bookmark  int
+	CreatedAt time.Time `db:"created_at"`
+	//Url is page url after redirects
+	Url   string
+	Title string
+	//Hash is sha256 of page html
+	Hash string
+	//Text is readable content of page as markdown
+	Text string
+	//Html is page html. It is compressed in database and loaded only with single snapshot
+	Html []byte
+}
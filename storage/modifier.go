@@ -17,28 +17,312 @@
 package storage
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
 )
 
+//Modifier describes changes to apply to bookmarks in bulk modify
 type Modifier struct {
-	Project    StringFilter
-	Tags       StringFilter
-	Archived   StringFilter
+	Project  StringFilter
+	Archived StringFilter
+	//State sets reading state
+	State StringFilter
+	//Priority sets priority if it is not nil
+	Priority *int
+	//DueAt sets due date if it is not nil
+	DueAt *time.Time
+	//ClearDue removes due date
+	ClearDue bool
+	//AddTags are added to bookmarks
+	AddTags []string
+	//RemoveTags are removed from bookmarks
+	RemoveTags []string
+	//ClearTags removes all tags before adding AddTags
+	ClearTags bool
+	//CustomTags are metadata key-values to set
 	CustomTags map[string]StringFilter
+	//RemoveMetadata are metadata keys to delete
+	RemoveMetadata []string
 }
 
-func NewModifier(key, value string) (*Modifier, error) {
-	m := &Modifier{}
+//NewModifier parses modifier query.
+//Query example: "+tags:x,y -tags:z project:'a.b' archived:true state:read author:'X' -publisher"
+//Rules: tags:a,b or +tags:a,b adds tags, -tags:a,b removes tags and -tags removes all tags,
+//project, archived, state, priority and due set bookmark fields and -due removes due date,
+//any other key:value sets metadata and -key deletes metadata.
+func NewModifier(query string) (*Modifier, error) {
+	m := &Modifier{
+		CustomTags: map[string]StringFilter{},
+	}
+
+	l := &lexer{input: []rune(query)}
+	tokens, err := l.tokens()
+	if err != nil {
+		return m, err
+	}
+
+	for i := 0; tokens[i].typ != tokenEOF; i++ {
+		t := tokens[i]
+		remove := false
+		if t.typ == tokenNot && t.text == "-" {
+			remove = true
+			i += 1
+			t = tokens[i]
+		}
+
+		switch t.typ {
+		case tokenField:
+			err = m.parseField(t, remove)
+		case tokenWord, tokenPhrase:
+			if !remove {
+				err = queryError(t.pos, "expected key:value, got %s", t)
+			} else {
+				err = m.parseRemove(t)
+			}
+		case tokenEOF:
+			err = queryError(t.pos, "expected key after '-'")
+		default:
+			err = queryError(t.pos, "unexpected %s, modifier cannot contain operators", t)
+		}
+		if err != nil {
+			return m, err
+		}
+	}
+
+	for _, tag := range m.AddTags {
+		if containsFold(m.RemoveTags, tag) {
+			return m, fmt.Errorf("tag '%s' is both added and removed", tag)
+		}
+	}
+	for key := range m.CustomTags {
+		if containsFold(m.RemoveMetadata, key) {
+			return m, fmt.Errorf("metadata '%s' is both set and deleted", key)
+		}
+	}
+	if m.DueAt != nil && m.ClearDue {
+		return m, fmt.Errorf("due date is both set and removed")
+	}
+	return m, nil
+}
 
-	switch strings.ToLower(key) {
+func containsFold(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+//modifierTags splits comma separated tags. Quoted value is single tag.
+func modifierTags(t token) []string {
+	if t.quoted {
+		return []string{t.text}
+	}
+	tags := []string{}
+	for _, tag := range strings.Split(t.text, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func (m *Modifier) parseField(t token, remove bool) error {
+	switch t.key {
+	case "tag", "tags":
+		tags := modifierTags(t)
+		if len(tags) == 0 {
+			return queryError(t.valuePos, "no tags in '%s'", t.text)
+		}
+		if remove {
+			m.RemoveTags = append(m.RemoveTags, tags...)
+		} else {
+			m.AddTags = append(m.AddTags, tags...)
+		}
+		return nil
+	}
+
+	if remove {
+		return queryError(t.pos, "'-%s:' is not supported, use '-%s' to delete metadata", t.key, t.key)
+	}
+
+	switch t.key {
 	case "project":
-		m.Project.Name = value
-		m.Project.Strict = true
-	case "tags":
+		m.Project = StringFilter{Name: t.text, Strict: true}
 	case "archived":
-		m.Archived.Name = value
+		value := strings.ToLower(t.text)
+		if value != "true" && value != "false" {
+			return queryError(t.valuePos, "invalid archived value '%s', expected true or false", t.text)
+		}
+		m.Archived = StringFilter{Name: value, Strict: true}
+	case "state":
+		value := strings.ToLower(t.text)
+		if !models.IsReadState(value) {
+			return queryError(t.valuePos, "invalid state '%s', expected unread, reading or read", t.text)
+		}
+		m.State = StringFilter{Name: value, Strict: true}
+	case "priority":
+		priority, err := strconv.Atoi(t.text)
+		if err != nil {
+			return queryError(t.valuePos, "invalid priority '%s'", t.text)
+		}
+		m.Priority = &priority
+	case "due":
+		due, err := models.ParseDueDate(t.text)
+		if err != nil {
+			return queryError(t.valuePos, "%v", err)
+		}
+		m.DueAt = due
+	case "name", "description", "link", "notes", "sort":
+		return queryError(t.pos, "cannot modify '%s' of multiple bookmarks", t.key)
 	default:
+		if _, ok := dateFields[t.key]; ok {
+			return queryError(t.pos, "cannot modify '%s' of multiple bookmarks", t.key)
+		}
+		m.CustomTags[t.rawKey] = StringFilter{Name: t.text, Strict: true}
 	}
+	return nil
+}
 
-	return m, nil
+//parseRemove parses '-key'
+func (m *Modifier) parseRemove(t token) error {
+	key := strings.ToLower(t.text)
+	switch key {
+	case "tag", "tags":
+		m.ClearTags = true
+	case "due":
+		m.ClearDue = true
+	case "project", "archived", "state", "priority", "name", "description", "link", "notes":
+		return queryError(t.pos, "cannot delete '%s'", key)
+	default:
+		m.RemoveMetadata = append(m.RemoveMetadata, key)
+	}
+	return nil
+}
+
+//IsEmpty returns true if modifier does not change anything
+func (m *Modifier) IsEmpty() bool {
+	return m.Project.Name == "" &&
+		m.Archived.Name == "" &&
+		m.State.Name == "" &&
+		m.Priority == nil &&
+		m.DueAt == nil &&
+		!m.ClearDue &&
+		len(m.AddTags) == 0 &&
+		len(m.RemoveTags) == 0 &&
+		!m.ClearTags &&
+		len(m.CustomTags) == 0 &&
+		len(m.RemoveMetadata) == 0
+}
+
+type statement struct {
+	query string
+	args  []interface{}
+}
+
+//placeholders returns '?,?,?' for n values
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+func appendStrings(args []interface{}, values []string, lower bool) []interface{} {
+	for _, v := range values {
+		if lower {
+			v = strings.ToLower(v)
+		}
+		args = append(args, v)
+	}
+	return args
+}
+
+//statements returns statements that apply modifier to bookmarks with given ids.
+//Tags in AddTags must exist before executing statements.
+func (m *Modifier) statements(ids []int, updatedAt time.Time) []statement {
+	idArgs := make([]interface{}, len(ids))
+	for i, v := range ids {
+		idArgs[i] = v
+	}
+	in := "(" + placeholders(len(ids)) + ")"
+	withIds := func(args ...interface{}) []interface{} {
+		return append(append([]interface{}{}, idArgs...), args...)
+	}
+
+	columns := []string{}
+	args := []interface{}{}
+	if m.Project.Name != "" {
+		columns = append(columns, "project = ?")
+		args = append(args, strings.ToLower(m.Project.Name))
+	}
+	if m.Archived.Name != "" {
+		columns = append(columns, "archived = ?")
+		args = append(args, m.Archived.Name == "true")
+	}
+	if m.State.Name != "" {
+		columns = append(columns, "state = ?")
+		args = append(args, m.State.Name)
+	}
+	if m.Priority != nil {
+		columns = append(columns, "priority = ?")
+		args = append(args, *m.Priority)
+	}
+	if m.DueAt != nil {
+		columns = append(columns, "due_at = ?")
+		args = append(args, *m.DueAt)
+	} else if m.ClearDue {
+		columns = append(columns, "due_at = NULL")
+	}
+	columns = append(columns, "updated_at = ?")
+	args = append(args, updatedAt)
+	statements := []statement{{
+		query: "UPDATE bookmarks SET " + strings.Join(columns, ", ") + " WHERE id IN " + in,
+		args:  append(args, idArgs...),
+	}}
+
+	if m.ClearTags {
+		statements = append(statements, statement{
+			query: "DELETE FROM bookmark_tags WHERE bookmark IN " + in,
+			args:  withIds(),
+		})
+	}
+	if len(m.RemoveTags) > 0 {
+		statements = append(statements, statement{
+			query: "DELETE FROM bookmark_tags WHERE bookmark IN " + in +
+				" AND tag IN (SELECT id FROM tags WHERE LOWER(name) IN (" + placeholders(len(m.RemoveTags)) + "))",
+			args: appendStrings(withIds(), m.RemoveTags, true),
+		})
+	}
+	if len(m.AddTags) > 0 {
+		statements = append(statements, statement{
+			query: "INSERT OR IGNORE INTO bookmark_tags (bookmark, tag) SELECT b.id, t.id FROM bookmarks b, tags t WHERE b.id IN " +
+				in + " AND t.name IN (" + placeholders(len(m.AddTags)) + ")",
+			args: appendStrings(withIds(), m.AddTags, false),
+		})
+	}
+
+	keys := make([]string, 0, len(m.CustomTags))
+	for key := range m.CustomTags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := m.CustomTags[key].Name
+		statements = append(statements, statement{
+			query: "INSERT INTO metadata (bookmark, key, key_lower, value, value_lower) SELECT id, ?, ?, ?, ? FROM bookmarks WHERE id IN " +
+				in + " ON CONFLICT(bookmark, key_lower) DO UPDATE SET value = excluded.value, value_lower = excluded.value_lower",
+			args: append([]interface{}{key, strings.ToLower(key), value, strings.ToLower(value)}, idArgs...),
+		})
+	}
+	if len(m.RemoveMetadata) > 0 {
+		statements = append(statements, statement{
+			query: "DELETE FROM metadata WHERE bookmark IN " + in + " AND key_lower IN (" + placeholders(len(m.RemoveMetadata)) + ")",
+			args:  appendStrings(withIds(), m.RemoveMetadata, true),
+		})
+	}
+	return statements
 }
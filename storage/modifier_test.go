@@ -0,0 +1,139 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewModifier(t *testing.T) {
+	priority := 3
+	tests := []struct {
+		name    string
+		query   string
+		want    *Modifier
+		wantErr bool
+	}{
+		{
+			name:  "full modifier",
+			query: "+tags:x,y -tags:z project:'a.b' archived:TRUE Author:'Rob Pike' -publisher",
+			want: &Modifier{
+				Project:        StringFilter{Name: "a.b", Strict: true},
+				Archived:       StringFilter{Name: "true", Strict: true},
+				AddTags:        []string{"x", "y"},
+				RemoveTags:     []string{"z"},
+				CustomTags:     map[string]StringFilter{"Author": {Name: "Rob Pike", Strict: true}},
+				RemoveMetadata: []string{"publisher"},
+			},
+		},
+		{
+			name:  "clear tags",
+			query: "-tags tag:'a,b'",
+			want: &Modifier{
+				AddTags:    []string{"a,b"},
+				ClearTags:  true,
+				CustomTags: map[string]StringFilter{},
+			},
+		},
+		{
+			name:  "reading list",
+			query: "state:Read priority:3 -due",
+			want: &Modifier{
+				State:      StringFilter{Name: "read", Strict: true},
+				Priority:   &priority,
+				ClearDue:   true,
+				CustomTags: map[string]StringFilter{},
+			},
+		},
+		{name: "plain word", query: "project", wantErr: true},
+		{name: "operator", query: "project:a OR archived:true", wantErr: true},
+		{name: "invalid archived", query: "archived:yes", wantErr: true},
+		{name: "remove project", query: "-project", wantErr: true},
+		{name: "remove metadata value", query: "-author:x", wantErr: true},
+		{name: "modify name", query: "name:x", wantErr: true},
+		{name: "add and remove tag", query: "+tags:a -tags:A", wantErr: true},
+		{name: "set and delete metadata", query: "author:a -author", wantErr: true},
+		{name: "missing key", query: "project:a -", wantErr: true},
+		{name: "invalid state", query: "state:done", wantErr: true},
+		{name: "invalid priority", query: "priority:high", wantErr: true},
+		{name: "invalid due", query: "due:tomorrow", wantErr: true},
+		{name: "set and remove due", query: "due:2020-02-01 -due", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewModifier(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewModifier() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewModifier() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModifier_statements(t *testing.T) {
+	m, err := NewModifier("project:Dev archived:false +tags:go -tags:old -author")
+	if err != nil {
+		t.Fatalf("NewModifier() error = %v", err)
+	}
+	now := time.Now()
+	statements := m.statements([]int{1, 2}, now)
+
+	want := []statement{
+		{
+			query: "UPDATE bookmarks SET project = ?, archived = ?, updated_at = ? WHERE id IN (?,?)",
+			args:  []interface{}{"dev", false, now, 1, 2},
+		},
+		{
+			query: "DELETE FROM bookmark_tags WHERE bookmark IN (?,?) AND tag IN (SELECT id FROM tags WHERE LOWER(name) IN (?))",
+			args:  []interface{}{1, 2, "old"},
+		},
+		{
+			query: "INSERT OR IGNORE INTO bookmark_tags (bookmark, tag) SELECT b.id, t.id FROM bookmarks b, tags t WHERE b.id IN (?,?) AND t.name IN (?)",
+			args:  []interface{}{1, 2, "go"},
+		},
+		{
+			query: "DELETE FROM metadata WHERE bookmark IN (?,?) AND key_lower IN (?)",
+			args:  []interface{}{1, 2, "author"},
+		},
+	}
+	if !reflect.DeepEqual(statements, want) {
+		t.Errorf("statements() = %+v, want %+v", statements, want)
+	}
+
+	m, _ = NewModifier("state:reading due:2020-02-01")
+	statements = m.statements([]int{3}, now)
+	due := time.Date(2020, 2, 1, 0, 0, 0, 0, time.Local)
+	if len(statements) != 1 || statements[0].query != "UPDATE bookmarks SET state = ?, due_at = ?, updated_at = ? WHERE id IN (?)" ||
+		!reflect.DeepEqual(statements[0].args, []interface{}{"reading", due, now, 3}) {
+		t.Fatalf("statements() = %+v, want state and due update", statements)
+	}
+
+	m, _ = NewModifier("Author:X")
+	statements = m.statements([]int{3}, now)
+	if len(statements) != 2 || !strings.HasPrefix(statements[1].query, "INSERT INTO metadata") {
+		t.Fatalf("statements() = %+v, want update and metadata insert", statements)
+	}
+	args := []interface{}{"Author", "author", "X", "x", 3}
+	if !reflect.DeepEqual(statements[1].args, args) {
+		t.Errorf("statements() args = %v, want %v", statements[1].args, args)
+	}
+}
@@ -24,10 +24,11 @@ import (
 	"fmt"
 	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
+	"sort"
 	"strings"
 	"time"
-	"tryffel.net/go/bookmarker/config"
 	"tryffel.net/go/bookmarker/storage/models"
+	"unicode/utf8"
 )
 
 //name is any query result that has field 'Name'
@@ -64,7 +65,7 @@ func (q *query) log(err error) {
 }
 
 //GetAllBookmarks returns all bookmarks filtered by their name.
-// cnofig.HideArchived is obeyd and limit is 500
+// Options.HideArchived is obeyd and limit is 500
 func (d *Database) GetAllBookmarks() ([]*models.Bookmark, error) {
 	query := `
 SELECT
@@ -86,8 +87,9 @@ GROUP BY b.id
 ORDER BY b.name ASC
 LIMIT 500;
 `
-	if config.Configuration.HideArchived {
-		query += " WHERE archived = false"
+	query += " WHERE b.deleted_at IS NULL"
+	if d.options.HideArchived {
+		query += " AND archived = false"
 	}
 	query += queryEnd
 
@@ -126,24 +128,28 @@ LIMIT 500;
 func (d *Database) GetAllProjects(name string, strict bool) ([]*models.Project, error) {
 	query := `
 SELECT 
-    project,
+    LOWER(project) AS project,
 	count(*) as count
-FROM bookmarks `
+FROM bookmarks 
+WHERE deleted_at IS NULL `
 
+	args := []interface{}{}
 	if name != "" {
-		query += " WHERE project "
+		query += " AND "
 		if strict {
-			query += "= ?"
+			query += "LOWER(project) = ?"
+			args = append(args, strings.ToLower(name))
 		} else {
-			query += "LIKE '%?%' "
+			query += "LOWER(project) LIKE ? ESCAPE '\\'"
+			args = append(args, likePattern(name))
 		}
 	}
 
-	query += " GROUP BY project ORDER BY project ASC;"
+	query += " GROUP BY LOWER(project) ORDER BY LOWER(project) ASC;"
 
 	logger := beginQuery(query, "get projects")
 
-	rows, err := d.conn.Query(query, name)
+	rows, err := d.conn.Query(query, args...)
 	if err != nil {
 		logger.log(err)
 		return nil, err
@@ -175,11 +181,13 @@ FROM bookmarks `
 func (d *Database) GetAllTags() (*map[string]int, error) {
 	query := `
 SELECT
-       name,
-       COUNT(*) as count
+       tags.name,
+       COUNT(b.id) as count
 FROM tags
 LEFT JOIN bookmark_tags bt ON tags.id = bt.tag
+LEFT JOIN bookmarks b ON bt.bookmark = b.id AND b.deleted_at IS NULL
 GROUP BY tags.name
+HAVING COUNT(b.id) > 0
 ORDER BY tags.name ASC;
 `
 	logger := beginQuery(query, "get tags")
@@ -214,79 +222,106 @@ ORDER BY tags.name ASC;
 func (d *Database) NewBookmark(b *models.Bookmark) error {
 	query := `
 INSERT INTO 
-bookmarks (name, lower_name, description, description_lower, content, project, created_at, updated_at, archived) 
-VALUES (?,?,?,?,?,?,?,?,?); SELECT last_insert_rowid() FROM bookmarks`
+bookmarks (name, lower_name, description, description_lower, content, project, created_at, updated_at, archived,
+	state, priority, due_at, notes) 
+VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?); SELECT last_insert_rowid() FROM bookmarks`
 
 	logger := beginQuery(query, "new bookmark")
-	res, err := d.conn.Exec(query, b.Name, b.LowerName, b.Description, strings.ToLower(b.Description), b.Content,
-		strings.ToLower(b.Project), b.CreatedAt, b.UpdatedAt, b.Archived)
-
-	if err != nil {
-		logger.log(err)
-		return err
-	}
+	err := d.journaled(fmt.Sprintf("new bookmark '%s'", b.Name), nil, func(tx *sqlx.Tx) ([]int, error) {
+		res, err := tx.Exec(query, b.Name, b.LowerName, b.Description, strings.ToLower(b.Description), b.Content,
+			strings.ToLower(b.Project), b.CreatedAt, b.UpdatedAt, b.Archived, b.ReadState(), b.Priority, b.DueAt,
+			b.Notes)
+		if err != nil {
+			return nil, err
+		}
 
-	id, err := res.LastInsertId()
-	if err != nil {
-		logger.log(err)
-		return err
-	}
-	logger.log(nil)
-	b.Id = int(id)
-	err = d.upsertMetadata(b)
-	if err != nil {
-		logrus.Errorf("Insert / update bookmark metadata: %v", err)
-	}
-	if len(b.Tags) > 0 {
-		err = d.InsertTags(b.Tags, nil)
+		id, err := res.LastInsertId()
 		if err != nil {
-			logrus.Errorf("Insert tags: %v", err)
-		} else {
-			err = d.UpdateBookmarkTags(b, b.Tags, nil)
+			return nil, err
+		}
+		b.Id = int(id)
+		err = upsertMetadata(tx, b.Id, bookmarkMetadata(b))
+		if err != nil {
+			return nil, fmt.Errorf("insert / update bookmark metadata: %v", err)
+		}
+		if len(b.Tags) > 0 {
+			err = d.InsertTags(b.Tags, tx)
 			if err != nil {
-				logrus.Errorf("Update bookmark tags: %v", err)
+				return nil, fmt.Errorf("insert tags: %v", err)
+			}
+			err = updateBookmarkTags(tx, b.Id, b.Tags)
+			if err != nil {
+				return nil, fmt.Errorf("update bookmark tags: %v", err)
 			}
 		}
-	}
+		return []int{b.Id}, nil
+	})
+	logger.log(err)
 	return err
 }
 
-//NewBookmarks creates batch of new bookmarks. Bookmark ids are not collected and need to be queried separately
-// AddTags allows defining any custom tags that are assigned to all bookmarks
-func (d *Database) NewBookmarks(bookmarks []*models.Bookmark, AddTags []string) error {
-	//Max variables for sqlite is 999, batch of 100 = 900 vars
+//NewBookmarks creates batch of new bookmarks and sets id of each created bookmark.
+// AddTags allows defining any custom tags that are assigned to all bookmarks.
+// Duplicates defines how bookmarks with existing links, or links repeated in bookmarks, are handled.
+func (d *Database) NewBookmarks(bookmarks []*models.Bookmark, AddTags []string,
+	duplicates DuplicatePolicy) (*ImportResult, error) {
+	return d.ImportBookmarks(bookmarks, AddTags, duplicates, nil)
+}
 
-	numArgs := 9
-	batchSize := 100
-	total := len(bookmarks)
+//ImportBookmarks is NewBookmarks that calls progress, if not nil, after each stored batch of bookmarks
+// with number of bookmarks stored so far and total number of bookmarks to create.
+func (d *Database) ImportBookmarks(bookmarks []*models.Bookmark, AddTags []string,
+	duplicates DuplicatePolicy, progress func(done, total int)) (*ImportResult, error) {
+	//Max variables for sqlite is 999, each bookmark in batch takes numArgs variables: 76 * 13 = 988 vars
+	numArgs := 13
+	batchSize := 999 / numArgs
 	imported := 0
+	result := &ImportResult{}
 
-	// Get last id
-	id := 0
-	rows, err := d.conn.Query("SELECT coalesce(max(id),1) FROM main.bookmarks;")
-	if err != nil {
-		return fmt.Errorf("failed to query last id: %v", err)
+	if len(bookmarks) == 0 {
+		return result, nil
 	}
 
-	id += 1
+	for _, v := range bookmarks {
+		if v.LowerName == "" {
+			v.LowerName = strings.ToLower(v.Name)
+		}
+		// projects are stored in lower case like in NewBookmark
+		v.Project = strings.ToLower(v.Project)
+		if v.CreatedAt == time.Unix(0, 0) {
+			v.CreatedAt = time.Now()
+		}
+		if v.UpdatedAt == time.Unix(0, 0) {
+			v.UpdatedAt = time.Now()
+		}
 
-	rows.Next()
-	err = rows.Scan(&id)
-	rows.Close()
+		if len(AddTags) > 0 {
+			v.AddTags(AddTags)
+		}
+	}
 
+	tx, err := d.conn.Beginx()
 	if err != nil {
-		return fmt.Errorf("failed to scan last id: %v", err)
+		return result, fmt.Errorf("start transaction: %v", err)
 	}
 
-	tx, err := d.conn.Beginx()
+	bookmarks, before, updated, err := d.resolveDuplicates(tx, bookmarks, duplicates, result)
 	if err != nil {
-		return fmt.Errorf("start transaction: %v", err)
+		_ = tx.Rollback()
+		return result, fmt.Errorf("resolve duplicates: %v", err)
+	}
+	err = d.restoreImages(tx, updated)
+	if err != nil {
+		_ = tx.Rollback()
+		return result, fmt.Errorf("update duplicates: %v", err)
 	}
 
+	total := len(bookmarks)
 	tagsMap := map[string]bool{}
+	ids := make([]int, 0, total)
 
 	// Import bookmarks in batches
-	for {
+	for total > 0 {
 		var batch []*models.Bookmark
 
 		if imported+batchSize < total {
@@ -298,10 +333,10 @@ func (d *Database) NewBookmarks(bookmarks []*models.Bookmark, AddTags []string)
 		query := `
 		INSERT INTO 
 		bookmarks (name, lower_name, description, description_lower, content, 
-		           project, created_at, updated_at, archived) 
+		           project, created_at, updated_at, archived, state, priority, due_at, notes) 
 		VALUES `
 
-		argList := "(?,?,?,?,?,?,?,?,?)"
+		argList := "(?,?,?,?,?,?,?,?,?,?,?,?,?)"
 		args := make([]interface{}, len(batch)*numArgs)
 
 		// Parse each bookmark, put tags to map, put bookmark to args list
@@ -310,22 +345,7 @@ func (d *Database) NewBookmarks(bookmarks []*models.Bookmark, AddTags []string)
 				query += ","
 			}
 			query += argList
-			v.Id = id
-			id += 1
 
-			if v.LowerName == "" {
-				v.LowerName = strings.ToLower(v.Name)
-			}
-			if v.CreatedAt == time.Unix(0, 0) {
-				v.CreatedAt = time.Now()
-			}
-			if v.UpdatedAt == time.Unix(0, 0) {
-				v.UpdatedAt = time.Now()
-			}
-
-			if len(AddTags) > 0 {
-				v.AddTags(AddTags)
-			}
 			args[numArgs*i] = v.Name
 			args[numArgs*i+1] = v.LowerName
 			args[numArgs*i+2] = v.Description
@@ -335,6 +355,10 @@ func (d *Database) NewBookmarks(bookmarks []*models.Bookmark, AddTags []string)
 			args[numArgs*i+6] = v.CreatedAt
 			args[numArgs*i+7] = v.UpdatedAt
 			args[numArgs*i+8] = v.Archived
+			args[numArgs*i+9] = v.ReadState()
+			args[numArgs*i+10] = v.Priority
+			args[numArgs*i+11] = v.DueAt
+			args[numArgs*i+12] = v.Notes
 
 			if len(v.Tags) > 0 {
 				for _, t := range v.Tags {
@@ -347,58 +371,92 @@ func (d *Database) NewBookmarks(bookmarks []*models.Bookmark, AddTags []string)
 		res, err := tx.Exec(query, args...)
 		if err != nil {
 			_ = tx.Rollback()
-			return fmt.Errorf("insert bookmarks: %v", err)
+			return result, fmt.Errorf("insert bookmarks: %v", err)
 		}
 
 		rows, err := res.RowsAffected()
+		if err != nil {
+			_ = tx.Rollback()
+			return result, fmt.Errorf("get inserted rows: %v", err)
+		}
 		if int(rows) != len(batch) {
 			logrus.Warning("Some bookmarks were not stored properly during import, probably due to duplicate")
 		}
+
+		// Rows inserted with single statement get consecutive ids
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			_ = tx.Rollback()
+			return result, fmt.Errorf("get last id: %v", err)
+		}
+		for i, v := range batch {
+			v.Id = int(lastId) - len(batch) + 1 + i
+			ids = append(ids, v.Id)
+		}
+
 		imported += len(batch)
+		if progress != nil {
+			progress(imported, total)
+		}
 		if imported == total {
 			break
 		}
 	}
 
-	/*
-		//Tags
-		tags := make([]string, len(tagsMap))
-		i := 0
-		for key, _ := range tagsMap {
-			tags[i] = key
-			i += 1
-		}
-		err = d.InsertTags(tags, tx)
-
+	for _, v := range bookmarks {
+		err = upsertMetadata(tx, v.Id, bookmarkMetadata(v))
 		if err != nil {
 			_ = tx.Rollback()
-			return fmt.Errorf("insert tags: %v", err)
+			return result, fmt.Errorf("insert metadata: %v", err)
 		}
+	}
 
-		//Tags bookmarks relations
-		//Add tags one bookmark at a time for now
-		for _, v := range bookmarks {
-			if len(v.Tags) > 0 {
-				err = d.UpdateBookmarkTags(v, v.Tags, tx)
-				if err != nil {
-					break
-				}
+	//Tags
+	tags := make([]string, len(tagsMap))
+	i := 0
+	for key := range tagsMap {
+		tags[i] = key
+		i += 1
+	}
+	err = d.InsertTags(tags, tx)
+
+	if err != nil {
+		_ = tx.Rollback()
+		return result, fmt.Errorf("insert tags: %v", err)
+	}
+
+	//Tags bookmarks relations
+	//Add tags one bookmark at a time for now
+	for _, v := range bookmarks {
+		if len(v.Tags) > 0 {
+			err = d.UpdateBookmarkTags(v, v.Tags, tx)
+			if err != nil {
+				break
 			}
 		}
+	}
 
-	*/
+	if err != nil {
+		_ = tx.Rollback()
+		return result, fmt.Errorf("udpate bookmark tags relations: %v", err)
+	}
 
+	after, err := loadImages(tx, append(ids, updated.ids()...))
+	if err == nil {
+		err = recordJournal(tx, fmt.Sprintf("import %d bookmarks", total+len(updated)), before, after)
+	}
 	if err != nil {
 		_ = tx.Rollback()
-		return fmt.Errorf("udpate bookmark tags relations: %v", err)
+		return result, fmt.Errorf("record journal: %v", err)
 	}
 
 	err = tx.Commit()
 	if err != nil {
-		return fmt.Errorf("transaction failed: %v", err)
+		return result, fmt.Errorf("transaction failed: %v", err)
 	}
 
-	return nil
+	result.Created = total
+	return result, nil
 }
 
 //GetBookmarkMetadata gets metadata related to bookmark
@@ -446,11 +504,16 @@ SELECT
 	b.created_at AS created_at,
 	b.updated_at AS updated_at,
 	b.archived AS archived,
+	b.state AS state,
+	b.priority AS priority,
+	b.due_at AS due_at,
+	b.notes AS notes,
 	GROUP_CONCAT(t.name) AS tags
 FROM bookmarks b
 LEFT JOIN bookmark_tags bt ON b.id = bt.bookmark
 LEFT JOIN tags t ON bt.tag = t.id
 WHERE b.id = ?
+GROUP BY b.id
 LIMIT 1`
 
 	b := &models.Bookmark{}
@@ -458,10 +521,14 @@ LIMIT 1`
 	if err != nil {
 		return b, err
 	}
+	defer rows.Close()
 
-	rows.Next()
+	if !rows.Next() {
+		return b, sql.ErrNoRows
+	}
 	var tags sql.NullString
-	err = rows.Scan(&b.Id, &b.Name, &b.Description, &b.Content, &b.Project, &b.CreatedAt, &b.UpdatedAt, &b.Archived, &tags)
+	err = rows.Scan(&b.Id, &b.Name, &b.Description, &b.Content, &b.Project, &b.CreatedAt, &b.UpdatedAt, &b.Archived,
+		&b.State, &b.Priority, &b.DueAt, &b.Notes, &tags)
 
 	if tags.String != "" {
 		b.Tags = strings.Split(tags.String, ",")
@@ -472,6 +539,23 @@ LIMIT 1`
 //SearchBookmarks searches both bookmarks table and additional metadata fields
 // If full text search is enabled, combine those results as well
 func (d *Database) SearchBookmarks(text string) ([]*models.Bookmark, error) {
+	bookmarks, _, err := d.SearchBookmarksPage(text, 0, -1)
+	return bookmarks, err
+}
+
+//SearchBookmarksPage returns page of search results starting at offset and total number of results.
+//Zero limit uses DefaultPageSize and negative limit returns all results.
+func (d *Database) SearchBookmarksPage(text string, offset, limit int) ([]*models.Bookmark, int, error) {
+	filter, err := NewFilter(text)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !filter.IsPlainQuery() {
+		filter.Offset = offset
+		filter.Limit = limit
+		return d.BookmarksPage(filter)
+	}
+	text = filter.Query
 
 	plainQuery := `
 -- metadata
@@ -486,11 +570,16 @@ SELECT * FROM
     	b.created_at AS created_at,
     	b.updated_at AS updated_at,
     	b.archived AS archived,
+    	b.state AS state,
+    	b.priority AS priority,
+    	b.due_at AS due_at,
+    	b.notes AS notes,
        	-- skip tags for now
     	'' as tags
 	FROM bookmarks b
          LEFT outer JOIN metadata m on b.id = m.bookmark
-	WHERE m.value_lower like ?
+	WHERE m.value_lower LIKE ? ESCAPE '\'
+		AND b.deleted_at IS NULL
 	UNION
 	-- bookmarks with tags
 	SELECT
@@ -502,25 +591,31 @@ SELECT * FROM
 		b.created_at AS created_at,
 		b.updated_at AS updated_at,
 		b.archived AS archived,
+		b.state AS state,
+		b.priority AS priority,
+		b.due_at AS due_at,
+		b.notes AS notes,
 		GROUP_CONCAT(t.name) AS tags
 	FROM bookmarks b
 		LEFT JOIN bookmark_tags bt ON b.id = bt.bookmark
 		LEFT JOIN tags t ON bt.tag = t.id
-	WHERE b.lower_name LIKE ?
-		OR b.description_lower LIKE ?
-		OR b.content LIKE ?
-		OR b.project LIKE ?
-		OR t.name LIKE ?
+	WHERE (b.lower_name LIKE ? ESCAPE '\'
+		OR b.description_lower LIKE ? ESCAPE '\'
+		OR b.content LIKE ? ESCAPE '\'
+		OR b.project LIKE ? ESCAPE '\'
+		OR b.notes LIKE ? ESCAPE '\'
+		OR t.name LIKE ? ESCAPE '\')
+		AND b.deleted_at IS NULL
+	GROUP BY b.id
 ) AS a
 GROUP BY a.id
-ORDER BY a.name ASC
-LIMIT 300;
+ORDER BY a.name ASC, a.id ASC
 `
 
 	ftsQuery := `
 SELECT
 id, name, description, content,
-       project, created_at, updated_at, archived, tags
+       project, created_at, updated_at, archived, state, priority, due_at, notes, tags
 FROM (
 -- bookmarks fts
 SELECT
@@ -532,10 +627,15 @@ SELECT
     b.created_at AS created_at,
     b.updated_at AS updated_at,
     b.archived AS archived,
+    b.state AS state,
+    b.priority AS priority,
+    b.due_at AS due_at,
+    b.notes AS notes,
     '' as tags
 FROM bookmark_fts
 JOIN bookmarks b ON bookmark_fts.id = b.id
 WHERE bookmark_fts MATCH '' || ? || ''
+    AND b.deleted_at IS NULL
 -- metadata fts
 UNION
 SELECT
@@ -547,10 +647,14 @@ SELECT
     b.created_at AS created_at,
     b.updated_at AS updated_at,
     b.archived as archived,
+    b.state AS state,
+    b.priority AS priority,
+    b.due_at AS due_at,
+    b.notes AS notes,
     -- skip tags for now
     '' AS tags
 FROM bookmarks b
-WHERE b.id IN (
+WHERE b.deleted_at IS NULL AND b.id IN (
     SELECT
         id
     FROM metadata_fts 
@@ -558,18 +662,33 @@ WHERE b.id IN (
 ORDER BY rank DESC))
 GROUP BY id`
 
-	var err error
-	var rows *sql.Rows
-	if config.Configuration.EnableFullTextSearch {
-		//text = "'" + text + "'"
-		rows, err = d.conn.Query(ftsQuery, text, text)
-	} else {
-		text = "%" + text + "%"
-		rows, err = d.conn.Query(plainQuery, text, text, text, text, text, text, text)
+	query := ftsQuery
+	match := ftsTerm(text)
+	args := []interface{}{match, match}
+	if !d.options.FullTextSearch {
+		query = plainQuery
+		if len(text) > 1 {
+			text = strings.TrimSuffix(text, "*")
+		}
+		text = likePattern(text)
+		args = []interface{}{text, text, text, text, text, text, text}
+	}
 
+	total := 0
+	countQuery := "SELECT COUNT(*) FROM (" + query + ")"
+	logger := beginQuery(countQuery, "count search results")
+	err = d.conn.Get(&total, countQuery, args...)
+	logger.log(err)
+	if err != nil {
+		return nil, 0, err
 	}
+
+	query = "SELECT * FROM (" + query + ")" + pageSql(offset, limit)
+	logger = beginQuery(query, "search bookmarks")
+	rows, err := d.conn.Query(query, args...)
+	logger.log(err)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	bookmarks := []*models.Bookmark{}
@@ -578,7 +697,8 @@ GROUP BY id`
 		var tag sql.NullString
 		b := models.Bookmark{}
 
-		err := rows.Scan(&b.Id, &b.Name, &b.Description, &b.Content, &b.Project, &b.CreatedAt, &b.UpdatedAt, &b.Archived, &tag)
+		err := rows.Scan(&b.Id, &b.Name, &b.Description, &b.Content, &b.Project, &b.CreatedAt, &b.UpdatedAt, &b.Archived,
+			&b.State, &b.Priority, &b.DueAt, &b.Notes, &tag)
 		if err != nil {
 			logrus.Errorf("scan bookmark rows: %v", err)
 			err = rows.Close()
@@ -591,7 +711,21 @@ GROUP BY id`
 
 		bookmarks = append(bookmarks, &b)
 	}
-	return bookmarks, nil
+	return bookmarks, total, nil
+}
+
+//ftsTerm quotes single search term for fts5 MATCH, so that operators and punctuation in term
+//are searched as text. Trailing '*' is kept as prefix search.
+func ftsTerm(text string) string {
+	prefix := len(text) > 1 && strings.HasSuffix(text, "*")
+	if prefix {
+		text = strings.TrimSuffix(text, "*")
+	}
+	text = `"` + strings.Replace(text, `"`, `""`, -1) + `"`
+	if prefix {
+		text += "*"
+	}
+	return text
 }
 
 //UpdateBookmark updates all fields on bookmark
@@ -605,38 +739,47 @@ UPDATE bookmarks SEt
 		content = ?,
 		project = ?,
 		updated_at = ?,
-		archived = ?
+		archived = ?,
+		state = ?,
+		priority = ?,
+		due_at = ?,
+		notes = ?
 WHERE id = ?;
 `
-	_, err := d.conn.Exec(query, b.Name, b.LowerName, b.Description, strings.ToLower(b.Description),
-		b.Content, strings.ToLower(b.Project), b.UpdatedAt, b.Archived, b.Id)
-
-	if err != nil {
-		return err
-	}
+	return d.journaled(fmt.Sprintf("update bookmark '%s'", b.Name), []int{b.Id}, func(tx *sqlx.Tx) ([]int, error) {
+		_, err := tx.Exec(query, b.Name, b.LowerName, b.Description, strings.ToLower(b.Description),
+			b.Content, strings.ToLower(b.Project), b.UpdatedAt, b.Archived, b.ReadState(), b.Priority, b.DueAt, b.Notes, b.Id)
+		if err != nil {
+			return nil, err
+		}
 
-	err = d.upsertMetadata(b)
-	if err != nil {
-		return err
-	}
+		err = upsertMetadata(tx, b.Id, bookmarkMetadata(b))
+		if err != nil {
+			return nil, err
+		}
 
-	if len(b.Tags) > 0 {
-		err = d.InsertTags(b.Tags, nil)
+		err = d.InsertTags(b.Tags, tx)
 		if err != nil {
-			logrus.Errorf("Insert tags: %v", err)
-		} else {
-			err = d.UpdateBookmarkTags(b, b.Tags, nil)
-			if err != nil {
-				logrus.Errorf("Update bookmark tags: %v", err)
-			}
+			return nil, fmt.Errorf("insert tags: %v", err)
 		}
-	}
+		err = updateBookmarkTags(tx, b.Id, b.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("update bookmark tags: %v", err)
+		}
+		return nil, nil
+	})
+}
 
-	return err
+//bookmarkMetadata returns metadata of bookmark or nil if bookmark has no metadata
+func bookmarkMetadata(b *models.Bookmark) map[string]string {
+	if b.Metadata == nil {
+		return nil
+	}
+	return *b.Metadata
 }
 
 //UpsertMetadata upserts (insert / update) metadata
-func (d *Database) upsertMetadata(b *models.Bookmark) error {
+func upsertMetadata(e sqlx.Execer, id int, metadata map[string]string) error {
 	query := `
 INSERT INTO metadata
 (bookmark, key, key_lower, value, value_lower)
@@ -649,90 +792,390 @@ WHERE bookmark = ?
 	logger := beginQuery(query, "update/insert bookmark metadata")
 	var err error
 
-	for key, value := range *b.Metadata {
+	for key, value := range metadata {
 		keyLower := strings.ToLower(key)
 		valueLower := strings.ToLower(value)
 
-		_, err = d.conn.Exec(query, b.Id, key, keyLower, value, valueLower, value, valueLower, b.Id)
+		_, err = e.Exec(query, id, key, keyLower, value, valueLower, value, valueLower, id)
 		if err != nil {
-			logrus.Errorf("Failed to insert/update metadata: %v", err)
+			break
 		}
 	}
 	logger.log(err)
-	return nil
+	return err
 }
 
 //InsertTag inserts tag for bookmark
 func (d *Database) InsertTags(tags []string, tx *sqlx.Tx) error {
-	query := "INSERT INTO tags (name) VALUES "
+	//Max variables for sqlite is 999
+	batchSize := 500
+	for len(tags) > 0 {
+		batch := tags
+		if len(batch) > batchSize {
+			batch = tags[:batchSize]
+		}
+		tags = tags[len(batch):]
 
-	args := make([]interface{}, len(tags))
-	for i, v := range tags {
-		if i > 0 {
-			query += ","
+		query := "INSERT INTO tags (name) VALUES "
+		args := make([]interface{}, len(batch))
+		for i, v := range batch {
+			if i > 0 {
+				query += ","
+			}
+			query += "(?) "
+			args[i] = v
 		}
-		query += "(?) "
-		args[i] = v
-	}
-	query += " ON CONFLICT (name) DO NOTHING;"
+		query += " ON CONFLICT (name) DO NOTHING;"
 
-	var err error
+		var err error
+		if tx != nil {
+			_, err = tx.Exec(query, args...)
+		} else {
+			_, err = d.conn.Exec(query, args...)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+//UpdateBookmarkTags sets bookmark tags to given tags, removing any other tags from bookmark.
+//Tags must exist before calling this function. If tx is nil, use database connection directly
+//and record change to journal. Otherwise caller is responsible for journaling the change.
+func (d *Database) UpdateBookmarkTags(bookmark *models.Bookmark, tags []string, tx *sqlx.Tx) error {
 	if tx != nil {
-		_, err = tx.Exec(query, args...)
-	} else {
-		_, err = d.conn.Exec(query, args...)
+		return updateBookmarkTags(tx, bookmark.Id, tags)
 	}
-	return err
+	operation := fmt.Sprintf("update tags of '%s'", bookmark.Name)
+	return d.journaled(operation, []int{bookmark.Id}, func(tx *sqlx.Tx) ([]int, error) {
+		return nil, updateBookmarkTags(tx, bookmark.Id, tags)
+	})
 }
 
-//AddTagsToBookmark adds tags to bookmark. Tags must exist before calling this function.
-//If tx is nil, use database connection directly
-func (d *Database) UpdateBookmarkTags(bookmark *models.Bookmark, tags []string, tx *sqlx.Tx) error {
-	query := `DELETE FROM main.bookmark_tags WHERE bookmark_tags.bookmark = ?; INSERT INTO bookmark_tags (bookmark, tag)
-	SELECT
-		bookmark,
-		tag.id AS tag
-		FROM (SELECT ? AS bookmark) 
-		LEFT JOIN (SELECT id FROM tags WHERE name IN (`
+func updateBookmarkTags(e sqlx.Execer, id int, tags []string) error {
+	query := `DELETE FROM bookmark_tags WHERE bookmark_tags.bookmark = ?;`
+	args := []interface{}{id}
 
-	args := make([]interface{}, len(tags)+2)
-	args[0] = bookmark.Id
-	for i, v := range tags {
-		if i > 0 {
-			query += ","
+	if len(tags) > 0 {
+		query += `
+INSERT OR IGNORE INTO bookmark_tags (bookmark, tag)
+SELECT ?, id FROM tags WHERE name IN (`
+		args = append(args, id)
+		for i, v := range tags {
+			if i > 0 {
+				query += ","
+			}
+			query += "?"
+			args = append(args, v)
 		}
-		query += "?"
-		args[i+1] = v
+		query += ");"
 	}
 
-	args[len(args)-1] = bookmark.Id
-	query += ")) AS tag;"
-
-	var err error
-	if tx != nil {
-		_, err = tx.Exec(query, args...)
-	} else {
-		_, err = d.conn.Exec(query, args...)
-	}
+	_, err := e.Exec(query, args...)
 	return err
 }
 
 //RenameProject renames project and all its children.
 // e.g. old: my-awesome-project, new: project:
 // results in my-awesome-project.a -> project.a
-func (d *Database) RenameProject(old string, new string) error {
-	return nil
+// Renaming fails if new project already exists, use MergeProject to combine projects.
+// Return number of bookmarks modified.
+func (d *Database) RenameProject(old string, new string) (int, error) {
+	return d.renameProject(old, new, false)
+}
+
+//MoveProject moves project and all its children under parent, keeping project name.
+// e.g. name: a.b, parent: c results in a.b.d -> c.b.d. Empty parent moves project to top level.
+func (d *Database) MoveProject(name string, parent string) (int, error) {
+	return d.renameProject(name, movedProject(name, parent), false)
+}
+
+//movedProject returns name of project after moving it under parent
+func movedProject(name string, parent string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	parent = strings.ToLower(strings.TrimSpace(parent))
+	target := name[strings.LastIndex(name, ".")+1:]
+	if parent != "" {
+		target = parent + "." + target
+	}
+	return target
+}
+
+//MergeProject moves bookmarks of source project and all its children into target project,
+//which may already exist. e.g. source: a, target: b results in a.c -> b.c
+func (d *Database) MergeProject(source string, target string) (int, error) {
+	return d.renameProject(source, target, true)
+}
+
+func (d *Database) renameProject(old string, new string, merge bool) (int, error) {
+	old = strings.ToLower(strings.TrimSpace(old))
+	new = strings.ToLower(strings.TrimSpace(new))
+	err := validateProjectRename(old, new)
+	if err != nil {
+		return 0, err
+	}
+
+	// project itself or its children, imported projects may have upper case letters
+	where := "LOWER(project) = ? OR LOWER(project) LIKE ? ESCAPE '\\'"
+
+	tx, err := d.conn.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %v", err)
+	}
+
+	if !merge {
+		var count int
+		err = tx.Get(&count, "SELECT COUNT(*) FROM bookmarks WHERE "+where, new, likePrefix(new+"."))
+		if err != nil {
+			_ = tx.Rollback()
+			return 0, err
+		}
+		if count > 0 {
+			_ = tx.Rollback()
+			return 0, fmt.Errorf("project '%s' already exists", new)
+		}
+	}
+
+	ids := []int{}
+	err = tx.Select(&ids, "SELECT id FROM bookmarks WHERE "+where, old, likePrefix(old+"."))
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+	before, err := loadImages(tx, ids)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+
+	query := `
+UPDATE bookmarks SET
+	project = ? || LOWER(SUBSTR(project, ?)),
+	updated_at = ?
+WHERE ` + where
 
+	logger := beginQuery(query, "rename project")
+	res, err := tx.Exec(query, new, utf8.RuneCountInString(old)+1, time.Now(), old, likePrefix(old+"."))
+	if err != nil {
+		_ = tx.Rollback()
+		logger.log(err)
+		return 0, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+		logger.log(err)
+		return 0, err
+	}
+	if count == 0 {
+		_ = tx.Rollback()
+		logger.log(nil)
+		return 0, fmt.Errorf("project '%s' not found", old)
+	}
+
+	after, err := loadImages(tx, ids)
+	if err == nil {
+		operation := "rename"
+		if merge {
+			operation = "merge"
+		}
+		err = recordJournal(tx, fmt.Sprintf("%s project '%s' to '%s'", operation, old, new), before, after)
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		logger.log(err)
+		return 0, err
+	}
+
+	err = tx.Commit()
+	logger.log(err)
+	return int(count), err
+}
+
+//validateProjectRename checks that project can be renamed
+func validateProjectRename(old string, new string) error {
+	if old == "" || new == "" {
+		return fmt.Errorf("project name cannot be empty")
+	}
+	if strings.HasPrefix(new, ".") || strings.HasSuffix(new, ".") || strings.Contains(new, "..") {
+		return fmt.Errorf("invalid project name '%s'", new)
+	}
+	if old == new {
+		return fmt.Errorf("project '%s' is already named '%s'", old, new)
+	}
+	if strings.HasPrefix(new, old+".") {
+		return fmt.Errorf("cannot move project '%s' into itself", old)
+	}
+	return nil
 }
 
-//DeleteBookmark deletes bookmark and its metadata
+//DeleteBookmark moves bookmark to trash. Deleted bookmark is hidden until it is restored or purged.
 func (d *Database) DeleteBookmark(bookmark *models.Bookmark) error {
+	operation := fmt.Sprintf("delete bookmark '%s'", bookmark.Name)
+	return d.journaled(operation, []int{bookmark.Id}, func(tx *sqlx.Tx) ([]int, error) {
+		_, err := tx.Exec("UPDATE bookmarks SET deleted_at = ? WHERE id = ?", time.Now(), bookmark.Id)
+		return nil, err
+	})
+}
+
+//SetMetadata upserts metadata of multiple bookmarks, keyed by bookmark id, in single transaction.
+//Other metadata of bookmarks is not modified. Changes are not journaled, so that e.g. link checks
+//do not replace user's changes in undo history.
+func (d *Database) SetMetadata(metadata map[int]map[string]string) error {
+	if len(metadata) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(metadata))
+	for id := range metadata {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	tx, err := d.conn.Beginx()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %v", err)
+	}
+	for _, id := range ids {
+		err = upsertMetadata(tx, id, metadata[id])
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("transaction failed: %v", err)
+	}
+	return nil
+}
+
+//RestoreBookmark restores bookmark from trash
+func (d *Database) RestoreBookmark(bookmark *models.Bookmark) error {
+	operation := fmt.Sprintf("restore bookmark '%s'", bookmark.Name)
+	return d.journaled(operation, []int{bookmark.Id}, func(tx *sqlx.Tx) ([]int, error) {
+		_, err := tx.Exec("UPDATE bookmarks SET deleted_at = NULL WHERE id = ?", bookmark.Id)
+		return nil, err
+	})
+}
+
+//PurgeBookmark deletes bookmark permanently along with its metadata, tags, snapshots and revisions.
+//Purging cannot be undone.
+func (d *Database) PurgeBookmark(bookmark *models.Bookmark) error {
+	return d.purge([]int{bookmark.Id})
+}
+
+//PurgeDeleted permanently deletes bookmarks that were moved to trash before given time.
+//Purging cannot be undone. Return number of bookmarks purged.
+func (d *Database) PurgeDeleted(before time.Time) (int, error) {
+	params := []interface{}{}
+	query := "SELECT b.id FROM bookmarks b WHERE b.deleted_at IS NOT NULL AND " +
+		dateCondition("b.deleted_at", false, before, &params)
+
+	logger := beginQuery(query, "purge deleted bookmarks")
+	ids := []int{}
+	err := d.conn.Select(&ids, query, params...)
+	if err != nil || len(ids) == 0 {
+		logger.log(err)
+		return 0, err
+	}
+
+	err = d.purge(ids)
+	logger.log(err)
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+//purge deletes bookmarks permanently. Purging is not journaled, and bookmarks are removed from
+//journal so that undoing earlier operations does not bring them back.
+func (d *Database) purge(ids []int) error {
+	tx, err := d.conn.Beginx()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %v", err)
+	}
+	for _, id := range ids {
+		err = deleteBookmark(tx, id)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	err = forgetJournal(tx, ids)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("transaction failed: %v", err)
+	}
+	return nil
+}
+
+//GetDeletedBookmarks returns bookmarks in trash, latest deleted first
+func (d *Database) GetDeletedBookmarks() ([]*models.Bookmark, error) {
 	query := `
+SELECT
+	b.id AS id,
+	b.name AS name,
+	b.description AS description,
+	b.content AS content,
+	b.project AS project,
+	b.created_at AS created_at,
+	b.updated_at AS updated_at,
+	b.archived AS archived,
+	b.state AS state,
+	b.priority AS priority,
+	b.due_at AS due_at,
+	b.notes AS notes,
+	b.deleted_at AS deleted_at,
+	(SELECT GROUP_CONCAT(t.name) FROM bookmark_tags bt
+		JOIN tags t ON bt.tag = t.id
+		WHERE bt.bookmark = b.id) AS tags
+FROM bookmarks b
+WHERE b.deleted_at IS NOT NULL
+ORDER BY b.deleted_at DESC`
+
+	logger := beginQuery(query, "get deleted bookmarks")
+	rows, err := d.conn.Query(query)
+	if err != nil {
+		logger.log(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	bookmarks := []*models.Bookmark{}
+	for rows.Next() {
+		var tags sql.NullString
+		b := &models.Bookmark{}
+		err = rows.Scan(&b.Id, &b.Name, &b.Description, &b.Content, &b.Project, &b.CreatedAt, &b.UpdatedAt,
+			&b.Archived, &b.State, &b.Priority, &b.DueAt, &b.Notes, &b.DeletedAt, &tags)
+		if err != nil {
+			logger.log(err)
+			return bookmarks, err
+		}
+		if tags.String != "" {
+			b.Tags = strings.Split(tags.String, ",")
+		}
+		bookmarks = append(bookmarks, b)
+	}
+	logger.log(nil)
+	return bookmarks, nil
+}
+
+//deleteBookmark deletes bookmark, its metadata, snapshots and tag relations
+func deleteBookmark(e sqlx.Execer, id int) error {
+	query := `
+DELETE FROM bookmark_tags WHERE bookmark = ?;
+DELETE FROM metadata WHERE bookmark = ?;
+DELETE FROM snapshots WHERE bookmark = ?;
+DELETE FROM bookmark_revisions WHERE bookmark = ?;
 DELETE FROM bookmarks
 WHERE bookmarks.id = ?`
 
-	_, err := d.conn.Exec(query, bookmark.Id)
+	_, err := e.Exec(query, id, id, id, id, id)
 	return err
 }
 
@@ -743,38 +1186,57 @@ func (d *Database) GetStatistics() (*Statistics, error) {
 	query := `
 	SELECT
 		COUNT(b.id) AS bookmarks,
-		(SELECT count(id) FROM bookmarks WHERE archived=true) AS archived,
-		COUNT(DISTINCT(b.project)) AS projects
-	FROM bookmarks b`
+		(SELECT count(id) FROM bookmarks WHERE archived=true AND deleted_at IS NULL) AS archived,
+		COUNT(DISTINCT(LOWER(b.project))) AS projects,
+		(SELECT COUNT(DISTINCT bt.tag) FROM bookmark_tags bt
+			JOIN bookmarks tb ON bt.bookmark = tb.id
+			WHERE tb.deleted_at IS NULL) AS tags,
+		(SELECT count(id) FROM bookmarks WHERE deleted_at IS NOT NULL) AS deleted
+	FROM bookmarks b
+	WHERE b.deleted_at IS NULL`
 
 	rows, err := d.conn.Query(query)
 	if err != nil {
-		e := rows.Close()
-		if e != nil {
-			err = fmt.Errorf("%v; %v", err, e)
-		}
 		return s, err
 	}
 
 	rows.Next()
-	err = rows.Scan(&s.Bookmarks, &s.Archived, &s.Projects)
+	err = rows.Scan(&s.Bookmarks, &s.Archived, &s.Projects, &s.Tags, &s.Deleted)
+	rows.Close()
 	if err != nil {
 		return s, err
 	}
 
+	query = `
+	SELECT
+		COALESCE(SUM(CASE WHEN state = ? THEN 1 ELSE 0 END), 0) AS unread,
+		COALESCE(SUM(CASE WHEN state = ? THEN 1 ELSE 0 END), 0) AS reading,
+		COALESCE(SUM(CASE WHEN state = ? THEN 1 ELSE 0 END), 0) AS read,
+		COALESCE(SUM(CASE WHEN state != ? AND datetime(due_at) < ? THEN 1 ELSE 0 END), 0) AS overdue
+	FROM bookmarks
+	WHERE deleted_at IS NULL`
+
+	err = d.conn.QueryRow(query, models.StateUnread, models.StateReading, models.StateRead, models.StateRead,
+		time.Now().UTC().Format(sqlTimeFormat)).Scan(&s.Unread, &s.Reading, &s.Read, &s.Overdue)
+	if err != nil {
+		return s, fmt.Errorf("count reading list: %v", err)
+	}
+
 	query = `
 SELECT b.created_at
 FROM bookmarks b
+WHERE b.deleted_at IS NULL
 ORDER BY b.created_at DESC
 LIMIT 1
 `
 	rows, err = d.conn.Query(query)
 	if err != nil {
-		rows.Close()
 		return s, err
 	}
-	rows.Next()
-	err = rows.Scan(&s.LastBookmark)
+	if rows.Next() {
+		err = rows.Scan(&s.LastBookmark)
+	}
+	rows.Close()
 
 	s.FullTextSearchSupported, err = d.FullTextSearchSupported()
 
@@ -800,9 +1262,11 @@ func (d *Database) FilterBookmarks(filter *Filter) ([]*models.Bookmark, error) {
 	bookmarks := []*models.Bookmark{}
 	for rows.Next() {
 		var tag sql.NullString
+		var linkStatus sql.NullString
 		b := models.Bookmark{}
 
-		err := rows.Scan(&b.Id, &b.Name, &b.Description, &b.Content, &b.Project, &b.CreatedAt, &b.UpdatedAt, &b.Archived, &tag)
+		err := rows.Scan(&b.Id, &b.Name, &b.Description, &b.Content, &b.Project, &b.CreatedAt, &b.UpdatedAt, &b.Archived,
+			&b.State, &b.Priority, &b.DueAt, &b.Notes, &tag, &linkStatus)
 		if err != nil {
 			logrus.Errorf("scan bookmark rows: %v", err)
 			err = rows.Close()
@@ -812,6 +1276,7 @@ func (d *Database) FilterBookmarks(filter *Filter) ([]*models.Bookmark, error) {
 		if tag.String != "" {
 			b.Tags = strings.Split(tag.String, ",")
 		}
+		b.LinkStatus = linkStatus.String
 
 		bookmarks = append(bookmarks, &b)
 	}
@@ -819,17 +1284,61 @@ func (d *Database) FilterBookmarks(filter *Filter) ([]*models.Bookmark, error) {
 	return bookmarks, nil
 }
 
+//BookmarksPage returns bookmarks that match filter starting from filter.Offset, and total number of
+//bookmarks that match filter. Plain query is a full text search.
+func (d *Database) BookmarksPage(filter *Filter) ([]*models.Bookmark, int, error) {
+	if filter.IsPlainQuery() {
+		return d.SearchBookmarksPage(filter.Query, filter.Offset, filter.Limit)
+	}
+	total, err := d.CountBookmarks(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	bookmarks, err := d.FilterBookmarks(filter)
+	return bookmarks, total, err
+}
+
+//ExportBookmarks returns all bookmarks that match filter with their metadata, regardless of filter limit.
+//Plain query is a full text search, and its results are returned without highlighting.
+func (d *Database) ExportBookmarks(filter *Filter) ([]*models.Bookmark, error) {
+	var bookmarks []*models.Bookmark
+	var err error
+	if filter.IsPlainQuery() {
+		bookmarks, err = d.SearchBookmarks(filter.Query)
+		for i := 0; err == nil && i < len(bookmarks); i++ {
+			bookmarks[i], err = d.GetBookmark(bookmarks[i].Id)
+		}
+	} else {
+		limit := filter.Limit
+		filter.Limit = -1
+		bookmarks, err = d.FilterBookmarks(filter)
+		filter.Limit = limit
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range bookmarks {
+		err = d.GetBookmarkMetadata(v)
+		if err != nil {
+			return nil, fmt.Errorf("get metadata: %v", err)
+		}
+	}
+	return bookmarks, nil
+}
+
 //SearchKeyValue searches any key-value item for bookmark
 func (d *Database) SearchKeyValue(key, value string) ([]string, error) {
 	key = strings.ToLower(key)
 	value = "%" + strings.ToLower(value) + "%"
-	limit := config.Configuration.AutoCompleteMaxResults
+	limit := d.options.AutoCompleteMaxResults
 
 	query := `
 SELECT value_lower
 FROM metadata
 WHERE key_lower = ? 
 AND value_lower LIKE ?
+AND bookmark IN (SELECT id FROM bookmarks WHERE deleted_at IS NULL)
 GROUP BY value_lower 
 ORDER BY value_lower ASC
 LIMIT ?;`
@@ -837,11 +1346,12 @@ LIMIT ?;`
 	//TODO: user filter for queries
 	if key == "project" {
 		query = `
-SELECT project
+SELECT LOWER(project)
 FROM bookmarks
 WHERE project LIKE ? 
-GROUP BY project
-ORDER BY project ASC
+AND deleted_at IS NULL
+GROUP BY LOWER(project)
+ORDER BY LOWER(project) ASC
 LIMIT ?;`
 	}
 
@@ -876,20 +1386,85 @@ LIMIT ?;`
 	return results, nil
 }
 
-//Bulk modify modifies multple bookmarks defined with filter to state defined in modifier
+//Bulk modify modifies multple bookmarks defined with filter to state defined in modifier.
+//All changes are made in single transaction. Return number of bookmarks modified.
 func (d *Database) BulkModify(filter *Filter, modifier *Modifier) (int, error) {
-	query, params, err := filter.bulkUpdateQuery(modifier)
+	if modifier.IsEmpty() {
+		return 0, fmt.Errorf("empty modifier")
+	}
+	query, params, err := filter.idsQuery()
 	if err != nil {
 		return 0, err
 	}
 
-	res, err := d.conn.Exec(query, *params...)
+	logger := beginQuery(query, "bulk modify")
+	tx, err := d.conn.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %v", err)
+	}
+
+	ids := []int{}
+	err = tx.Select(&ids, query, *params...)
 	if err != nil {
+		_ = tx.Rollback()
+		logger.log(err)
 		return 0, err
 	}
+	if len(ids) == 0 {
+		_ = tx.Rollback()
+		logger.log(nil)
+		return 0, nil
+	}
 
-	count, err := res.RowsAffected()
-	return int(count), err
+	before, err := loadImages(tx, ids)
+	if err != nil {
+		_ = tx.Rollback()
+		logger.log(err)
+		return 0, err
+	}
+
+	err = d.InsertTags(modifier.AddTags, tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, fmt.Errorf("insert tags: %v", err)
+	}
+
+	//Max variables for sqlite is 999
+	batchSize := 500
+	updatedAt := time.Now()
+	for left := ids; len(left) > 0; {
+		batch := left
+		if len(batch) > batchSize {
+			batch = left[:batchSize]
+		}
+		left = left[len(batch):]
+
+		for _, s := range modifier.statements(batch, updatedAt) {
+			_, err = tx.Exec(s.query, s.args...)
+			if err != nil {
+				_ = tx.Rollback()
+				logger.log(err)
+				return 0, err
+			}
+		}
+	}
+
+	after, err := loadImages(tx, ids)
+	if err == nil {
+		err = recordJournal(tx, fmt.Sprintf("modify %d bookmarks", len(ids)), before, after)
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		logger.log(err)
+		return 0, err
+	}
+
+	err = tx.Commit()
+	logger.log(err)
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
 }
 
 // FilterProject filters projects by given filter. If only filter.Project is defined
@@ -897,7 +1472,7 @@ func (d *Database) BulkModify(filter *Filter, modifier *Modifier) (int, error) {
 func (d *Database) FilterProject(filter *Filter) ([]*models.Project, error) {
 	query := `
 	SELECT 
-	project,
+	LOWER(project) AS project,
 	count(id) as count
 	FROM (
 	`
@@ -908,7 +1483,7 @@ func (d *Database) FilterProject(filter *Filter) ([]*models.Project, error) {
 	}
 
 	query += q
-	query += ") GROUP BY project ORDER BY project ASC"
+	query += ") GROUP BY LOWER(project) ORDER BY LOWER(project) ASC"
 
 	logger := beginQuery(query, "filter projects")
 
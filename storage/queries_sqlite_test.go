@@ -0,0 +1,154 @@
+//go:build fts5
+// +build fts5
+
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+func TestDatabase_SearchBookmarks(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{query: "alpha", want: []int{1}},
+		{query: "alph*", want: []int{1}},
+		{query: "-alpha", want: []int{2, 3, 4}},
+		{query: "NOT alpha", want: []int{2, 3, 4}},
+		{query: "alpha OR beta", want: []int{1, 2}},
+		{query: `"alpha one"`, want: []int{1}},
+		{query: "alpha one", want: []int{1}},
+		{query: "one two", want: []int{}},
+		{query: "foo-bar", want: []int{3}},
+		{query: `"foo-bar" OR gamma`, want: []int{3, 4}},
+		{query: "100%", want: []int{}},
+		{query: "kernel", want: []int{4}},
+	}
+
+	for _, fts := range []bool{true, false} {
+		db, cleanup := newTestDatabase(t, fts)
+		addTestBookmarks(t, db,
+			&models.Bookmark{Name: "Alpha one"},
+			&models.Bookmark{Name: "Beta two"},
+			&models.Bookmark{Name: "foo-bar thing"},
+			&models.Bookmark{Name: "Gamma", Notes: "# Kernel\n- notes"},
+		)
+
+		for _, tt := range tests {
+			bookmarks, err := db.SearchBookmarks(tt.query)
+			if err != nil {
+				t.Errorf("fts %t: SearchBookmarks(%s) error: %v", fts, tt.query, err)
+				continue
+			}
+			if got := bookmarkIds(bookmarks); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fts %t: SearchBookmarks(%s) = %v, want %v", fts, tt.query, got, tt.want)
+			}
+
+			filter, err := NewFilter(tt.query)
+			if err != nil {
+				t.Fatalf("NewFilter(%s): %v", tt.query, err)
+			}
+			bookmarks, total, err := db.BookmarksPage(filter)
+			if err != nil {
+				t.Errorf("fts %t: BookmarksPage(%s) error: %v", fts, tt.query, err)
+				continue
+			}
+			if got := bookmarkIds(bookmarks); !reflect.DeepEqual(got, tt.want) || total != len(tt.want) {
+				t.Errorf("fts %t: BookmarksPage(%s) = %v (total %d), want %v", fts, tt.query, got, total, tt.want)
+			}
+		}
+		cleanup()
+	}
+}
+
+func TestDatabase_RenameProject_mixedCase(t *testing.T) {
+	db, cleanup := newTestDatabase(t, true)
+	defer cleanup()
+	addTestBookmarks(t, db,
+		&models.Bookmark{Name: "a"},
+		&models.Bookmark{Name: "b"},
+		&models.Bookmark{Name: "c"},
+		&models.Bookmark{Name: "d", Project: "other"},
+	)
+	// projects of bookmarks imported by earlier versions keep their case
+	imported := []string{"Toolbar", "Toolbar.Dev", "toolbar.dev.Go", "other"}
+	for i, v := range imported {
+		_, err := db.conn.Exec("UPDATE bookmarks SET project = ? WHERE id = ?", v, i+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	projects, err := db.GetAllProjects("", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, v := range projects {
+		names = append(names, v.FullName())
+	}
+	if want := []string{"other", "toolbar"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("GetAllProjects() = %v, want %v", names, want)
+	}
+	projects, err = db.GetAllProjects("TOOLBAR.dev", true)
+	if err != nil || len(projects) != 1 || projects[0].TotalCount() != 1 {
+		t.Errorf("GetAllProjects(strict) = %v, %v, want toolbar.dev", projects, err)
+	}
+
+	count, err := db.RenameProject("Toolbar", "work")
+	if err != nil || count != 3 {
+		t.Fatalf("RenameProject() = %d, %v, want 3", count, err)
+	}
+	if got, want := testProjects(t, db), []string{"work", "work.dev", "work.dev.go", "other"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("projects after rename = %v, want %v", got, want)
+	}
+
+	_, err = db.Undo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := testProjects(t, db); !reflect.DeepEqual(got, imported) {
+		t.Errorf("projects after undo = %v, want %v", got, imported)
+	}
+
+	count, err = db.MergeProject("Toolbar.Dev", "Other")
+	if err != nil || count != 2 {
+		t.Fatalf("MergeProject() = %d, %v, want 2", count, err)
+	}
+	if got, want := testProjects(t, db), []string{"Toolbar", "other", "other.go", "other"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("projects after merge = %v, want %v", got, want)
+	}
+
+	_, err = db.RenameProject("toolbar", "OTHER")
+	if err == nil {
+		t.Errorf("RenameProject() to existing project did not fail")
+	}
+
+	_, err = db.ImportBookmarks([]*models.Bookmark{{Name: "e", Content: "https://e.org", Project: "Bookmarks.Menu"}},
+		nil, DuplicateCreate, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := testProjects(t, db); got[len(got)-1] != "bookmarks.menu" {
+		t.Errorf("imported project = %s, want bookmarks.menu", got[len(got)-1])
+	}
+}
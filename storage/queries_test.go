@@ -0,0 +1,47 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import "testing"
+
+func Test_validateProjectRename(t *testing.T) {
+	tests := []struct {
+		old     string
+		new     string
+		wantErr bool
+	}{
+		{old: "a", new: "b"},
+		{old: "a.b", new: "c"},
+		{old: "a", new: "ab"},
+		{old: "a.b", new: "a"},
+		{old: "a", new: "a.b", wantErr: true},
+		{old: "a", new: "a", wantErr: true},
+		{old: "", new: "a", wantErr: true},
+		{old: "a", new: "", wantErr: true},
+		{old: "a", new: "b.", wantErr: true},
+		{old: "a", new: ".b", wantErr: true},
+		{old: "a", new: "b..c", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.old+" -> "+tt.new, func(t *testing.T) {
+			err := validateProjectRename(tt.old, tt.new)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateProjectRename() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
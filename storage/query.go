@@ -0,0 +1,884 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+	"unicode"
+)
+
+//QueryError is a syntax error in filter query. Pos is 1-based character position in query.
+type QueryError struct {
+	Pos int
+	Msg string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+func queryError(pos int, format string, args ...interface{}) *QueryError {
+	return &QueryError{Pos: pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	// bare word
+	tokenWord
+	// quoted phrase
+	tokenPhrase
+	// key:value pair
+	tokenField
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLeftParen
+	tokenRightParen
+)
+
+type token struct {
+	typ tokenType
+	// word, phrase or field value
+	text string
+	// field key in lower case
+	key string
+	// field key as written
+	rawKey string
+	// value was quoted
+	quoted bool
+	pos    int
+	// position of field value
+	valuePos int
+}
+
+func (t token) String() string {
+	switch t.typ {
+	case tokenEOF:
+		return "end of query"
+	case tokenField:
+		return fmt.Sprintf("'%s:%s'", t.key, t.text)
+	default:
+		return fmt.Sprintf("'%s'", t.text)
+	}
+}
+
+//lexer splits query into tokens
+type lexer struct {
+	input []rune
+	pos   int
+}
+
+func isQuote(r rune) bool {
+	return r == '"' || r == '\''
+}
+
+//isWordEnd returns true if rune at offset ends bare word. ':' followed by '//' starts url,
+//e.g. 'https://example.com/a:b', and any ':' in url is part of word instead of starting field.
+func (l *lexer) isWordEnd(offset int, url bool) bool {
+	r := l.peek(offset)
+	if r == ':' {
+		return !url && (l.peek(offset+1) != '/' || l.peek(offset+2) != '/')
+	}
+	return unicode.IsSpace(r) || r == '(' || r == ')'
+}
+
+func (l *lexer) peek(offset int) rune {
+	if l.pos+offset >= len(l.input) {
+		return 0
+	}
+	return l.input[l.pos+offset]
+}
+
+//tokens returns all tokens in input, last one being tokenEOF
+func (l *lexer) tokens() ([]token, error) {
+	tokens := []token{}
+	for {
+		t, err := l.next()
+		if err != nil {
+			return tokens, err
+		}
+		tokens = append(tokens, t)
+		if t.typ == tokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.input) && unicode.IsSpace(l.input[l.pos]) {
+		l.pos += 1
+	}
+	start := l.pos
+	if l.pos >= len(l.input) {
+		return token{typ: tokenEOF, pos: start}, nil
+	}
+
+	r := l.input[l.pos]
+	switch {
+	case r == '(':
+		l.pos += 1
+		return token{typ: tokenLeftParen, text: "(", pos: start}, nil
+	case r == ')':
+		l.pos += 1
+		return token{typ: tokenRightParen, text: ")", pos: start}, nil
+	case r == ':':
+		return token{}, queryError(start, "missing key before ':'")
+	case (r == '-' || r == '+') && l.peek(1) != 0 && !unicode.IsSpace(l.peek(1)):
+		// prefix: -term negates term, +term is same as term
+		l.pos += 1
+		if r == '-' {
+			return token{typ: tokenNot, text: "-", pos: start}, nil
+		}
+		return l.next()
+	}
+
+	var t token
+	var err error
+	if isQuote(r) {
+		t, err = l.phrase()
+	} else {
+		t = l.word()
+	}
+	if err != nil {
+		return t, err
+	}
+
+	if l.peek(0) == ':' {
+		return l.field(t)
+	}
+	if t.typ == tokenWord {
+		switch t.text {
+		case "AND":
+			t.typ = tokenAnd
+		case "OR":
+			t.typ = tokenOr
+		case "NOT":
+			t.typ = tokenNot
+		}
+	}
+	return t, nil
+}
+
+func (l *lexer) word() token {
+	start := l.pos
+	url := false
+	for l.pos < len(l.input) && !l.isWordEnd(0, url) {
+		url = url || l.input[l.pos] == ':'
+		l.pos += 1
+	}
+	return token{typ: tokenWord, text: string(l.input[start:l.pos]), pos: start}
+}
+
+//phrase reads quoted text. Backslash escapes next character.
+func (l *lexer) phrase() (token, error) {
+	start := l.pos
+	quote := l.input[l.pos]
+	l.pos += 1
+	text := strings.Builder{}
+	for l.pos < len(l.input) {
+		r := l.input[l.pos]
+		l.pos += 1
+		if r == '\\' && l.pos < len(l.input) {
+			text.WriteRune(l.input[l.pos])
+			l.pos += 1
+		} else if r == quote {
+			return token{typ: tokenPhrase, text: text.String(), quoted: true, pos: start}, nil
+		} else {
+			text.WriteRune(r)
+		}
+	}
+	return token{}, queryError(start, "unterminated quote")
+}
+
+//field reads value for key that is followed by ':'. Bare value ends only at space or ')'.
+func (l *lexer) field(key token) (token, error) {
+	t := token{typ: tokenField, key: strings.ToLower(key.text), rawKey: key.text, pos: key.pos}
+	if t.key == "" {
+		return t, queryError(key.pos, "missing key before ':'")
+	}
+	l.pos += 1
+	t.valuePos = l.pos
+
+	if l.pos < len(l.input) && isQuote(l.input[l.pos]) {
+		value, err := l.phrase()
+		if err != nil {
+			return t, err
+		}
+		t.text = value.text
+		t.quoted = true
+		return t, nil
+	}
+
+	start := l.pos
+	for l.pos < len(l.input) && !unicode.IsSpace(l.input[l.pos]) && l.input[l.pos] != ')' {
+		l.pos += 1
+	}
+	t.text = string(l.input[start:l.pos])
+	if t.text == "" {
+		return t, queryError(key.pos, "missing value for '%s'", key.text)
+	}
+	return t, nil
+}
+
+//queryNode is a node in parsed query. Each node compiles into sql condition for bookmarks b,
+//or matches bookmark in memory.
+type queryNode interface {
+	String() string
+	sql(params *[]interface{}) string
+	match(b *models.Bookmark) bool
+}
+
+type andNode struct {
+	nodes []queryNode
+}
+
+type orNode struct {
+	nodes []queryNode
+}
+
+type notNode struct {
+	node queryNode
+}
+
+//textNode is a free text term that matches any field of bookmark
+type textNode struct {
+	text   string
+	phrase bool
+}
+
+//fieldNode is a key:value term. Quoted value must match exactly.
+type fieldNode struct {
+	key    string
+	value  string
+	strict bool
+	pos    int
+}
+
+//dateNode limits created or updated time
+type dateNode struct {
+	key    string
+	value  string
+	column string
+	after  bool
+	time   time.Time
+}
+
+//priorityNode compares priority to value, e.g. priority:>2
+type priorityNode struct {
+	op    string
+	value int
+	text  string
+}
+
+//dueNode matches due date: 'overdue', 'none', 'any' or due before given date
+type dueNode struct {
+	value string
+	time  time.Time
+}
+
+//priorityOperators are comparisons allowed in priority value, longest first
+var priorityOperators = []string{">=", "<=", ">", "<", "="}
+
+//dateFields maps date keys to column and whether key matches times at or after given date
+var dateFields = map[string]struct {
+	column string
+	after  bool
+}{
+	"after":    {"b.created_at", true},
+	"before":   {"b.created_at", false},
+	"created>": {"b.created_at", true},
+	"created<": {"b.created_at", false},
+	"updated>": {"b.updated_at", true},
+	"updated<": {"b.updated_at", false},
+}
+
+func joinNodes(op string, nodes []queryNode) string {
+	out := make([]string, len(nodes))
+	for i, v := range nodes {
+		out[i] = v.String()
+	}
+	return "(" + op + " " + strings.Join(out, " ") + ")"
+}
+
+func (n *andNode) String() string {
+	return joinNodes("AND", n.nodes)
+}
+
+func (n *orNode) String() string {
+	return joinNodes("OR", n.nodes)
+}
+
+func (n *notNode) String() string {
+	return "(NOT " + n.node.String() + ")"
+}
+
+func (n *textNode) String() string {
+	if n.phrase {
+		return `"` + n.text + `"`
+	}
+	return n.text
+}
+
+func (n *dateNode) String() string {
+	return n.key + ":" + n.value
+}
+
+func (n *priorityNode) String() string {
+	return "priority:" + n.text
+}
+
+func (n *dueNode) String() string {
+	return "due:" + n.value
+}
+
+func (n *fieldNode) String() string {
+	if n.strict {
+		return n.key + ":'" + n.value + "'"
+	}
+	return n.key + ":" + n.value
+}
+
+//parser parses tokens into query tree with grammar:
+// or      = and { "OR" and }
+// and     = unary { ["AND"] unary }
+// unary   = ( "NOT" | "-" ) unary | primary
+// primary = "(" or ")" | word | phrase | key:value
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+//parseQuery parses query into tree. Empty query returns nil node.
+func parseQuery(query string) (queryNode, error) {
+	l := &lexer{input: []rune(query)}
+	tokens, err := l.tokens()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	if p.current().typ == tokenEOF {
+		return nil, nil
+	}
+	node, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.current(); t.typ != tokenEOF {
+		if t.typ == tokenRightParen {
+			return nil, queryError(t.pos, "unmatched ')'")
+		}
+		return nil, queryError(t.pos, "unexpected %s", t)
+	}
+	return node, nil
+}
+
+func (p *parser) current() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokenEOF {
+		p.pos += 1
+	}
+	return t
+}
+
+func (p *parser) or() (queryNode, error) {
+	node, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []queryNode{node}
+	for p.current().typ == tokenOr {
+		p.advance()
+		node, err = p.and()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return &orNode{nodes: nodes}, nil
+}
+
+func (p *parser) and() (queryNode, error) {
+	node, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []queryNode{node}
+	for {
+		switch p.current().typ {
+		case tokenAnd:
+			p.advance()
+		case tokenWord, tokenPhrase, tokenField, tokenNot, tokenLeftParen:
+		default:
+			if len(nodes) == 1 {
+				return nodes[0], nil
+			}
+			return &andNode{nodes: nodes}, nil
+		}
+		node, err = p.unary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+}
+
+func (p *parser) unary() (queryNode, error) {
+	if p.current().typ == tokenNot {
+		p.advance()
+		node, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{node: node}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (queryNode, error) {
+	t := p.advance()
+	switch t.typ {
+	case tokenLeftParen:
+		if p.current().typ == tokenRightParen {
+			return nil, queryError(t.pos, "empty parentheses")
+		}
+		node, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.current().typ != tokenRightParen {
+			return nil, queryError(t.pos, "missing closing ')'")
+		}
+		p.advance()
+		return node, nil
+	case tokenWord, tokenPhrase:
+		text := t.text
+		if !t.quoted && len(text) > 1 {
+			// prefix search 'pag*' matches same as 'pag' anywhere in field
+			text = strings.TrimSuffix(text, "*")
+		}
+		return &textNode{text: text, phrase: t.quoted}, nil
+	case tokenField:
+		return newFieldNode(t)
+	case tokenEOF:
+		return nil, queryError(t.pos, "expected term before end of query")
+	default:
+		return nil, queryError(t.pos, "unexpected %s", t)
+	}
+}
+
+//newFieldNode validates field token
+func newFieldNode(t token) (queryNode, error) {
+	node := &fieldNode{key: t.key, value: t.text, strict: t.quoted, pos: t.pos}
+	switch t.key {
+	case "tag", "tags":
+		node.key = "tags"
+		if node.strict {
+			break
+		}
+		tags := tagsNode(node.value)
+		if tags == nil {
+			return nil, queryError(t.valuePos, "no tags in '%s'", t.text)
+		}
+		return tags, nil
+	case "archived":
+		value := strings.ToLower(t.text)
+		if value != "true" && value != "false" {
+			return nil, queryError(t.valuePos, "invalid archived value '%s', expected true or false", t.text)
+		}
+		node.value = value
+	case "state":
+		value := strings.ToLower(t.text)
+		if !models.IsReadState(value) {
+			return nil, queryError(t.valuePos, "invalid state '%s', expected unread, reading or read", t.text)
+		}
+		node.value = value
+	case "priority":
+		return newPriorityNode(t)
+	case "due":
+		return newDueNode(t)
+	case "sort":
+		node.value = strings.ToLower(t.text)
+		if sortFields[node.value] == "" {
+			return nil, queryError(t.valuePos, "invalid sort field '%s'", t.text)
+		}
+	}
+	if field, ok := dateFields[t.key]; ok {
+		date, err := parseDate(t.text, time.Now())
+		if err != nil {
+			return nil, queryError(t.valuePos, "%v", err)
+		}
+		return &dateNode{key: t.key, value: t.text, column: field.column, after: field.after, time: date}, nil
+	}
+	return node, nil
+}
+
+//newPriorityNode parses priority comparison, e.g. '3', '>2' or '<=1'
+func newPriorityNode(t token) (queryNode, error) {
+	node := &priorityNode{op: "=", text: t.text}
+	value := t.text
+	for _, op := range priorityOperators {
+		if strings.HasPrefix(value, op) {
+			node.op = op
+			value = strings.TrimPrefix(value, op)
+			break
+		}
+	}
+	priority, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, queryError(t.valuePos, "invalid priority '%s'", t.text)
+	}
+	node.value = priority
+	return node, nil
+}
+
+//newDueNode parses due value: overdue, none, any or date
+func newDueNode(t token) (queryNode, error) {
+	node := &dueNode{value: strings.ToLower(t.text), time: time.Now()}
+	switch node.value {
+	case "overdue", "none", "any":
+		return node, nil
+	}
+	date, err := parseDate(t.text, node.time)
+	if err != nil {
+		return nil, queryError(t.valuePos, "%v", err)
+	}
+	node.value = t.text
+	node.time = date
+	return node, nil
+}
+
+//tagsNode parses tag list where bookmark must have all of comma separated tags
+//and any of '|' separated tags, e.g. 'a,b|c' = a AND (b OR c). Returns nil if there are no tags.
+func tagsNode(value string) queryNode {
+	all := []queryNode{}
+	for _, group := range strings.Split(value, ",") {
+		any := []queryNode{}
+		for _, name := range strings.Split(group, "|") {
+			name = strings.TrimSpace(name)
+			if name != "" {
+				any = append(any, &fieldNode{key: "tags", value: name})
+			}
+		}
+		if len(any) == 1 {
+			all = append(all, any[0])
+		} else if len(any) > 1 {
+			all = append(all, &orNode{nodes: any})
+		}
+	}
+	if len(all) == 0 {
+		return nil
+	} else if len(all) == 1 {
+		return all[0]
+	}
+	return &andNode{nodes: all}
+}
+
+//escapeLike escapes LIKE wildcards in lower case text. Use with ESCAPE '\'.
+func escapeLike(text string) string {
+	text = strings.ToLower(text)
+	text = strings.Replace(text, `\`, `\\`, -1)
+	text = strings.Replace(text, "%", `\%`, -1)
+	return strings.Replace(text, "_", `\_`, -1)
+}
+
+//likePattern returns case-insensitive LIKE pattern that matches text anywhere. Use with ESCAPE '\'.
+func likePattern(text string) string {
+	return "%" + escapeLike(text) + "%"
+}
+
+//likePrefix returns case-insensitive LIKE pattern that matches text at start. Use with ESCAPE '\'.
+func likePrefix(text string) string {
+	return escapeLike(text) + "%"
+}
+
+//bookmarkColumns maps field keys to bookmark columns that are matched in lower case
+var bookmarkColumns = map[string]string{
+	"name":        "b.lower_name",
+	"description": "b.description_lower",
+	"link":        "LOWER(b.content)",
+	"project":     "LOWER(b.project)",
+	"notes":       "LOWER(b.notes)",
+}
+
+//compare returns sql condition for column against value
+func compare(column, value string, strict bool, params *[]interface{}) string {
+	if strict {
+		*params = append(*params, strings.ToLower(value))
+		return column + " = ?"
+	}
+	*params = append(*params, likePattern(value))
+	return column + ` LIKE ? ESCAPE '\'`
+}
+
+//tagCondition matches bookmarks that have tag with exact name or any of its children, e.g. lang matches lang/go
+func tagCondition(name string, params *[]interface{}) string {
+	name = strings.TrimSuffix(name, TagSeparator)
+	return `EXISTS (SELECT 1 FROM bookmark_tags bt JOIN tags t ON bt.tag = t.id WHERE bt.bookmark = b.id AND ` +
+		tagTreeCondition(name, params) + ")"
+}
+
+func metadataCondition(key, value string, strict bool, params *[]interface{}) string {
+	*params = append(*params, strings.ToLower(key))
+	return `EXISTS (SELECT 1 FROM metadata m WHERE m.bookmark = b.id AND m.key_lower = ? AND ` +
+		compare("m.value_lower", value, strict, params) + ")"
+}
+
+func joinSql(op string, nodes []queryNode, params *[]interface{}) string {
+	out := make([]string, len(nodes))
+	for i, v := range nodes {
+		out[i] = v.sql(params)
+	}
+	return "(" + strings.Join(out, " "+op+" ") + ")"
+}
+
+func (n *andNode) sql(params *[]interface{}) string {
+	return joinSql("AND", n.nodes, params)
+}
+
+func (n *orNode) sql(params *[]interface{}) string {
+	return joinSql("OR", n.nodes, params)
+}
+
+func (n *notNode) sql(params *[]interface{}) string {
+	return "NOT " + n.node.sql(params)
+}
+
+func (n *textNode) sql(params *[]interface{}) string {
+	conditions := []string{
+		compare("b.lower_name", n.text, false, params),
+		compare("b.description_lower", n.text, false, params),
+		compare("LOWER(b.content)", n.text, false, params),
+		compare("LOWER(b.project)", n.text, false, params),
+		compare("LOWER(b.notes)", n.text, false, params),
+		`EXISTS (SELECT 1 FROM bookmark_tags bt JOIN tags t ON bt.tag = t.id WHERE bt.bookmark = b.id AND ` +
+			compare("LOWER(t.name)", n.text, false, params) + ")",
+		`EXISTS (SELECT 1 FROM metadata m WHERE m.bookmark = b.id AND ` +
+			compare("m.value_lower", n.text, false, params) + ")",
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")"
+}
+
+func (n *dateNode) sql(params *[]interface{}) string {
+	return dateCondition(n.column, n.after, n.time, params)
+}
+
+func (n *priorityNode) sql(params *[]interface{}) string {
+	*params = append(*params, n.value)
+	return "(b.priority " + n.op + " ?)"
+}
+
+func (n *dueNode) sql(params *[]interface{}) string {
+	switch n.value {
+	case "none":
+		return "(b.due_at IS NULL)"
+	case "any":
+		return "(b.due_at IS NOT NULL)"
+	case "overdue":
+		condition := dateCondition("b.due_at", false, n.time, params)
+		*params = append(*params, models.StateRead)
+		return "(" + condition + " AND b.state != ?)"
+	default:
+		return dateCondition("b.due_at", false, n.time, params)
+	}
+}
+
+func (n *fieldNode) sql(params *[]interface{}) string {
+	if column, ok := bookmarkColumns[n.key]; ok {
+		return "(" + compare(column, n.value, n.strict, params) + ")"
+	}
+	switch n.key {
+	case "tags":
+		return tagCondition(n.value, params)
+	case "archived":
+		*params = append(*params, n.value == "true")
+		return "(b.archived = ?)"
+	case "state":
+		*params = append(*params, n.value)
+		return "(b.state = ?)"
+	default:
+		return metadataCondition(n.key, n.value, n.strict, params)
+	}
+}
+
+//matchValue matches value like compare does in sql
+func matchValue(value, text string, strict bool) bool {
+	value = strings.ToLower(value)
+	text = strings.ToLower(text)
+	if strict {
+		return value == text
+	}
+	return strings.Contains(value, text)
+}
+
+//matchTag returns true if bookmark has tag with exact name or any of its children
+func matchTag(b *models.Bookmark, name string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, TagSeparator))
+	for _, v := range b.Tags {
+		tag := strings.ToLower(v)
+		if tag == name || strings.HasPrefix(tag, name+TagSeparator) {
+			return true
+		}
+	}
+	return false
+}
+
+//matchMetadata returns true if bookmark has metadata key, or any key if key is empty, that matches value
+func matchMetadata(b *models.Bookmark, key, value string, strict bool) bool {
+	if b.Metadata == nil {
+		return false
+	}
+	for k, v := range *b.Metadata {
+		if (key == "" || strings.ToLower(k) == strings.ToLower(key)) && matchValue(v, value, strict) {
+			return true
+		}
+	}
+	return false
+}
+
+//matchDate matches time like dateCondition does in sql
+func matchDate(t time.Time, after bool, date time.Time) bool {
+	t = t.Truncate(time.Second)
+	if after {
+		return !t.Before(date)
+	}
+	return t.Before(date)
+}
+
+//bookmarkField returns value of field key that is in bookmarkColumns
+func bookmarkField(b *models.Bookmark, key string) string {
+	switch key {
+	case "name":
+		return b.Name
+	case "description":
+		return b.Description
+	case "link":
+		return b.Content
+	case "project":
+		return b.Project
+	case "notes":
+		return b.Notes
+	}
+	return ""
+}
+
+func (n *andNode) match(b *models.Bookmark) bool {
+	for _, v := range n.nodes {
+		if !v.match(b) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *orNode) match(b *models.Bookmark) bool {
+	for _, v := range n.nodes {
+		if v.match(b) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *notNode) match(b *models.Bookmark) bool {
+	return !n.node.match(b)
+}
+
+func (n *textNode) match(b *models.Bookmark) bool {
+	for _, v := range []string{b.Name, b.Description, b.Content, b.Project, b.Notes} {
+		if matchValue(v, n.text, false) {
+			return true
+		}
+	}
+	for _, v := range b.Tags {
+		if matchValue(v, n.text, false) {
+			return true
+		}
+	}
+	return matchMetadata(b, "", n.text, false)
+}
+
+func (n *dateNode) match(b *models.Bookmark) bool {
+	if n.column == "b.updated_at" {
+		return matchDate(b.UpdatedAt, n.after, n.time)
+	}
+	return matchDate(b.CreatedAt, n.after, n.time)
+}
+
+func (n *priorityNode) match(b *models.Bookmark) bool {
+	switch n.op {
+	case ">=":
+		return b.Priority >= n.value
+	case "<=":
+		return b.Priority <= n.value
+	case ">":
+		return b.Priority > n.value
+	case "<":
+		return b.Priority < n.value
+	default:
+		return b.Priority == n.value
+	}
+}
+
+func (n *dueNode) match(b *models.Bookmark) bool {
+	switch n.value {
+	case "none":
+		return b.DueAt == nil
+	case "any":
+		return b.DueAt != nil
+	case "overdue":
+		return b.IsOverdue(n.time)
+	default:
+		return b.DueAt != nil && matchDate(*b.DueAt, false, n.time)
+	}
+}
+
+func (n *fieldNode) match(b *models.Bookmark) bool {
+	if _, ok := bookmarkColumns[n.key]; ok {
+		return matchValue(bookmarkField(b, n.key), n.value, n.strict)
+	}
+	switch n.key {
+	case "tags":
+		return matchTag(b, n.value)
+	case "archived":
+		return b.Archived == (n.value == "true")
+	case "state":
+		return b.ReadState() == n.value
+	default:
+		return matchMetadata(b, n.key, n.value, n.strict)
+	}
+}
+
+//isPlainQuery returns true if node is a single word, which can be searched with full text search.
+//Operators, phrases and multiple terms are matched with sql conditions of query nodes.
+func isPlainQuery(node queryNode) bool {
+	text, ok := node.(*textNode)
+	return ok && !text.phrase
+}
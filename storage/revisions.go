@@ -0,0 +1,193 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//maxRevisions is number of revisions kept for each bookmark. Older revisions are removed.
+const maxRevisions = 100
+
+//revision is a row in bookmark_revisions
+type revision struct {
+	Id          int       `db:"id"`
+	Bookmark    int       `db:"bookmark"`
+	CreatedAt   time.Time `db:"created_at"`
+	Operation   string    `db:"operation"`
+	Name        string    `db:"name"`
+	Description string    `db:"description"`
+	Content     string    `db:"content"`
+	Project     string    `db:"project"`
+	Notes       string    `db:"notes"`
+	Archived    bool      `db:"archived"`
+	Tags        string    `db:"tags"`
+	Metadata    string    `db:"metadata"`
+}
+
+func (r *revision) revision() (*models.Revision, error) {
+	rev := &models.Revision{
+		Id:          r.Id,
+		Bookmark:    r.Bookmark,
+		CreatedAt:   r.CreatedAt,
+		Operation:   r.Operation,
+		Name:        r.Name,
+		Description: r.Description,
+		Content:     r.Content,
+		Project:     r.Project,
+		Notes:       r.Notes,
+		Archived:    r.Archived,
+	}
+	err := json.Unmarshal([]byte(r.Tags), &rev.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("parse tags of revision %d: %v", r.Id, err)
+	}
+	err = json.Unmarshal([]byte(r.Metadata), &rev.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("parse metadata of revision %d: %v", r.Id, err)
+	}
+	return rev, nil
+}
+
+//revisionMetadata returns metadata without link check results, which are not kept in revisions,
+//and without empty values, which equal missing keys
+func revisionMetadata(metadata map[string]string) map[string]string {
+	fields := map[string]string{}
+	for key, value := range metadata {
+		if value != "" && !models.IsLinkCheckMetadata(key) {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+//untrackedChange returns true if only reading state, priority, due date, link check metadata
+//or snapshots changed, which are not kept in revisions
+func untrackedChange(before, after *bookmarkImage) bool {
+	old := *before
+	old.Metadata = revisionMetadata(before.Metadata)
+	other := *after
+	other.State = before.State
+	other.Priority = before.Priority
+	other.DueAt = before.DueAt
+	other.UpdatedAt = before.UpdatedAt
+	other.Snapshots = before.Snapshots
+	other.Metadata = revisionMetadata(after.Metadata)
+	oldJson, _ := json.Marshal(&old)
+	newJson, _ := json.Marshal(&other)
+	return string(oldJson) == string(newJson)
+}
+
+//recordRevisions stores states in before as revisions of bookmarks that changed in operation.
+//Bookmarks that were created or removed in operation have no revision.
+func recordRevisions(tx *sqlx.Tx, operation string, before, after images) error {
+	query := `
+INSERT INTO bookmark_revisions (bookmark, created_at, operation, name, description, content,
+	project, notes, archived, tags, metadata)
+VALUES (?,?,?,?,?,?,?,?,?,?,?)`
+
+	now := time.Now()
+	for _, id := range before.ids() {
+		image := before[id]
+		if image == nil || after[id] == nil || untrackedChange(image, after[id]) {
+			continue
+		}
+		tags, err := json.Marshal(image.Tags)
+		if err != nil {
+			return fmt.Errorf("marshal tags: %v", err)
+		}
+		metadata, err := json.Marshal(revisionMetadata(image.Metadata))
+		if err != nil {
+			return fmt.Errorf("marshal metadata: %v", err)
+		}
+		_, err = tx.Exec(query, id, now, operation, image.Name, image.Description, image.Content,
+			image.Project, image.Notes, image.Archived, string(tags), string(metadata))
+		if err != nil {
+			return fmt.Errorf("insert revision: %v", err)
+		}
+		_, err = tx.Exec(`
+DELETE FROM bookmark_revisions WHERE bookmark = ? AND id NOT IN (
+	SELECT id FROM bookmark_revisions WHERE bookmark = ? ORDER BY id DESC LIMIT ?
+)`, id, id, maxRevisions)
+		if err != nil {
+			return fmt.Errorf("remove old revisions: %v", err)
+		}
+	}
+	return nil
+}
+
+//GetRevisions returns revisions of bookmark, latest first
+func (d *Database) GetRevisions(bookmark int) ([]*models.Revision, error) {
+	query := "SELECT * FROM bookmark_revisions WHERE bookmark = ? ORDER BY id DESC"
+
+	logger := beginQuery(query, "get revisions")
+	rows := []*revision{}
+	err := d.conn.Select(&rows, query, bookmark)
+	logger.log(err)
+	if err != nil {
+		return nil, err
+	}
+	revisions := make([]*models.Revision, len(rows))
+	for i, v := range rows {
+		revisions[i], err = v.revision()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return revisions, nil
+}
+
+//RestoreRevision sets name, description, link, project, archived status, tags and metadata
+//of bookmark to their state in revision. Restoring is recorded to journal and adds new revision.
+func (d *Database) RestoreRevision(id int) error {
+	row := &revision{}
+	err := d.conn.Get(row, "SELECT * FROM bookmark_revisions WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("get revision %d: %v", id, err)
+	}
+	rev, err := row.revision()
+	if err != nil {
+		return err
+	}
+
+	operation := fmt.Sprintf("restore revision of bookmark '%s' from %s", rev.Name,
+		rev.CreatedAt.Local().Format("2006-01-02 15:04"))
+	return d.journaled(operation, []int{rev.Bookmark}, func(tx *sqlx.Tx) ([]int, error) {
+		current, err := loadImages(tx, []int{rev.Bookmark})
+		if err != nil {
+			return nil, err
+		}
+		image := current[rev.Bookmark]
+		if image == nil {
+			return nil, fmt.Errorf("bookmark %d not found", rev.Bookmark)
+		}
+		image.Name = rev.Name
+		image.Description = rev.Description
+		image.Content = rev.Content
+		image.Project = rev.Project
+		image.Notes = rev.Notes
+		image.Archived = rev.Archived
+		image.Tags = rev.Tags
+		image.Metadata = rev.Metadata
+		image.UpdatedAt = time.Now()
+		return nil, d.restoreImages(tx, current)
+	})
+}
@@ -0,0 +1,88 @@
+//go:build fts5
+// +build fts5
+
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"testing"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+func TestDatabase_Revisions_linkCheckMetadata(t *testing.T) {
+	db, cleanup := newTestDatabase(t, false)
+	defer cleanup()
+	b := &models.Bookmark{Name: "Golang", Metadata: &map[string]string{"Author": "rob"}}
+	addTestBookmarks(t, db, b)
+
+	for _, checked := range []string{"2020-01-01", "2020-01-02", "2020-01-03"} {
+		err := db.SetMetadata(map[int]map[string]string{
+			b.Id: {models.MetadataLinkStatus: models.LinkStatusOk, models.MetadataCheckedAt: checked},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b = getTestBookmark(t, db, b.Id)
+	(*b.Metadata)[models.MetadataCheckedAt] = "2020-01-04"
+	err := db.UpdateBookmark(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := db.GetRevisions(b.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 0 {
+		t.Fatalf("link check metadata added %d revisions, want 0", len(revisions))
+	}
+
+	b.Description = "language"
+	err = db.UpdateBookmark(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisions, err = db.GetRevisions(b.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 {
+		t.Fatalf("got %d revisions, want 1", len(revisions))
+	}
+	for key := range revisions[0].Metadata {
+		if models.IsLinkCheckMetadata(key) {
+			t.Errorf("revision has link check metadata %q", key)
+		}
+	}
+	if revisions[0].Metadata["Author"] != "rob" {
+		t.Errorf("revision metadata = %v", revisions[0].Metadata)
+	}
+
+	err = db.RestoreRevision(revisions[0].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := getTestBookmark(t, db, b.Id)
+	if got.Description != "" {
+		t.Errorf("restored description = %q, want empty", got.Description)
+	}
+	if checked := (*got.Metadata)[models.MetadataCheckedAt]; checked != "2020-01-04" {
+		t.Errorf("restore changed %s to %q", models.MetadataCheckedAt, checked)
+	}
+}
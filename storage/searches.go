@@ -0,0 +1,186 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//NewSavedSearchFilter parses query of saved search and applies its sort settings
+func NewSavedSearchFilter(s *models.SavedSearch) (*Filter, error) {
+	filter, err := NewFilter(s.Query)
+	if err != nil {
+		return filter, err
+	}
+	if s.SortField != "" {
+		filter.SortField = s.SortField
+		filter.SortDir = s.SortDir
+	}
+	return filter, nil
+}
+
+//validateSavedSearch checks that search has a name and a valid query
+func validateSavedSearch(s *models.SavedSearch) error {
+	s.Name = strings.TrimSpace(s.Name)
+	s.Query = strings.TrimSpace(s.Query)
+	if s.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if s.Query == "" {
+		return fmt.Errorf("query is required")
+	}
+	_, err := NewFilter(s.Query)
+	if err != nil {
+		return fmt.Errorf("invalid query: %v", err)
+	}
+	if s.SortDir != "DESC" {
+		s.SortDir = "ASC"
+	}
+	return nil
+}
+
+//NewSavedSearch stores new saved search. Name must be unique.
+func (d *Database) NewSavedSearch(s *models.SavedSearch) error {
+	err := validateSavedSearch(s)
+	if err != nil {
+		return err
+	}
+	existing, err := d.GetSavedSearch(s.Name)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("saved search '%s' already exists", s.Name)
+	}
+
+	query := `
+INSERT INTO saved_searches (name, query, sort_field, sort_dir, created_at)
+VALUES (?, ?, ?, ?, ?)`
+
+	s.CreatedAt = time.Now()
+	logger := beginQuery(query, "new saved search")
+	res, err := d.conn.Exec(query, s.Name, s.Query, s.SortField, s.SortDir, s.CreatedAt)
+	logger.log(err)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	s.Id = int(id)
+	return nil
+}
+
+//UpdateSavedSearch updates name, query and sort settings of saved search
+func (d *Database) UpdateSavedSearch(s *models.SavedSearch) error {
+	err := validateSavedSearch(s)
+	if err != nil {
+		return err
+	}
+	existing, err := d.GetSavedSearch(s.Name)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.Id != s.Id {
+		return fmt.Errorf("saved search '%s' already exists", s.Name)
+	}
+
+	query := `
+UPDATE saved_searches
+SET name = ?, query = ?, sort_field = ?, sort_dir = ?
+WHERE id = ?`
+
+	logger := beginQuery(query, "update saved search")
+	_, err = d.conn.Exec(query, s.Name, s.Query, s.SortField, s.SortDir, s.Id)
+	logger.log(err)
+	return err
+}
+
+//DeleteSavedSearch deletes saved search
+func (d *Database) DeleteSavedSearch(id int) error {
+	query := `DELETE FROM saved_searches WHERE id = ?`
+	logger := beginQuery(query, "delete saved search")
+	_, err := d.conn.Exec(query, id)
+	logger.log(err)
+	return err
+}
+
+//GetSavedSearch returns saved search by name, or nil if it does not exist
+func (d *Database) GetSavedSearch(name string) (*models.SavedSearch, error) {
+	query := `
+SELECT id, name, query, sort_field, sort_dir, created_at
+FROM saved_searches
+WHERE name = ?`
+
+	logger := beginQuery(query, "get saved search")
+	searches := []*models.SavedSearch{}
+	err := d.conn.Select(&searches, query, strings.TrimSpace(name))
+	logger.log(err)
+	if err != nil || len(searches) == 0 {
+		return nil, err
+	}
+	return searches[0], nil
+}
+
+//GetSavedSearches returns all saved searches ordered by name with number of bookmarks matching each search.
+//Search with invalid query has count -1.
+func (d *Database) GetSavedSearches() ([]*models.SavedSearch, error) {
+	query := `
+SELECT id, name, query, sort_field, sort_dir, created_at
+FROM saved_searches
+ORDER BY name ASC`
+
+	logger := beginQuery(query, "get saved searches")
+	searches := []*models.SavedSearch{}
+	err := d.conn.Select(&searches, query)
+	logger.log(err)
+	if err != nil {
+		return searches, err
+	}
+
+	for _, v := range searches {
+		filter, err := NewSavedSearchFilter(v)
+		if err != nil {
+			v.Count = -1
+			continue
+		}
+		v.Count, err = d.CountBookmarks(filter)
+		if err != nil {
+			return searches, fmt.Errorf("count saved search '%s': %v", v.Name, err)
+		}
+	}
+	return searches, nil
+}
+
+//CountBookmarks returns number of bookmarks that match filter. Plain query is counted from full text search.
+func (d *Database) CountBookmarks(filter *Filter) (int, error) {
+	if filter.IsPlainQuery() {
+		_, total, err := d.SearchBookmarksPage(filter.Query, 0, 1)
+		return total, err
+	}
+
+	query, params := filter.countQuery()
+	logger := beginQuery(query, "count bookmarks")
+	count := 0
+	err := d.conn.Get(&count, query, *params...)
+	logger.log(err)
+	return count, err
+}
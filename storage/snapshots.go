@@ -0,0 +1,135 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//NewSnapshot stores snapshot of bookmark. Snapshot is not stored if page has not changed
+//since latest snapshot, in which case returned bool is false and s.Id is set to latest snapshot.
+func (d *Database) NewSnapshot(s *models.Snapshot) (bool, error) {
+	latestQuery := `
+SELECT id, hash
+FROM snapshots
+WHERE bookmark = ?
+ORDER BY created_at DESC, id DESC
+LIMIT 1`
+
+	insertQuery := `
+INSERT INTO snapshots (bookmark, created_at, url, title, hash, text, html)
+VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	var latestId int
+	var latestHash string
+	err := d.conn.QueryRow(latestQuery, s.Bookmark).Scan(&latestId, &latestHash)
+	if err != nil && err != sql.ErrNoRows {
+		return false, fmt.Errorf("get latest snapshot: %v", err)
+	}
+	if err == nil && latestHash == s.Hash {
+		s.Id = latestId
+		return false, nil
+	}
+
+	html, err := compress(s.Html)
+	if err != nil {
+		return false, fmt.Errorf("compress html: %v", err)
+	}
+
+	logger := beginQuery(insertQuery, "new snapshot")
+	res, err := d.conn.Exec(insertQuery, s.Bookmark, s.CreatedAt, s.Url, s.Title, s.Hash, s.Text, html)
+	logger.log(err)
+	if err != nil {
+		return false, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	s.Id = int(id)
+	return true, nil
+}
+
+//GetSnapshots returns snapshots of bookmark, latest first. Html is not loaded.
+func (d *Database) GetSnapshots(bookmark int) ([]*models.Snapshot, error) {
+	query := `
+SELECT id, bookmark, created_at, url, title, hash, text
+FROM snapshots
+WHERE bookmark = ?
+ORDER BY created_at DESC, id DESC`
+
+	logger := beginQuery(query, "get snapshots")
+	snapshots := []*models.Snapshot{}
+	err := d.conn.Select(&snapshots, query, bookmark)
+	logger.log(err)
+	return snapshots, err
+}
+
+//GetSnapshot returns single snapshot with html
+func (d *Database) GetSnapshot(id int) (*models.Snapshot, error) {
+	query := `
+SELECT id, bookmark, created_at, url, title, hash, text, html
+FROM snapshots
+WHERE id = ?`
+
+	logger := beginQuery(query, "get snapshot")
+	s := &models.Snapshot{}
+	err := d.conn.Get(s, query, id)
+	logger.log(err)
+	if err != nil {
+		return nil, err
+	}
+	s.Html, err = decompress(s.Html)
+	if err != nil {
+		return nil, fmt.Errorf("decompress html: %v", err)
+	}
+	return s, nil
+}
+
+//DeleteSnapshot deletes single snapshot
+func (d *Database) DeleteSnapshot(id int) error {
+	query := `DELETE FROM snapshots WHERE id = ?`
+	logger := beginQuery(query, "delete snapshot")
+	_, err := d.conn.Exec(query, id)
+	logger.log(err)
+	return err
+}
+
+func compress(data []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writer := gzip.NewWriter(buf)
+	_, err := writer.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.Close()
+	return buf.Bytes(), err
+}
+
+func decompress(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
@@ -21,10 +21,18 @@ import "time"
 type Statistics struct {
 	Bookmarks               int
 	Archived                int
+	Deleted                 int
 	Tags                    int
 	Projects                int
 	IpfsLinks               int
 	LastBookmark            time.Time
 	FullTextSearchSupported bool
 	MetadataKeys            []string
+
+	//Unread, Reading and Read count bookmarks in each reading state
+	Unread  int
+	Reading int
+	Read    int
+	//Overdue counts bookmarks that are not read and are past their due date
+	Overdue int
 }
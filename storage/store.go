@@ -0,0 +1,139 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"errors"
+	"time"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//ErrNotSupported is returned when store does not implement feature, e.g. MemoryStore has no journal
+var ErrNotSupported = errors.New("not supported by bookmark store")
+
+//BookmarkStore stores bookmarks with their tags, projects and metadata. Database implements it with sqlite
+//and MemoryStore keeps bookmarks in memory. Features that need sqlite, like journal, trash, snapshots and
+//saved searches, are in separate interfaces that only Database implements.
+type BookmarkStore interface {
+	//Options returns options store was created with
+	Options() Options
+
+	GetBookmark(id int) (*models.Bookmark, error)
+	GetBookmarksByLink(link string) ([]*models.Bookmark, error)
+	NewBookmark(b *models.Bookmark) error
+	UpdateBookmark(b *models.Bookmark) error
+	DeleteBookmark(bookmark *models.Bookmark) error
+
+	FilterBookmarks(filter *Filter) ([]*models.Bookmark, error)
+	SearchBookmarks(text string) ([]*models.Bookmark, error)
+	SearchBookmarksPage(text string, offset, limit int) ([]*models.Bookmark, int, error)
+	BookmarksPage(filter *Filter) ([]*models.Bookmark, int, error)
+	CountBookmarks(filter *Filter) (int, error)
+
+	GetBookmarkMetadata(bookmark *models.Bookmark) error
+	GetMetadataKeys() ([]string, error)
+	SearchKeyValue(key, value string) ([]string, error)
+
+	GetAllTags() (*map[string]int, error)
+	RenameTag(old string, new string) (int, error)
+	MergeTag(source string, target string) (int, error)
+	DeleteTag(name string) (int, error)
+
+	GetAllProjects(name string, strict bool) ([]*models.Project, error)
+	FilterProject(filter *Filter) ([]*models.Project, error)
+	RenameProject(old string, new string) (int, error)
+	MoveProject(name string, parent string) (int, error)
+	MergeProject(source string, target string) (int, error)
+
+	GetStatistics() (*Statistics, error)
+}
+
+//JournalStore undoes and redoes operations
+type JournalStore interface {
+	Undo() (string, error)
+	Redo() (string, error)
+}
+
+//ImportStore imports and exports bookmarks
+type ImportStore interface {
+	PreviewImport(bookmarks []*models.Bookmark) (*ImportPreview, error)
+	NewBookmarks(bookmarks []*models.Bookmark, AddTags []string, duplicates DuplicatePolicy) (*ImportResult, error)
+	ImportBookmarks(bookmarks []*models.Bookmark, AddTags []string, duplicates DuplicatePolicy,
+		progress func(done, total int)) (*ImportResult, error)
+	ExportBookmarks(filter *Filter) ([]*models.Bookmark, error)
+}
+
+//DuplicateStore finds and merges bookmarks that have same canonical link
+type DuplicateStore interface {
+	GetDuplicates() ([][]*models.Bookmark, error)
+	MergeDuplicates(ids []int) (*models.Bookmark, error)
+	MergeBookmark(id int, bookmark *models.Bookmark, overwrite bool) error
+}
+
+//TrashStore restores and purges deleted bookmarks
+type TrashStore interface {
+	GetDeletedBookmarks() ([]*models.Bookmark, error)
+	RestoreBookmark(bookmark *models.Bookmark) error
+	PurgeBookmark(bookmark *models.Bookmark) error
+	PurgeDeleted(before time.Time) (int, error)
+}
+
+//SavedSearchStore stores named queries
+type SavedSearchStore interface {
+	GetSavedSearches() ([]*models.SavedSearch, error)
+	GetSavedSearch(name string) (*models.SavedSearch, error)
+	NewSavedSearch(s *models.SavedSearch) error
+	UpdateSavedSearch(s *models.SavedSearch) error
+	DeleteSavedSearch(id int) error
+}
+
+//SnapshotStore stores archived copies of bookmarked pages
+type SnapshotStore interface {
+	NewSnapshot(s *models.Snapshot) (bool, error)
+	GetSnapshots(bookmark int) ([]*models.Snapshot, error)
+	GetSnapshot(id int) (*models.Snapshot, error)
+	DeleteSnapshot(id int) error
+}
+
+//RevisionStore lists and restores earlier states of bookmarks
+type RevisionStore interface {
+	GetRevisions(bookmark int) ([]*models.Revision, error)
+	RestoreRevision(id int) error
+}
+
+//BulkStore modifies all bookmarks that match filter
+type BulkStore interface {
+	BulkModify(filter *Filter, modifier *Modifier) (int, error)
+}
+
+//UnusedTagStore lists and removes tags that have no bookmarks
+type UnusedTagStore interface {
+	GetUnusedTags() ([]string, error)
+	DeleteUnusedTags() (int, error)
+}
+
+var _ BookmarkStore = &Database{}
+var _ BookmarkStore = &MemoryStore{}
+var _ JournalStore = &Database{}
+var _ ImportStore = &Database{}
+var _ DuplicateStore = &Database{}
+var _ TrashStore = &Database{}
+var _ SavedSearchStore = &Database{}
+var _ SnapshotStore = &Database{}
+var _ RevisionStore = &Database{}
+var _ BulkStore = &Database{}
+var _ UnusedTagStore = &Database{}
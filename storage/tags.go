@@ -0,0 +1,217 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"sort"
+	"strings"
+)
+
+//TagSeparator separates parent and child in hierarchical tag name, e.g. lang/go.
+//Filtering with parent tag matches its children too.
+const TagSeparator = "/"
+
+//validateTagName checks that tag name can be used in filters
+func validateTagName(name string) error {
+	if name == "" {
+		return fmt.Errorf("tag name cannot be empty")
+	}
+	if strings.ContainsAny(name, ",|") || strings.HasPrefix(name, TagSeparator) ||
+		strings.HasSuffix(name, TagSeparator) || strings.Contains(name, TagSeparator+TagSeparator) {
+		return fmt.Errorf("invalid tag name '%s'", name)
+	}
+	return nil
+}
+
+//tagTreeCondition matches tag t with name or any of its children
+func tagTreeCondition(name string, params *[]interface{}) string {
+	*params = append(*params, strings.ToLower(name), likePrefix(name+TagSeparator))
+	return `(LOWER(t.name) = ? OR LOWER(t.name) LIKE ? ESCAPE '\')`
+}
+
+//RenameTag renames tag and its children on all bookmarks, e.g. old: lang, new: language
+//renames lang/go to language/go. Renaming fails if new tag already exists, use MergeTag to combine tags.
+//Return number of bookmarks modified.
+func (d *Database) RenameTag(old string, new string) (int, error) {
+	return d.renameTag(old, new, false)
+}
+
+//MergeTag replaces source tag and its children with target tag, which may already exist.
+//e.g. source: golang, target: lang/go results in golang/web -> lang/go/web
+func (d *Database) MergeTag(source string, target string) (int, error) {
+	return d.renameTag(source, target, true)
+}
+
+//DeleteTag removes tag and its children from all bookmarks. Return number of bookmarks modified.
+func (d *Database) DeleteTag(name string) (int, error) {
+	return d.renameTag(name, "", true)
+}
+
+//renameTag replaces tag old and its children with new. Empty new deletes tags.
+func (d *Database) renameTag(old string, new string, merge bool) (int, error) {
+	old = strings.TrimSpace(old)
+	new = strings.TrimSpace(new)
+	if old == "" {
+		return 0, fmt.Errorf("tag name cannot be empty")
+	}
+	if new != "" {
+		err := validateTagName(new)
+		if err != nil {
+			return 0, err
+		}
+		if old == new {
+			return 0, fmt.Errorf("tag '%s' is already named '%s'", old, new)
+		}
+		if strings.HasPrefix(strings.ToLower(new), strings.ToLower(old+TagSeparator)) {
+			return 0, fmt.Errorf("cannot move tag '%s' into itself", old)
+		}
+	}
+
+	params := &[]interface{}{}
+	condition := tagTreeCondition(old, params)
+	tags := []string{}
+	err := d.conn.Select(&tags, "SELECT t.name FROM tags t WHERE "+condition+" ORDER BY t.name", *params...)
+	if err != nil {
+		return 0, err
+	}
+	if len(tags) == 0 {
+		return 0, fmt.Errorf("tag '%s' not found", old)
+	}
+
+	renames := map[string]string{}
+	for _, v := range tags {
+		if new == "" || len(v) < len(old) {
+			renames[v] = ""
+		} else {
+			renames[v] = new + v[len(old):]
+		}
+	}
+
+	if !merge {
+		for _, v := range renames {
+			var existing []string
+			err = d.conn.Select(&existing, "SELECT name FROM tags WHERE LOWER(name) = ?", strings.ToLower(v))
+			if err != nil {
+				return 0, err
+			}
+			for _, name := range existing {
+				if _, renamed := renames[name]; !renamed {
+					return 0, fmt.Errorf("tag '%s' already exists", name)
+				}
+			}
+		}
+	}
+
+	ids := []int{}
+	err = d.conn.Select(&ids, "SELECT DISTINCT bt.bookmark FROM bookmark_tags bt JOIN tags t ON bt.tag = t.id WHERE "+
+		condition, *params...)
+	if err != nil {
+		return 0, err
+	}
+
+	var operation string
+	if new == "" {
+		operation = fmt.Sprintf("delete tag '%s'", old)
+	} else if merge {
+		operation = fmt.Sprintf("merge tag '%s' to '%s'", old, new)
+	} else {
+		operation = fmt.Sprintf("rename tag '%s' to '%s'", old, new)
+	}
+
+	query := `
+INSERT OR IGNORE INTO bookmark_tags (bookmark, tag)
+SELECT bt.bookmark, (SELECT id FROM tags WHERE name = ?)
+FROM bookmark_tags bt
+JOIN tags t ON bt.tag = t.id
+WHERE t.name = ?`
+
+	logger := beginQuery(query, operation)
+	err = d.journaled(operation, ids, func(tx *sqlx.Tx) ([]int, error) {
+		for _, from := range tags {
+			to := renames[from]
+			if to != "" {
+				err := d.InsertTags([]string{to}, tx)
+				if err != nil {
+					return nil, err
+				}
+				_, err = tx.Exec(query, to, from)
+				if err != nil {
+					return nil, err
+				}
+			}
+			_, err := tx.Exec("DELETE FROM bookmark_tags WHERE tag IN (SELECT id FROM tags WHERE name = ?)", from)
+			if err != nil {
+				return nil, err
+			}
+			_, err = tx.Exec("DELETE FROM tags WHERE name = ?", from)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	logger.log(err)
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+//GetUnusedTags returns tags that are not assigned to any bookmark
+func (d *Database) GetUnusedTags() ([]string, error) {
+	query := `
+SELECT name
+FROM tags t
+WHERE NOT EXISTS (SELECT 1 FROM bookmark_tags bt WHERE bt.tag = t.id)
+ORDER BY name ASC`
+
+	logger := beginQuery(query, "get unused tags")
+	tags := []string{}
+	err := d.conn.Select(&tags, query)
+	logger.log(err)
+	return tags, err
+}
+
+//DeleteUnusedTags deletes tags that are not assigned to any bookmark. Return number of tags deleted.
+func (d *Database) DeleteUnusedTags() (int, error) {
+	query := `DELETE FROM tags WHERE NOT EXISTS (SELECT 1 FROM bookmark_tags bt WHERE bt.tag = tags.id)`
+
+	logger := beginQuery(query, "delete unused tags")
+	res, err := d.conn.Exec(query)
+	logger.log(err)
+	if err != nil {
+		return 0, err
+	}
+	count, err := res.RowsAffected()
+	return int(count), err
+}
+
+//SortTags sorts tag names so that children follow their parent, e.g. lang, lang/go, lang-old
+func SortTags(tags []string) {
+	sort.Slice(tags, func(i, j int) bool {
+		a := strings.Split(strings.ToLower(tags[i]), TagSeparator)
+		b := strings.Split(strings.ToLower(tags[j]), TagSeparator)
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+}
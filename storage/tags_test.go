@@ -0,0 +1,56 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_validateTagName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: "go"},
+		{name: "lang/go"},
+		{name: "read later"},
+		{name: "", wantErr: true},
+		{name: "a,b", wantErr: true},
+		{name: "a|b", wantErr: true},
+		{name: "/go", wantErr: true},
+		{name: "lang/", wantErr: true},
+		{name: "lang//go", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTagName(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateTagName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSortTags(t *testing.T) {
+	tags := []string{"lang-old", "web", "lang/go/web", "Lang", "lang/c", "lang/go"}
+	SortTags(tags)
+	want := []string{"Lang", "lang/c", "lang/go", "lang/go/web", "lang-old", "web"}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("SortTags() = %v, want %v", tags, want)
+	}
+}
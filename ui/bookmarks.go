@@ -17,13 +17,19 @@
 package ui
 
 import (
+	"fmt"
 	"github.com/gdamore/tcell"
 	"github.com/rivo/tview"
+	"github.com/sirupsen/logrus"
+	"time"
 	"tryffel.net/go/bookmarker/config"
 	"tryffel.net/go/bookmarker/storage/models"
 	"tryffel.net/go/twidgets"
 )
 
+//number of rows before end of table when next page is loaded
+const loadMoreRows = 50
+
 type BookmarkTable struct {
 	table        *twidgets.Table
 	items        []*models.Bookmark
@@ -31,6 +37,10 @@ type BookmarkTable struct {
 	metadataFunc func(bookmark *models.Bookmark)
 	deleteFunc   func(bookmark *models.Bookmark)
 	sortFunc     func(column string, sort twidgets.Sort)
+	stateFunc    func(bookmark *models.Bookmark)
+	//total is number of bookmarks that match current filter, items may contain only first pages of them
+	total    int
+	moreFunc func(offset int) ([]*models.Bookmark, error)
 }
 
 func (b *BookmarkTable) Draw(screen tcell.Screen) {
@@ -58,6 +68,12 @@ func (b *BookmarkTable) InputHandler() func(event *tcell.EventKey, setFocus func
 				bookmark := b.items[index-1]
 				b.deleteFunc(bookmark)
 			}
+		} else if event.Rune() == 's' {
+			if b.stateFunc != nil {
+				if bookmark := b.GetSelection(); bookmark != nil {
+					b.stateFunc(bookmark)
+				}
+			}
 		} else if event.Rune() == 'n' {
 			b.moveCursor(10)
 		} else if event.Rune() == 'm' {
@@ -65,9 +81,35 @@ func (b *BookmarkTable) InputHandler() func(event *tcell.EventKey, setFocus func
 		} else {
 			b.table.InputHandler()(event, setFocus)
 		}
+		b.loadMore()
 	}
 }
 
+//SetMoreFunc sets function that returns next page of bookmarks starting at offset
+func (b *BookmarkTable) SetMoreFunc(moreFunc func(offset int) ([]*models.Bookmark, error)) {
+	b.moreFunc = moreFunc
+}
+
+//loadMore appends next page of bookmarks when cursor is near the end of loaded bookmarks
+func (b *BookmarkTable) loadMore() {
+	index, _ := b.table.GetSelection()
+	if b.moreFunc == nil || len(b.items) >= b.total || index < len(b.items)-loadMoreRows {
+		return
+	}
+	more, err := b.moreFunc(len(b.items))
+	if err != nil {
+		logrus.Errorf("Load more bookmarks: %v", err)
+		return
+	}
+	if len(more) == 0 {
+		// bookmarks have been deleted since counting
+		b.total = len(b.items)
+	}
+	b.addRows(len(b.items), more)
+	b.items = append(b.items, more...)
+	b.updateTitle()
+}
+
 func (b *BookmarkTable) moveCursor(n int) {
 	index, col := b.table.GetSelection()
 	result := index + n
@@ -95,27 +137,64 @@ func (b *BookmarkTable) GetFocusable() tview.Focusable {
 	return b.table.GetFocusable()
 }
 
+//SetData shows all bookmarks
 func (b *BookmarkTable) SetData(data []*models.Bookmark) {
+	b.SetPage(data, len(data))
+}
+
+//SetPage shows first page of bookmarks, total is number of all bookmarks. Rest of bookmarks
+//are loaded with more function when cursor is moved near the end of table.
+func (b *BookmarkTable) SetPage(data []*models.Bookmark, total int) {
 	if data == nil {
 		return
 	}
 	b.items = data
+	b.total = total
+	if b.total < len(data) {
+		b.total = len(data)
+	}
 
 	b.table.Clear(false)
+	b.addRows(0, data)
+	if len(b.items) > 0 {
+		b.table.Select(1, 0)
+	}
+	b.updateTitle()
+}
+
+func (b *BookmarkTable) updateTitle() {
+	b.table.SetTitle(fmt.Sprintf("Showing %d of %d", len(b.items), b.total))
+}
+
+//addRows adds bookmarks to table starting at index
+func (b *BookmarkTable) addRows(index int, data []*models.Bookmark) {
+	now := time.Now()
 	for i, v := range data {
+		domain := v.ContentDomain()
+		if v.LinkStatus == models.LinkStatusDead {
+			domain = "[red]" + domain + "[-]"
+		}
+		priority := ""
+		if v.Priority != 0 {
+			priority = fmt.Sprint(v.Priority)
+		}
+		due := v.DueString()
+		if v.IsOverdue(now) {
+			due = "[red]" + due + "[-]"
+		}
 		row := []string{
 			v.Name,
 			v.Description,
 			v.Project,
-			v.ContentDomain(),
+			domain,
 			v.TagsString(true),
 			ShortTimeSince(v.CreatedAt),
+			v.ReadState(),
+			priority,
+			due,
 		}
 
-		b.table.AddRow(i, row...)
-	}
-	if len(b.items) > 0 {
-		b.table.Select(1, 0)
+		b.table.AddRow(index+i, row...)
 	}
 }
 
@@ -132,6 +211,22 @@ func (b *BookmarkTable) SetSortFunc(sort func(column string, sort twidgets.Sort)
 	b.sortFunc = sort
 }
 
+//SetStateFunc sets function that is called to cycle reading state of selected bookmark
+func (b *BookmarkTable) SetStateFunc(stateFunc func(bookmark *models.Bookmark)) {
+	b.stateFunc = stateFunc
+}
+
+//UpdateBookmark redraws row of bookmark if it is in table
+func (b *BookmarkTable) UpdateBookmark(bookmark *models.Bookmark) {
+	for i, v := range b.items {
+		if v.Id == bookmark.Id {
+			b.items[i] = bookmark
+			b.addRows(i, []*models.Bookmark{bookmark})
+			return
+		}
+	}
+}
+
 func tableCell(text string) *tview.TableCell {
 	c := tview.NewTableCell(text)
 	c.SetTextColor(config.Configuration.Colors.Bookmarks.Text)
@@ -159,15 +254,16 @@ func NewBookmarkTable(openMetadata func(bookmark *models.Bookmark)) *BookmarkTab
 	b.table.SetBorder(true)
 	b.table.SetBorders(false)
 	b.table.SetBorderColor(config.Configuration.Colors.Border)
+	b.table.SetTitleColor(config.Configuration.Colors.TextPrimary)
 	b.table.SetSelectedStyle(colors.TextSelected, colors.BackgroundSelected, 0)
 	b.table.SetSelectable(true, false)
 	b.table.SetFixed(1, 10)
 
 	b.table.SetAddCellFunc(b.addCell)
 	b.table.SetShowIndex(true)
-	b.table.SetColumns([]string{"Name", "Description", "Project", "Link", "Tags", "Added at"})
-	b.table.SetColumnWidths([]int{3, 25, 35, 20, 10, 15, 10})
-	b.table.SetColumnExpansions([]int{0, 1, 3, 1, 1, 1, 1})
+	b.table.SetColumns([]string{"Name", "Description", "Project", "Link", "Tags", "Added at", "State", "Priority", "Due"})
+	b.table.SetColumnWidths([]int{3, 25, 35, 20, 10, 15, 10, 8, 8, 10})
+	b.table.SetColumnExpansions([]int{0, 1, 3, 1, 1, 1, 1, 0, 0, 0})
 	b.table.SetSort(0, twidgets.SortAsc)
 	b.table.SetSortFunc(b.sort)
 	return b
@@ -17,9 +17,11 @@
 package ui
 
 import (
+	"fmt"
 	"github.com/gdamore/tcell"
 	"github.com/rivo/tview"
 	"github.com/sirupsen/logrus"
+	"strconv"
 	"strings"
 	"time"
 	"tryffel.net/go/bookmarker/config"
@@ -33,12 +35,15 @@ const (
 	metadataLink        = "Link"
 	metadataProject     = "Project"
 	metadataTags        = "Tags"
+	metadataState       = "State"
+	metadataPriority    = "Priority"
+	metadataDue         = "Due"
 	metadataCreatedAt   = "Created at"
 	metadataUpdatedAt   = "Updated at"
 )
 
 var metadataDefaults = []string{metadataName, metadataDescription, metadataLink, metadataProject, metadataTags,
-	metadataCreatedAt, metadataUpdatedAt}
+	metadataState, metadataPriority, metadataDue, metadataCreatedAt, metadataUpdatedAt}
 
 var CustomMetadataFields = make([]string, 0)
 
@@ -60,14 +65,29 @@ type Metadata struct {
 	customKeys   *[]string
 	archived     *tview.Checkbox
 
-	doneFunc   func(save bool, bookmark *models.Bookmark) bool
-	searchFunc func(key, value string) ([]string, error)
+	doneFunc     func(save bool, bookmark *models.Bookmark) bool
+	searchFunc   func(key, value string) ([]string, error)
+	snapshotFunc func(bookmark *models.Bookmark)
+	historyFunc  func(bookmark *models.Bookmark)
+	notesFunc    func(bookmark *models.Bookmark)
 }
 
 func (m *Metadata) SetSearchFunc(searchFunc func(key, value string) ([]string, error)) {
 	m.searchFunc = searchFunc
 }
 
+func (m *Metadata) SetSnapshotFunc(snapshotFunc func(bookmark *models.Bookmark)) {
+	m.snapshotFunc = snapshotFunc
+}
+
+func (m *Metadata) SetHistoryFunc(historyFunc func(bookmark *models.Bookmark)) {
+	m.historyFunc = historyFunc
+}
+
+func (m *Metadata) SetNotesFunc(notesFunc func(bookmark *models.Bookmark)) {
+	m.notesFunc = notesFunc
+}
+
 func (m *Metadata) Draw(screen tcell.Screen) {
 	m.form.Draw(screen)
 }
@@ -139,6 +159,10 @@ func NewMetadata(doneFunc func(save bool, bookmark *models.Bookmark) bool) *Meta
 	m.defaultFields[metadataProject].SetFieldWidth(width).SetAcceptanceFunc(m.editEnabled).
 		SetAutocompleteFunc(m.wrapSearch(metadataProject))
 	m.defaultFields[metadataTags].SetFieldWidth(width).SetAcceptanceFunc(m.editEnabled)
+	m.defaultFields[metadataState].SetFieldWidth(width).SetAcceptanceFunc(m.editEnabled)
+	m.defaultFields[metadataPriority].SetFieldWidth(width).SetAcceptanceFunc(m.editEnabled)
+	m.defaultFields[metadataDue].SetFieldWidth(width).SetAcceptanceFunc(m.editEnabled).
+		SetPlaceholder(models.DueDateFormat)
 	m.defaultFields[metadataCreatedAt].SetFieldWidth(width).SetAcceptanceFunc(m.editEnabled)
 	m.defaultFields[metadataUpdatedAt].SetFieldWidth(width).SetAcceptanceFunc(m.editEnabled)
 	m.archived.SetLabel("Archived")
@@ -171,6 +195,9 @@ func (m *Metadata) setFields(bookmark *models.Bookmark) {
 	m.defaultFields[metadataLink].SetText(bookmark.Content)
 	m.defaultFields[metadataProject].SetText(bookmark.Project)
 	m.defaultFields[metadataTags].SetText(bookmark.TagsString(true))
+	m.defaultFields[metadataState].SetText(bookmark.ReadState())
+	m.defaultFields[metadataPriority].SetText(fmt.Sprint(bookmark.Priority))
+	m.defaultFields[metadataDue].SetText(bookmark.DueString())
 	m.defaultFields[metadataCreatedAt].SetText(bookmark.CreatedAt.Format("2006-01-02 15:04"))
 	m.defaultFields[metadataUpdatedAt].SetText(bookmark.UpdatedAt.Format("2006-01-02 15:04"))
 	m.archived.SetChecked(bookmark.Archived)
@@ -183,12 +210,11 @@ func (m *Metadata) toggleEdit() {
 	} else {
 		m.enableEdit = true
 		m.form.SetFieldBackgroundColor(config.Configuration.Colors.Metadata.BackgroundEditable)
-		ind := m.form.GetButtonIndex("Edit")
-		m.form.RemoveButton(ind)
+		m.form.ClearButtons()
 
 		m.form.AddButton("Save", m.save)
 		m.form.AddButton("Cancel", m.cancel)
-		m.form.AddButton("Get title", m.getTitle)
+		m.form.AddButton("Get metadata", m.getMetadata)
 	}
 }
 
@@ -201,6 +227,27 @@ func (m *Metadata) initDefaults() {
 
 func (m *Metadata) initButtons() {
 	m.form.AddButton("Edit", m.toggleEdit)
+	m.form.AddButton("Snapshots", m.showSnapshots)
+	m.form.AddButton("History", m.showHistory)
+	m.form.AddButton("Notes", m.showNotes)
+}
+
+func (m *Metadata) showSnapshots() {
+	if m.bookmark != nil && m.snapshotFunc != nil {
+		m.snapshotFunc(m.bookmark)
+	}
+}
+
+func (m *Metadata) showHistory() {
+	if m.bookmark != nil && m.historyFunc != nil {
+		m.historyFunc(m.bookmark)
+	}
+}
+
+func (m *Metadata) showNotes() {
+	if m.bookmark != nil && m.notesFunc != nil {
+		m.notesFunc(m.bookmark)
+	}
 }
 
 func (m *Metadata) initCustomFields() {
@@ -236,7 +283,37 @@ func (m *Metadata) cancel() {
 	m.initButtons()
 }
 
+//readingList parses reading state, priority and due date fields
+func (m *Metadata) readingList() (string, int, *time.Time, error) {
+	state := strings.ToLower(strings.TrimSpace(m.defaultFields[metadataState].GetText()))
+	if state == "" {
+		state = models.StateUnread
+	}
+	if !models.IsReadState(state) {
+		return "", 0, nil, fmt.Errorf("invalid state '%s', expected unread, reading or read", state)
+	}
+	priority := 0
+	text := strings.TrimSpace(m.defaultFields[metadataPriority].GetText())
+	if text != "" {
+		var err error
+		priority, err = strconv.Atoi(text)
+		if err != nil {
+			return "", 0, nil, fmt.Errorf("invalid priority '%s'", text)
+		}
+	}
+	due, err := models.ParseDueDate(m.defaultFields[metadataDue].GetText())
+	if err != nil {
+		return "", 0, nil, err
+	}
+	return state, priority, due, nil
+}
+
 func (m *Metadata) save() {
+	state, priority, due, err := m.readingList()
+	if err != nil {
+		logrus.Errorf("Save bookmark: %v", err)
+		return
+	}
 	m.tmpBookmark = &models.Bookmark{
 		Id:           m.bookmark.Id,
 		Name:         m.defaultFields[metadataName].GetText(),
@@ -247,6 +324,10 @@ func (m *Metadata) save() {
 		CreatedAt:    m.bookmark.CreatedAt,
 		UpdatedAt:    time.Now(),
 		Archived:     m.archived.IsChecked(),
+		Notes:        m.bookmark.Notes,
+		State:        state,
+		Priority:     priority,
+		DueAt:        due,
 		Tags:         nil,
 		Metadata:     m.bookmark.Metadata,
 		MetadataKeys: m.bookmark.MetadataKeys,
@@ -279,7 +360,9 @@ func (m *Metadata) exitEdit() {
 	m.form.SetFieldBackgroundColor(config.Configuration.Colors.Metadata.TextBackground)
 }
 
-func (m *Metadata) getTitle() {
+//getMetadata fetches page metadata and fills it into metadata fields. Title is always updated,
+//other fields only if they are empty.
+func (m *Metadata) getMetadata() {
 	//TODO: run in background
 	url := m.defaultFields["Link"].GetText()
 	if url == "" {
@@ -287,9 +370,24 @@ func (m *Metadata) getTitle() {
 	}
 	metadata, err := external.GetPageMetadata(url)
 	if err != nil {
-		logrus.Errorf("get site title: %v", err)
-	} else {
-		(*m.customFields)["Title"].SetText(metadata.Title)
+		logrus.Errorf("get page metadata: %v", err)
+		return
+	}
+
+	for key, value := range metadata.Fields() {
+		field := (*m.customFields)[key]
+		if field == nil {
+			continue
+		}
+		if key == "Title" || field.GetText() == "" {
+			field.SetText(value)
+		}
+	}
+	if m.defaultFields[metadataName].GetText() == "" {
+		m.defaultFields[metadataName].SetText(metadata.Title)
+	}
+	if m.defaultFields[metadataDescription].GetText() == "" {
+		m.defaultFields[metadataDescription].SetText(metadata.Description)
 	}
 }
 
@@ -17,6 +17,7 @@
 package modals
 
 import (
+	"fmt"
 	"github.com/gdamore/tcell"
 	"github.com/rivo/tview"
 	"github.com/sirupsen/logrus"
@@ -32,12 +33,14 @@ type BookmarkForm struct {
 	doneFunc   func()
 	formFunc   func(bookmark *models.Bookmark)
 	searchFunc func(key, value string) ([]string, error)
+	editFunc   func(text string) (string, error)
 
 	nameField        *tview.InputField
 	descriptionField *tview.InputField
 	linkField        *tview.InputField
 	projectField     *tview.InputField
 	tagsField        *tview.InputField
+	notes            string
 }
 
 func NewBookmarkForm(createFunc func(bookmark *models.Bookmark)) *BookmarkForm {
@@ -84,6 +87,11 @@ func (n *BookmarkForm) SetSearchFunc(search func(key, value string) ([]string, e
 	n.searchFunc = search
 }
 
+//SetEditFunc sets func that edits notes of new bookmark in external editor
+func (n *BookmarkForm) SetEditFunc(editFunc func(text string) (string, error)) {
+	n.editFunc = editFunc
+}
+
 func (n *BookmarkForm) Draw(screen tcell.Screen) {
 	n.form.Draw(screen)
 }
@@ -123,6 +131,7 @@ func (n *BookmarkForm) create() {
 		Description: n.descriptionField.GetText(),
 		Content:     n.linkField.GetText(),
 		Project:     n.projectField.GetText(),
+		Notes:       n.notes,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
@@ -153,6 +162,8 @@ func (n *BookmarkForm) Clear() {
 	n.linkField.SetText("")
 	n.projectField.SetText("")
 	n.tagsField.SetText("")
+	n.notes = ""
+	n.form.SetTitle("New bookmark")
 	n.initForm()
 }
 
@@ -171,18 +182,55 @@ func (n *BookmarkForm) initForm() {
 
 	n.form.AddButton("Create", n.create)
 	n.form.AddButton("Cancel", n.doneFunc)
-	n.form.AddButton("Get title", n.getTitle)
+	n.form.AddButton("Get metadata", n.getMetadata)
+	n.form.AddButton("Notes", n.editNotes)
+}
+
+//editNotes edits notes of new bookmark
+func (n *BookmarkForm) editNotes() {
+	if n.editFunc == nil {
+		return
+	}
+	notes, err := n.editFunc(n.notes)
+	if err != nil {
+		logrus.Errorf("edit notes: %v", err)
+		return
+	}
+	n.notes = notes
+	if notes == "" {
+		n.form.SetTitle("New bookmark")
+	} else {
+		n.form.SetTitle(fmt.Sprintf("New bookmark (notes: %d lines)", strings.Count(notes, "\n")+1))
+	}
 }
 
-func (n *BookmarkForm) getTitle() {
+//getMetadata fetches page metadata and fills it into metadata fields. Title is always updated,
+//other fields only if they are empty.
+func (n *BookmarkForm) getMetadata() {
 	if n.linkField.GetText() == "" {
 		return
 	}
 	metadata, err := external.GetPageMetadata(n.linkField.GetText())
 	if err != nil {
-		logrus.Errorf("get site title: %v", err)
-	} else {
-		n.form.GetFormItemByLabel("Title").(*tview.InputField).SetText(metadata.Title)
+		logrus.Errorf("get page metadata: %v", err)
+		return
+	}
+
+	for key, value := range metadata.Fields() {
+		item := n.form.GetFormItemByLabel(key)
+		if item == nil {
+			continue
+		}
+		field := item.(*tview.InputField)
+		if key == "Title" || field.GetText() == "" {
+			field.SetText(value)
+		}
+	}
+	if n.nameField.GetText() == "" {
+		n.nameField.SetText(metadata.Title)
+	}
+	if n.descriptionField.GetText() == "" {
+		n.descriptionField.SetText(metadata.Description)
 	}
 }
 
@@ -48,7 +48,7 @@ func NewDeleteBookmark(doneFunc func(bool), bookmark *models.Bookmark) *DeleteBo
 	d.Modal.SetTextColor(colors.Text)
 	d.Modal.SetTitle("Delete Bookmark")
 
-	d.SetText(fmt.Sprintf("Are you sure you want to delete bookmark \"%s\"", bookmark.Name))
+	d.SetText(fmt.Sprintf("Move bookmark \"%s\" to trash?", bookmark.Name))
 	d.Modal.SetButtonBackgroundColor(col.ButtonBackground)
 	d.Modal.SetButtonTextColor(col.ButtonLabel)
 
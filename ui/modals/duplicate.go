@@ -0,0 +1,76 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package modals
+
+import (
+	"fmt"
+	"github.com/rivo/tview"
+	"tryffel.net/go/bookmarker/config"
+	"tryffel.net/go/bookmarker/storage"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+var duplicateButtons = map[string]storage.DuplicatePolicy{
+	"Skip":   storage.DuplicateSkip,
+	"Update": storage.DuplicateUpdate,
+	"Merge":  storage.DuplicateMerge,
+	"Create": storage.DuplicateCreate,
+}
+
+//DuplicateBookmark asks how to save new bookmark, whose link already exists
+type DuplicateBookmark struct {
+	*tview.Modal
+	done func(policy storage.DuplicatePolicy)
+}
+
+func (d *DuplicateBookmark) SetDoneFunc(doneFunc func()) {
+}
+
+func (d *DuplicateBookmark) SetVisible(visible bool) {
+}
+
+func NewDuplicateBookmark(doneFunc func(policy storage.DuplicatePolicy), bookmark,
+	existing *models.Bookmark) *DuplicateBookmark {
+	d := &DuplicateBookmark{
+		Modal: tview.NewModal(),
+		done:  doneFunc,
+	}
+
+	colors := config.Configuration.Colors.BookmarkForm
+	col := config.Configuration.Colors
+	d.Modal.SetBackgroundColor(colors.Background)
+	d.Modal.SetBorder(true)
+	d.Modal.SetBorderColor(col.BorderFocus)
+	d.Modal.SetTextColor(colors.Text)
+	d.Modal.SetTitle("Duplicate Bookmark")
+
+	d.SetText(fmt.Sprintf("Link %s already exists in bookmark \"%s\".\n"+
+		"Skip new bookmark, update existing bookmark with it, merge it into existing one or create it anyway?",
+		bookmark.Content, existing.Name))
+	d.Modal.SetButtonBackgroundColor(col.ButtonBackground)
+	d.Modal.SetButtonTextColor(col.ButtonLabel)
+
+	d.AddButtons([]string{"Skip", "Update", "Merge", "Create"})
+	d.Modal.SetDoneFunc(d.doneFunc)
+	return d
+}
+
+func (d *DuplicateBookmark) doneFunc(index int, label string) {
+	if policy, ok := duplicateButtons[label]; ok && d.done != nil {
+		d.done(policy)
+	}
+}
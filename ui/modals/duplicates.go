@@ -0,0 +1,127 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package modals
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"strings"
+	"tryffel.net/go/bookmarker/config"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+const duplicatesTitle = "Duplicates (m: merge, M: merge all)"
+
+//Duplicates lists groups of bookmarks that have same link. Group is merged into its oldest bookmark.
+type Duplicates struct {
+	*tview.List
+	loadFunc  func() ([][]*models.Bookmark, error)
+	mergeFunc func(bookmarks []*models.Bookmark) error
+
+	groups [][]*models.Bookmark
+}
+
+func (d *Duplicates) SetDoneFunc(doneFunc func()) {
+}
+
+func (d *Duplicates) SetVisible(visible bool) {
+}
+
+func NewDuplicates(loadFunc func() ([][]*models.Bookmark, error),
+	mergeFunc func(bookmarks []*models.Bookmark) error) *Duplicates {
+	d := &Duplicates{
+		List:      tview.NewList(),
+		loadFunc:  loadFunc,
+		mergeFunc: mergeFunc,
+	}
+
+	colors := config.Configuration.Colors.BookmarkForm
+	d.SetBackgroundColor(colors.Background)
+	d.SetBorder(true)
+	d.SetTitle(duplicatesTitle)
+	d.SetBorderColor(config.Configuration.Colors.Border)
+	d.SetMainTextColor(colors.Text)
+	d.SetSecondaryTextColor(colors.Label)
+	d.SetSelectedBackgroundColor(colors.TextSelected)
+	return d
+}
+
+//Reload loads duplicates into list
+func (d *Duplicates) Reload() {
+	index := d.GetCurrentItem()
+	d.SetTitle(duplicatesTitle)
+	d.Clear()
+	d.groups = nil
+	if d.loadFunc != nil {
+		groups, err := d.loadFunc()
+		if err != nil {
+			d.AddItem("Error", err.Error(), 0, nil)
+			return
+		}
+		d.groups = groups
+	}
+
+	if len(d.groups) == 0 {
+		d.AddItem("No duplicates", "", 0, nil)
+		return
+	}
+	for _, group := range d.groups {
+		names := make([]string, len(group))
+		for i, v := range group {
+			names[i] = v.Name
+		}
+		d.AddItem(tview.Escape(fmt.Sprintf("%s (%d bookmarks)", group[0].Content, len(group))),
+			tview.Escape(strings.Join(names, ", ")), 0, nil)
+	}
+	if index >= len(d.groups) {
+		index = len(d.groups) - 1
+	}
+	d.SetCurrentItem(index)
+}
+
+func (d *Duplicates) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if event.Key() != tcell.KeyRune || d.mergeFunc == nil {
+			d.List.InputHandler()(event, setFocus)
+			return
+		}
+
+		var err error
+		switch event.Rune() {
+		case 'm':
+			index := d.GetCurrentItem()
+			if index >= 0 && index < len(d.groups) {
+				err = d.mergeFunc(d.groups[index])
+			}
+		case 'M':
+			for _, group := range d.groups {
+				err = d.mergeFunc(group)
+				if err != nil {
+					break
+				}
+			}
+		default:
+			d.List.InputHandler()(event, setFocus)
+			return
+		}
+		d.Reload()
+		if err != nil {
+			d.SetTitle(fmt.Sprintf("Duplicates: Error: %v", err))
+		}
+	}
+}
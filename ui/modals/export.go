@@ -0,0 +1,136 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package modals
+
+import (
+	"fmt"
+	"github.com/rivo/tview"
+	"path/filepath"
+	"strings"
+	"tryffel.net/go/bookmarker/config"
+	"tryffel.net/go/bookmarker/external"
+)
+
+type ExportData struct {
+	File string
+	//Format is name of external.Exporter
+	Format string
+	//Filter is storage.Filter query. Empty filter exports all bookmarks
+	Filter string
+}
+
+//ExportForm is a modal for exporting bookmarks into a file
+type ExportForm struct {
+	*tview.Form
+	exportOngoing bool
+
+	exportFunc func(data *ExportData)
+	closeFunc  func()
+}
+
+func (e *ExportForm) SetDoneFunc(doneFunc func()) {
+	e.closeFunc = doneFunc
+}
+
+func (e *ExportForm) SetVisible(visible bool) {
+}
+
+func (e *ExportForm) SetExportFunc(exportFunc func(data *ExportData)) {
+	e.exportFunc = exportFunc
+}
+
+func NewExportForm() *ExportForm {
+	e := &ExportForm{Form: tview.NewForm()}
+
+	colors := config.Configuration.Colors.BookmarkForm
+	e.SetTitle("Export bookmarks")
+	e.SetTitleColor(colors.Text)
+
+	e.SetBorder(true)
+	e.SetBorderColor(config.Configuration.Colors.Border)
+	e.SetBackgroundColor(colors.Background)
+	e.SetLabelColor(colors.Label)
+	e.SetFieldBackgroundColor(colors.TextBackground)
+	e.SetFieldTextColor(colors.Text)
+
+	e.initForm()
+	return e
+}
+
+func (e *ExportForm) initForm() {
+	e.AddInputField("File", "bookmarks-export.html", 0, nil, nil)
+	e.AddDropDown("Format", external.ExporterNames(), 0, e.selectFormat)
+	filter := tview.NewInputField().SetLabel("Filter").SetPlaceholder("project:bookmarks")
+	filter.SetPlaceholderTextColor(config.Configuration.Colors.BookmarkForm.TextPlaceHolder)
+	e.AddFormItem(filter)
+	e.AddButton("Export", e.doExport)
+}
+
+//selectFormat changes extension of file to match format
+func (e *ExportForm) selectFormat(format string, index int) {
+	field, ok := e.GetFormItemByLabel("File").(*tview.InputField)
+	if !ok || index < 0 {
+		return
+	}
+	file := field.GetText()
+	file = strings.TrimSuffix(file, filepath.Ext(file)) + external.Exporters[index].Extension()
+	field.SetText(file)
+}
+
+func (e *ExportForm) doExport() {
+	if e.exportFunc != nil && !e.exportOngoing {
+		e.exportOngoing = true
+		data := &ExportData{
+			File:   e.GetFormItemByLabel("File").(*tview.InputField).GetText(),
+			Filter: e.GetFormItemByLabel("Filter").(*tview.InputField).GetText(),
+		}
+		_, data.Format = e.GetFormItemByLabel("Format").(*tview.DropDown).GetCurrentOption()
+		e.exportFunc(data)
+	}
+}
+
+func (e *ExportForm) ExportDone(count int, msg string, ok bool) {
+	e.Clear(true)
+	if ok {
+		e.AddInputField("Status", "Successful", 0, e.denyInput, nil)
+	} else {
+		e.AddInputField("Status", "Failed", 0, e.denyInput, nil)
+	}
+	e.AddInputField("Exported", fmt.Sprintf("%d bookmarks", count), 0, e.denyInput, nil)
+	if msg != "" {
+		e.AddInputField("Message", msg, 0, e.denyInput, nil)
+	}
+
+	e.AddButton("Close", e.close)
+}
+
+func (e *ExportForm) denyInput(string, rune) bool {
+	return false
+}
+
+//Reset resets form, which must be called before creating new export
+func (e *ExportForm) Reset() {
+	e.exportOngoing = false
+	e.Clear(true)
+	e.initForm()
+}
+
+func (e *ExportForm) close() {
+	if e.closeFunc != nil {
+		e.closeFunc()
+	}
+}
@@ -151,11 +151,15 @@ func (h *Help) statsPage() string {
 	runtime.ReadMemStats(&runStats)
 
 	timeFormat := "2006-01-02 15:04:05"
-	text += fmt.Sprintf("Bookmarks: %d\nArchived: %d\nTags: %d\nProjects: %d\nLast Bookmark: %s\n",
-		stats.Bookmarks, stats.Archived, stats.Tags, stats.Projects, stats.LastBookmark.Format(timeFormat))
+	text += fmt.Sprintf("Bookmarks: %d\nArchived: %d\nIn trash: %d\nTags: %d\nProjects: %d\nLast Bookmark: %s\n",
+		stats.Bookmarks, stats.Archived, stats.Deleted, stats.Tags, stats.Projects, stats.LastBookmark.Format(timeFormat))
 
 	text += fmt.Sprintf("Memory: %s\n", formatBytes(runStats.Alloc))
 
+	text += "\n[yellow]Reading list[-]\n"
+	text += fmt.Sprintf("Unread: %d\nReading: %d\nRead: %d\nOverdue: %d\n",
+		stats.Unread, stats.Reading, stats.Read, stats.Overdue)
+
 	text += "\n[yellow]Additional info[-]\n"
 	text += fmt.Sprintf("Data location: %s\n", config.Configuration.ConfigDir())
 	text += fmt.Sprintf("Full text search engine supported: %v\n", stats.FullTextSearchSupported)
@@ -178,14 +182,14 @@ func (h *Help) searchPage() string {
 	return "" +
 		`[yellow]Full text search[-]
 If Bookmarker was built with full text search support, 
-plain search queries (no filters) will result in full text queries. Results are then highlighted.
-Full text queries can be any text:
+search query that is a single word will result in full text query. Results are then highlighted.
+'[#00d7ff]mypage[-]'
+Prefix search is supported:
+'[#00d7ff]mypag*[-]'
+Queries with more words match bookmarks that contain each word:
 '[#00d7ff]my awesome site[-]'
-this will return any bookmark that contains phrase containing each word in query.
 You can use AND/OR/NOT clauses to modify query:
-'[#00d7ff](mypage AND com) OR mypage.com))[-]'
-Wildcards are supported:
-'[#00d7ff]mypag*[-]'
+'[#00d7ff](mypage AND com) OR mypage.com[-]'
 Exact matches:
 '[#00d7ff]"mypage that contains a"[-]'
 
@@ -200,6 +204,16 @@ Negations can be applied with preceding '-':
 Matching field exactly can be done by enclosing value with '. e.g.:
 '[#00d7ff]link:'mypage.com'[-]'
 Would match exactly link mypage.com
+
+[yellow]Reading list[-]
+Bookmarks have reading state, priority and optional due date:
+'[#00d7ff]state:unread priority:>2 sort:priority[-]'
+'[#00d7ff]due:overdue[-]' (not read and past due date), '[#00d7ff]due:none[-]', '[#00d7ff]due:any[-]'
+or '[#00d7ff]due:2020-06-01[-]' (due before date)
+
+[yellow]Notes[-]
+Notes are included in full text search and can be filtered with:
+'[#00d7ff]notes:todo[-]'
 `
 }
 
@@ -220,8 +234,36 @@ func (h *Help) shortcutsPage() string {
 * Search: Enter
 * Cancel: Escape
 
+[yellow]Bookmarks[-]:
+* s cycles reading state of selected bookmark: unread, reading, read
+
 [yellow]Metadata[-]:
 * Ctrl-space opens metadata viewer for selected bookmark
+* Snapshots shows archived copies of page: a archives page now, n / p moves to newer / older snapshot,
+o opens archived html in browser
+* History shows previous revisions and their changes: n / p moves to newer / older revision, 
+r restores bookmark to revision
+* Notes shows markdown notes of bookmark: e edits notes in $EDITOR
+
+[yellow]Import preview[-]:
+* Space selects / deselects bookmark, a selects all or none
+* p moves folder to another project, P moves single bookmark, i imports selected bookmarks
+
+[yellow]History[-]:
+* Undo: Ctrl-Z
+* Redo: Ctrl-Y
+
+[yellow]Projects[-]:
+* r renames, moves or merges selected project and its children
+
+[yellow]Tags[-]:
+* Enter filters with selected tag, space adds / removes tag from filter, c clears tag filter
+* o combines selected tags with all of / any of, tags are combined with selected project
+* r renames, m merges and d deletes selected tag and its children
+
+[yellow]Saved searches[-]:
+* Enter shows bookmarks of selected search
+* n saves current search query, e edits or deletes selected search
 
 [yellow]Sorting[-]:
 * Navigate to any column header and press enter to sort either ascending or descending
@@ -0,0 +1,167 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package modals
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"strings"
+	"tryffel.net/go/bookmarker/config"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+const historyKeys = "n/p: newer/older, r: restore"
+
+//History shows previous revisions of bookmark and changes made after each of them
+type History struct {
+	*tview.TextView
+	loadFunc    func(bookmark int) ([]*models.Revision, error)
+	restoreFunc func(revision *models.Revision) (*models.Bookmark, error)
+
+	bookmark  *models.Bookmark
+	revisions []*models.Revision
+	// index of shown revision, 0 is latest
+	index int
+}
+
+func (h *History) SetDoneFunc(doneFunc func()) {
+}
+
+func (h *History) SetVisible(visible bool) {
+}
+
+//NewHistory creates new history view. RestoreFunc restores bookmark to revision and returns updated bookmark.
+func NewHistory(loadFunc func(bookmark int) ([]*models.Revision, error),
+	restoreFunc func(revision *models.Revision) (*models.Bookmark, error)) *History {
+	h := &History{
+		TextView:    tview.NewTextView(),
+		loadFunc:    loadFunc,
+		restoreFunc: restoreFunc,
+	}
+
+	colors := config.Configuration.Colors.BookmarkForm
+	h.SetBackgroundColor(colors.Background)
+	h.SetTextColor(colors.Text)
+	h.SetBorder(true)
+	h.SetBorderColor(config.Configuration.Colors.Border)
+	h.SetWordWrap(true)
+	h.SetDynamicColors(true)
+	return h
+}
+
+//SetBookmark loads revisions of bookmark and shows latest of them. Bookmark must have its metadata.
+func (h *History) SetBookmark(bookmark *models.Bookmark) {
+	h.bookmark = bookmark
+	h.index = 0
+	h.Reload()
+}
+
+//Reload loads revisions and shows selected revision
+func (h *History) Reload() {
+	h.revisions = nil
+	if h.bookmark != nil && h.loadFunc != nil {
+		revisions, err := h.loadFunc(h.bookmark.Id)
+		if err != nil {
+			h.showStatus(fmt.Sprintf("Error: %v", err))
+			h.SetText("")
+			return
+		}
+		h.revisions = revisions
+	}
+	h.show()
+}
+
+func (h *History) show() {
+	if len(h.revisions) == 0 {
+		h.showStatus("No revisions")
+		h.SetText("Bookmark has not been modified.")
+		return
+	}
+	if h.index >= len(h.revisions) {
+		h.index = len(h.revisions) - 1
+	}
+	revision := h.revisions[h.index]
+	h.showStatus(fmt.Sprintf("%s (%d/%d)", revision.CreatedAt.Local().Format("2006-01-02 15:04"), h.index+1,
+		len(h.revisions)))
+
+	// compare to next newer revision or current state
+	newer := models.NewRevision(h.bookmark)
+	if h.index > 0 {
+		newer = h.revisions[h.index-1]
+	}
+
+	text := fmt.Sprintf("Changed by %s\n\n", tview.Escape(revision.Operation))
+	changes := revision.Diff(newer)
+	if len(changes) == 0 {
+		text += "No changes"
+	}
+	for _, v := range changes {
+		text += fmt.Sprintf("%s\n", tview.Escape(v.Field))
+		if v.Old != "" {
+			text += fmt.Sprintf("[red]- %s[-]\n", tview.Escape(indent(v.Old)))
+		}
+		if v.New != "" {
+			text += fmt.Sprintf("[green]+ %s[-]\n", tview.Escape(indent(v.New)))
+		}
+	}
+	h.SetText(text)
+	h.ScrollToBeginning()
+}
+
+//indent indents lines after first line of multi-line value
+func indent(value string) string {
+	return strings.Replace(value, "\n", "\n  ", -1)
+}
+
+//showStatus shows status and available keys in title
+func (h *History) showStatus(status string) {
+	h.SetTitle(fmt.Sprintf("History: %s (%s)", status, historyKeys))
+}
+
+func (h *History) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if event.Key() != tcell.KeyRune {
+			h.TextView.InputHandler()(event, setFocus)
+			return
+		}
+
+		switch event.Rune() {
+		case 'n':
+			if h.index > 0 {
+				h.index -= 1
+				h.show()
+			}
+		case 'p':
+			if h.index < len(h.revisions)-1 {
+				h.index += 1
+				h.show()
+			}
+		case 'r':
+			if h.index < len(h.revisions) && h.restoreFunc != nil {
+				bookmark, err := h.restoreFunc(h.revisions[h.index])
+				if err != nil {
+					h.showStatus(fmt.Sprintf("Error: %v", err))
+					return
+				}
+				h.SetBookmark(bookmark)
+			}
+		default:
+			h.TextView.InputHandler()(event, setFocus)
+		}
+	}
+}
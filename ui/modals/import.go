@@ -22,14 +22,21 @@ import (
 	"strings"
 	"time"
 	"tryffel.net/go/bookmarker/config"
+	"tryffel.net/go/bookmarker/external"
+	"tryffel.net/go/bookmarker/storage"
 )
 
 type ImportData struct {
 	File               string
 	Tags               []string
 	MapFoldersProjects bool
+	Duplicates         storage.DuplicatePolicy
 }
 
+//duplicate policies in order of import form options
+var importDuplicateOptions = []storage.DuplicatePolicy{storage.DuplicateSkip, storage.DuplicateUpdate,
+	storage.DuplicateMerge, storage.DuplicateCreate}
+
 type ImportForm struct {
 	*tview.Form
 	importOngoing bool
@@ -70,10 +77,27 @@ func NewImportForm() *ImportForm {
 func (i *ImportForm) initForm() {
 	now := time.Now()
 	ts := fmt.Sprintf("%d-%d-%d", now.Year(), now.Month(), now.Day())
-	i.AddInputField("File", "bookmarks.html", 0, nil, nil)
+	i.AddInputField("File", "bookmarks.html", 0, nil, i.detectFormat)
+	i.AddInputField("Format", "", 0, i.denyInput, nil)
 	i.AddInputField("Add tags", fmt.Sprintf("import-%s", ts), 0, nil, nil)
 	i.AddCheckbox("Map folders to projects", true, nil)
+	options := make([]string, len(importDuplicateOptions))
+	for index, v := range importDuplicateOptions {
+		options[index] = v.String()
+	}
+	i.AddDropDown("Existing links", options, 0, nil)
 	i.AddButton("Import", i.doImport)
+	i.detectFormat("bookmarks.html")
+}
+
+//detectFormat shows format of file, which is detected from its contents
+func (i *ImportForm) detectFormat(file string) {
+	format := "unknown"
+	importer, err := external.DetectImporter(file)
+	if err == nil {
+		format = importer.Name()
+	}
+	i.GetFormItemByLabel("Format").(*tview.InputField).SetText(format)
 }
 
 func (i *ImportForm) doImport() {
@@ -83,6 +107,10 @@ func (i *ImportForm) doImport() {
 			File:               i.GetFormItemByLabel("File").(*tview.InputField).GetText(),
 			MapFoldersProjects: i.GetFormItemByLabel("Map folders to projects").(*tview.Checkbox).IsChecked(),
 		}
+		option, _ := i.GetFormItemByLabel("Existing links").(*tview.DropDown).GetCurrentOption()
+		if option >= 0 {
+			data.Duplicates = importDuplicateOptions[option]
+		}
 		tags := i.GetFormItemByLabel("Add tags").(*tview.InputField).GetText()
 		if tags != "" {
 			data.Tags = strings.Split(tags, ",")
@@ -91,6 +119,12 @@ func (i *ImportForm) doImport() {
 	}
 }
 
+//ImportStarted shows status while file is being read
+func (i *ImportForm) ImportStarted(status string) {
+	i.Clear(true)
+	i.AddInputField("Status", status, 0, i.denyInput, nil)
+}
+
 func (i *ImportForm) ImportDone(count int, msg string, ok bool) {
 	i.Clear(true)
 	if ok {
@@ -0,0 +1,291 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package modals
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"sort"
+	"strings"
+	"tryffel.net/go/bookmarker/config"
+	"tryffel.net/go/bookmarker/storage"
+)
+
+const importPreviewKeys = "space: select, a: all/none, p: move folder, P: move bookmark, i: import"
+
+//ImportPreview lists parsed bookmarks before importing them. Bookmarks can be deselected and moved
+//to other projects, and summary shows counts per project, duplicates, invalid links and folder mappings.
+type ImportPreview struct {
+	*tview.Flex
+	summary *tview.TextView
+	table   *tview.Table
+	input   *tview.InputField
+
+	importFunc func(preview *storage.ImportPreview, data *ImportData)
+
+	preview *storage.ImportPreview
+	data    *ImportData
+	format  string
+	// editing project of whole folder (p) or single bookmark (P)
+	editing    bool
+	editFolder bool
+	importing  bool
+}
+
+func (i *ImportPreview) SetDoneFunc(doneFunc func()) {
+}
+
+func (i *ImportPreview) SetVisible(visible bool) {
+}
+
+func NewImportPreview(importFunc func(preview *storage.ImportPreview, data *ImportData)) *ImportPreview {
+	i := &ImportPreview{
+		Flex:       tview.NewFlex(),
+		summary:    tview.NewTextView(),
+		table:      tview.NewTable(),
+		input:      tview.NewInputField(),
+		importFunc: importFunc,
+	}
+
+	colors := config.Configuration.Colors.BookmarkForm
+	i.SetBackgroundColor(colors.Background)
+	i.SetBorder(true)
+	i.SetBorderColor(config.Configuration.Colors.Border)
+	i.SetTitleColor(colors.Text)
+	i.summary.SetBackgroundColor(colors.Background)
+	i.summary.SetTextColor(colors.Text)
+	i.summary.SetWordWrap(true)
+	i.table.SetBackgroundColor(colors.Background)
+	i.table.SetSelectable(true, false)
+	i.table.SetFixed(1, 0)
+	i.table.SetSelectedStyle(colors.Text, colors.TextSelected, tcell.AttrNone)
+	i.input.SetBackgroundColor(colors.Background)
+	i.input.SetLabelColor(colors.Label)
+	i.input.SetFieldBackgroundColor(colors.TextBackground)
+	i.input.SetFieldTextColor(colors.Text)
+
+	i.SetDirection(tview.FlexRow)
+	i.AddItem(i.summary, 6, 0, false)
+	i.AddItem(i.table, 0, 1, true)
+	i.AddItem(i.input, 1, 0, false)
+	return i
+}
+
+//SetPreview shows preview of bookmarks read from file in format
+func (i *ImportPreview) SetPreview(preview *storage.ImportPreview, format string, data *ImportData) {
+	i.preview = preview
+	i.format = format
+	i.data = data
+	i.editing = false
+	i.importing = false
+	i.input.SetLabel("").SetText("")
+	i.input.Blur()
+	i.SetTitle(fmt.Sprintf("Import preview (%s)", importPreviewKeys))
+	i.refresh()
+	i.table.Select(1, 0)
+	i.table.ScrollToBeginning()
+}
+
+//ImportProgress shows number of imported bookmarks
+func (i *ImportPreview) ImportProgress(done, total int) {
+	i.SetTitle(fmt.Sprintf("Importing %d / %d", done, total))
+}
+
+//ImportFailed shows error and allows modifying preview and importing again
+func (i *ImportPreview) ImportFailed(err error) {
+	i.importing = false
+	i.SetTitle(fmt.Sprintf("Import failed: %v (%s)", err, importPreviewKeys))
+}
+
+func (i *ImportPreview) refresh() {
+	i.table.Clear()
+	for column, v := range []string{"", "Name", "Project", "Status", "Link"} {
+		i.table.SetCell(0, column, tview.NewTableCell(v).SetSelectable(false).
+			SetTextColor(config.Configuration.Colors.BookmarkForm.Label))
+	}
+	if i.preview == nil {
+		return
+	}
+	for index := range i.preview.Items {
+		i.setRow(index)
+	}
+	i.showSummary()
+}
+
+//setRow updates row of item at index
+func (i *ImportPreview) setRow(index int) {
+	item := i.preview.Items[index]
+	selected := "[ ]"
+	if item.Selected {
+		selected = "[x]"
+	}
+	status := "new"
+	switch {
+	case item.Invalid != "":
+		status = "invalid: " + item.Invalid
+	case item.Repeated:
+		status = "repeated"
+	case item.Existing != 0:
+		status = fmt.Sprintf("exists #%d", item.Existing)
+	}
+	project := item.Bookmark.Project
+	if project != item.Folder {
+		project = fmt.Sprintf("%s <- %s", project, item.Folder)
+	}
+
+	color := config.Configuration.Colors.BookmarkForm.Text
+	for column, v := range []string{selected, item.Bookmark.Name, project, status, item.Bookmark.Content} {
+		cell := tview.NewTableCell(tview.Escape(v)).SetTextColor(color).SetMaxWidth(40)
+		if column == 4 {
+			cell.SetExpansion(1)
+		}
+		i.table.SetCell(index+1, column, cell)
+	}
+}
+
+func (i *ImportPreview) showSummary() {
+	total, selected, existing, repeated, invalid := i.preview.Counts()
+	policy := storage.DuplicateSkip
+	if i.data != nil {
+		policy = i.data.Duplicates
+	}
+	text := fmt.Sprintf("Format %s: %d bookmarks, %d selected. %d existing links (%s), %d repeated in file, %d invalid links\n",
+		i.format, total, selected, existing, policy, repeated, invalid)
+
+	counts := i.preview.Projects()
+	projects := make([]string, 0, len(counts))
+	for project := range counts {
+		projects = append(projects, project)
+	}
+	sort.Strings(projects)
+	for index, project := range projects {
+		name := project
+		if name == "" {
+			name = "(no project)"
+		}
+		projects[index] = fmt.Sprintf("%s: %d", name, counts[project])
+	}
+	text += "Projects: " + strings.Join(projects, ", ") + "\n"
+
+	mappings := []string{}
+	for _, v := range i.preview.Mappings() {
+		if v.Folder != v.Project {
+			mappings = append(mappings, fmt.Sprintf("%s -> %s: %d", v.Folder, v.Project, v.Count))
+		}
+	}
+	if len(mappings) > 0 {
+		text += "Moved folders: " + strings.Join(mappings, ", ")
+	}
+	i.summary.SetText(tview.Escape(text))
+}
+
+//current returns index of selected item or -1
+func (i *ImportPreview) current() int {
+	row, _ := i.table.GetSelection()
+	if i.preview == nil || row < 1 || row > len(i.preview.Items) {
+		return -1
+	}
+	return row - 1
+}
+
+func (i *ImportPreview) Focus(delegate func(p tview.Primitive)) {
+	i.Box.Focus(delegate)
+	i.table.Focus(delegate)
+}
+
+func (i *ImportPreview) Blur() {
+	i.table.Blur()
+	i.input.Blur()
+	i.Box.Blur()
+}
+
+func (i *ImportPreview) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if i.preview == nil || i.importing {
+			return
+		}
+		if i.editing {
+			i.editInput(event, setFocus)
+			return
+		}
+		if event.Key() != tcell.KeyRune {
+			i.table.InputHandler()(event, setFocus)
+			return
+		}
+
+		index := i.current()
+		switch event.Rune() {
+		case ' ':
+			if index >= 0 {
+				item := i.preview.Items[index]
+				item.Selected = !item.Selected
+				i.setRow(index)
+				i.showSummary()
+			}
+		case 'a':
+			_, selected, _, _, invalid := i.preview.Counts()
+			i.preview.SelectAll(selected < len(i.preview.Items)-invalid)
+			i.refresh()
+		case 'p', 'P':
+			if index >= 0 {
+				item := i.preview.Items[index]
+				i.editing = true
+				i.editFolder = event.Rune() == 'p'
+				label := fmt.Sprintf("Project for '%s': ", item.Bookmark.Name)
+				if i.editFolder {
+					label = fmt.Sprintf("Project for folder '%s': ", item.Folder)
+				}
+				i.input.SetLabel(tview.Escape(label)).SetText(item.Bookmark.Project)
+				i.table.Blur()
+				i.input.Focus(nil)
+			}
+		case 'i':
+			if i.importFunc != nil {
+				i.importing = true
+				i.ImportProgress(0, len(i.preview.Selected()))
+				i.importFunc(i.preview, i.data)
+			}
+		default:
+			i.table.InputHandler()(event, setFocus)
+		}
+	}
+}
+
+//editInput passes event to project input, applying project on enter
+func (i *ImportPreview) editInput(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	if event.Key() != tcell.KeyEnter {
+		i.input.InputHandler()(event, setFocus)
+		return
+	}
+
+	index := i.current()
+	if index >= 0 {
+		item := i.preview.Items[index]
+		project := strings.Trim(strings.TrimSpace(i.input.GetText()), ".")
+		if i.editFolder {
+			i.preview.Retarget(item.Folder, project)
+		} else {
+			item.Bookmark.Project = project
+		}
+	}
+	i.editing = false
+	i.input.SetLabel("").SetText("")
+	i.input.Blur()
+	i.table.Focus(nil)
+	i.refresh()
+}
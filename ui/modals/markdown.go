@@ -0,0 +1,119 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package modals
+
+import (
+	"github.com/rivo/tview"
+	"regexp"
+	"strings"
+)
+
+//markdown styles as tview color tags
+const (
+	markdownHeading  = "[yellow::b]"
+	markdownHeading1 = "[yellow::bu]"
+	markdownCode     = "[#00d7ff]"
+	markdownLink     = "[#5f87ff::u]"
+	markdownQuote    = "[gray]"
+	markdownRule     = "────────────────────"
+)
+
+var (
+	markdownHeadingRegex = regexp.MustCompile(`^(#{1,6})\s+(.*?)(\s+#+)?\s*$`)
+	markdownListRegex    = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	markdownQuoteRegex   = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	markdownRuleRegex    = regexp.MustCompile(`^\s*(-{3,}|\*{3,}|_{3,})\s*$`)
+	markdownLinkRegex    = regexp.MustCompile(`^\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+//renderMarkdown renders basic markdown with tview color tags: headings, lists, block quotes,
+//horizontal rules, code blocks, inline code, bold text and links. Other text is shown as is.
+func renderMarkdown(text string) string {
+	lines := strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")
+	out := make([]string, 0, len(lines))
+	code := false
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			code = !code
+			continue
+		}
+		if code {
+			out = append(out, markdownCode+"  "+tview.Escape(line)+"[-]")
+			continue
+		}
+
+		if match := markdownHeadingRegex.FindStringSubmatch(line); match != nil {
+			style := markdownHeading
+			if len(match[1]) == 1 {
+				style = markdownHeading1
+			}
+			out = append(out, style+tview.Escape(match[2])+"[-::-]")
+		} else if markdownRuleRegex.MatchString(line) {
+			out = append(out, markdownRule)
+		} else if match := markdownListRegex.FindStringSubmatch(line); match != nil {
+			marker := match[2]
+			if strings.ContainsAny(marker, "-*+") {
+				marker = "•"
+			}
+			out = append(out, match[1]+"  "+marker+" "+renderInline(match[3]))
+		} else if match := markdownQuoteRegex.FindStringSubmatch(line); match != nil {
+			out = append(out, markdownQuote+"│ "+tview.Escape(match[1])+"[-]")
+		} else {
+			out = append(out, renderInline(line))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+//renderInline renders inline code, bold text and links of single line
+func renderInline(text string) string {
+	out := strings.Builder{}
+	plain := strings.Builder{}
+	flush := func() {
+		out.WriteString(tview.Escape(plain.String()))
+		plain.Reset()
+	}
+
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		if strings.HasPrefix(rest, "`") {
+			if end := strings.Index(rest[1:], "`"); end >= 0 {
+				flush()
+				out.WriteString(markdownCode + tview.Escape(rest[1:end+1]) + "[-]")
+				i += end + 2
+				continue
+			}
+		} else if strings.HasPrefix(rest, "**") {
+			if end := strings.Index(rest[2:], "**"); end > 0 {
+				flush()
+				out.WriteString("[::b]" + tview.Escape(rest[2:end+2]) + "[::-]")
+				i += end + 4
+				continue
+			}
+		} else if match := markdownLinkRegex.FindStringSubmatch(rest); match != nil {
+			flush()
+			out.WriteString(markdownLink + tview.Escape(match[1]) + "[-::-] " +
+				markdownQuote + "(" + tview.Escape(match[2]) + ")[-]")
+			i += len(match[0])
+			continue
+		}
+		plain.WriteByte(text[i])
+		i += 1
+	}
+	flush()
+	return out.String()
+}
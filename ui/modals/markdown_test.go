@@ -0,0 +1,55 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package modals
+
+import "testing"
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "headings",
+			text: "# Title #\n## Sub",
+			want: "[yellow::bu]Title[-::-]\n[yellow::b]Sub[-::-]",
+		},
+		{
+			name: "inline",
+			text: "some **bold**, `code` and [go](https://golang.org) [red] text",
+			want: "some [::b]bold[::-], [#00d7ff]code[-] and [#5f87ff::u]go[-::-] [gray](https://golang.org)[-] [red[] text",
+		},
+		{
+			name: "lists",
+			text: "- item\n  * nested\n1. first",
+			want: "  • item\n    • nested\n  1. first",
+		},
+		{
+			name: "blocks",
+			text: "> quote\n---\n```go\nfunc main() {}\n```\nend ** not bold",
+			want: "[gray]│ quote[-]\n────────────────────\n[#00d7ff]  func main() {}[-]\nend ** not bold",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderMarkdown(tt.text); got != tt.want {
+				t.Errorf("renderMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
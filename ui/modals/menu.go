@@ -29,6 +29,9 @@ const (
 	MenuActionImport
 	MenuActionExport
 	MenuActionModify
+	MenuActionTrash
+	MenuActionCheckLinks
+	MenuActionDuplicates
 )
 
 //Menu provides modal to perform multiple actions
@@ -53,8 +56,11 @@ func NewMenu() *Menu {
 	m.SetSelectedBackgroundColor(colors.TextSelected)
 
 	m.AddItem("Import bookmarks", "Import from bookmarks.html file", 'i', m.doImport)
-	m.AddItem("Export bookmarks (not implemented)", "Export into bookmarks.html file", 'e', m.doExport)
+	m.AddItem("Export bookmarks", "Export into bookmarks.html file", 'e', m.doExport)
 	m.AddItem("Bulk Modify", "Modify multiple bookmarks with given filter", 'm', m.doModify)
+	m.AddItem("Trash", "Restore or purge deleted bookmarks", 't', m.doTrash)
+	m.AddItem("Check links", "Check links of listed bookmarks in background", 'c', m.doCheckLinks)
+	m.AddItem("Find duplicates", "Merge bookmarks that have same link", 'd', m.doDuplicates)
 
 	return m
 }
@@ -99,3 +105,21 @@ func (m *Menu) doModify() {
 		m.doneFunc(MenuActionModify)
 	}
 }
+
+func (m *Menu) doTrash() {
+	if (m.doneFunc) != nil {
+		m.doneFunc(MenuActionTrash)
+	}
+}
+
+func (m *Menu) doCheckLinks() {
+	if (m.doneFunc) != nil {
+		m.doneFunc(MenuActionCheckLinks)
+	}
+}
+
+func (m *Menu) doDuplicates() {
+	if (m.doneFunc) != nil {
+		m.doneFunc(MenuActionDuplicates)
+	}
+}
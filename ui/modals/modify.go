@@ -19,20 +19,30 @@ package modals
 import (
 	"fmt"
 	"github.com/rivo/tview"
+	"strings"
 	"tryffel.net/go/bookmarker/config"
 	"tryffel.net/go/bookmarker/storage"
+	"tryffel.net/go/bookmarker/storage/models"
 )
 
-//Modify is a modal that operates on bulk of bookmarks defined with filter and modifiers
+//max bookmarks to list in preview
+const modifyPreviewNames = 10
+
+//Modify is a modal that operates on bulk of bookmarks defined with filter and modifiers.
+//Bookmarks to modify are previewed before executing modification.
 type Modify struct {
 	*tview.Form
-	doneFunc   func()
-	modifyFunc func(filter *storage.Filter, modifier *storage.Modifier) (int, error)
+	doneFunc    func()
+	modifyFunc  func(filter *storage.Filter, modifier *storage.Modifier) (int, error)
+	previewFunc func(filter *storage.Filter) ([]*models.Bookmark, error)
+
+	filter   *tview.InputField
+	modifier *tview.InputField
+	preview  *tview.InputField
+	status   *tview.InputField
 
-	filter *tview.InputField
-	key    *tview.InputField
-	value  *tview.InputField
-	status *tview.InputField
+	// filter and modifier that were previewed last
+	previewed string
 }
 
 func (m *Modify) SetDoneFunc(doneFunc func()) {
@@ -41,17 +51,21 @@ func (m *Modify) SetDoneFunc(doneFunc func()) {
 func (m *Modify) SetVisible(visible bool) {
 }
 
-func NewModify(modifyFunc func(filter *storage.Filter, modifier *storage.Modifier) (int, error)) *Modify {
+func NewModify(modifyFunc func(filter *storage.Filter, modifier *storage.Modifier) (int, error),
+	previewFunc func(filter *storage.Filter) ([]*models.Bookmark, error)) *Modify {
+	readOnly := func(string, rune) bool {
+		return false
+	}
 	m := &Modify{
-		Form:       tview.NewForm(),
-		doneFunc:   nil,
-		modifyFunc: modifyFunc,
-		filter:     tview.NewInputField().SetLabel("Filter").SetPlaceholder("project:bookmarks"),
-		key:        tview.NewInputField().SetLabel("Key").SetPlaceholder("archived"),
-		value:      tview.NewInputField().SetLabel("Value").SetPlaceholder("true"),
-		status: tview.NewInputField().SetLabel("Status").SetAcceptanceFunc(func(string, rune) bool {
-			return false
-		}),
+		Form:        tview.NewForm(),
+		doneFunc:    nil,
+		modifyFunc:  modifyFunc,
+		previewFunc: previewFunc,
+		filter:      tview.NewInputField().SetLabel("Filter").SetPlaceholder("project:bookmarks"),
+		modifier: tview.NewInputField().SetLabel("Modifier").
+			SetPlaceholder("+tags:new -tags:old project:'a.b' author:'X' -publisher"),
+		preview: tview.NewInputField().SetLabel("Preview").SetAcceptanceFunc(readOnly),
+		status:  tview.NewInputField().SetLabel("Status").SetAcceptanceFunc(readOnly),
 	}
 	colors := config.Configuration.Colors.BookmarkForm
 
@@ -66,41 +80,101 @@ func NewModify(modifyFunc func(filter *storage.Filter, modifier *storage.Modifie
 	m.SetFieldTextColor(colors.Text)
 
 	warning := tview.NewInputField().SetLabel("[::u]Warning[::-]").
-		SetText("This is experimental feature. Use at your own risk (backup database file first)")
+		SetText("Changes can be reverted with undo (Ctrl-Z)")
 	//disable edits
-	warning.SetAcceptanceFunc(func(string, rune) bool { return false })
+	warning.SetAcceptanceFunc(readOnly)
 	warning.SetBackgroundColor(config.Configuration.Colors.ModalBackground)
 	m.AddFormItem(warning)
 
 	m.AddFormItem(m.filter)
-	m.AddFormItem(m.key)
-	m.AddFormItem(m.value)
+	m.AddFormItem(m.modifier)
+	m.AddFormItem(m.preview)
 	m.AddFormItem(m.status)
 
+	m.AddButton("Preview", func() {
+		m.status.SetText("")
+		m.showPreview()
+	})
 	m.AddButton("Execute", m.save)
 	return m
 }
 
-func (m *Modify) save() {
-	m.status.SetText("")
+//parse parses filter and modifier, setting status on error
+func (m *Modify) parse() (*storage.Filter, *storage.Modifier, bool) {
 	filter, err := storage.NewFilter(m.filter.GetText())
 	if err != nil {
 		m.status.SetText(fmt.Errorf("Error: invalid filter: %v", err).Error())
-		return
+		return nil, nil, false
+	}
+	if filter.IsEmpty() {
+		m.status.SetText("Error: empty filter")
+		return nil, nil, false
 	}
 
-	modifier, err := storage.NewModifier(m.key.GetText(), m.value.GetText())
+	modifier, err := storage.NewModifier(m.modifier.GetText())
 	if err != nil {
 		m.status.SetText(fmt.Errorf("Error: invalid modifier: %v", err).Error())
+		return nil, nil, false
+	}
+	if modifier.IsEmpty() {
+		m.status.SetText("Error: empty modifier")
+		return nil, nil, false
+	}
+	return filter, modifier, true
+}
+
+//showPreview lists bookmarks that match filter
+func (m *Modify) showPreview() bool {
+	m.preview.SetText("")
+	m.previewed = ""
+	filter, _, ok := m.parse()
+	if !ok || m.previewFunc == nil {
+		return false
+	}
+
+	bookmarks, err := m.previewFunc(filter)
+	if err != nil {
+		m.status.SetText(fmt.Errorf("Error: %v", err).Error())
+		return false
+	}
+
+	names := []string{}
+	for i := 0; i < len(bookmarks) && i < modifyPreviewNames; i++ {
+		names = append(names, bookmarks[i].Name)
+	}
+	text := fmt.Sprintf("%d bookmarks", len(bookmarks))
+	if len(names) > 0 {
+		text += ": " + strings.Join(names, ", ")
+	}
+	if len(bookmarks) > len(names) {
+		text += ", ..."
+	}
+	m.preview.SetText(text)
+	m.previewed = m.filter.GetText() + "\n" + m.modifier.GetText()
+	return true
+}
+
+func (m *Modify) save() {
+	m.status.SetText("")
+	if m.previewed != m.filter.GetText()+"\n"+m.modifier.GetText() {
+		if m.showPreview() {
+			m.status.SetText("Check preview and press Execute again")
+		}
+		return
+	}
+
+	filter, modifier, ok := m.parse()
+	if !ok {
 		return
 	}
 
 	if m.modifyFunc != nil {
-		count, error := m.modifyFunc(filter, modifier)
-		if error == nil {
+		count, err := m.modifyFunc(filter, modifier)
+		if err == nil {
 			m.status.SetText(fmt.Sprintf("%d Bookmarks modified", count))
 		} else {
 			m.status.SetText(fmt.Errorf("Error: %v", err).Error())
 		}
 	}
+	m.previewed = ""
 }
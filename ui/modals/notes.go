@@ -0,0 +1,93 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package modals
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"strings"
+	"tryffel.net/go/bookmarker/config"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+const notesKeys = "e: edit"
+
+//Notes shows rendered markdown notes of bookmark
+type Notes struct {
+	*tview.TextView
+	editFunc func(bookmark *models.Bookmark) (*models.Bookmark, error)
+
+	bookmark *models.Bookmark
+}
+
+func (n *Notes) SetDoneFunc(doneFunc func()) {
+}
+
+func (n *Notes) SetVisible(visible bool) {
+}
+
+//NewNotes creates new notes view. EditFunc edits notes of bookmark and returns updated bookmark.
+func NewNotes(editFunc func(bookmark *models.Bookmark) (*models.Bookmark, error)) *Notes {
+	n := &Notes{
+		TextView: tview.NewTextView(),
+		editFunc: editFunc,
+	}
+
+	colors := config.Configuration.Colors.BookmarkForm
+	n.SetBackgroundColor(colors.Background)
+	n.SetTextColor(colors.Text)
+	n.SetBorder(true)
+	n.SetBorderColor(config.Configuration.Colors.Border)
+	n.SetWordWrap(true)
+	n.SetDynamicColors(true)
+	return n
+}
+
+//SetBookmark shows notes of bookmark
+func (n *Notes) SetBookmark(bookmark *models.Bookmark) {
+	n.bookmark = bookmark
+	n.showStatus(tview.Escape(bookmark.Name))
+	if strings.TrimSpace(bookmark.Notes) == "" {
+		n.SetText("No notes. Press e to write notes in editor.")
+	} else {
+		n.SetText(renderMarkdown(bookmark.Notes))
+	}
+	n.ScrollToBeginning()
+}
+
+//showStatus shows status and available keys in title
+func (n *Notes) showStatus(status string) {
+	n.SetTitle(fmt.Sprintf("Notes: %s (%s)", status, notesKeys))
+}
+
+func (n *Notes) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if event.Key() == tcell.KeyRune && event.Rune() == 'e' {
+			if n.bookmark != nil && n.editFunc != nil {
+				bookmark, err := n.editFunc(n.bookmark)
+				if err != nil {
+					n.showStatus(fmt.Sprintf("Error: %v", err))
+					return
+				}
+				n.SetBookmark(bookmark)
+			}
+			return
+		}
+		n.TextView.InputHandler()(event, setFocus)
+	}
+}
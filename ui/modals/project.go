@@ -0,0 +1,142 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package modals
+
+import (
+	"fmt"
+	"github.com/rivo/tview"
+	"tryffel.net/go/bookmarker/config"
+)
+
+//ProjectAction is an action that modifies project and all its children
+type ProjectAction int
+
+const (
+	//ProjectActionRename renames project
+	ProjectActionRename ProjectAction = iota
+	//ProjectActionMove moves project under another parent
+	ProjectActionMove
+	//ProjectActionMerge merges project into existing project
+	ProjectActionMerge
+)
+
+var projectActions = []struct {
+	name        string
+	label       string
+	placeholder string
+}{
+	{"Rename", "New name", "project.new-name"},
+	{"Move", "New parent", "empty moves to top level"},
+	{"Merge", "Merge into", "existing.project"},
+}
+
+//ProjectForm is a modal for renaming, moving or merging project
+type ProjectForm struct {
+	*tview.Form
+	project    string
+	actionFunc func(action ProjectAction, project, target string) (int, error)
+	closeFunc  func()
+
+	name   *tview.InputField
+	action *tview.DropDown
+	target *tview.InputField
+	status *tview.InputField
+}
+
+func (p *ProjectForm) SetDoneFunc(doneFunc func()) {
+	p.closeFunc = doneFunc
+}
+
+func (p *ProjectForm) SetVisible(visible bool) {
+}
+
+func NewProjectForm(actionFunc func(action ProjectAction, project, target string) (int, error)) *ProjectForm {
+	readOnly := func(string, rune) bool {
+		return false
+	}
+	p := &ProjectForm{
+		Form:       tview.NewForm(),
+		actionFunc: actionFunc,
+		name:       tview.NewInputField().SetLabel("Project").SetAcceptanceFunc(readOnly),
+		action:     tview.NewDropDown().SetLabel("Action"),
+		target:     tview.NewInputField(),
+		status:     tview.NewInputField().SetLabel("Status").SetAcceptanceFunc(readOnly),
+	}
+
+	colors := config.Configuration.Colors.BookmarkForm
+	p.SetTitle("Modify project")
+	p.SetTitleColor(colors.Text)
+
+	p.SetBorder(true)
+	p.SetBorderColor(config.Configuration.Colors.Border)
+	p.SetBackgroundColor(colors.Background)
+	p.SetLabelColor(colors.Label)
+	p.SetFieldBackgroundColor(colors.TextBackground)
+	p.SetFieldTextColor(colors.Text)
+	p.target.SetPlaceholderTextColor(colors.TextPlaceHolder)
+
+	options := make([]string, len(projectActions))
+	for i, v := range projectActions {
+		options[i] = v.name
+	}
+	p.action.SetOptions(options, p.selectAction)
+	p.action.SetCurrentOption(0)
+
+	p.AddFormItem(p.name)
+	p.AddFormItem(p.action)
+	p.AddFormItem(p.target)
+	p.AddFormItem(p.status)
+	p.AddButton("Execute", p.execute)
+	p.AddButton("Close", p.close)
+	return p
+}
+
+//SetProject resets form to modify given project
+func (p *ProjectForm) SetProject(project string) {
+	p.project = project
+	p.name.SetText(project)
+	p.action.SetCurrentOption(0)
+	p.target.SetText(project)
+	p.status.SetText("")
+}
+
+func (p *ProjectForm) selectAction(option string, index int) {
+	if index < 0 || index >= len(projectActions) {
+		return
+	}
+	p.target.SetLabel(projectActions[index].label)
+	p.target.SetPlaceholder(projectActions[index].placeholder)
+}
+
+func (p *ProjectForm) execute() {
+	if p.actionFunc == nil {
+		return
+	}
+	index, _ := p.action.GetCurrentOption()
+	count, err := p.actionFunc(ProjectAction(index), p.project, p.target.GetText())
+	if err != nil {
+		p.status.SetText(fmt.Sprintf("Error: %v", err))
+	} else {
+		p.status.SetText(fmt.Sprintf("%d Bookmarks modified", count))
+	}
+}
+
+func (p *ProjectForm) close() {
+	if p.closeFunc != nil {
+		p.closeFunc()
+	}
+}
@@ -0,0 +1,160 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package modals
+
+import (
+	"fmt"
+	"github.com/rivo/tview"
+	"tryffel.net/go/bookmarker/config"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//sort fields of saved search, empty is default sorting
+var savedSearchSortFields = []string{"", "Name", "Description", "Project", "Link", "Added at", "State", "Priority", "Due"}
+
+//SavedSearchForm is a modal for creating, editing and deleting saved search
+type SavedSearchForm struct {
+	*tview.Form
+	search     *models.SavedSearch
+	saveFunc   func(search *models.SavedSearch) error
+	deleteFunc func(search *models.SavedSearch) error
+	closeFunc  func()
+
+	name       *tview.InputField
+	query      *tview.InputField
+	sort       *tview.DropDown
+	descending *tview.Checkbox
+	status     *tview.InputField
+}
+
+func (s *SavedSearchForm) SetDoneFunc(doneFunc func()) {
+	s.closeFunc = doneFunc
+}
+
+func (s *SavedSearchForm) SetVisible(visible bool) {
+}
+
+func NewSavedSearchForm(saveFunc, deleteFunc func(search *models.SavedSearch) error) *SavedSearchForm {
+	s := &SavedSearchForm{
+		Form:       tview.NewForm(),
+		saveFunc:   saveFunc,
+		deleteFunc: deleteFunc,
+		name:       tview.NewInputField().SetLabel("Name"),
+		query:      tview.NewInputField().SetLabel("Query"),
+		sort:       tview.NewDropDown().SetLabel("Sort by"),
+		descending: tview.NewCheckbox().SetLabel("Descending"),
+		status: tview.NewInputField().SetLabel("Status").SetAcceptanceFunc(func(string, rune) bool {
+			return false
+		}),
+	}
+
+	colors := config.Configuration.Colors.BookmarkForm
+	s.SetTitleColor(colors.Text)
+
+	s.SetBorder(true)
+	s.SetBorderColor(config.Configuration.Colors.Border)
+	s.SetBackgroundColor(colors.Background)
+	s.SetLabelColor(colors.Label)
+	s.SetFieldBackgroundColor(colors.TextBackground)
+	s.SetFieldTextColor(colors.Text)
+	s.query.SetPlaceholderTextColor(colors.TextPlaceHolder)
+	s.query.SetPlaceholder("tags:ml project:papers -archived:true")
+
+	options := make([]string, len(savedSearchSortFields))
+	for i, v := range savedSearchSortFields {
+		options[i] = v
+		if v == "" {
+			options[i] = "Default"
+		}
+	}
+	s.sort.SetOptions(options, nil)
+
+	s.AddFormItem(s.name)
+	s.AddFormItem(s.query)
+	s.AddFormItem(s.sort)
+	s.AddFormItem(s.descending)
+	s.AddFormItem(s.status)
+	s.AddButton("Save", s.save)
+	s.AddButton("Delete", s.delete)
+	s.AddButton("Close", s.close)
+	return s
+}
+
+//SetSearch resets form to edit search. Search without id is created on save.
+func (s *SavedSearchForm) SetSearch(search *models.SavedSearch) {
+	s.search = search
+	if search.Id == 0 {
+		s.SetTitle("New saved search")
+	} else {
+		s.SetTitle("Edit saved search")
+	}
+	s.name.SetText(search.Name)
+	s.query.SetText(search.Query)
+	s.sort.SetCurrentOption(0)
+	for i, v := range savedSearchSortFields {
+		if v == search.SortField {
+			s.sort.SetCurrentOption(i)
+		}
+	}
+	s.descending.SetChecked(search.SortDir == "DESC")
+	s.status.SetText("")
+	s.SetFocus(0)
+}
+
+func (s *SavedSearchForm) save() {
+	if s.saveFunc == nil || s.search == nil {
+		return
+	}
+	search := *s.search
+	search.Name = s.name.GetText()
+	search.Query = s.query.GetText()
+	index, _ := s.sort.GetCurrentOption()
+	search.SortField = ""
+	if index > 0 {
+		search.SortField = savedSearchSortFields[index]
+	}
+	search.SortDir = "ASC"
+	if s.descending.IsChecked() {
+		search.SortDir = "DESC"
+	}
+
+	err := s.saveFunc(&search)
+	if err != nil {
+		s.status.SetText(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	*s.search = search
+	s.close()
+}
+
+func (s *SavedSearchForm) delete() {
+	if s.deleteFunc == nil || s.search == nil || s.search.Id == 0 {
+		return
+	}
+	err := s.deleteFunc(s.search)
+	if err != nil {
+		s.status.SetText(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	s.close()
+}
+
+func (s *SavedSearchForm) close() {
+	if s.closeFunc != nil {
+		s.closeFunc()
+	}
+}
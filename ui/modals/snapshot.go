@@ -0,0 +1,174 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package modals
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"tryffel.net/go/bookmarker/config"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+const snapshotKeys = "a: archive now, n/p: newer/older, o: open html"
+
+//Snapshot shows readable text of archived pages of bookmark
+type Snapshot struct {
+	*tview.TextView
+	loadFunc    func(bookmark int) ([]*models.Snapshot, error)
+	archiveFunc func(bookmark *models.Bookmark, doneFunc func(created bool, err error))
+	openFunc    func(snapshot *models.Snapshot) error
+
+	bookmark  *models.Bookmark
+	snapshots []*models.Snapshot
+	// index of shown snapshot, 0 is latest
+	index     int
+	archiving bool
+}
+
+func (s *Snapshot) SetDoneFunc(doneFunc func()) {
+}
+
+func (s *Snapshot) SetVisible(visible bool) {
+}
+
+func NewSnapshot(loadFunc func(bookmark int) ([]*models.Snapshot, error),
+	archiveFunc func(bookmark *models.Bookmark, doneFunc func(created bool, err error)),
+	openFunc func(snapshot *models.Snapshot) error) *Snapshot {
+	s := &Snapshot{
+		TextView:    tview.NewTextView(),
+		loadFunc:    loadFunc,
+		archiveFunc: archiveFunc,
+		openFunc:    openFunc,
+	}
+
+	colors := config.Configuration.Colors.BookmarkForm
+	s.SetBackgroundColor(colors.Background)
+	s.SetTextColor(colors.Text)
+	s.SetBorder(true)
+	s.SetBorderColor(config.Configuration.Colors.Border)
+	s.SetWordWrap(true)
+	s.SetDynamicColors(false)
+	return s
+}
+
+//SetBookmark loads snapshots of bookmark and shows latest of them
+func (s *Snapshot) SetBookmark(bookmark *models.Bookmark) {
+	s.bookmark = bookmark
+	s.index = 0
+	s.Reload()
+}
+
+//Reload loads snapshots and shows selected snapshot
+func (s *Snapshot) Reload() {
+	s.snapshots = nil
+	if s.bookmark != nil && s.loadFunc != nil {
+		snapshots, err := s.loadFunc(s.bookmark.Id)
+		if err != nil {
+			s.showStatus(fmt.Sprintf("Error: %v", err))
+			s.SetText("")
+			return
+		}
+		s.snapshots = snapshots
+	}
+	s.show()
+}
+
+func (s *Snapshot) show() {
+	if len(s.snapshots) == 0 {
+		s.showStatus("No snapshots")
+		s.SetText("Bookmark has no snapshots. Press 'a' to archive page now.")
+		return
+	}
+	if s.index >= len(s.snapshots) {
+		s.index = len(s.snapshots) - 1
+	}
+	snapshot := s.snapshots[s.index]
+	s.showStatus(fmt.Sprintf("%s (%d/%d)", snapshot.CreatedAt.Format("2006-01-02 15:04"), s.index+1,
+		len(s.snapshots)))
+	text := snapshot.Text
+	if snapshot.Title != "" {
+		text = snapshot.Title + "\n" + snapshot.Url + "\n\n" + text
+	}
+	s.SetText(text)
+	s.ScrollToBeginning()
+}
+
+//showStatus shows status and available keys in title
+func (s *Snapshot) showStatus(status string) {
+	if s.archiving {
+		status = "Archiving..."
+	}
+	s.SetTitle(fmt.Sprintf("Snapshot: %s (%s)", status, snapshotKeys))
+}
+
+func (s *Snapshot) selected() *models.Snapshot {
+	if s.index < 0 || s.index >= len(s.snapshots) {
+		return nil
+	}
+	return s.snapshots[s.index]
+}
+
+func (s *Snapshot) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if event.Key() != tcell.KeyRune {
+			s.TextView.InputHandler()(event, setFocus)
+			return
+		}
+
+		switch event.Rune() {
+		case 'a':
+			if s.bookmark != nil && s.archiveFunc != nil && !s.archiving {
+				s.archiving = true
+				s.showStatus("")
+				bookmark := s.bookmark
+				s.archiveFunc(bookmark, func(created bool, err error) {
+					s.archiving = false
+					if s.bookmark != bookmark {
+						return
+					}
+					s.index = 0
+					s.Reload()
+					if err != nil {
+						s.showStatus(fmt.Sprintf("Error: %v", err))
+					} else if !created {
+						s.showStatus("Page has not changed since latest snapshot")
+					}
+				})
+			}
+		case 'n':
+			if s.index > 0 {
+				s.index -= 1
+				s.show()
+			}
+		case 'p':
+			if s.index < len(s.snapshots)-1 {
+				s.index += 1
+				s.show()
+			}
+		case 'o':
+			if snapshot := s.selected(); snapshot != nil && s.openFunc != nil {
+				err := s.openFunc(snapshot)
+				if err != nil {
+					s.showStatus(fmt.Sprintf("Error: %v", err))
+				}
+			}
+		default:
+			s.TextView.InputHandler()(event, setFocus)
+		}
+	}
+}
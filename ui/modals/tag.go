@@ -0,0 +1,150 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package modals
+
+import (
+	"fmt"
+	"github.com/rivo/tview"
+	"tryffel.net/go/bookmarker/config"
+)
+
+//TagAction is an action that modifies tag and its children on all bookmarks
+type TagAction int
+
+const (
+	//TagActionRename renames tag
+	TagActionRename TagAction = iota
+	//TagActionMerge merges tag into another tag
+	TagActionMerge
+	//TagActionDelete removes tag from bookmarks
+	TagActionDelete
+)
+
+var tagActions = []struct {
+	name        string
+	label       string
+	placeholder string
+}{
+	{"Rename", "New name", "parent/new-name"},
+	{"Merge", "Merge into", "existing/tag"},
+	{"Delete", "Confirm", "removes tag from all bookmarks"},
+}
+
+//TagForm is a modal for renaming, merging or deleting tag
+type TagForm struct {
+	*tview.Form
+	tag        string
+	actionFunc func(action TagAction, tag, target string) (int, error)
+	closeFunc  func()
+
+	name   *tview.InputField
+	action *tview.DropDown
+	target *tview.InputField
+	status *tview.InputField
+}
+
+func (t *TagForm) SetDoneFunc(doneFunc func()) {
+	t.closeFunc = doneFunc
+}
+
+func (t *TagForm) SetVisible(visible bool) {
+}
+
+func NewTagForm(actionFunc func(action TagAction, tag, target string) (int, error)) *TagForm {
+	readOnly := func(string, rune) bool {
+		return false
+	}
+	t := &TagForm{
+		Form:       tview.NewForm(),
+		actionFunc: actionFunc,
+		name:       tview.NewInputField().SetLabel("Tag").SetAcceptanceFunc(readOnly),
+		action:     tview.NewDropDown().SetLabel("Action"),
+		target:     tview.NewInputField(),
+		status:     tview.NewInputField().SetLabel("Status").SetAcceptanceFunc(readOnly),
+	}
+
+	colors := config.Configuration.Colors.BookmarkForm
+	t.SetTitle("Modify tag")
+	t.SetTitleColor(colors.Text)
+
+	t.SetBorder(true)
+	t.SetBorderColor(config.Configuration.Colors.Border)
+	t.SetBackgroundColor(colors.Background)
+	t.SetLabelColor(colors.Label)
+	t.SetFieldBackgroundColor(colors.TextBackground)
+	t.SetFieldTextColor(colors.Text)
+	t.target.SetPlaceholderTextColor(colors.TextPlaceHolder)
+
+	options := make([]string, len(tagActions))
+	for i, v := range tagActions {
+		options[i] = v.name
+	}
+	t.action.SetOptions(options, t.selectAction)
+
+	t.AddFormItem(t.name)
+	t.AddFormItem(t.action)
+	t.AddFormItem(t.target)
+	t.AddFormItem(t.status)
+	t.AddButton("Execute", t.execute)
+	t.AddButton("Close", t.close)
+	return t
+}
+
+//SetTag resets form to modify given tag with action
+func (t *TagForm) SetTag(tag string, action TagAction) {
+	t.tag = tag
+	t.name.SetText(tag)
+	t.action.SetCurrentOption(int(action))
+	t.target.SetText(tag)
+	if action == TagActionDelete {
+		t.target.SetText("")
+	}
+	t.status.SetText("")
+}
+
+func (t *TagForm) selectAction(option string, index int) {
+	if index < 0 || index >= len(tagActions) {
+		return
+	}
+	t.target.SetLabel(tagActions[index].label)
+	t.target.SetPlaceholder(tagActions[index].placeholder)
+}
+
+func (t *TagForm) execute() {
+	if t.actionFunc == nil || t.tag == "" {
+		return
+	}
+	index, _ := t.action.GetCurrentOption()
+	count, err := t.actionFunc(TagAction(index), t.tag, t.target.GetText())
+	if err != nil {
+		t.status.SetText(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	t.status.SetText(fmt.Sprintf("%d Bookmarks modified", count))
+	if TagAction(index) != TagActionRename {
+		t.tag = ""
+	} else {
+		t.tag = t.target.GetText()
+		t.name.SetText(t.tag)
+	}
+}
+
+func (t *TagForm) close() {
+	if t.closeFunc != nil {
+		t.closeFunc()
+	}
+}
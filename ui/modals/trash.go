@@ -0,0 +1,138 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package modals
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"tryffel.net/go/bookmarker/config"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+const trashTitle = "Trash (r: restore, p: purge, E: empty trash)"
+
+//Trash lists deleted bookmarks, which can be restored or purged permanently
+type Trash struct {
+	*tview.List
+	loadFunc    func() ([]*models.Bookmark, error)
+	restoreFunc func(bookmark *models.Bookmark) error
+	purgeFunc   func(bookmark *models.Bookmark) error
+	emptyFunc   func() error
+
+	bookmarks []*models.Bookmark
+}
+
+func (t *Trash) SetDoneFunc(doneFunc func()) {
+}
+
+func (t *Trash) SetVisible(visible bool) {
+}
+
+func NewTrash(loadFunc func() ([]*models.Bookmark, error), restoreFunc, purgeFunc func(bookmark *models.Bookmark) error,
+	emptyFunc func() error) *Trash {
+	t := &Trash{
+		List:        tview.NewList(),
+		loadFunc:    loadFunc,
+		restoreFunc: restoreFunc,
+		purgeFunc:   purgeFunc,
+		emptyFunc:   emptyFunc,
+	}
+
+	colors := config.Configuration.Colors.BookmarkForm
+	t.SetBackgroundColor(colors.Background)
+	t.SetBorder(true)
+	t.SetTitle(trashTitle)
+	t.SetBorderColor(config.Configuration.Colors.Border)
+	t.SetMainTextColor(colors.Text)
+	t.SetSecondaryTextColor(colors.Label)
+	t.SetSelectedBackgroundColor(colors.TextSelected)
+	return t
+}
+
+//Reload loads deleted bookmarks into list
+func (t *Trash) Reload() {
+	index := t.GetCurrentItem()
+	t.SetTitle(trashTitle)
+	t.Clear()
+	t.bookmarks = nil
+	if t.loadFunc != nil {
+		bookmarks, err := t.loadFunc()
+		if err != nil {
+			t.AddItem("Error", err.Error(), 0, nil)
+			return
+		}
+		t.bookmarks = bookmarks
+	}
+
+	if len(t.bookmarks) == 0 {
+		t.AddItem("Trash is empty", "", 0, nil)
+		return
+	}
+	timeFormat := "2006-01-02 15:04"
+	for _, v := range t.bookmarks {
+		deleted := ""
+		if v.DeletedAt != nil {
+			deleted = v.DeletedAt.Format(timeFormat)
+		}
+		t.AddItem(tview.Escape(v.Name), fmt.Sprintf("deleted %s, project: %s", deleted, v.Project), 0, nil)
+	}
+	if index >= len(t.bookmarks) {
+		index = len(t.bookmarks) - 1
+	}
+	t.SetCurrentItem(index)
+}
+
+func (t *Trash) selected() *models.Bookmark {
+	index := t.GetCurrentItem()
+	if index < 0 || index >= len(t.bookmarks) {
+		return nil
+	}
+	return t.bookmarks[index]
+}
+
+func (t *Trash) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if event.Key() != tcell.KeyRune {
+			t.List.InputHandler()(event, setFocus)
+			return
+		}
+
+		var err error
+		switch event.Rune() {
+		case 'r':
+			if b := t.selected(); b != nil && t.restoreFunc != nil {
+				err = t.restoreFunc(b)
+			}
+		case 'p':
+			if b := t.selected(); b != nil && t.purgeFunc != nil {
+				err = t.purgeFunc(b)
+			}
+		case 'E':
+			if len(t.bookmarks) > 0 && t.emptyFunc != nil {
+				err = t.emptyFunc()
+			}
+		default:
+			t.List.InputHandler()(event, setFocus)
+			return
+		}
+		t.Reload()
+		if err != nil {
+			t.SetTitle(fmt.Sprintf("Trash: Error: %v", err))
+		}
+	}
+}
@@ -32,6 +32,7 @@ type Projects struct {
 
 	selected   bool
 	selectFunc func(bookmark *models.Project)
+	editFunc   func(project *models.Project)
 }
 
 func NewProjects() *Projects {
@@ -56,6 +57,11 @@ func (p *Projects) SetSelectFunc(selectFunc func(project *models.Project)) {
 	p.selectFunc = selectFunc
 }
 
+//SetEditFunc sets function that is called when user wants to rename, move or merge project
+func (p *Projects) SetEditFunc(editFunc func(project *models.Project)) {
+	p.editFunc = editFunc
+}
+
 func (p *Projects) Draw(screen tcell.Screen) {
 	p.table.Draw(screen)
 }
@@ -69,7 +75,16 @@ func (p *Projects) SetRect(x, y, width, height int) {
 }
 
 func (p *Projects) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
-	return p.table.InputHandler()
+	return func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if event.Rune() == 'r' {
+			row, _ := p.table.GetSelection()
+			if p.editFunc != nil && row >= 2 && row-2 < len(p.rows) {
+				p.editFunc(p.rows[row-2])
+			}
+		} else {
+			p.table.InputHandler()(event, setFocus)
+		}
+	}
 }
 
 func (p *Projects) Focus(delegate func(p tview.Primitive)) {
@@ -0,0 +1,140 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package ui
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"tryffel.net/go/bookmarker/config"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//Searches lists saved searches with number of matching bookmarks.
+//Enter runs search, n saves current search and e edits selected search.
+type Searches struct {
+	table    *tview.Table
+	searches []*models.SavedSearch
+
+	selectFunc func(search *models.SavedSearch)
+	editFunc   func(search *models.SavedSearch)
+}
+
+func NewSearches() *Searches {
+	s := &Searches{table: tview.NewTable()}
+
+	colors := config.Configuration.Colors.Tags
+	s.table.SetTitle("Searches")
+	s.table.SetTitleColor(config.Configuration.Colors.TextPrimary)
+	s.table.SetBackgroundColor(colors.Background)
+	s.table.SetBorder(true)
+	s.table.SetBorders(false)
+	s.table.SetBorderColor(config.Configuration.Colors.Border)
+	s.table.SetSelectedStyle(colors.TextSelected, colors.BackgroundSelected, 0)
+	s.table.SetSelectable(true, false)
+	s.table.SetSelectedFunc(s.selectSearch)
+	return s
+}
+
+//SetSelectFunc sets function that is called when user selects search
+func (s *Searches) SetSelectFunc(selectFunc func(search *models.SavedSearch)) {
+	s.selectFunc = selectFunc
+}
+
+//SetEditFunc sets function that is called when user wants to edit search. Nil search creates new search.
+func (s *Searches) SetEditFunc(editFunc func(search *models.SavedSearch)) {
+	s.editFunc = editFunc
+}
+
+func (s *Searches) Draw(screen tcell.Screen) {
+	s.table.Draw(screen)
+}
+
+func (s *Searches) GetRect() (int, int, int, int) {
+	return s.table.GetRect()
+}
+
+func (s *Searches) SetRect(x, y, width, height int) {
+	s.table.SetRect(x, y, width, height)
+}
+
+func (s *Searches) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		switch event.Rune() {
+		case 'n':
+			if s.editFunc != nil {
+				s.editFunc(nil)
+			}
+		case 'e':
+			if search := s.selected(); s.editFunc != nil && search != nil {
+				s.editFunc(search)
+			}
+		default:
+			s.table.InputHandler()(event, setFocus)
+		}
+	}
+}
+
+func (s *Searches) Focus(delegate func(p tview.Primitive)) {
+	s.table.Focus(delegate)
+	s.table.SetBorderColor(config.Configuration.Colors.BorderFocus)
+}
+
+func (s *Searches) Blur() {
+	s.table.Blur()
+	s.table.SetBorderColor(config.Configuration.Colors.Border)
+}
+
+func (s *Searches) GetFocusable() tview.Focusable {
+	return s.table.GetFocusable()
+}
+
+//SetData shows searches, selection is kept on same row
+func (s *Searches) SetData(searches []*models.SavedSearch) {
+	s.searches = searches
+	row, _ := s.table.GetSelection()
+	s.table.Clear()
+
+	for i, v := range searches {
+		count := fmt.Sprint(v.Count)
+		if v.Count < 0 {
+			count = "!"
+		}
+		s.table.SetCell(i, 0, tableCell(v.Name))
+		s.table.SetCell(i, 1, tableCell(count))
+	}
+	if row >= len(searches) {
+		row = len(searches) - 1
+	}
+	if row >= 0 {
+		s.table.Select(row, 0)
+	}
+}
+
+func (s *Searches) selected() *models.SavedSearch {
+	row, _ := s.table.GetSelection()
+	if row < 0 || row >= len(s.searches) {
+		return nil
+	}
+	return s.searches[row]
+}
+
+func (s *Searches) selectSearch(row, col int) {
+	if search := s.selected(); s.selectFunc != nil && search != nil {
+		s.selectFunc(search)
+	}
+}
@@ -20,13 +20,29 @@ import (
 	"fmt"
 	"github.com/gdamore/tcell"
 	"github.com/rivo/tview"
+	"strings"
 	"tryffel.net/go/bookmarker/config"
+	"tryffel.net/go/bookmarker/storage"
+	"tryffel.net/go/bookmarker/ui/modals"
 )
 
+//Tags lists tags with bookmark counts, child tags are indented under their parent.
+//Enter filters bookmarks with tag, space adds or removes tag from filter, o switches between
+//all of and any of selected tags and c clears selection. r renames, m merges and d deletes tag.
 type Tags struct {
 	table         *tview.Table
 	tags          *map[string]int
+	rows          []string
+	labels        []string
 	lastSelectRow int
+
+	//selected tags in order of selection
+	selected []string
+	//any combines selected tags with OR instead of AND
+	any bool
+
+	selectFunc func(tags []string, any bool)
+	editFunc   func(tag string, action modals.TagAction)
 }
 
 func NewTags() *Tags {
@@ -44,6 +60,16 @@ func NewTags() *Tags {
 	return t
 }
 
+//SetSelectFunc sets function that is called when tag selection changes
+func (t *Tags) SetSelectFunc(selectFunc func(tags []string, any bool)) {
+	t.selectFunc = selectFunc
+}
+
+//SetEditFunc sets function that is called when user wants to rename, merge or delete tag
+func (t *Tags) SetEditFunc(editFunc func(tag string, action modals.TagAction)) {
+	t.editFunc = editFunc
+}
+
 func (t *Tags) Draw(screen tcell.Screen) {
 	t.table.Draw(screen)
 }
@@ -57,7 +83,66 @@ func (t *Tags) SetRect(x, y, width, height int) {
 }
 
 func (t *Tags) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
-	return t.table.InputHandler()
+	return func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		actions := map[rune]modals.TagAction{
+			'r': modals.TagActionRename,
+			'm': modals.TagActionMerge,
+			'd': modals.TagActionDelete,
+		}
+		row, _ := t.table.GetSelection()
+		tag := ""
+		if row >= 0 && row < len(t.rows) {
+			tag = t.rows[row]
+		}
+
+		if action, ok := actions[event.Rune()]; ok {
+			if t.editFunc != nil && tag != "" {
+				t.editFunc(tag, action)
+			}
+			return
+		}
+		switch {
+		case event.Key() == tcell.KeyEnter && tag != "":
+			t.selected = []string{tag}
+		case event.Rune() == ' ' && tag != "":
+			t.toggle(tag)
+		case event.Rune() == 'o':
+			t.any = !t.any
+			if len(t.selected) < 2 {
+				t.render()
+				return
+			}
+		case event.Rune() == 'c':
+			t.selected = nil
+		default:
+			t.table.InputHandler()(event, setFocus)
+			return
+		}
+		t.render()
+		if t.selectFunc != nil {
+			t.selectFunc(t.selected, t.any)
+		}
+	}
+}
+
+//toggle adds tag to or removes it from selection
+func (t *Tags) toggle(tag string) {
+	for i, v := range t.selected {
+		if v == tag {
+			t.selected = append(t.selected[:i], t.selected[i+1:]...)
+			return
+		}
+	}
+	t.selected = append(t.selected, tag)
+}
+
+func (t *Tags) isSelected(tag string) bool {
+	for _, v := range t.selected {
+		if v == tag {
+			return true
+		}
+	}
+	return false
 }
 
 func (t *Tags) Focus(delegate func(p tview.Primitive)) {
@@ -86,14 +171,57 @@ func (t *Tags) SetData(tags *map[string]int) {
 		return
 	}
 	t.tags = tags
-	t.table.Clear()
 
-	i := 0
-	for key, count := range *tags {
-		t.table.SetCell(i, 0, tableCell(key))
-		t.table.SetCell(i, 1, tableCell(fmt.Sprint(count)))
-		i += 1
+	t.rows = make([]string, 0, len(*tags))
+	for name := range *tags {
+		t.rows = append(t.rows, name)
 	}
-
+	storage.SortTags(t.rows)
+
+	//keep selected tags that still exist
+	selected := []string{}
+	for _, v := range t.selected {
+		if _, ok := (*tags)[v]; ok {
+			selected = append(selected, v)
+		}
+	}
+	t.selected = selected
+
+	t.labels = make([]string, len(t.rows))
+	indents := map[string]string{}
+	for i, name := range t.rows {
+		label := name
+		for parent := name; strings.Contains(parent, storage.TagSeparator); {
+			parent = parent[:strings.LastIndex(parent, storage.TagSeparator)]
+			if indent, ok := indents[parent]; ok {
+				label = indent + "  " + name[len(parent)+1:]
+				break
+			}
+		}
+		indents[name] = label[:len(label)-len(strings.TrimLeft(label, " "))]
+		t.labels[i] = label
+	}
+	t.render()
 	t.lastSelectRow = -1
 }
+
+//render shows tags and highlights selected tags
+func (t *Tags) render() {
+	t.table.Clear()
+	for i, name := range t.rows {
+		cell := tableCell(t.labels[i])
+		if t.isSelected(name) {
+			cell.SetTextColor(config.Configuration.Colors.Tags.Text)
+		}
+		t.table.SetCell(i, 0, cell)
+		t.table.SetCell(i, 1, tableCell(fmt.Sprint((*t.tags)[name])))
+	}
+
+	title := "Tags"
+	if len(t.selected) > 1 && t.any {
+		title = "Tags (any of)"
+	} else if len(t.selected) > 1 {
+		title = "Tags (all of)"
+	}
+	t.table.SetTitle(title)
+}
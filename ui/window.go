@@ -17,14 +17,17 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"github.com/gdamore/tcell"
 	"github.com/rivo/tview"
 	"github.com/sirupsen/logrus"
+	"io/ioutil"
 	"os"
 	"time"
 	"tryffel.net/go/bookmarker/config"
 	"tryffel.net/go/bookmarker/external"
+	"tryffel.net/go/bookmarker/linkcheck"
 	"tryffel.net/go/bookmarker/storage"
 	"tryffel.net/go/bookmarker/storage/models"
 	"tryffel.net/go/bookmarker/ui/modals"
@@ -38,23 +41,36 @@ var navBarShortucts = make([]tcell.Key, 0)
 
 type Window struct {
 	app *tview.Application
-	db  *storage.Database
+	//store serves bookmarks, tags, projects and metadata. Features that need sqlite, like journal, trash,
+	//snapshots, imports and saved searches, are used if store implements their interface.
+	store storage.BookmarkStore
 
 	layout   *twidgets.ModalLayout
 	grid     *tview.Grid
 	gridAxis []int
 	gridSize int
 
-	navBar     *twidgets.NavBar
-	project    *Projects
-	tags       *Tags
-	bookmarks  *BookmarkTable
-	metadata   *Metadata
-	search     *Search
-	menu       *modals.Menu
-	importForm *modals.ImportForm
-	modify     *modals.Modify
-	searchOpen bool
+	navBar        *twidgets.NavBar
+	project       *Projects
+	tags          *Tags
+	searches      *Searches
+	bookmarks     *BookmarkTable
+	metadata      *Metadata
+	search        *Search
+	menu          *modals.Menu
+	importForm    *modals.ImportForm
+	importPreview *modals.ImportPreview
+	exportForm    *modals.ExportForm
+	projectForm   *modals.ProjectForm
+	tagForm       *modals.TagForm
+	modify        *modals.Modify
+	trash         *modals.Trash
+	snapshot      *modals.Snapshot
+	history       *modals.History
+	notes         *modals.Notes
+	duplicates    *modals.Duplicates
+	searchForm    *modals.SavedSearchForm
+	searchOpen    bool
 
 	help         *modals.Help
 	bookmarkForm *modals.BookmarkForm
@@ -67,9 +83,15 @@ type Window struct {
 	tabWidgets     []tview.Primitive
 	createFunc     func(bookmark *models.Bookmark)
 
-	metadataOpen bool
+	metadataOpen  bool
+	checkingLinks bool
 
 	filter *storage.Filter
+
+	//project and tags selected in side panels
+	selectedProject string
+	selectedTags    []string
+	selectedTagsAny bool
 }
 
 func (w *Window) Draw(screen tcell.Screen) {
@@ -102,7 +124,7 @@ func (w *Window) inputCapture(event *tcell.EventKey) *tcell.EventKey {
 	case navbar.Help:
 		if !w.hasModal {
 
-			stats, err := w.db.GetStatistics()
+			stats, err := w.store.GetStatistics()
 			if err != nil {
 				logrus.Errorf("Get statistics: %v", err)
 			}
@@ -151,6 +173,13 @@ func (w *Window) inputCapture(event *tcell.EventKey) *tcell.EventKey {
 		w.app.SetFocus(w.search)
 		w.searchOpen = true
 
+	case tcell.KeyCtrlZ, tcell.KeyCtrlY:
+		if !w.metadataOpen && !w.hasModal {
+			w.stepHistory(key == tcell.KeyCtrlZ)
+		} else {
+			return event
+		}
+
 	case tcell.KeyTAB:
 		if !w.metadataOpen && !w.hasModal {
 			w.nextWidget()
@@ -197,16 +226,18 @@ func (w *Window) GetFocusable() tview.Focusable {
 	return w.layout.GetFocusable()
 }
 
-func NewWindow(colors config.Colors, shortcuts *config.Shortcuts, db *storage.Database) *Window {
+func NewWindow(colors config.Colors, shortcuts *config.Shortcuts, store storage.BookmarkStore) *Window {
 	w := &Window{
 		app:        tview.NewApplication(),
-		db:         db,
+		store:      store,
 		layout:     twidgets.NewModalLayout(),
 		grid:       tview.NewGrid(),
 		project:    NewProjects(),
 		tags:       NewTags(),
+		searches:   NewSearches(),
 		help:       modals.NewHelp(),
 		importForm: modals.NewImportForm(),
+		exportForm: modals.NewExportForm(),
 	}
 
 	w.app.SetRoot(w, true)
@@ -216,18 +247,42 @@ func NewWindow(colors config.Colors, shortcuts *config.Shortcuts, db *storage.Da
 	w.bookmarks = NewBookmarkTable(w.openBookmark)
 	w.bookmarks.SetDeleteFunc(w.deleteBookmark)
 	w.bookmarks.SetSortFunc(w.SortBookmarks)
+	w.bookmarks.SetStateFunc(w.cycleState)
+	w.bookmarks.SetMoreFunc(w.moreBookmarks)
 	w.metadata = NewMetadata(w.closeMetadata)
 	w.metadata.SetSearchFunc(w.autoComplete)
+	w.metadata.SetSnapshotFunc(w.showSnapshots)
+	w.metadata.SetHistoryFunc(w.showHistory)
+	w.metadata.SetNotesFunc(w.showNotes)
 
 	w.bookmarkForm = modals.NewBookmarkForm(w.createBookmark)
 	w.bookmarkForm.SetSearchFunc(w.autoComplete)
+	w.bookmarkForm.SetEditFunc(w.editText)
 	w.grid.SetBackgroundColor(colors.Background)
 	w.search = NewSearch(w.Search)
 	w.project.SetSelectFunc(w.FilterByProject)
 	w.menu = modals.NewMenu()
 	w.menu.SetActionFunc(w.menuAction)
 	w.importForm.SetCreateFunc(w.doImport)
-	w.modify = modals.NewModify(w.modifyBookmark)
+	w.importPreview = modals.NewImportPreview(w.importBookmarks)
+	w.exportForm.SetExportFunc(w.doExport)
+	w.modify = modals.NewModify(w.modifyBookmark, w.previewModify)
+	w.projectForm = modals.NewProjectForm(w.modifyProject)
+	w.projectForm.SetDoneFunc(w.closeModal)
+	w.trash = modals.NewTrash(w.getDeletedBookmarks, w.restoreBookmark, w.purgeBookmark, w.emptyTrash)
+	w.snapshot = modals.NewSnapshot(w.getSnapshots, w.archiveBookmark, w.openSnapshot)
+	w.history = modals.NewHistory(w.getRevisions, w.restoreRevision)
+	w.notes = modals.NewNotes(w.editNotes)
+	w.duplicates = modals.NewDuplicates(w.getDuplicates, w.mergeDuplicates)
+	w.project.SetEditFunc(w.editProject)
+	w.tagForm = modals.NewTagForm(w.modifyTag)
+	w.tagForm.SetDoneFunc(w.closeModal)
+	w.tags.SetEditFunc(w.editTag)
+	w.tags.SetSelectFunc(w.FilterByTags)
+	w.searches.SetSelectFunc(w.FilterBySearch)
+	w.searches.SetEditFunc(w.editSearch)
+	w.searchForm = modals.NewSavedSearchForm(w.saveSearch, w.deleteSearch)
+	w.searchForm.SetDoneFunc(w.closeModal)
 
 	w.gridSize = 6
 	w.grid.SetRows(1, -1)
@@ -235,7 +290,7 @@ func NewWindow(colors config.Colors, shortcuts *config.Shortcuts, db *storage.Da
 	w.grid.SetMinSize(1, 2)
 
 	w.filter, _ = storage.NewFilter("")
-	w.filter.Clear()
+	w.filter.Clear(w.store.Options().HideArchived)
 
 	col := colors.NavBar.ToNavBar()
 
@@ -256,6 +311,7 @@ func NewWindow(colors config.Colors, shortcuts *config.Shortcuts, db *storage.Da
 
 	w.tabWidgets = append(w.tabWidgets, w.bookmarks)
 	w.tabWidgets = append(w.tabWidgets, w.project)
+	w.tabWidgets = append(w.tabWidgets, w.searches)
 	w.tabWidgets = append(w.tabWidgets, w.tags)
 
 	w.initDefaultLayout()
@@ -273,7 +329,7 @@ func (w *Window) closeMetadata(save bool, bookmark *models.Bookmark) bool {
 		return false
 	}
 	if save {
-		err := w.db.UpdateBookmark(bookmark)
+		err := w.store.UpdateBookmark(bookmark)
 
 		if err != nil {
 			logrus.Errorf("Failed to update bookmark %d %s: %v", bookmark.Id, bookmark.Name, err)
@@ -297,7 +353,8 @@ func (w *Window) closeMetadata(save bool, bookmark *models.Bookmark) bool {
 func (w *Window) initDefaultLayout() {
 	w.layout.Grid().Clear()
 
-	w.layout.Grid().AddItem(w.project, 0, 0, 7, 1, 5, 5, false)
+	w.layout.Grid().AddItem(w.project, 0, 0, 5, 1, 5, 5, false)
+	w.layout.Grid().AddItem(w.searches, 5, 0, 2, 1, 3, 5, false)
 	w.layout.Grid().AddItem(w.tags, 7, 0, 3, 1, 5, 5, false)
 	w.layout.Grid().AddItem(w.bookmarks, 0, 1, 9, 9, 10, 10, true)
 	w.layout.Grid().AddItem(w.search, 9, 1, 1, 9, 1, 10, false)
@@ -328,11 +385,11 @@ func (w *Window) openMetadata() {
 	w.layout.Grid().AddItem(w.metadata, 0, 7, 10, 3, 10, 10, true)
 
 	w.app.QueueUpdateDraw(func() {
-		bookmark, err := w.db.GetBookmark(id)
+		bookmark, err := w.store.GetBookmark(id)
 		if err != nil {
 			logrus.Errorf("get bookmark %v", err)
 		}
-		err = w.db.GetBookmarkMetadata(bookmark)
+		err = w.store.GetBookmarkMetadata(bookmark)
 		if err != nil {
 			logrus.Errorf("Get metadata: %v", err)
 		}
@@ -345,60 +402,91 @@ func (w *Window) openMetadata() {
 func (w *Window) createBookmark(bookmark *models.Bookmark) {
 	logrus.Debugf("Create new bookmark: %v", bookmark)
 
-	err := w.db.NewBookmark(bookmark)
+	existing, err := w.store.GetBookmarksByLink(bookmark.Content)
+	if err != nil {
+		logrus.Errorf("Find duplicate bookmarks: %v", err)
+	}
+	if len(existing) > 0 {
+		doneFunc := func(policy storage.DuplicatePolicy) {
+			w.closeModal()
+			w.saveBookmark(bookmark, existing[0], policy)
+		}
+		w.closeModal()
+		w.addModal(modals.NewDuplicateBookmark(doneFunc, bookmark, existing[0]), twidgets.ModalSizeSmall)
+		return
+	}
+	w.saveBookmark(bookmark, nil, storage.DuplicateCreate)
+}
+
+//saveBookmark creates new bookmark or saves it into existing bookmark with given duplicate policy
+func (w *Window) saveBookmark(bookmark, existing *models.Bookmark, policy storage.DuplicatePolicy) {
+	var err error
+	if existing == nil || policy == storage.DuplicateCreate {
+		err = w.store.NewBookmark(bookmark)
+	} else if policy != storage.DuplicateSkip {
+		err = storage.ErrNotSupported
+		if duplicates, ok := w.store.(storage.DuplicateStore); ok {
+			err = duplicates.MergeBookmark(existing.Id, bookmark, policy == storage.DuplicateUpdate)
+		}
+	}
 	if err != nil {
 		logrus.Error("Failed to create bookmark: ", err)
 	} else {
 		w.bookmarkForm.Clear()
-		bookmarks, err := w.db.GetAllBookmarks()
+		err = w.showBookmarks()
 		if err != nil {
-			return
-		}
-		w.bookmarks.SetData(bookmarks)
-		if w.hasModal {
-			w.layout.RemoveModal(w.modal)
-			w.app.SetFocus(w.lastFocus)
-			w.lastFocus = nil
-			w.modal = nil
-			w.hasModal = false
+			logrus.Errorf("Refresh bookmarks: %v", err)
 		}
+		w.closeModal()
 	}
 }
 
 func (w *Window) Search(text string) {
 	if text == "" {
-		w.RefreshBookmarks()
+		w.filter.Clear(w.store.Options().HideArchived)
 	} else {
-		var err error
-		w.filter, err = storage.NewFilter(text)
+		filter, err := storage.NewFilter(text)
 		if err != nil {
 			logrus.Errorf("Failed to parse search query: %v", err)
 			return
 		}
+		w.filter = filter
 	}
-	if w.filter.IsPlainQuery() {
-		bookmarks, err := w.db.SearchBookmarks(text)
-		if err != nil {
-			logrus.Errorf("Search bookmarks: %v", err)
-			return
-		}
-		w.bookmarks.SetData(bookmarks)
-		w.bookmarks.ResetCursor()
-		//w.refreshProjects()
-	} else {
-		bookmarks, err := w.db.FilterBookmarks(w.filter)
-		if err != nil {
-			logrus.Errorf("Search bookmarks: %v", err)
-			return
-		}
-		w.bookmarks.SetData(bookmarks)
-		w.bookmarks.ResetCursor()
+	err := w.showBookmarks()
+	if err != nil {
+		logrus.Errorf("Search bookmarks: %v", err)
+		return
+	}
+	w.bookmarks.ResetCursor()
+	if !w.filter.IsPlainQuery() {
 		w.refreshProjects()
 	}
 }
 
+//showBookmarks shows first page of bookmarks that match current filter
+func (w *Window) showBookmarks() error {
+	filter := *w.filter
+	filter.Offset = 0
+	filter.Limit = 0
+	bookmarks, total, err := w.store.BookmarksPage(&filter)
+	if err != nil {
+		return err
+	}
+	w.bookmarks.SetPage(bookmarks, total)
+	return nil
+}
+
+//moreBookmarks returns next page of bookmarks that match current filter
+func (w *Window) moreBookmarks(offset int) ([]*models.Bookmark, error) {
+	filter := *w.filter
+	filter.Offset = offset
+	filter.Limit = 0
+	bookmarks, _, err := w.store.BookmarksPage(&filter)
+	return bookmarks, err
+}
+
 func (w *Window) refreshProjects() {
-	projects, err := w.db.FilterProject(w.filter)
+	projects, err := w.store.FilterProject(w.filter)
 	if err != nil {
 		logrus.Errorf("get projects: %v", err)
 	} else {
@@ -406,36 +494,118 @@ func (w *Window) refreshProjects() {
 	}
 }
 
-func (w *Window) FilterByProject(project *models.Project) {
-	if project == nil {
-		bookmarks, err := w.db.GetAllBookmarks()
-		if err != nil {
-			logrus.Errorf("Get all bookmarks: %v", err)
-		} else {
-			w.bookmarks.SetData(bookmarks)
-			w.bookmarks.ResetCursor()
-		}
+//FilterBySearch shows bookmarks that match saved search
+func (w *Window) FilterBySearch(search *models.SavedSearch) {
+	filter, err := storage.NewSavedSearchFilter(search)
+	if err != nil {
+		logrus.Errorf("Parse saved search %s: %v", search.Name, err)
+		return
+	}
+	w.filter = filter
+	w.search.SetText(search.Query)
+
+	err = w.showBookmarks()
+	if err != nil {
+		logrus.Errorf("Filter bookmarks with saved search %s: %v", search.Name, err)
+		return
+	}
+	w.bookmarks.ResetCursor()
+}
+
+//refreshSearches reloads saved searches with their counts
+func (w *Window) refreshSearches() {
+	store, ok := w.store.(storage.SavedSearchStore)
+	if !ok {
+		return
+	}
+	searches, err := store.GetSavedSearches()
+	if err != nil {
+		logrus.Errorf("Refresh saved searches: %v", err)
 	} else {
-		name := ""
-		strict := true
-		name = project.FullName()
-		filt := &storage.Filter{}
-		filt.Clear()
-		filt.Project.Name = name
-		filt.Project.Strict = strict
-		logrus.Debug("Filtering with projects: ", name)
-		if project.Parent != nil || len(project.Children) > 0 {
-			strict = false
-		}
-		bookmarks, err := w.db.FilterBookmarks(filt)
-		if err != nil {
+		w.searches.SetData(searches)
+	}
+}
 
-			logrus.Errorf("Get bookmarks by project: %v", err)
-		} else {
-			w.bookmarks.SetData(bookmarks)
-			w.bookmarks.ResetCursor()
-		}
+//editSearch opens saved search form. Nil search creates new search from current search query.
+func (w *Window) editSearch(search *models.SavedSearch) {
+	if search == nil {
+		search = &models.SavedSearch{Query: w.search.GetText()}
+	}
+	w.searchForm.SetSearch(search)
+	w.addModal(w.searchForm, twidgets.ModalSizeMedium)
+}
+
+func (w *Window) saveSearch(search *models.SavedSearch) error {
+	store, ok := w.store.(storage.SavedSearchStore)
+	if !ok {
+		return storage.ErrNotSupported
+	}
+	var err error
+	if search.Id == 0 {
+		err = store.NewSavedSearch(search)
+	} else {
+		err = store.UpdateSavedSearch(search)
+	}
+	if err != nil {
+		return err
+	}
+	w.refreshSearches()
+	return nil
+}
+
+func (w *Window) deleteSearch(search *models.SavedSearch) error {
+	store, ok := w.store.(storage.SavedSearchStore)
+	if !ok {
+		return storage.ErrNotSupported
+	}
+	err := store.DeleteSavedSearch(search.Id)
+	if err != nil {
+		return err
+	}
+	w.refreshSearches()
+	return nil
+}
+
+//FilterByProject shows bookmarks in project combined with selected tags. Nil project clears project selection.
+func (w *Window) FilterByProject(project *models.Project) {
+	w.selectedProject = ""
+	if project != nil {
+		w.selectedProject = project.FullName()
+	}
+	w.filterBySelection()
+}
+
+//FilterByTags shows bookmarks that have all of tags, or any of tags, combined with selected project
+func (w *Window) FilterByTags(tags []string, any bool) {
+	w.selectedTags = tags
+	w.selectedTagsAny = any
+	w.filterBySelection()
+}
+
+//filterBySelection combines selected project and tags into filter and shows its query in search bar,
+//where user can refine it
+func (w *Window) filterBySelection() {
+	query := storage.SelectionQuery(w.selectedProject, w.selectedTags, w.selectedTagsAny)
+	w.search.SetText(query)
+	logrus.Debug("Filtering with selection: ", query)
+
+	filter, err := storage.NewFilter(query)
+	if err != nil {
+		logrus.Errorf("Parse selection query: %v", err)
+		return
+	}
+	if w.store.Options().HideArchived {
+		filter.Archived.Strict = true
+		filter.Archived.Name = "false"
+	}
+	w.filter = filter
+
+	err = w.showBookmarks()
+	if err != nil {
+		logrus.Errorf("Filter bookmarks by selection: %v", err)
+		return
 	}
+	w.bookmarks.ResetCursor()
 }
 
 func (w *Window) menuAction(action modals.MenuAction) {
@@ -445,47 +615,93 @@ func (w *Window) menuAction(action modals.MenuAction) {
 		w.layout.RemoveModal(w.modal)
 		w.hasModal = false
 		w.addModal(w.importForm, twidgets.ModalSizeMedium)
+	case modals.MenuActionExport:
+		w.layout.RemoveModal(w.modal)
+		w.hasModal = false
+		w.addModal(w.exportForm, twidgets.ModalSizeMedium)
 	case modals.MenuActionModify:
 		w.layout.RemoveModal(w.modal)
 		w.hasModal = false
 		w.addModal(w.modify, twidgets.ModalSizeMedium)
+	case modals.MenuActionTrash:
+		w.layout.RemoveModal(w.modal)
+		w.hasModal = false
+		w.trash.Reload()
+		w.addModal(w.trash, twidgets.ModalSizeMedium)
+	case modals.MenuActionCheckLinks:
+		w.closeModal()
+		w.checkLinks()
+	case modals.MenuActionDuplicates:
+		w.layout.RemoveModal(w.modal)
+		w.hasModal = false
+		w.duplicates.Reload()
+		w.addModal(w.duplicates, twidgets.ModalSizeMedium)
 	}
 }
 
+//doImport reads file in background and shows preview of bookmarks to import
 func (w *Window) doImport(data *modals.ImportData) {
 	logrus.Info("User wants to import: ", data)
+	w.importForm.ImportStarted(fmt.Sprintf("Reading %s", data.File))
 
-	ok := false
-	msg := ""
-	count := 0
+	go func() {
+		start := time.Now()
+		bookmarks, format, err := external.ImportFile(data.File, data.MapFoldersProjects)
+		var preview *storage.ImportPreview
+		store, ok := w.store.(storage.ImportStore)
+		if err == nil && !ok {
+			err = storage.ErrNotSupported
+		}
+		if err == nil {
+			preview, err = store.PreviewImport(bookmarks)
+		}
+		w.app.QueueUpdateDraw(func() {
+			if err != nil {
+				logrus.Error(err)
+				w.importForm.SetDoneFunc(w.closeImport)
+				w.importForm.ImportDone(0, fmt.Errorf("read bookmarks: %v", err).Error(), false)
+				return
+			}
+			logrus.Infof("Read %d bookmarks from %s in %d ms", len(bookmarks), format,
+				time.Since(start).Milliseconds())
+			w.closeImport()
+			w.importPreview.SetPreview(preview, format, data)
+			w.addModal(w.importPreview, twidgets.ModalSizeMedium)
+		})
+	}()
+}
 
-	file, err := os.Open(data.File)
-	defer file.Close()
-	if err != nil {
-		logrus.Error(err)
-		msg = fmt.Errorf("failed to open file: %v", err).Error()
-	} else {
+//importBookmarks imports selected bookmarks of preview in background, reporting progress in preview
+func (w *Window) importBookmarks(preview *storage.ImportPreview, data *modals.ImportData) {
+	store, ok := w.store.(storage.ImportStore)
+	if !ok {
+		w.importPreview.ImportFailed(storage.ErrNotSupported)
+		return
+	}
+	bookmarks := preview.Selected()
+	go func() {
 		start := time.Now()
-		bookmarks, err := external.ImportBookmarksHtml(file, data.MapFoldersProjects)
-		if err != nil {
-			logrus.Error(err)
-			msg = fmt.Errorf("parse bookmarks.html: %v", err).Error()
-		} else {
-			err = w.db.NewBookmarks(bookmarks, data.Tags)
-			took := time.Since(start)
+		result, err := store.ImportBookmarks(bookmarks, data.Tags, data.Duplicates, func(done, total int) {
+			w.app.QueueUpdateDraw(func() {
+				w.importPreview.ImportProgress(done, total)
+			})
+		})
+		took := time.Since(start)
+		w.app.QueueUpdateDraw(func() {
 			if err != nil {
 				logrus.Errorf("Batch import and create bookmarks: %v", err)
-				msg = fmt.Errorf("save new bookmarks: %v", err).Error()
-			} else {
-				logrus.Infof("Imported %d bookmarks in %d ms", len(bookmarks), took.Milliseconds())
-				ok = true
-				msg = fmt.Sprintf("Took %d ms", took.Milliseconds())
-				count = len(bookmarks)
+				w.importPreview.ImportFailed(err)
+				return
 			}
-		}
-	}
-	w.importForm.SetDoneFunc(w.closeImport)
-	w.importForm.ImportDone(count, msg, ok)
+			logrus.Infof("Imported %d bookmarks in %d ms", result.Created, took.Milliseconds())
+			w.closeModal()
+			w.importForm.SetDoneFunc(w.closeImport)
+			w.importForm.ImportDone(result.Created, fmt.Sprintf("Updated %d, skipped %d duplicates. Took %d ms",
+				result.Updated, result.Skipped, took.Milliseconds()), true)
+			w.addModal(w.importForm, twidgets.ModalSizeMedium)
+			w.refreshAll()
+		})
+	}()
 }
 
 func (w *Window) closeImport() {
@@ -493,6 +709,65 @@ func (w *Window) closeImport() {
 	w.closeModal()
 }
 
+func (w *Window) doExport(data *modals.ExportData) {
+	logrus.Info("User wants to export: ", data)
+
+	start := time.Now()
+	count, err := exportBookmarks(w.store, data.Format, data.Filter, data.File)
+	ok := err == nil
+	msg := ""
+	if ok {
+		took := time.Since(start)
+		logrus.Infof("Exported %d bookmarks in %d ms", count, took.Milliseconds())
+		msg = fmt.Sprintf("Took %d ms", took.Milliseconds())
+	} else {
+		logrus.Errorf("Export bookmarks: %v", err)
+		msg = err.Error()
+	}
+	w.exportForm.SetDoneFunc(w.closeExport)
+	w.exportForm.ExportDone(count, msg, ok)
+}
+
+//exportBookmarks writes bookmarks that match query to file in given format. Return number of bookmarks exported.
+func exportBookmarks(store storage.BookmarkStore, format, query, file string) (int, error) {
+	exporter, err := external.GetExporter(format)
+	if err != nil {
+		return 0, err
+	}
+	importStore, ok := store.(storage.ImportStore)
+	if !ok {
+		return 0, storage.ErrNotSupported
+	}
+	filter, err := storage.NewFilter(query)
+	if err != nil {
+		return 0, fmt.Errorf("invalid filter: %v", err)
+	}
+	bookmarks, err := importStore.ExportBookmarks(filter)
+	if err != nil {
+		return 0, fmt.Errorf("get bookmarks: %v", err)
+	}
+
+	out, err := os.Create(file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file: %v", err)
+	}
+	err = exporter.Export(out, bookmarks)
+	if err == nil {
+		err = out.Close()
+	} else {
+		out.Close()
+	}
+	if err != nil {
+		return 0, fmt.Errorf("write %s: %v", exporter.Name(), err)
+	}
+	return len(bookmarks), nil
+}
+
+func (w *Window) closeExport() {
+	w.exportForm.Reset()
+	w.closeModal()
+}
+
 func (w *Window) closeModal() {
 	if w.hasModal {
 		w.layout.RemoveModal(w.modal)
@@ -506,7 +781,7 @@ func (w *Window) closeModal() {
 func (w *Window) deleteBookmark(bookmark *models.Bookmark) {
 	doneFunc := func(del bool) {
 		if del {
-			err := w.db.DeleteBookmark(bookmark)
+			err := w.store.DeleteBookmark(bookmark)
 			if err != nil {
 				logrus.Errorf("Delete bookmark: %v", err)
 			}
@@ -519,23 +794,315 @@ func (w *Window) deleteBookmark(bookmark *models.Bookmark) {
 	w.addModal(del, twidgets.ModalSizeSmall)
 }
 
-func (w *Window) RefreshBookmarks() {
-	bookmarks, err := w.db.GetAllBookmarks()
-	w.filter.Clear()
+//getDeletedBookmarks returns bookmarks in trash
+func (w *Window) getDeletedBookmarks() ([]*models.Bookmark, error) {
+	trash, ok := w.store.(storage.TrashStore)
+	if !ok {
+		return nil, storage.ErrNotSupported
+	}
+	return trash.GetDeletedBookmarks()
+}
+
+func (w *Window) restoreBookmark(bookmark *models.Bookmark) error {
+	trash, ok := w.store.(storage.TrashStore)
+	if !ok {
+		return storage.ErrNotSupported
+	}
+	err := trash.RestoreBookmark(bookmark)
 	if err != nil {
+		logrus.Errorf("Restore bookmark: %v", err)
+		return err
+	}
+	w.refreshAll()
+	return nil
+}
+
+func (w *Window) purgeBookmark(bookmark *models.Bookmark) error {
+	trash, ok := w.store.(storage.TrashStore)
+	if !ok {
+		return storage.ErrNotSupported
+	}
+	err := trash.PurgeBookmark(bookmark)
+	if err != nil {
+		logrus.Errorf("Purge bookmark: %v", err)
+	}
+	return err
+}
+
+//getDuplicates returns groups of bookmarks that have same link
+func (w *Window) getDuplicates() ([][]*models.Bookmark, error) {
+	duplicates, ok := w.store.(storage.DuplicateStore)
+	if !ok {
+		return nil, storage.ErrNotSupported
+	}
+	return duplicates.GetDuplicates()
+}
+
+func (w *Window) mergeDuplicates(bookmarks []*models.Bookmark) error {
+	duplicates, ok := w.store.(storage.DuplicateStore)
+	if !ok {
+		return storage.ErrNotSupported
+	}
+	ids := make([]int, len(bookmarks))
+	for i, v := range bookmarks {
+		ids[i] = v.Id
+	}
+	merged, err := duplicates.MergeDuplicates(ids)
+	if err != nil {
+		logrus.Errorf("Merge duplicates: %v", err)
+		return err
+	}
+	logrus.Infof("Merged %d bookmarks into '%s'", len(ids), merged.Name)
+	w.refreshAll()
+	return nil
+}
+
+func (w *Window) emptyTrash() error {
+	trash, ok := w.store.(storage.TrashStore)
+	if !ok {
+		return storage.ErrNotSupported
+	}
+	count, err := trash.PurgeDeleted(time.Now())
+	if err != nil {
+		logrus.Errorf("Empty trash: %v", err)
+		return err
+	}
+	logrus.Infof("Purged %d bookmarks from trash", count)
+	return nil
+}
+
+//checkLinks checks links of listed bookmarks in background and refreshes bookmarks when done
+func (w *Window) checkLinks() {
+	if w.checkingLinks {
+		logrus.Warning("Link check is already running")
+		return
+	}
+	store, ok := w.store.(linkcheck.Store)
+	if !ok {
+		logrus.Errorf("Check links: %v", storage.ErrNotSupported)
+		return
+	}
+	w.checkingLinks = true
+	bookmarks := make([]*models.Bookmark, len(w.bookmarks.items))
+	copy(bookmarks, w.bookmarks.items)
+
+	conf := config.Configuration
+	checker := linkcheck.NewChecker(conf.LinkCheckWorkers, time.Duration(conf.LinkCheckTimeout)*time.Second,
+		time.Duration(conf.LinkCheckHostInterval)*time.Millisecond)
+	logrus.Infof("Checking links of %d bookmarks", len(bookmarks))
+	go func() {
+		start := time.Now()
+		dead := 0
+		results, err := checker.Run(context.Background(), store, bookmarks, func(result *linkcheck.Result) {
+			if result.Dead() {
+				dead += 1
+			}
+		})
+		if err != nil {
+			logrus.Errorf("Check links: %v", err)
+		} else {
+			logrus.Infof("Checked %d links in %d ms, %d dead", len(results), time.Since(start).Milliseconds(), dead)
+		}
+		w.app.QueueUpdateDraw(func() {
+			w.checkingLinks = false
+			w.refreshAll()
+		})
+	}()
+}
+
+func (w *Window) showSnapshots(bookmark *models.Bookmark) {
+	w.snapshot.SetBookmark(bookmark)
+	w.addModal(w.snapshot, twidgets.ModalSizeMedium)
+}
+
+//getSnapshots returns snapshots of bookmark, latest first
+func (w *Window) getSnapshots(bookmark int) ([]*models.Snapshot, error) {
+	snapshots, ok := w.store.(storage.SnapshotStore)
+	if !ok {
+		return nil, storage.ErrNotSupported
+	}
+	return snapshots.GetSnapshots(bookmark)
+}
+
+//getRevisions returns revisions of bookmark, latest first
+func (w *Window) getRevisions(bookmark int) ([]*models.Revision, error) {
+	revisions, ok := w.store.(storage.RevisionStore)
+	if !ok {
+		return nil, storage.ErrNotSupported
+	}
+	return revisions.GetRevisions(bookmark)
+}
+
+func (w *Window) showHistory(bookmark *models.Bookmark) {
+	w.history.SetBookmark(bookmark)
+	w.addModal(w.history, twidgets.ModalSizeMedium)
+}
+
+func (w *Window) showNotes(bookmark *models.Bookmark) {
+	w.notes.SetBookmark(bookmark)
+	w.addModal(w.notes, twidgets.ModalSizeLarge)
+}
+
+//editText edits text in external editor while terminal ui is suspended
+func (w *Window) editText(text string) (string, error) {
+	edited := text
+	var err error
+	suspended := w.app.Suspend(func() {
+		edited, err = external.EditText(text, config.AppNameLower+"-notes")
+	})
+	if !suspended {
+		return text, fmt.Errorf("cannot suspend terminal")
+	}
+	return edited, err
+}
+
+//editNotes edits notes of bookmark in external editor and saves them
+func (w *Window) editNotes(bookmark *models.Bookmark) (*models.Bookmark, error) {
+	b, err := w.store.GetBookmark(bookmark.Id)
+	if err != nil {
+		return nil, err
+	}
+	err = w.store.GetBookmarkMetadata(b)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := w.editText(b.Notes)
+	if err != nil {
+		logrus.Errorf("Edit notes: %v", err)
+		return nil, err
+	}
+	if notes == b.Notes {
+		return b, nil
+	}
+	b.Notes = notes
+	b.UpdatedAt = time.Now()
+	err = w.store.UpdateBookmark(b)
+	if err != nil {
+		logrus.Errorf("Update notes of bookmark %d: %v", b.Id, err)
+		return nil, err
+	}
+	w.refreshAll()
+	w.metadata.setData(b)
+	return b, nil
+}
+
+//cycleState moves bookmark to next reading state
+func (w *Window) cycleState(bookmark *models.Bookmark) {
+	b, err := w.store.GetBookmark(bookmark.Id)
+	if err != nil {
+		logrus.Errorf("Get bookmark %d: %v", bookmark.Id, err)
 		return
 	}
-	w.bookmarks.SetData(bookmarks)
+	err = w.store.GetBookmarkMetadata(b)
+	if err != nil {
+		logrus.Errorf("Get bookmark metadata: %v", err)
+		return
+	}
+	b.State = b.NextState()
+	b.UpdatedAt = time.Now()
+	err = w.store.UpdateBookmark(b)
+	if err != nil {
+		logrus.Errorf("Update reading state of bookmark %d: %v", b.Id, err)
+		return
+	}
+	bookmark.State = b.State
+	bookmark.UpdatedAt = b.UpdatedAt
+	w.bookmarks.UpdateBookmark(bookmark)
+}
+
+//restoreRevision restores bookmark to revision and shows restored bookmark
+func (w *Window) restoreRevision(revision *models.Revision) (*models.Bookmark, error) {
+	revisions, ok := w.store.(storage.RevisionStore)
+	if !ok {
+		return nil, storage.ErrNotSupported
+	}
+	err := revisions.RestoreRevision(revision.Id)
+	if err != nil {
+		logrus.Errorf("Restore revision: %v", err)
+		return nil, err
+	}
+	w.refreshAll()
+	bookmark, err := w.store.GetBookmark(revision.Bookmark)
+	if err != nil {
+		return nil, err
+	}
+	err = w.store.GetBookmarkMetadata(bookmark)
+	if err != nil {
+		return nil, err
+	}
+	w.metadata.setData(bookmark)
+	return bookmark, nil
+}
+
+//archiveBookmark saves snapshot of bookmarked page in background
+func (w *Window) archiveBookmark(bookmark *models.Bookmark, doneFunc func(created bool, err error)) {
+	snapshots, ok := w.store.(storage.SnapshotStore)
+	if !ok {
+		doneFunc(false, storage.ErrNotSupported)
+		return
+	}
+	go func() {
+		created := false
+		snapshot, err := external.ArchivePage(bookmark)
+		if err == nil {
+			created, err = snapshots.NewSnapshot(snapshot)
+		}
+		if err != nil {
+			logrus.Errorf("Archive page %s: %v", bookmark.Content, err)
+		}
+		w.app.QueueUpdateDraw(func() {
+			doneFunc(created, err)
+		})
+	}()
+}
+
+//openSnapshot writes snapshot html to temporary file and opens it in browser
+func (w *Window) openSnapshot(snapshot *models.Snapshot) error {
+	snapshots, ok := w.store.(storage.SnapshotStore)
+	if !ok {
+		return storage.ErrNotSupported
+	}
+	snapshot, err := snapshots.GetSnapshot(snapshot.Id)
+	if err != nil {
+		logrus.Errorf("Get snapshot: %v", err)
+		return err
+	}
+	file, err := ioutil.TempFile("", config.AppNameLower+"-snapshot-*.html")
+	if err != nil {
+		return fmt.Errorf("create file: %v", err)
+	}
+	_, err = file.Write(snapshot.Html)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("write file: %v", err)
+	}
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("write file: %v", err)
+	}
+	err = external.OpenUrlInBrowser("file://" + file.Name())
+	if err != nil {
+		logrus.Errorf("Open snapshot in browser: %v", err)
+	}
+	return err
+}
+
+func (w *Window) RefreshBookmarks() {
+	w.filter.Clear(w.store.Options().HideArchived)
+	err := w.showBookmarks()
+	if err != nil {
+		logrus.Errorf("Refresh bookmarks: %v", err)
+	}
 }
 
 func (w *Window) Run() error {
-	bookmarks, _ := w.db.GetAllBookmarks()
-	projects, _ := w.db.GetAllProjects("", false)
-	tags, _ := w.db.GetAllTags()
+	projects, _ := w.store.GetAllProjects("", false)
+	tags, _ := w.store.GetAllTags()
 
-	w.bookmarks.SetData(bookmarks)
+	w.RefreshBookmarks()
 	w.tags.SetData(tags)
 	w.project.SetData(projects)
+	w.refreshSearches()
 
 	return w.app.Run()
 }
@@ -545,12 +1112,145 @@ func (w *Window) quit() {
 }
 
 func (w *Window) modifyBookmark(filter *storage.Filter, modifier *storage.Modifier) (int, error) {
-	return w.db.BulkModify(filter, modifier)
+	bulk, ok := w.store.(storage.BulkStore)
+	if !ok {
+		return 0, storage.ErrNotSupported
+	}
+	count, err := bulk.BulkModify(filter, modifier)
+	if err != nil || count == 0 {
+		return count, err
+	}
+
+	err = w.showBookmarks()
+	if err != nil {
+		logrus.Errorf("Refresh bookmarks: %v", err)
+	}
+	tags, err := w.store.GetAllTags()
+	if err != nil {
+		logrus.Errorf("Refresh tags: %v", err)
+	} else {
+		w.tags.SetData(tags)
+	}
+	return count, nil
+}
+
+func (w *Window) previewModify(filter *storage.Filter) ([]*models.Bookmark, error) {
+	filter.Limit = -1
+	return w.store.FilterBookmarks(filter)
+}
+
+func (w *Window) editProject(project *models.Project) {
+	w.projectForm.SetProject(project.FullName())
+	w.addModal(w.projectForm, twidgets.ModalSizeMedium)
+}
+
+func (w *Window) editTag(tag string, action modals.TagAction) {
+	w.tagForm.SetTag(tag, action)
+	w.addModal(w.tagForm, twidgets.ModalSizeMedium)
+}
+
+func (w *Window) modifyTag(action modals.TagAction, tag, target string) (int, error) {
+	var count int
+	var err error
+	switch action {
+	case modals.TagActionRename:
+		count, err = w.store.RenameTag(tag, target)
+	case modals.TagActionMerge:
+		count, err = w.store.MergeTag(tag, target)
+	case modals.TagActionDelete:
+		count, err = w.store.DeleteTag(tag)
+	default:
+		err = fmt.Errorf("unknown action: %d", action)
+	}
+	if err != nil {
+		logrus.Errorf("Modify tag %s: %v", tag, err)
+		return count, err
+	}
+	w.refreshAll()
+	return count, nil
+}
+
+func (w *Window) modifyProject(action modals.ProjectAction, project, target string) (int, error) {
+	var count int
+	var err error
+	switch action {
+	case modals.ProjectActionRename:
+		count, err = w.store.RenameProject(project, target)
+	case modals.ProjectActionMove:
+		count, err = w.store.MoveProject(project, target)
+	case modals.ProjectActionMerge:
+		count, err = w.store.MergeProject(project, target)
+	default:
+		err = fmt.Errorf("unknown action: %d", action)
+	}
+	if err != nil {
+		logrus.Errorf("Modify project %s: %v", project, err)
+		return count, err
+	}
+
+	projects, err := w.store.GetAllProjects("", false)
+	if err != nil {
+		logrus.Errorf("Refresh projects: %v", err)
+	} else {
+		w.project.SetData(projects)
+	}
+	err = w.showBookmarks()
+	if err != nil {
+		logrus.Errorf("Refresh bookmarks: %v", err)
+	}
+	return count, nil
+}
+
+//stepHistory undoes or redoes latest change and refreshes views
+func (w *Window) stepHistory(undo bool) {
+	journal, ok := w.store.(storage.JournalStore)
+	if !ok {
+		logrus.Errorf("Undo / redo: %v", storage.ErrNotSupported)
+		return
+	}
+	var operation string
+	var err error
+	if undo {
+		operation, err = journal.Undo()
+	} else {
+		operation, err = journal.Redo()
+	}
+	if err != nil {
+		logrus.Errorf("Undo / redo: %v", err)
+		return
+	}
+	if undo {
+		logrus.Infof("Undo %s", operation)
+	} else {
+		logrus.Infof("Redo %s", operation)
+	}
+	w.refreshAll()
+}
+
+//refreshAll reloads bookmarks, tags, projects and saved search counts
+func (w *Window) refreshAll() {
+	err := w.showBookmarks()
+	if err != nil {
+		logrus.Errorf("Refresh bookmarks: %v", err)
+	}
+	tags, err := w.store.GetAllTags()
+	if err != nil {
+		logrus.Errorf("Refresh tags: %v", err)
+	} else {
+		w.tags.SetData(tags)
+	}
+	projects, err := w.store.GetAllProjects("", false)
+	if err != nil {
+		logrus.Errorf("Refresh projects: %v", err)
+	} else {
+		w.project.SetData(projects)
+	}
+	w.refreshSearches()
 }
 
 func (w *Window) autoComplete(key, value string) ([]string, error) {
 	if config.Configuration.AutoComplete {
-		return w.db.SearchKeyValue(key, value)
+		return w.store.SearchKeyValue(key, value)
 	} else {
 		return nil, nil
 	}
@@ -565,10 +1265,8 @@ func (w *Window) SortBookmarks(column string, sort twidgets.Sort) {
 		w.filter.SortDir = "DESC"
 	}
 
-	bookmarks, err := w.db.FilterBookmarks(w.filter)
+	err := w.showBookmarks()
 	if err != nil {
 		logrus.Error(err)
-	} else {
-		w.bookmarks.SetData(bookmarks)
 	}
 }
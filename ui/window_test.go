@@ -0,0 +1,98 @@
+/*
+ *   Copyright 2020 Tero Vierimaa
+ *
+ *   Licensed under the Apache License, Version 2.0 (the "License");
+ *   you may not use this file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing, software
+ *   distributed under the License is distributed on an "AS IS" BASIS,
+ *   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *   See the License for the specific language governing permissions and
+ *   limitations under the License.
+ */
+
+package ui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"tryffel.net/go/bookmarker/storage"
+	"tryffel.net/go/bookmarker/storage/models"
+)
+
+//exportStore is MemoryStore that exports bookmarks or fails with err
+type exportStore struct {
+	*storage.MemoryStore
+	err error
+}
+
+func (e *exportStore) PreviewImport(bookmarks []*models.Bookmark) (*storage.ImportPreview, error) {
+	return nil, storage.ErrNotSupported
+}
+
+func (e *exportStore) NewBookmarks(bookmarks []*models.Bookmark, AddTags []string,
+	duplicates storage.DuplicatePolicy) (*storage.ImportResult, error) {
+	return nil, storage.ErrNotSupported
+}
+
+func (e *exportStore) ImportBookmarks(bookmarks []*models.Bookmark, AddTags []string,
+	duplicates storage.DuplicatePolicy, progress func(done, total int)) (*storage.ImportResult, error) {
+	return nil, storage.ErrNotSupported
+}
+
+func (e *exportStore) ExportBookmarks(filter *storage.Filter) ([]*models.Bookmark, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	filter.Limit = -1
+	return e.FilterBookmarks(filter)
+}
+
+func Test_exportBookmarks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bookmarker-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	memory := storage.NewMemoryStore(storage.Options{})
+	err = memory.NewBookmark(&models.Bookmark{Name: "golang", Content: "https://golang.org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "bookmarks.json")
+
+	tests := []struct {
+		name   string
+		store  storage.BookmarkStore
+		format string
+		query  string
+		file   string
+		want   int
+		ok     bool
+	}{
+		{name: "export", store: &exportStore{MemoryStore: memory}, format: "json", file: file, want: 1, ok: true},
+		{name: "unknown format", store: &exportStore{MemoryStore: memory}, format: "pdf", file: file},
+		{name: "not supported", store: memory, format: "json", file: file},
+		{name: "invalid filter", store: &exportStore{MemoryStore: memory}, format: "json", query: "archived:maybe",
+			file: file},
+		{name: "store error", store: &exportStore{MemoryStore: memory, err: fmt.Errorf("disk I/O error")},
+			format: "json", file: file},
+		{name: "create file", store: &exportStore{MemoryStore: memory}, format: "json",
+			file: filepath.Join(dir, "missing", "bookmarks.json")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := exportBookmarks(tt.store, tt.format, tt.query, tt.file)
+			if (err == nil) != tt.ok || count != tt.want {
+				t.Errorf("exportBookmarks() = %d, %v, want %d, ok %t", count, err, tt.want, tt.ok)
+			}
+		})
+	}
+}